@@ -7,10 +7,23 @@ import (
 	"gopkg.in/yaml.v3"
 )
 type Config struct {
-	Server   ServerConfig   `yaml:"server"`
-	Database DatabaseConfig `yaml:"database"`
-	Sync     SyncConfig     `yaml:"sync"`
-	Logging  LoggingConfig  `yaml:"logging"`
+	Server          ServerConfig          `yaml:"server"`
+	Database        DatabaseConfig        `yaml:"database"`
+	Sync            SyncConfig            `yaml:"sync"`
+	Health          HealthConfig          `yaml:"health"`
+	Alert           AlertConfig           `yaml:"alert"`
+	Metrics         MetricsConfig         `yaml:"metrics"`
+	ACME            ACMEConfig            `yaml:"acme"`
+	DomainVerify    DomainVerifyConfig    `yaml:"domain_verify"`
+	Uniqueness      UniquenessConfig      `yaml:"uniqueness"`
+	Traffic         TrafficConfig         `yaml:"traffic"`
+	ResourceHistory ResourceHistoryConfig `yaml:"resource_history"`
+	Scheduler       SchedulerConfig       `yaml:"scheduler"`
+	Webhook         WebhookConfig         `yaml:"webhook"`
+	Console         ConsoleConfig         `yaml:"console"`
+	FileManager     FileManagerConfig     `yaml:"file_manager"`
+	Exec            ExecConfig            `yaml:"exec"`
+	Logging         LoggingConfig         `yaml:"logging"`
 }
 type ServerConfig struct {
 	Address       string `yaml:"address"`
@@ -28,6 +41,93 @@ type SyncConfig struct {
 	BatchSize     int `yaml:"batch_size"`
 	BatchInterval int `yaml:"batch_interval"`
 }
+type HealthConfig struct {
+	Interval          int `yaml:"interval"`
+	Timeout           int `yaml:"timeout"`
+	FailThreshold     int `yaml:"fail_threshold"`
+	RecoverThreshold  int `yaml:"recover_threshold"`
+	DegradedLatencyMs int `yaml:"degraded_latency_ms"`
+	RetentionDays     int `yaml:"retention_days"`
+}
+type AlertConfig struct {
+	EvalInterval   int `yaml:"eval_interval"`
+	RepeatInterval int `yaml:"repeat_interval"`
+	NotifyTimeout  int `yaml:"notify_timeout"`
+	RetentionDays  int `yaml:"retention_days"`
+}
+type MetricsConfig struct {
+	Enabled  bool     `yaml:"enabled"`
+	Token    string   `yaml:"token"`
+	AllowIPs []string `yaml:"allow_ips"`
+}
+type ACMEConfig struct {
+	Enabled            bool   `yaml:"enabled"`
+	DirectoryURL       string `yaml:"directory_url"`
+	Email              string `yaml:"email"`
+	CACertFile         string `yaml:"ca_cert_file"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+	ChallengeMode      string `yaml:"challenge_mode"`
+	HTTPAddress        string `yaml:"http_address"`
+	RenewBeforeDays    int    `yaml:"renew_before_days"`
+	CheckInterval      int    `yaml:"check_interval"`
+	Timeout            int    `yaml:"timeout"`
+}
+type DomainVerifyConfig struct {
+	Required  bool   `yaml:"required"`
+	Resolver  string `yaml:"resolver"`
+	TXTPrefix string `yaml:"txt_prefix"`
+	TokenTTL  int    `yaml:"token_ttl"`
+	ValidDays int    `yaml:"valid_days"`
+	Timeout   int    `yaml:"timeout"`
+}
+type UniquenessConfig struct {
+	DomainScope string `yaml:"domain_scope"`
+	IPScope     string `yaml:"ip_scope"`
+	PortScope   string `yaml:"port_scope"`
+}
+type TrafficConfig struct {
+	SampleRetention  int `yaml:"sample_retention"`
+	DefaultAnchorDay int `yaml:"default_anchor_day"`
+}
+type ResourceHistoryConfig struct {
+	RawHours    int `yaml:"raw_hours"`
+	FiveMinDays int `yaml:"five_min_days"`
+	HourlyDays  int `yaml:"hourly_days"`
+	DailyDays   int `yaml:"daily_days"`
+}
+type SchedulerConfig struct {
+	Timezone      string `yaml:"timezone"`
+	CatchupWindow int    `yaml:"catchup_window"`
+	RunRetention  int    `yaml:"run_retention"`
+}
+type WebhookConfig struct {
+	Timeout           int `yaml:"timeout"`
+	MaxAttempts       int `yaml:"max_attempts"`
+	RetryBaseSeconds  int `yaml:"retry_base_seconds"`
+	RetryMaxSeconds   int `yaml:"retry_max_seconds"`
+	DeliveryRetention int `yaml:"delivery_retention"`
+}
+type ConsoleConfig struct {
+	TokenTTL            int    `yaml:"token_ttl"`
+	SessionTTL          int    `yaml:"session_ttl"`
+	MaxSessions         int    `yaml:"max_sessions"`
+	MaxSessionsPerAdmin int    `yaml:"max_sessions_per_admin"`
+	NodeWSPath          string `yaml:"node_ws_path"`
+	SessionRetention    int    `yaml:"session_retention"`
+}
+type FileManagerConfig struct {
+	MaxUploadSize   int      `yaml:"max_upload_size"`
+	MaxDownloadSize int      `yaml:"max_download_size"`
+	MaxEditSize     int      `yaml:"max_edit_size"`
+	DeniedPaths     []string `yaml:"denied_paths"`
+	ReadOnlyPaths   []string `yaml:"read_only_paths"`
+}
+type ExecConfig struct {
+	DefaultTimeout int `yaml:"default_timeout"`
+	MaxTimeout     int `yaml:"max_timeout"`
+	MaxOutputSize  int `yaml:"max_output_size"`
+	MaxTargets     int `yaml:"max_targets"`
+}
 type LoggingConfig struct {
 	Level      string `yaml:"level"`
 	File       string `yaml:"file"`
@@ -80,6 +180,156 @@ func LoadConfig() error {
 	if AppConfig.Sync.BatchInterval <= 0 {
 		AppConfig.Sync.BatchInterval = 2
 	}
+	if AppConfig.Health.Interval <= 0 {
+		AppConfig.Health.Interval = 30
+	}
+	if AppConfig.Health.Timeout <= 0 {
+		AppConfig.Health.Timeout = 5
+	}
+	if AppConfig.Health.FailThreshold <= 0 {
+		AppConfig.Health.FailThreshold = 3
+	}
+	if AppConfig.Health.RecoverThreshold <= 0 {
+		AppConfig.Health.RecoverThreshold = 2
+	}
+	if AppConfig.Health.DegradedLatencyMs <= 0 {
+		AppConfig.Health.DegradedLatencyMs = 1000
+	}
+	if AppConfig.Health.RetentionDays <= 0 {
+		AppConfig.Health.RetentionDays = 30
+	}
+	if AppConfig.Alert.EvalInterval <= 0 {
+		AppConfig.Alert.EvalInterval = 60
+	}
+	if AppConfig.Alert.RepeatInterval <= 0 {
+		AppConfig.Alert.RepeatInterval = 240
+	}
+	if AppConfig.Alert.NotifyTimeout <= 0 {
+		AppConfig.Alert.NotifyTimeout = 10
+	}
+	if AppConfig.Alert.RetentionDays <= 0 {
+		AppConfig.Alert.RetentionDays = 90
+	}
+	if AppConfig.ACME.DirectoryURL == "" {
+		AppConfig.ACME.DirectoryURL = "https://acme-v02.api.letsencrypt.org/directory"
+	}
+	if AppConfig.ACME.ChallengeMode == "" {
+		AppConfig.ACME.ChallengeMode = "node"
+	}
+	if AppConfig.ACME.RenewBeforeDays <= 0 {
+		AppConfig.ACME.RenewBeforeDays = 30
+	}
+	if AppConfig.ACME.CheckInterval <= 0 {
+		AppConfig.ACME.CheckInterval = 3600
+	}
+	if AppConfig.ACME.Timeout <= 0 {
+		AppConfig.ACME.Timeout = 180
+	}
+	if AppConfig.DomainVerify.TXTPrefix == "" {
+		AppConfig.DomainVerify.TXTPrefix = "_lxdweb-verify"
+	}
+	if AppConfig.DomainVerify.TokenTTL <= 0 {
+		AppConfig.DomainVerify.TokenTTL = 72
+	}
+	if AppConfig.DomainVerify.Timeout <= 0 {
+		AppConfig.DomainVerify.Timeout = 10
+	}
+	if AppConfig.Uniqueness.DomainScope != "node" {
+		AppConfig.Uniqueness.DomainScope = "global"
+	}
+	if AppConfig.Uniqueness.IPScope != "node" {
+		AppConfig.Uniqueness.IPScope = "global"
+	}
+	if AppConfig.Uniqueness.PortScope != "global" {
+		AppConfig.Uniqueness.PortScope = "node"
+	}
+	if AppConfig.Traffic.SampleRetention <= 0 {
+		AppConfig.Traffic.SampleRetention = 30
+	}
+	if AppConfig.Traffic.DefaultAnchorDay < 1 || AppConfig.Traffic.DefaultAnchorDay > 31 {
+		AppConfig.Traffic.DefaultAnchorDay = 1
+	}
+	if AppConfig.ResourceHistory.RawHours <= 0 {
+		AppConfig.ResourceHistory.RawHours = 24
+	}
+	if AppConfig.ResourceHistory.FiveMinDays <= 0 {
+		AppConfig.ResourceHistory.FiveMinDays = 7
+	}
+	if AppConfig.ResourceHistory.HourlyDays <= 0 {
+		AppConfig.ResourceHistory.HourlyDays = 30
+	}
+	if AppConfig.ResourceHistory.DailyDays <= 0 {
+		AppConfig.ResourceHistory.DailyDays = 365
+	}
+	if AppConfig.Scheduler.Timezone == "" {
+		AppConfig.Scheduler.Timezone = "Local"
+	}
+	if AppConfig.Scheduler.CatchupWindow <= 0 {
+		AppConfig.Scheduler.CatchupWindow = 60
+	}
+	if AppConfig.Scheduler.RunRetention <= 0 {
+		AppConfig.Scheduler.RunRetention = 30
+	}
+	if AppConfig.Webhook.Timeout <= 0 {
+		AppConfig.Webhook.Timeout = 10
+	}
+	if AppConfig.Webhook.MaxAttempts <= 0 {
+		AppConfig.Webhook.MaxAttempts = 8
+	}
+	if AppConfig.Webhook.RetryBaseSeconds <= 0 {
+		AppConfig.Webhook.RetryBaseSeconds = 30
+	}
+	if AppConfig.Webhook.RetryMaxSeconds <= 0 {
+		AppConfig.Webhook.RetryMaxSeconds = 3600
+	}
+	if AppConfig.Webhook.DeliveryRetention <= 0 {
+		AppConfig.Webhook.DeliveryRetention = 30
+	}
+	if AppConfig.Console.TokenTTL <= 0 {
+		AppConfig.Console.TokenTTL = 60
+	}
+	if AppConfig.Console.SessionTTL <= 0 {
+		AppConfig.Console.SessionTTL = 3600
+	}
+	if AppConfig.Console.MaxSessions <= 0 {
+		AppConfig.Console.MaxSessions = 50
+	}
+	if AppConfig.Console.MaxSessionsPerAdmin <= 0 {
+		AppConfig.Console.MaxSessionsPerAdmin = 5
+	}
+	if AppConfig.Console.NodeWSPath == "" {
+		AppConfig.Console.NodeWSPath = "/console/ws"
+	}
+	if AppConfig.Console.SessionRetention <= 0 {
+		AppConfig.Console.SessionRetention = 90
+	}
+	if AppConfig.FileManager.MaxUploadSize <= 0 {
+		AppConfig.FileManager.MaxUploadSize = 50
+	}
+	if AppConfig.FileManager.MaxDownloadSize <= 0 {
+		AppConfig.FileManager.MaxDownloadSize = 200
+	}
+	if AppConfig.FileManager.MaxEditSize <= 0 {
+		AppConfig.FileManager.MaxEditSize = 1024
+	}
+	if AppConfig.FileManager.DeniedPaths == nil {
+		AppConfig.FileManager.DeniedPaths = []string{"/proc", "/sys", "/dev"}
+	}
+	if AppConfig.FileManager.ReadOnlyPaths == nil {
+		AppConfig.FileManager.ReadOnlyPaths = []string{"/bin", "/sbin", "/lib", "/lib64", "/usr", "/boot"}
+	}
+	if AppConfig.Exec.DefaultTimeout <= 0 {
+		AppConfig.Exec.DefaultTimeout = 60
+	}
+	if AppConfig.Exec.MaxTimeout <= 0 {
+		AppConfig.Exec.MaxTimeout = 1800
+	}
+	if AppConfig.Exec.MaxOutputSize <= 0 {
+		AppConfig.Exec.MaxOutputSize = 64
+	}
+	if AppConfig.Exec.MaxTargets <= 0 {
+		AppConfig.Exec.MaxTargets = 500
+	}
 	if AppConfig.Logging.Level == "" {
 		AppConfig.Logging.Level = "info"
 	}
@@ -126,6 +376,167 @@ sync:
   # 批次间隔（秒）
   batch_interval: 2
 
+health:
+  # 节点健康检查间隔（秒）
+  interval: 30
+  # 单次检查超时（秒）
+  timeout: 5
+  # 连续失败多少次判定为离线
+  fail_threshold: 3
+  # 连续成功多少次恢复为在线
+  recover_threshold: 2
+  # 响应延迟超过该值（毫秒）判定为降级
+  degraded_latency_ms: 1000
+  # 状态历史保留天数
+  retention_days: 30
+
+alert:
+  # 告警规则评估间隔（秒）
+  eval_interval: 60
+  # 持续告警的默认重复通知间隔（分钟），规则可单独设置
+  repeat_interval: 240
+  # 单次通知发送超时（秒）
+  notify_timeout: 10
+  # 已恢复告警事件保留天数
+  retention_days: 90
+
+metrics:
+  # 启用 Prometheus /metrics 接口
+  enabled: true
+  # 访问令牌（Authorization: Bearer <token>），留空则只按 IP 白名单校验
+  token: ""
+  # 允许访问的 IP 或网段（按连接来源地址，不信任 X-Forwarded-For），令牌正确时不受此限制
+  allow_ips:
+    - "127.0.0.1"
+    - "::1"
+
+acme:
+  # 启用反向代理证书 ACME 自动签发与续期
+  enabled: false
+  # ACME 目录地址，测试时可指向本地 Pebble，如 https://127.0.0.1:14000/dir
+  directory_url: "https://acme-v02.api.letsencrypt.org/directory"
+  # 账户联系邮箱
+  email: ""
+  # 额外信任的 CA 证书（PEM），用于本地测试 CA 的 HTTPS 目录
+  ca_cert_file: ""
+  # 跳过 ACME 目录的 TLS 校验（仅限测试）
+  insecure_skip_verify: false
+  # HTTP-01 验证方式: node（推送到节点，由 nginx 提供，节点需实现 /api/proxy/acme-challenge，见 docs/node_api.md）| standalone（由 lxdweb 直接响应）
+  challenge_mode: "node"
+  # standalone 模式下额外监听的 HTTP 地址，如 ":80"；留空则只在主服务的 /.well-known/acme-challenge/ 响应
+  http_address: ""
+  # 证书到期前多少天自动续期
+  renew_before_days: 30
+  # 证书检查与续期间隔（秒），检查时会连接节点443端口读取实际提供的证书
+  check_interval: 3600
+  # 单次签发超时（秒）
+  timeout: 180
+
+domain_verify:
+  # 创建反向代理时要求域名已通过所有权验证
+  required: false
+  # 验证使用的 DNS 服务器，如 "127.0.0.1:5353"；留空使用系统解析
+  resolver: ""
+  # TXT 验证记录前缀，记录名为 <前缀>.<域名>
+  txt_prefix: "_lxdweb-verify"
+  # 验证令牌有效期（小时）
+  token_ttl: 72
+  # 验证通过后的有效天数，0 表示长期有效
+  valid_days: 0
+  # 单次验证超时（秒）
+  timeout: 10
+
+uniqueness:
+  # 唯一性检查范围: global 为全部节点，node 为仅当前节点
+  # 域名：同一域名解析只能指向一个节点，默认全局唯一
+  domain_scope: "global"
+  # 公网IP：同一地址不能同时绑定在多个节点上，默认全局唯一
+  ip_scope: "global"
+  # NAT外部端口：各节点公网IP不同时按节点检查；多个节点共用出口IP时设为 global
+  port_scope: "node"
+
+traffic:
+  # 流量采样明细保留天数，账期汇总长期保留
+  sample_retention: 30
+  # 默认账期起始日（1-31），大于当月天数时取当月最后一天
+  default_anchor_day: 1
+
+resource_history:
+  # 容器和节点资源使用历史，按精度逐级汇总，超过保留时间的数据自动删除
+  # 原始采样保留小时数
+  raw_hours: 24
+  # 5分钟汇总保留天数
+  five_min_days: 7
+  # 小时汇总保留天数
+  hourly_days: 30
+  # 天汇总保留天数
+  daily_days: 365
+
+scheduler:
+  # 计划任务默认时区，任务未单独设置时使用，Local 为服务器时区
+  timezone: "Local"
+  # 错过执行时间后允许补执行的窗口（分钟），仅对补执行策略为 run_once 的任务生效
+  catchup_window: 60
+  # 执行记录保留天数
+  run_retention: 30
+
+webhook:
+  # 单次投递超时（秒）
+  timeout: 10
+  # 最大投递次数，超过后标记为失败，可手动重新投递
+  max_attempts: 8
+  # 重试退避基数（秒），第 n 次失败后等待 基数*2^(n-1) 秒
+  retry_base_seconds: 30
+  # 重试最大间隔（秒）
+  retry_max_seconds: 3600
+  # 投递记录保留天数
+  delivery_retention: 30
+
+console:
+  # 控制台令牌有效期（秒），打开控制台页面后需在此时间内建立连接，令牌只能使用一次
+  token_ttl: 60
+  # 单个控制台会话最长时长（秒），同时作为节点控制台令牌的有效期
+  session_ttl: 3600
+  # 全局最大并发控制台会话数
+  max_sessions: 50
+  # 每个管理员最大并发控制台会话数
+  max_sessions_per_admin: 5
+  # 节点控制台 WebSocket 路径，连接时附带 ?token=<节点令牌>，接口约定见 docs/node_api.md
+  node_ws_path: "/console/ws"
+  # 会话记录保留天数
+  session_retention: 90
+
+file_manager:
+  # 单个上传文件大小上限（MB）
+  max_upload_size: 50
+  # 单个下载文件大小上限（MB）
+  max_download_size: 200
+  # 在线编辑文件大小上限（KB）
+  max_edit_size: 1024
+  # 禁止访问的路径（含子路径）
+  denied_paths:
+    - /proc
+    - /sys
+    - /dev
+  # 只读路径（含子路径），禁止上传、编辑、删除和新建目录
+  read_only_paths:
+    - /bin
+    - /sbin
+    - /lib
+    - /lib64
+    - /usr
+    - /boot
+
+exec:
+  # 命令默认超时时间（秒）
+  default_timeout: 60
+  # 允许设置的最大超时时间（秒）
+  max_timeout: 1800
+  # 每个容器保存的 stdout/stderr 上限（KB），超出部分截断
+  max_output_size: 64
+  # 单次执行最多目标容器数
+  max_targets: 500
+
 logging:
   # 日志级别: debug | info | warn | error
   level: "info"
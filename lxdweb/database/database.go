@@ -26,6 +26,7 @@ func InitDB() {
 	err = DB.AutoMigrate(
 		&models.Admin{},
 		&models.Node{},
+		&models.NodeStatusHistory{},
 		&models.Container{},
 		&models.ContainerCache{},
 		&models.SyncTask{},
@@ -33,11 +34,39 @@ func InitDB() {
 		&models.NATRule{},
 		&models.NATRuleCache{},
 		&models.NATSyncTask{},
+		&models.NATPortPool{},
+		&models.NATPortQuota{},
 		&models.IPv6BindingCache{},
 		&models.IPv6SyncTask{},
+		&models.IPv4BindingCache{},
+		&models.IPv4SyncTask{},
+		&models.IPPool{},
+		&models.IPAddress{},
 		&models.ProxyConfigCache{},
 		&models.ProxySyncTask{},
+		&models.ProxyCertificate{},
+		&models.ACMEAccount{},
+		&models.DomainVerification{},
+		&models.TrafficSample{},
+		&models.TrafficPeriod{},
+		&models.TrafficBillingCycle{},
+		&models.ResourceMetric{},
+		&models.TrafficPolicy{},
+		&models.TrafficPolicyBinding{},
+		&models.TrafficEnforcement{},
+		&models.BatchTask{},
+		&models.ScheduledTask{},
+		&models.ScheduledTaskRun{},
+		&models.Webhook{},
+		&models.WebhookDelivery{},
+		&models.ConsoleSession{},
+		&models.UserDataTemplate{},
+		&models.SSHKey{},
 		&models.OperationLog{},
+		&models.AlertRule{},
+		&models.AlertChannel{},
+		&models.AlertEvent{},
+		&models.AlertSilence{},
 		&models.Image{},
 	)
 	if err != nil {
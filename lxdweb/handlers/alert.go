@@ -0,0 +1,652 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"lxdweb/database"
+	"lxdweb/models"
+	"lxdweb/pkg/logger"
+	"lxdweb/services"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const maskedSecret = "******"
+
+// AlertsPage 告警管理页面
+// @Summary 告警管理页面
+// @Description 显示告警事件、规则、通知渠道和静默管理页面
+// @Tags 告警管理
+// @Produce html
+// @Success 200 {string} string "HTML页面"
+// @Router /alerts [get]
+func AlertsPage(c *gin.Context) {
+	session := sessions.Default(c)
+	username := session.Get("username")
+	c.HTML(http.StatusOK, "alerts.html", gin.H{
+		"title":    "告警管理 - LXD管理后台",
+		"username": username,
+	})
+}
+
+// GetAlertEvents 获取告警事件
+// @Summary 获取告警事件
+// @Description 查询告警事件，默认返回未恢复的事件
+// @Tags 告警管理
+// @Produce json
+// @Param status query string false "事件状态(pending/firing/resolved/all)，默认 pending+firing"
+// @Param node_id query string false "节点ID"
+// @Param rule_id query string false "规则ID"
+// @Param limit query int false "返回条数，默认100，最大1000"
+// @Success 200 {object} map[string]interface{} "成功返回事件列表"
+// @Router /api/alerts/events [get]
+func GetAlertEvents(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+
+	query := database.DB.Order("started_at desc").Limit(limit)
+	switch status := c.Query("status"); status {
+	case "":
+		query = query.Where("status IN ?", []string{models.AlertStatusPending, models.AlertStatusFiring})
+	case "all":
+	default:
+		query = query.Where("status = ?", status)
+	}
+	if nodeID := c.Query("node_id"); nodeID != "" {
+		query = query.Where("node_id = ?", nodeID)
+	}
+	if ruleID := c.Query("rule_id"); ruleID != "" {
+		query = query.Where("rule_id = ?", ruleID)
+	}
+
+	var events []models.AlertEvent
+	if err := query.Find(&events).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "查询失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "success",
+		"data": events,
+	})
+}
+
+// GetAlertRules 获取告警规则列表
+// @Summary 获取告警规则列表
+// @Description 获取所有告警规则
+// @Tags 告警管理
+// @Produce json
+// @Success 200 {object} map[string]interface{} "成功返回规则列表"
+// @Router /api/alerts/rules [get]
+func GetAlertRules(c *gin.Context) {
+	var rules []models.AlertRule
+	if err := database.DB.Order("id asc").Find(&rules).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "查询失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "success",
+		"data": rules,
+	})
+}
+
+// CreateAlertRule 创建告警规则
+// @Summary 创建告警规则
+// @Description 创建告警规则，duration 为条件持续多少秒后触发，repeat_interval 为持续告警的重复通知间隔（分钟）
+// @Tags 告警管理
+// @Accept json
+// @Produce json
+// @Param body body models.AlertRuleRequest true "规则参数"
+// @Success 200 {object} map[string]interface{} "创建成功"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Router /api/alerts/rules [post]
+func CreateAlertRule(c *gin.Context) {
+	var req models.AlertRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	rule := models.AlertRule{Enabled: true}
+	applyAlertRuleRequest(&rule, req)
+	if err := database.DB.Create(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "创建失败: " + err.Error(),
+		})
+		return
+	}
+
+	recordAlertOperation(c, "alert_rule_create", "alert_rule", rule.ID, rule)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "创建成功",
+		"data": rule,
+	})
+}
+
+// UpdateAlertRule 更新告警规则
+// @Summary 更新告警规则
+// @Description 更新指定告警规则
+// @Tags 告警管理
+// @Accept json
+// @Produce json
+// @Param id path string true "规则ID"
+// @Param body body models.AlertRuleRequest true "规则参数"
+// @Success 200 {object} map[string]interface{} "更新成功"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Failure 404 {object} map[string]interface{} "规则不存在"
+// @Router /api/alerts/rules/{id} [put]
+func UpdateAlertRule(c *gin.Context) {
+	var rule models.AlertRule
+	if err := database.DB.First(&rule, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "规则不存在",
+		})
+		return
+	}
+
+	var req models.AlertRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	applyAlertRuleRequest(&rule, req)
+	if err := database.DB.Save(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "更新失败: " + err.Error(),
+		})
+		return
+	}
+
+	recordAlertOperation(c, "alert_rule_update", "alert_rule", rule.ID, rule)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "更新成功",
+		"data": rule,
+	})
+}
+
+// DeleteAlertRule 删除告警规则
+// @Summary 删除告警规则
+// @Description 删除告警规则，该规则未恢复的事件会在下次评估时关闭
+// @Tags 告警管理
+// @Produce json
+// @Param id path string true "规则ID"
+// @Success 200 {object} map[string]interface{} "删除成功"
+// @Failure 404 {object} map[string]interface{} "规则不存在"
+// @Router /api/alerts/rules/{id} [delete]
+func DeleteAlertRule(c *gin.Context) {
+	var rule models.AlertRule
+	if err := database.DB.First(&rule, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "规则不存在",
+		})
+		return
+	}
+
+	if err := database.DB.Delete(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "删除失败: " + err.Error(),
+		})
+		return
+	}
+
+	recordAlertOperation(c, "alert_rule_delete", "alert_rule", rule.ID, gin.H{"name": rule.Name})
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "删除成功",
+	})
+}
+
+func applyAlertRuleRequest(rule *models.AlertRule, req models.AlertRuleRequest) {
+	rule.Name = req.Name
+	rule.Description = req.Description
+	rule.Metric = req.Metric
+	rule.Operator = req.Operator
+	if rule.Operator == "" {
+		rule.Operator = "gte"
+	}
+	rule.Threshold = req.Threshold
+	rule.Duration = req.Duration
+	rule.RepeatInterval = req.RepeatInterval
+	rule.Severity = req.Severity
+	if rule.Severity == "" {
+		rule.Severity = "warning"
+	}
+	rule.NodeID = req.NodeID
+	rule.NodeGroup = req.NodeGroup
+
+	ids := make([]string, 0, len(req.ChannelIDs))
+	for _, id := range req.ChannelIDs {
+		ids = append(ids, strconv.FormatUint(uint64(id), 10))
+	}
+	rule.ChannelIDs = strings.Join(ids, ",")
+
+	if req.Enabled != nil {
+		rule.Enabled = *req.Enabled
+	}
+}
+
+// GetAlertChannels 获取通知渠道列表
+// @Summary 获取通知渠道列表
+// @Description 获取所有告警通知渠道，密码和令牌会被隐藏
+// @Tags 告警管理
+// @Produce json
+// @Success 200 {object} map[string]interface{} "成功返回渠道列表"
+// @Router /api/alerts/channels [get]
+func GetAlertChannels(c *gin.Context) {
+	var channels []models.AlertChannel
+	if err := database.DB.Order("id asc").Find(&channels).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "查询失败",
+		})
+		return
+	}
+
+	result := make([]gin.H, 0, len(channels))
+	for _, ch := range channels {
+		result = append(result, alertChannelOutput(ch))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "success",
+		"data": result,
+	})
+}
+
+// CreateAlertChannel 创建通知渠道
+// @Summary 创建通知渠道
+// @Description 创建 webhook、smtp、telegram 或 log 类型的告警通知渠道
+// @Tags 告警管理
+// @Accept json
+// @Produce json
+// @Param body body models.AlertChannelRequest true "渠道参数"
+// @Success 200 {object} map[string]interface{} "创建成功"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Router /api/alerts/channels [post]
+func CreateAlertChannel(c *gin.Context) {
+	var req models.AlertChannelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	ch := models.AlertChannel{Enabled: true}
+	if err := applyAlertChannelRequest(&ch, req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  err.Error(),
+		})
+		return
+	}
+	if err := database.DB.Create(&ch).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "创建失败: " + err.Error(),
+		})
+		return
+	}
+
+	recordAlertOperation(c, "alert_channel_create", "alert_channel", ch.ID, gin.H{"name": ch.Name, "type": ch.Type})
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "创建成功",
+		"data": alertChannelOutput(ch),
+	})
+}
+
+// UpdateAlertChannel 更新通知渠道
+// @Summary 更新通知渠道
+// @Description 更新通知渠道，密码和令牌传入 ****** 时保持原值
+// @Tags 告警管理
+// @Accept json
+// @Produce json
+// @Param id path string true "渠道ID"
+// @Param body body models.AlertChannelRequest true "渠道参数"
+// @Success 200 {object} map[string]interface{} "更新成功"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Failure 404 {object} map[string]interface{} "渠道不存在"
+// @Router /api/alerts/channels/{id} [put]
+func UpdateAlertChannel(c *gin.Context) {
+	var ch models.AlertChannel
+	if err := database.DB.First(&ch, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "渠道不存在",
+		})
+		return
+	}
+
+	var req models.AlertChannelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	var oldCfg models.AlertChannelConfig
+	json.Unmarshal([]byte(ch.Config), &oldCfg)
+	if req.Config.Password == maskedSecret {
+		req.Config.Password = oldCfg.Password
+	}
+	if req.Config.BotToken == maskedSecret {
+		req.Config.BotToken = oldCfg.BotToken
+	}
+
+	if err := applyAlertChannelRequest(&ch, req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  err.Error(),
+		})
+		return
+	}
+	if err := database.DB.Save(&ch).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "更新失败: " + err.Error(),
+		})
+		return
+	}
+
+	recordAlertOperation(c, "alert_channel_update", "alert_channel", ch.ID, gin.H{"name": ch.Name, "type": ch.Type})
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "更新成功",
+		"data": alertChannelOutput(ch),
+	})
+}
+
+// DeleteAlertChannel 删除通知渠道
+// @Summary 删除通知渠道
+// @Description 删除告警通知渠道
+// @Tags 告警管理
+// @Produce json
+// @Param id path string true "渠道ID"
+// @Success 200 {object} map[string]interface{} "删除成功"
+// @Failure 404 {object} map[string]interface{} "渠道不存在"
+// @Router /api/alerts/channels/{id} [delete]
+func DeleteAlertChannel(c *gin.Context) {
+	var ch models.AlertChannel
+	if err := database.DB.First(&ch, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "渠道不存在",
+		})
+		return
+	}
+
+	if err := database.DB.Delete(&ch).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "删除失败: " + err.Error(),
+		})
+		return
+	}
+
+	recordAlertOperation(c, "alert_channel_delete", "alert_channel", ch.ID, gin.H{"name": ch.Name, "type": ch.Type})
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "删除成功",
+	})
+}
+
+// TestAlertChannel 测试通知渠道
+// @Summary 测试通知渠道
+// @Description 通过指定渠道发送一条测试告警
+// @Tags 告警管理
+// @Produce json
+// @Param id path string true "渠道ID"
+// @Success 200 {object} map[string]interface{} "发送成功"
+// @Failure 404 {object} map[string]interface{} "渠道不存在"
+// @Router /api/alerts/channels/{id}/test [post]
+func TestAlertChannel(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var ch models.AlertChannel
+	if err := database.DB.First(&ch, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "渠道不存在",
+		})
+		return
+	}
+
+	now := time.Now()
+	err := services.SendAlertToChannel(ch, services.AlertMessage{
+		Title:     "[TEST] LXD 管理后台告警测试",
+		Text:      fmt.Sprintf("这是一条来自渠道 %s 的测试消息\n发送时间: %s", ch.Name, now.Format("2006-01-02 15:04:05")),
+		Status:    "test",
+		Severity:  "info",
+		StartedAt: now,
+		Time:      now,
+	})
+	if err != nil {
+		logger.Global.Warn(ctx, "告警渠道测试失败",
+			zap.Uint("channel_id", ch.ID),
+			zap.Error(err),
+			zap.String("action", "test_alert_channel"))
+		c.JSON(http.StatusOK, gin.H{
+			"code": 500,
+			"msg":  "发送失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "测试消息已发送",
+	})
+}
+
+func applyAlertChannelRequest(ch *models.AlertChannel, req models.AlertChannelRequest) error {
+	cfg, err := json.Marshal(req.Config)
+	if err != nil {
+		return err
+	}
+	ch.Name = req.Name
+	ch.Type = req.Type
+	ch.Config = string(cfg)
+	if req.Enabled != nil {
+		ch.Enabled = *req.Enabled
+	}
+	if _, err := services.NewAlertNotifier(*ch); err != nil {
+		return err
+	}
+	return nil
+}
+
+func alertChannelOutput(ch models.AlertChannel) gin.H {
+	var cfg models.AlertChannelConfig
+	json.Unmarshal([]byte(ch.Config), &cfg)
+	if cfg.Password != "" {
+		cfg.Password = maskedSecret
+	}
+	if cfg.BotToken != "" {
+		cfg.BotToken = maskedSecret
+	}
+	return gin.H{
+		"id":         ch.ID,
+		"name":       ch.Name,
+		"type":       ch.Type,
+		"config":     cfg,
+		"enabled":    ch.Enabled,
+		"created_at": ch.CreatedAt,
+		"updated_at": ch.UpdatedAt,
+	}
+}
+
+// GetAlertSilences 获取告警静默列表
+// @Summary 获取告警静默列表
+// @Description 获取告警静默，默认只返回未过期的静默
+// @Tags 告警管理
+// @Produce json
+// @Param all query bool false "是否包含已过期静默"
+// @Success 200 {object} map[string]interface{} "成功返回静默列表"
+// @Router /api/alerts/silences [get]
+func GetAlertSilences(c *gin.Context) {
+	query := database.DB.Order("ends_at desc")
+	if c.Query("all") != "true" {
+		query = query.Where("ends_at > ?", time.Now())
+	}
+
+	var silences []models.AlertSilence
+	if err := query.Find(&silences).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "查询失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "success",
+		"data": silences,
+	})
+}
+
+// CreateAlertSilence 创建告警静默
+// @Summary 创建告警静默
+// @Description 在指定时间段内屏蔽匹配规则、节点或容器的告警通知，rule_id/node_id 为0、hostname 为空表示匹配全部
+// @Tags 告警管理
+// @Accept json
+// @Produce json
+// @Param body body models.AlertSilenceRequest true "静默参数"
+// @Success 200 {object} map[string]interface{} "创建成功"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Router /api/alerts/silences [post]
+func CreateAlertSilence(c *gin.Context) {
+	var req models.AlertSilenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	startsAt := time.Now()
+	if req.StartsAt != nil {
+		startsAt = *req.StartsAt
+	}
+	if !req.EndsAt.After(startsAt) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "结束时间必须晚于开始时间",
+		})
+		return
+	}
+
+	silence := models.AlertSilence{
+		RuleID:    req.RuleID,
+		NodeID:    req.NodeID,
+		Hostname:  req.Hostname,
+		Reason:    req.Reason,
+		StartsAt:  startsAt,
+		EndsAt:    req.EndsAt,
+		CreatedBy: currentAdminID(c),
+	}
+	if err := database.DB.Create(&silence).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "创建失败: " + err.Error(),
+		})
+		return
+	}
+
+	recordAlertOperation(c, "alert_silence_create", "alert_silence", silence.ID, silence)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "创建成功",
+		"data": silence,
+	})
+}
+
+// DeleteAlertSilence 删除告警静默
+// @Summary 删除告警静默
+// @Description 提前结束告警静默
+// @Tags 告警管理
+// @Produce json
+// @Param id path string true "静默ID"
+// @Success 200 {object} map[string]interface{} "删除成功"
+// @Failure 404 {object} map[string]interface{} "静默不存在"
+// @Router /api/alerts/silences/{id} [delete]
+func DeleteAlertSilence(c *gin.Context) {
+	var silence models.AlertSilence
+	if err := database.DB.First(&silence, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "静默不存在",
+		})
+		return
+	}
+
+	if err := database.DB.Delete(&silence).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "删除失败: " + err.Error(),
+		})
+		return
+	}
+
+	recordAlertOperation(c, "alert_silence_delete", "alert_silence", silence.ID, gin.H{"reason": silence.Reason})
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "删除成功",
+	})
+}
+
+func recordAlertOperation(c *gin.Context, opType, targetType string, targetID uint, details interface{}) {
+	services.RecordOperation(services.AuditEntry{
+		AdminID:       currentAdminID(c),
+		OperationType: opType,
+		TargetType:    targetType,
+		TargetID:      targetID,
+		Details:       details,
+		IPAddress:     c.ClientIP(),
+	})
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"lxdweb/database"
+	"lxdweb/models"
+	"lxdweb/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// currentAdminID 获取当前登录管理员ID
+func currentAdminID(c *gin.Context) uint {
+	session := sessions.Default(c)
+	if adminID, ok := session.Get("admin_id").(uint); ok {
+		return adminID
+	}
+	return 0
+}
+
+// recordOperation 以当前管理员和客户端IP写入审计日志
+func recordOperation(c *gin.Context, opType, targetType string, targetID uint, details interface{}) {
+	services.RecordOperation(services.AuditEntry{
+		AdminID:       currentAdminID(c),
+		OperationType: opType,
+		TargetType:    targetType,
+		TargetID:      targetID,
+		Details:       details,
+		IPAddress:     c.ClientIP(),
+	})
+}
+
+// GetOperationLogs 获取操作审计日志
+// @Summary 获取操作审计日志
+// @Description 查询操作审计日志，支持按操作类型、目标类型和目标ID过滤
+// @Tags 系统管理
+// @Produce json
+// @Param operation_type query string false "操作类型"
+// @Param target_type query string false "目标类型"
+// @Param target_id query string false "目标ID"
+// @Param limit query int false "返回条数，默认100，最大1000"
+// @Success 200 {object} map[string]interface{} "成功返回日志列表"
+// @Failure 500 {object} map[string]interface{} "查询失败"
+// @Router /api/audit-logs [get]
+func GetOperationLogs(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+
+	var logs []models.OperationLog
+	query := database.DB.Order("created_at desc").Limit(limit)
+
+	if opType := c.Query("operation_type"); opType != "" {
+		query = query.Where("operation_type = ?", opType)
+	}
+	if targetType := c.Query("target_type"); targetType != "" {
+		query = query.Where("target_type = ?", targetType)
+	}
+	if targetID := c.Query("target_id"); targetID != "" {
+		query = query.Where("target_id = ?", targetID)
+	}
+
+	if err := query.Find(&logs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "查询失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "success",
+		"data": logs,
+	})
+}
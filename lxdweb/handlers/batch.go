@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"lxdweb/database"
+	"lxdweb/models"
+	"lxdweb/pkg/logger"
+	"lxdweb/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// BatchContainerAction 批量容器操作
+// @Summary 批量容器操作
+// @Description 对指定节点、分组、地域或标签下的容器批量执行启动、停止、重启、暂停、恢复操作
+// @Tags 容器管理
+// @Accept json
+// @Produce json
+// @Param body body models.BatchContainerRequest true "批量操作参数"
+// @Success 200 {object} map[string]interface{} "任务已创建"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Failure 404 {object} map[string]interface{} "没有匹配的容器"
+// @Failure 409 {object} map[string]interface{} "指定的节点处于维护模式"
+// @Router /api/containers/batch [post]
+func BatchContainerAction(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req models.BatchContainerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	sel := services.NodeSelector{Group: req.Group, Region: req.Region, Tags: req.Tags}
+	if sel.IsEmpty() && len(req.NodeIDs) == 0 && len(req.Hostnames) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "请指定节点、分组、地域、标签或容器名",
+		})
+		return
+	}
+
+	targets, err := services.ResolveBatchTargets(sel, req.NodeIDs, req.Hostnames)
+	if err != nil {
+		status := http.StatusNotFound
+		if errors.Is(err, services.ErrNodeInMaintenance) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{
+			"code": status,
+			"msg":  err.Error(),
+		})
+		return
+	}
+	if len(targets) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "没有匹配的容器",
+		})
+		return
+	}
+
+	fn, err := services.ContainerActionFunc(req.Action)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  err.Error(),
+		})
+		return
+	}
+
+	selector, _ := json.Marshal(req)
+	task, err := services.StartBatchTask("container_"+req.Action, string(selector), targets, fn)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "创建任务失败: " + err.Error(),
+		})
+		return
+	}
+
+	logger.Global.Info(ctx, "批量容器操作任务已创建",
+		zap.Uint("task_id", task.ID),
+		zap.String("operation", req.Action),
+		zap.Int("total", len(targets)),
+		zap.String("action", "batch_container_action"))
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  fmt.Sprintf("批量任务已创建，共 %d 个容器", len(targets)),
+		"data": task,
+	})
+}
+
+// GetBatchTasks 获取批量任务列表
+// @Summary 获取批量任务列表
+// @Description 查询最近50条批量操作任务记录
+// @Tags 容器管理
+// @Produce json
+// @Param action query string false "操作类型"
+// @Success 200 {object} map[string]interface{} "成功返回任务列表"
+// @Failure 500 {object} map[string]interface{} "查询失败"
+// @Router /api/batch/tasks [get]
+func GetBatchTasks(c *gin.Context) {
+	var tasks []models.BatchTask
+	query := database.DB.Omit("results").Order("created_at desc").Limit(50)
+
+	if action := c.Query("action"); action != "" {
+		query = query.Where("action = ?", action)
+	}
+
+	if err := query.Find(&tasks).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "查询失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "success",
+		"data": tasks,
+	})
+}
+
+// GetBatchTask 获取批量任务详情
+// @Summary 获取批量任务详情
+// @Description 根据ID获取批量任务及每个目标的执行结果
+// @Tags 容器管理
+// @Produce json
+// @Param id path string true "任务ID"
+// @Success 200 {object} map[string]interface{} "成功返回任务详情"
+// @Failure 404 {object} map[string]interface{} "任务不存在"
+// @Router /api/batch/tasks/{id} [get]
+func GetBatchTask(c *gin.Context) {
+	id := c.Param("id")
+	var task models.BatchTask
+	if err := database.DB.First(&task, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "任务不存在",
+		})
+		return
+	}
+
+	results := []models.BatchItemResult{}
+	if task.Results != "" {
+		json.Unmarshal([]byte(task.Results), &results)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "success",
+		"data": gin.H{
+			"task":    task,
+			"results": results,
+		},
+	})
+}
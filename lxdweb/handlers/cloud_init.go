@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"lxdweb/database"
+	"lxdweb/models"
+	"lxdweb/services"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetUserDataTemplates 获取 user-data 模板列表
+// @Summary 获取 user-data 模板列表
+// @Description 获取所有可复用的 cloud-init user-data 模板
+// @Tags 容器管理
+// @Produce json
+// @Success 200 {object} map[string]interface{} "成功返回模板列表"
+// @Router /api/user-data-templates [get]
+func GetUserDataTemplates(c *gin.Context) {
+	var templates []models.UserDataTemplate
+	database.DB.Order("name").Find(&templates)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "success",
+		"data": templates,
+	})
+}
+
+// CreateUserDataTemplate 创建 user-data 模板
+// @Summary 创建 user-data 模板
+// @Description 创建可复用的 cloud-init user-data 模板，内容须为 #cloud-config YAML 或 #! 脚本
+// @Tags 容器管理
+// @Accept json
+// @Produce json
+// @Param body body models.UserDataTemplateRequest true "模板名称、说明和内容"
+// @Success 200 {object} map[string]interface{} "创建成功"
+// @Failure 400 {object} map[string]interface{} "参数错误或内容无效"
+// @Router /api/user-data-templates [post]
+func CreateUserDataTemplate(c *gin.Context) {
+	var req models.UserDataTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "参数错误: " + err.Error(),
+		})
+		return
+	}
+	if _, err := services.ValidateUserData(req.Content); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  err.Error(),
+		})
+		return
+	}
+
+	tmpl := models.UserDataTemplate{
+		Name:        strings.TrimSpace(req.Name),
+		Description: req.Description,
+		Content:     req.Content,
+		CreatedBy:   currentAdminID(c),
+	}
+	if err := database.DB.Create(&tmpl).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "创建失败，模板名称可能已存在",
+		})
+		return
+	}
+
+	recordOperation(c, "user_data_template_create", "user_data_template", tmpl.ID, gin.H{"name": tmpl.Name})
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "创建成功",
+		"data": tmpl,
+	})
+}
+
+// UpdateUserDataTemplate 更新 user-data 模板
+// @Summary 更新 user-data 模板
+// @Description 更新 user-data 模板，已创建的容器不受影响
+// @Tags 容器管理
+// @Accept json
+// @Produce json
+// @Param id path string true "模板ID"
+// @Param body body models.UserDataTemplateRequest true "模板名称、说明和内容"
+// @Success 200 {object} map[string]interface{} "更新成功"
+// @Failure 400 {object} map[string]interface{} "参数错误或内容无效"
+// @Failure 404 {object} map[string]interface{} "模板不存在"
+// @Router /api/user-data-templates/{id} [put]
+func UpdateUserDataTemplate(c *gin.Context) {
+	var tmpl models.UserDataTemplate
+	if err := database.DB.First(&tmpl, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "模板不存在",
+		})
+		return
+	}
+	var req models.UserDataTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "参数错误: " + err.Error(),
+		})
+		return
+	}
+	if _, err := services.ValidateUserData(req.Content); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  err.Error(),
+		})
+		return
+	}
+
+	tmpl.Name = strings.TrimSpace(req.Name)
+	tmpl.Description = req.Description
+	tmpl.Content = req.Content
+	if err := database.DB.Save(&tmpl).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "更新失败，模板名称可能已存在",
+		})
+		return
+	}
+
+	recordOperation(c, "user_data_template_update", "user_data_template", tmpl.ID, gin.H{"name": tmpl.Name})
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "更新成功",
+		"data": tmpl,
+	})
+}
+
+// DeleteUserDataTemplate 删除 user-data 模板
+// @Summary 删除 user-data 模板
+// @Description 删除 user-data 模板
+// @Tags 容器管理
+// @Produce json
+// @Param id path string true "模板ID"
+// @Success 200 {object} map[string]interface{} "删除成功"
+// @Failure 404 {object} map[string]interface{} "模板不存在"
+// @Router /api/user-data-templates/{id} [delete]
+func DeleteUserDataTemplate(c *gin.Context) {
+	var tmpl models.UserDataTemplate
+	if err := database.DB.First(&tmpl, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "模板不存在",
+		})
+		return
+	}
+	database.DB.Delete(&tmpl)
+
+	recordOperation(c, "user_data_template_delete", "user_data_template", tmpl.ID, gin.H{"name": tmpl.Name})
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "删除成功",
+	})
+}
+
+// ValidateCloudInit 校验 cloud-init 参数
+// @Summary 校验 cloud-init 参数
+// @Description 在创建或重装前校验 SSH 公钥、user-data 和 vendor-data，返回 cloud-config 的顶层配置项
+// @Tags 容器管理
+// @Accept json
+// @Produce json
+// @Param body body models.CloudInitOptions true "cloud-init 参数"
+// @Success 200 {object} map[string]interface{} "校验通过"
+// @Failure 400 {object} map[string]interface{} "校验失败"
+// @Router /api/cloud-init/validate [post]
+func ValidateCloudInit(c *gin.Context) {
+	var req models.CloudInitOptions
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "参数错误: " + err.Error(),
+		})
+		return
+	}
+	fields, err := services.BuildCloudInit(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  err.Error(),
+		})
+		return
+	}
+
+	data := gin.H{"ssh_keys": 0}
+	if keys, ok := fields["ssh_keys"].([]string); ok {
+		data["ssh_keys"] = len(keys)
+	}
+	if userData, ok := fields["user_data"].(string); ok {
+		data["user_data_keys"], _ = services.ValidateUserData(userData)
+	}
+	if vendorData, ok := fields["vendor_data"].(string); ok {
+		data["vendor_data_keys"], _ = services.ValidateUserData(vendorData)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "校验通过",
+		"data": data,
+	})
+}
@@ -1,18 +1,16 @@
 package handlers
 import (
-	"bytes"
-	"crypto/tls"
-	"encoding/json"
-	"fmt"
-	"io"
+	"errors"
 	"lxdweb/database"
 	"lxdweb/models"
+	"lxdweb/services"
 	"net/http"
+	"strconv"
 	"github.com/gin-gonic/gin"
 )
 // CreateConsoleToken 创建控制台令牌
 // @Summary 创建控制台令牌
-// @Description 为指定容器创建Web控制台访问令牌
+// @Description 为指定容器创建Web控制台访问令牌，控制台通过 lxdweb 代理连接节点，令牌一次性使用且有效期较短
 // @Tags 容器管理
 // @Accept json
 // @Produce json
@@ -20,7 +18,9 @@ import (
 // @Success 200 {object} map[string]interface{} "成功返回令牌和控制台URL"
 // @Failure 400 {object} map[string]interface{} "参数错误"
 // @Failure 404 {object} map[string]interface{} "节点不存在"
+// @Failure 429 {object} map[string]interface{} "会话数已达上限"
 // @Failure 500 {object} map[string]interface{} "创建失败"
+// @Failure 502 {object} map[string]interface{} "节点创建令牌失败"
 // @Router /api/console/create-token [post]
 func CreateConsoleToken(c *gin.Context) {
 	var req struct {
@@ -42,57 +42,131 @@ func CreateConsoleToken(c *gin.Context) {
 		})
 		return
 	}
-	tokenReq := map[string]interface{}{
-		"hostname":   req.Hostname,
-		"user_id":    1, 
-		"service_id": 0,
-		"server_ip":  c.ClientIP(),
-		"expires_in": 3600, 
+	session, err := services.CreateConsoleSession(node, req.Hostname, currentAdminID(c), c.ClientIP())
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, services.ErrConsoleLimit):
+			status = http.StatusTooManyRequests
+		case errors.Is(err, services.ErrConsoleNode):
+			status = http.StatusBadGateway
+		}
+		c.JSON(status, gin.H{
+			"code": status,
+			"msg":  err.Error(),
+		})
+		return
 	}
-	reqBody, _ := json.Marshal(tokenReq)
-	client := &http.Client{
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	recordOperation(c, "console_create", "container", node.ID, gin.H{
+		"hostname":   req.Hostname,
+		"session_id": session.ID,
+	})
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "success",
+		"data": gin.H{
+			"token":       session.Token,
+			"console_url": "/console/" + session.Token,
+			"hostname":    req.Hostname,
+			"expires_at":  session.ExpiresAt,
 		},
-	}
-	url := fmt.Sprintf("%s/api/console/create-token", node.Address)
-	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(reqBody))
+	})
+}
+// ConsolePage 控制台页面
+func ConsolePage(c *gin.Context) {
+	session, err := services.FindConsoleSession(c.Param("token"), currentAdminID(c))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code": 500,
-			"msg":  "创建请求失败: " + err.Error(),
-		})
+		c.String(http.StatusNotFound, err.Error())
 		return
 	}
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("apikey", node.APIKey)
-	resp, err := client.Do(httpReq)
+	c.HTML(http.StatusOK, "console.html", gin.H{
+		"title":    "Shell 控制台 - " + session.Hostname,
+		"token":    session.Token,
+		"hostname": session.Hostname,
+		"nodeID":   session.NodeID,
+		"nodeName": session.NodeName,
+	})
+}
+// ConsoleWebSocket 代理控制台 WebSocket 到节点
+func ConsoleWebSocket(c *gin.Context) {
+	session, err := services.ClaimConsoleSession(c.Param("token"), currentAdminID(c))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code": 500,
-			"msg":  "调用节点API失败: " + err.Error(),
+		c.JSON(http.StatusForbidden, gin.H{
+			"code": 403,
+			"msg":  err.Error(),
 		})
 		return
 	}
-	defer resp.Body.Close()
-	body, _ := io.ReadAll(resp.Body)
-	var result map[string]interface{}
-	json.Unmarshal(body, &result)
-	if result["code"] != float64(200) {
-		c.JSON(http.StatusOK, result)
-		return
+	services.ProxyConsoleSession(c.Writer, c.Request, session, c.ClientIP())
+}
+// GetConsoleSessions 获取控制台会话
+// @Summary 获取控制台会话
+// @Description 按状态、节点和容器查询控制台会话记录
+// @Tags 容器管理
+// @Produce json
+// @Param status query string false "状态: pending/active/closed/expired"
+// @Param node_id query int false "节点ID"
+// @Param hostname query string false "容器名称"
+// @Param limit query int false "返回条数，默认100，最大500"
+// @Success 200 {object} map[string]interface{} "成功返回会话列表"
+// @Router /api/console/sessions [get]
+func GetConsoleSessions(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+	query := database.DB.Order("id DESC").Limit(limit)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if nodeID := c.Query("node_id"); nodeID != "" {
+		query = query.Where("node_id = ?", nodeID)
+	}
+	if hostname := c.Query("hostname"); hostname != "" {
+		query = query.Where("hostname = ?", hostname)
+	}
+	var list []models.ConsoleSession
+	query.Find(&list)
+	var admins []models.Admin
+	database.DB.Find(&admins)
+	names := make(map[uint]string, len(admins))
+	for _, a := range admins {
+		names[a.ID] = a.Username
+	}
+	data := make([]gin.H, 0, len(list))
+	for _, s := range list {
+		data = append(data, gin.H{
+			"session":  s,
+			"username": names[s.AdminID],
+		})
 	}
-	data := result["data"].(map[string]interface{})
-	token := data["token"].(string)
-	consoleURL := fmt.Sprintf("%s/console?token=%s", node.Address, token)
 	c.JSON(http.StatusOK, gin.H{
 		"code": 200,
 		"msg":  "success",
-		"data": gin.H{
-			"token":       token,
-			"console_url": consoleURL,
-			"node_url":    node.Address,
-			"hostname":    req.Hostname,
-		},
+		"data": data,
+	})
+}
+// TerminateConsoleSession 终止控制台会话
+// @Summary 终止控制台会话
+// @Description 断开正在进行的控制台连接，或作废尚未使用的令牌
+// @Tags 容器管理
+// @Produce json
+// @Param id path string true "会话ID"
+// @Success 200 {object} map[string]interface{} "已终止"
+// @Failure 400 {object} map[string]interface{} "会话不存在或已结束"
+// @Router /api/console/sessions/{id}/terminate [post]
+func TerminateConsoleSession(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err := services.TerminateConsoleSession(uint(id)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  err.Error(),
+		})
+		return
+	}
+	recordOperation(c, "console_terminate", "console_session", uint(id), nil)
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "会话已终止",
 	})
 }
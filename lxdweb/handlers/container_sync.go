@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
 
@@ -33,7 +34,7 @@ func SyncAllNodes(c *gin.Context) {
 	}
 
 	var nodes []models.Node
-	if err := database.DB.Where("status = ?", "active").Find(&nodes).Error; err != nil {
+	if err := database.DB.Where("status IN ? AND maintenance = ?", models.OnlineNodeStatuses, false).Find(&nodes).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code": 500,
 			"msg":  "查询节点失败",
@@ -100,6 +101,58 @@ func SyncNode(c *gin.Context) {
 	})
 }
 
+// SyncNodeGroup 同步节点分组
+// @Summary 按分组同步节点
+// @Description 按分组、地域或标签筛选在线节点，并完整同步容器、NAT、IPv6、IPv4和反向代理数据
+// @Tags 容器同步
+// @Accept json
+// @Produce json
+// @Param body body services.NodeSelector true "节点筛选条件"
+// @Success 200 {object} map[string]interface{} "同步任务已启动"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Failure 404 {object} map[string]interface{} "没有符合条件的节点"
+// @Router /api/sync/group [post]
+func SyncNodeGroup(c *gin.Context) {
+	var sel services.NodeSelector
+	if err := c.ShouldBindJSON(&sel); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "参数错误: " + err.Error(),
+		})
+		return
+	}
+	if sel.IsEmpty() {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "请至少指定分组、地域或标签之一",
+		})
+		return
+	}
+
+	nodes, err := services.FindNodes(sel, true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "查询节点失败",
+		})
+		return
+	}
+	if len(nodes) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "没有符合条件的在线节点",
+		})
+		return
+	}
+
+	go services.SyncNodeGroupFullAsync(sel)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  fmt.Sprintf("已启动 %d 个节点的完整同步任务，请稍后刷新页面查看结果", len(nodes)),
+	})
+}
+
 // GetSyncTasks 获取同步任务列表
 // @Summary 获取容器同步任务列表
 // @Description 查询最近50条容器同步任务记录
@@ -7,10 +7,13 @@ import (
 	"io"
 	"lxdweb/database"
 	"lxdweb/models"
+	"lxdweb/pkg/logger"
+	"lxdweb/services"
 	"net/http"
 	"time"
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 // ContainersPage 容器管理页面
 // @Summary 容器管理页面
@@ -258,11 +261,13 @@ func DeleteContainer(c *gin.Context) {
 	}
 	result := callNodeAPI(node, "GET", "/api/delete?hostname="+name, nil)
 	if result["code"] == float64(200) {
+		services.EmitContainerEvent(models.WebhookEventContainerDeleted, node, name, map[string]interface{}{"source": "panel"})
 		database.DB.Unscoped().Where("node_id = ? AND hostname = ?", node.ID, name).Delete(&models.Container{})
 		database.DB.Unscoped().Where("node_id = ? AND hostname = ?", node.ID, name).Delete(&models.ContainerCache{})
 		database.DB.Unscoped().Where("node_id = ? AND container_hostname = ?", node.ID, name).Delete(&models.NATRule{})
 		database.DB.Unscoped().Where("node_id = ? AND container_hostname = ?", node.ID, name).Delete(&models.NATRuleCache{})
 		database.DB.Unscoped().Where("node_id = ? AND hostname = ?", node.ID, name).Delete(&models.IPv6BindingCache{})
+		database.DB.Unscoped().Where("node_id = ? AND hostname = ?", node.ID, name).Delete(&models.IPv4BindingCache{})
 		database.DB.Unscoped().Where("node_id = ? AND hostname = ?", node.ID, name).Delete(&models.ProxyConfigCache{})
 	}
 	c.JSON(http.StatusOK, result)
@@ -299,7 +304,7 @@ func RefreshSingleContainer(c *gin.Context) {
 
 // ReinstallContainer 重装容器系统
 // @Summary 重装容器系统
-// @Description 重装指定容器的操作系统
+// @Description 重装指定容器的操作系统，可通过 ssh_keys、user_data（或 user_data_template_id）和 vendor_data 传入 cloud-init 配置，disable_password_auth 为 true 时 password 可省略
 // @Tags 容器管理
 // @Accept json
 // @Produce json
@@ -308,6 +313,7 @@ func RefreshSingleContainer(c *gin.Context) {
 // @Success 200 {object} map[string]interface{} "重装成功"
 // @Failure 400 {object} map[string]interface{} "参数错误"
 // @Failure 404 {object} map[string]interface{} "节点不存在"
+// @Failure 409 {object} map[string]interface{} "节点处于维护模式"
 // @Router /api/containers/{name}/reinstall [post]
 func ReinstallContainer(c *gin.Context) {
 	name := c.Param("name")
@@ -315,7 +321,7 @@ func ReinstallContainer(c *gin.Context) {
 	var req struct {
 		NodeID       uint   `json:"node_id" binding:"required"`
 		Image        string `json:"image" binding:"required"`
-		Password     string `json:"password" binding:"required"`
+		Password     string `json:"password"`
 		CPUs         int    `json:"cpus"`
 		Memory       string `json:"memory"`
 		Disk         string `json:"disk"`
@@ -329,6 +335,7 @@ func ReinstallContainer(c *gin.Context) {
 		DiskIOLimit  string `json:"disk_io_limit"`
 		Privileged   bool   `json:"privileged"`
 		EnableLXCFS  bool   `json:"enable_lxcfs"`
+		models.CloudInitOptions
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -339,6 +346,23 @@ func ReinstallContainer(c *gin.Context) {
 		return
 	}
 
+	cloudInit, err := services.BuildCloudInit(req.CloudInitOptions)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  err.Error(),
+		})
+		return
+	}
+	// 禁用密码登录时 BuildCloudInit 已确保至少有一个公钥，此时可以不设置 root 密码
+	if req.Password == "" && !req.DisablePasswordAuth {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "参数错误: 未禁用密码登录时 password 不能为空",
+		})
+		return
+	}
+
 	var node models.Node
 	if err := database.DB.First(&node, req.NodeID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
@@ -347,11 +371,17 @@ func ReinstallContainer(c *gin.Context) {
 		})
 		return
 	}
+	if err := services.CheckNodeAvailable(node); err != nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"code": 409,
+			"msg":  err.Error() + "，不能重装容器",
+		})
+		return
+	}
 
 	reinstallData := map[string]interface{}{
 		"hostname":      name,
 		"system":        req.Image,
-		"password":      req.Password,
 		"cpus":          req.CPUs,
 		"memory":        req.Memory,
 		"disk":          req.Disk,
@@ -369,6 +399,12 @@ func ReinstallContainer(c *gin.Context) {
 	if req.DiskIOLimit != "" {
 		reinstallData["disk_io_limit"] = req.DiskIOLimit
 	}
+	if req.Password != "" {
+		reinstallData["password"] = req.Password
+	}
+	for k, v := range cloudInit {
+		reinstallData[k] = v
+	}
 
 	result := callNodeAPI(node, "POST", "/api/reinstall", reinstallData)
 	if result["code"] == float64(200) {
@@ -449,6 +485,7 @@ func SuspendContainer(c *gin.Context) {
 	
 	result := callNodeAPI(node, "GET", "/api/suspend?hostname="+name, nil)
 	if result["code"] == float64(200) {
+		services.EmitContainerEvent(models.WebhookEventContainerSuspended, node, name, map[string]interface{}{"source": "panel"})
 		time.Sleep(1 * time.Second)
 		callNodeAPI(node, "GET", fmt.Sprintf("/api/info?hostname=%s", name), nil)
 	}
@@ -488,7 +525,7 @@ func UnsuspendContainer(c *gin.Context) {
 
 // ResetContainerTraffic 重置容器流量
 // @Summary 重置容器流量
-// @Description 重置指定容器的流量统计
+// @Description 重置指定容器的流量统计，成功后关闭当前账期并从重置时刻开始新账期，历史账期保留，当前账期的超额处理随之解除
 // @Tags 容器管理
 // @Produce json
 // @Param name path string true "容器名称"
@@ -510,11 +547,18 @@ func ResetContainerTraffic(c *gin.Context) {
 	}
 	
 	result := callNodeAPI(node, "POST", "/api/traffic/reset?hostname="+name, nil)
+	if result["code"] == float64(200) {
+		if err := services.CloseTrafficPeriod(node.ID, name, models.TrafficCloseReset); err != nil {
+			logger.Global.Warn(c.Request.Context(), "关闭流量账期失败", zap.String("hostname", name), zap.Error(err))
+		} else {
+			services.RestoreTrafficEnforcements()
+		}
+	}
 	c.JSON(http.StatusOK, result)
 }
 // CreateContainer 创建容器
 // @Summary 创建容器
-// @Description 在指定节点创建新的LXD容器
+// @Description 在指定节点创建新的LXD容器，未指定节点时按分组、地域和标签自动选择容器最少的在线节点，可通过 ssh_keys、user_data（或 user_data_template_id）和 vendor_data 传入 cloud-init 配置，disable_password_auth 为 true 时 password 可省略
 // @Tags 容器管理
 // @Accept json
 // @Produce json
@@ -522,25 +566,30 @@ func ResetContainerTraffic(c *gin.Context) {
 // @Success 200 {object} map[string]interface{} "创建成功"
 // @Failure 400 {object} map[string]interface{} "参数错误"
 // @Failure 404 {object} map[string]interface{} "节点不存在"
+// @Failure 409 {object} map[string]interface{} "节点处于维护模式"
 // @Router /api/containers/create [post]
 func CreateContainer(c *gin.Context) {
 	var req struct {
-		NodeID        uint   `json:"node_id" binding:"required"`
-		Hostname      string `json:"hostname" binding:"required"`
-		Password      string `json:"password" binding:"required"`
-		Image         string `json:"image" binding:"required"`
-		CPUs          int    `json:"cpus"`
-		Memory        string `json:"memory"`
-		Disk          string `json:"disk"`
-		Ingress       string `json:"ingress"`
-		Egress        string `json:"egress"`
-		TrafficLimit  int    `json:"traffic_limit"`
-		AllowNesting  bool   `json:"allow_nesting"`
-		MemorySwap    bool   `json:"memory_swap"`
-		MaxProcesses  int    `json:"max_processes"`
-		CPUAllowance  string `json:"cpu_allowance"`
-		DiskIOLimit   string `json:"disk_io_limit"`
-		Privileged    bool   `json:"privileged"`
+		NodeID        uint     `json:"node_id"`
+		NodeGroup     string   `json:"node_group"`
+		NodeRegion    string   `json:"node_region"`
+		NodeTags      []string `json:"node_tags"`
+		Hostname      string   `json:"hostname" binding:"required"`
+		Password      string   `json:"password"`
+		Image         string   `json:"image" binding:"required"`
+		CPUs          int      `json:"cpus"`
+		Memory        string   `json:"memory"`
+		Disk          string   `json:"disk"`
+		Ingress       string   `json:"ingress"`
+		Egress        string   `json:"egress"`
+		TrafficLimit  int      `json:"traffic_limit"`
+		AllowNesting  bool     `json:"allow_nesting"`
+		MemorySwap    bool     `json:"memory_swap"`
+		MaxProcesses  int      `json:"max_processes"`
+		CPUAllowance  string   `json:"cpu_allowance"`
+		DiskIOLimit   string   `json:"disk_io_limit"`
+		Privileged    bool     `json:"privileged"`
+		models.CloudInitOptions
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -551,14 +600,52 @@ func CreateContainer(c *gin.Context) {
 		return
 	}
 
+	cloudInit, err := services.BuildCloudInit(req.CloudInitOptions)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  err.Error(),
+		})
+		return
+	}
+	// 禁用密码登录时 BuildCloudInit 已确保至少有一个公钥，此时可以不设置 root 密码
+	if req.Password == "" && !req.DisablePasswordAuth {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "参数错误: 未禁用密码登录时 password 不能为空",
+		})
+		return
+	}
+
 	var node models.Node
-	if err := database.DB.First(&node, req.NodeID).Error; err != nil {
+	if req.NodeID == 0 {
+		selected, err := services.SelectPlacementNode(services.NodeSelector{
+			Group:  req.NodeGroup,
+			Region: req.NodeRegion,
+			Tags:   req.NodeTags,
+		})
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code": 400,
+				"msg":  err.Error(),
+			})
+			return
+		}
+		node = *selected
+	} else if err := database.DB.First(&node, req.NodeID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"code": 404,
 			"msg":  "节点不存在",
 		})
 		return
 	}
+	if err := services.CheckNodeAvailable(node); err != nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"code": 409,
+			"msg":  err.Error() + "，不能创建容器",
+		})
+		return
+	}
 
 	if req.CPUs == 0 {
 		req.CPUs = 1
@@ -584,7 +671,6 @@ func CreateContainer(c *gin.Context) {
 
 	createData := map[string]interface{}{
 		"hostname":       req.Hostname,
-		"password":       req.Password,
 		"image":          req.Image,
 		"cpus":           req.CPUs,
 		"memory":         req.Memory,
@@ -601,12 +687,27 @@ func CreateContainer(c *gin.Context) {
 	if req.DiskIOLimit != "" {
 		createData["disk_io_limit"] = req.DiskIOLimit
 	}
+	if req.Password != "" {
+		createData["password"] = req.Password
+	}
+	for k, v := range cloudInit {
+		createData[k] = v
+	}
 
 	result := callNodeAPI(node, "POST", "/api/create", createData)
 	if result["code"] == float64(200) {
+		services.EmitContainerEvent(models.WebhookEventContainerCreated, node, req.Hostname, map[string]interface{}{
+			"image":         req.Image,
+			"cpus":          req.CPUs,
+			"memory":        req.Memory,
+			"disk":          req.Disk,
+			"traffic_limit": req.TrafficLimit,
+		})
 		time.Sleep(2 * time.Second)
 		callNodeAPI(node, "GET", fmt.Sprintf("/api/info?hostname=%s", req.Hostname), nil)
 	}
+	result["node_id"] = node.ID
+	result["node_name"] = node.Name
 	c.JSON(http.StatusOK, result)
 }
 func fetchContainersFromNode(node models.Node) []map[string]interface{} {
@@ -643,10 +744,10 @@ func fetchContainersFromNode(node models.Node) []map[string]interface{} {
 						if diskTotal, ok := detailData["disk"].(float64); ok {
 							container["disk_total"] = uint64(diskTotal * 1024 * 1024) 
 						}
-						if trafficRaw, ok := detailData["traffic_usage_raw"].(float64); ok {
-							container["traffic_total"] = uint64(trafficRaw)
-							container["traffic_in"] = uint64(trafficRaw * 0.5)
-							container["traffic_out"] = uint64(trafficRaw * 0.5)
+						if counter, ok := services.ParseTrafficCounter(detailData); ok {
+							container["traffic_total"] = counter.Total
+							container["traffic_in"] = counter.In
+							container["traffic_out"] = counter.Out
 						}
 					}
 				}
@@ -668,9 +769,9 @@ func fetchContainerDetail(node models.Node, name string) map[string]interface{}
 func callNodeAPI(node models.Node, method, path string, data interface{}) map[string]interface{} {
 	client := &http.Client{
 		Timeout: 30 * time.Second,
-		Transport: &http.Transport{
+		Transport: services.NodeAPITransport(node, &http.Transport{
 			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		},
+		}),
 	}
 	var body io.Reader
 	if data != nil {
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"lxdweb/config"
+	"lxdweb/database"
+	"lxdweb/models"
+	"lxdweb/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetDomainVerifications 获取域名验证列表
+// @Summary 获取域名验证列表
+// @Description 查询域名所有权验证记录及需要配置的TXT记录或验证文件
+// @Tags 反向代理管理
+// @Produce json
+// @Param status query string false "状态(pending/verified/failed/expired)"
+// @Success 200 {object} map[string]interface{} "成功返回验证列表"
+// @Failure 500 {object} map[string]interface{} "查询失败"
+// @Router /api/domain-verifications [get]
+func GetDomainVerifications(c *gin.Context) {
+	var list []models.DomainVerification
+	query := database.DB.Order("domain")
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	if err := query.Find(&list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "查询失败: " + err.Error(),
+		})
+		return
+	}
+
+	data := make([]gin.H, 0, len(list))
+	for _, v := range list {
+		data = append(data, gin.H{
+			"verification": v,
+			"instructions": services.DomainVerifyInstructions(v),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":     200,
+		"msg":      "success",
+		"data":     data,
+		"required": config.AppConfig.DomainVerify.Required,
+	})
+}
+
+// CreateDomainVerification 申请域名验证
+// @Summary 申请域名验证
+// @Description 为域名生成验证令牌；TXT 方式需添加 <前缀>.<域名> 的TXT记录，验证通过后覆盖所有子域名；HTTP 方式需在站点放置验证文件
+// @Tags 反向代理管理
+// @Accept json
+// @Produce json
+// @Param body body models.CreateDomainVerificationRequest true "域名与验证方式"
+// @Success 200 {object} map[string]interface{} "返回验证令牌与配置说明"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Router /api/domain-verifications [post]
+func CreateDomainVerification(c *gin.Context) {
+	var req models.CreateDomainVerificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	v, err := services.RequestDomainVerification(req.Domain, req.Method, currentAdminID(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  err.Error(),
+		})
+		return
+	}
+
+	recordOperation(c, "domain_verify_request", "domain_verification", v.ID, gin.H{
+		"domain": v.Domain,
+		"method": v.Method,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "验证令牌已生成",
+		"data": gin.H{
+			"verification": v,
+			"instructions": services.DomainVerifyInstructions(*v),
+		},
+	})
+}
+
+// CheckDomainVerification 执行域名验证
+// @Summary 执行域名验证
+// @Description 通过配置的DNS解析器查询TXT记录或访问验证文件，检查令牌是否匹配
+// @Tags 反向代理管理
+// @Produce json
+// @Param id path string true "验证记录ID"
+// @Success 200 {object} map[string]interface{} "验证通过"
+// @Failure 400 {object} map[string]interface{} "验证未通过"
+// @Failure 404 {object} map[string]interface{} "记录不存在"
+// @Router /api/domain-verifications/{id}/check [post]
+func CheckDomainVerification(c *gin.Context) {
+	var v models.DomainVerification
+	if err := database.DB.First(&v, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "验证记录不存在",
+		})
+		return
+	}
+
+	err := services.CheckDomainVerification(&v)
+	recordOperation(c, "domain_verify_check", "domain_verification", v.ID, gin.H{
+		"domain": v.Domain,
+		"status": v.Status,
+		"error":  v.LastError,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "验证未通过: " + err.Error(),
+			"data": v,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "验证通过",
+		"data": v,
+	})
+}
+
+// DeleteDomainVerification 删除域名验证
+// @Summary 删除域名验证
+// @Description 删除域名验证记录，已创建的反向代理不受影响
+// @Tags 反向代理管理
+// @Produce json
+// @Param id path string true "验证记录ID"
+// @Success 200 {object} map[string]interface{} "删除成功"
+// @Failure 404 {object} map[string]interface{} "记录不存在"
+// @Router /api/domain-verifications/{id} [delete]
+func DeleteDomainVerification(c *gin.Context) {
+	var v models.DomainVerification
+	if err := database.DB.First(&v, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "验证记录不存在",
+		})
+		return
+	}
+
+	database.DB.Delete(&v)
+	recordOperation(c, "domain_verify_delete", "domain_verification", v.ID, gin.H{
+		"domain": v.Domain,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "删除成功",
+	})
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"lxdweb/config"
+	"lxdweb/models"
+	"lxdweb/services"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExecContainers 在容器内执行命令
+// @Summary 在容器内执行命令
+// @Description 在指定节点、分组、地域、标签或容器名匹配的容器内非交互执行命令，创建批量任务并在任务结果中保存每个容器的退出码、stdout 和 stderr
+// @Tags 容器管理
+// @Accept json
+// @Produce json
+// @Param body body models.ExecRequest true "命令、环境变量、工作目录、超时及目标容器"
+// @Success 200 {object} map[string]interface{} "任务已创建"
+// @Failure 400 {object} map[string]interface{} "参数错误或目标过多"
+// @Failure 404 {object} map[string]interface{} "没有匹配的容器"
+// @Failure 409 {object} map[string]interface{} "指定的节点处于维护模式"
+// @Router /api/containers/exec [post]
+func ExecContainers(c *gin.Context) {
+	var req models.ExecRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "参数错误: " + err.Error(),
+		})
+		return
+	}
+	if err := services.NormalizeExecRequest(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  err.Error(),
+		})
+		return
+	}
+
+	sel := services.NodeSelector{Group: req.Group, Region: req.Region, Tags: req.Tags}
+	if sel.IsEmpty() && len(req.NodeIDs) == 0 && len(req.Hostnames) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "请指定节点、分组、地域、标签或容器名",
+		})
+		return
+	}
+
+	targets, err := services.ResolveBatchTargets(sel, req.NodeIDs, req.Hostnames)
+	if err != nil {
+		status := http.StatusNotFound
+		if errors.Is(err, services.ErrNodeInMaintenance) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{
+			"code": status,
+			"msg":  err.Error(),
+		})
+		return
+	}
+	if len(targets) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "没有匹配的容器",
+		})
+		return
+	}
+	if maxTargets := config.AppConfig.Exec.MaxTargets; len(targets) > maxTargets {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  fmt.Sprintf("匹配到 %d 个容器，超过单次执行上限 %d", len(targets), maxTargets),
+		})
+		return
+	}
+
+	// 环境变量值可能包含密钥，任务选择器和审计日志只记录变量名
+	envKeys := make([]string, 0, len(req.Env))
+	for key := range req.Env {
+		envKeys = append(envKeys, key)
+	}
+	sort.Strings(envKeys)
+	logged := req
+	logged.Env = nil
+	selector, _ := json.Marshal(gin.H{"request": logged, "env_keys": envKeys})
+
+	task, err := services.StartBatchOutputTask("container_exec", string(selector), targets, services.ExecFunc(req))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "创建任务失败: " + err.Error(),
+		})
+		return
+	}
+
+	hostnames := make([]string, 0, len(targets))
+	for _, t := range targets {
+		hostnames = append(hostnames, fmt.Sprintf("%s/%s", t.Node.Name, t.Hostname))
+	}
+	recordOperation(c, "container_exec", "batch_task", task.ID, gin.H{
+		"command":  req.Command,
+		"cwd":      req.Cwd,
+		"env_keys": envKeys,
+		"timeout":  req.Timeout,
+		"targets":  hostnames,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  fmt.Sprintf("执行任务已创建，共 %d 个容器", len(targets)),
+		"data": task,
+	})
+}
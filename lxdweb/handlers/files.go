@@ -0,0 +1,420 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"lxdweb/config"
+	"lxdweb/database"
+	"lxdweb/models"
+	"lxdweb/services"
+	"net/http"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fileNode 按 node_id 查询参数加载容器所在节点
+func fileNode(c *gin.Context) (models.Node, bool) {
+	var node models.Node
+	if err := database.DB.First(&node, c.Query("node_id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "节点不存在",
+		})
+		return node, false
+	}
+	return node, true
+}
+
+// recordFileOperation 记录文件写操作审计，失败的操作同样记录
+func recordFileOperation(c *gin.Context, opType string, node models.Node, hostname string, details gin.H, err error) {
+	details["hostname"] = hostname
+	services.RecordOperation(services.AuditEntry{
+		AdminID:       currentAdminID(c),
+		OperationType: opType,
+		TargetType:    "container",
+		TargetID:      node.ID,
+		Details:       details,
+		IPAddress:     c.ClientIP(),
+		Err:           err,
+	})
+}
+
+func fileError(c *gin.Context, status int, err error) {
+	c.JSON(status, gin.H{
+		"code": status,
+		"msg":  err.Error(),
+	})
+}
+
+// ListContainerFiles 列出容器目录
+// @Summary 列出容器目录
+// @Description 通过节点列出容器内指定目录下的文件，受禁止访问路径限制
+// @Tags 文件管理
+// @Produce json
+// @Param name path string true "容器名称"
+// @Param node_id query int true "节点ID"
+// @Param path query string false "目录路径，默认 /root"
+// @Success 200 {object} map[string]interface{} "成功返回文件列表"
+// @Failure 400 {object} map[string]interface{} "路径不允许访问"
+// @Failure 502 {object} map[string]interface{} "节点返回错误"
+// @Router /api/containers/{name}/files [get]
+func ListContainerFiles(c *gin.Context) {
+	node, ok := fileNode(c)
+	if !ok {
+		return
+	}
+	dir, err := services.CheckFilePath(c.DefaultQuery("path", "/root"), false)
+	if err != nil {
+		fileError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	files, err := services.ListContainerFiles(node, c.Param("name"), dir)
+	if err != nil {
+		fileError(c, http.StatusBadGateway, err)
+		return
+	}
+
+	cfg := config.AppConfig.FileManager
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "success",
+		"data": gin.H{
+			"path":  dir,
+			"files": files,
+			"limits": gin.H{
+				"max_upload_size":   int64(cfg.MaxUploadSize) << 20,
+				"max_download_size": int64(cfg.MaxDownloadSize) << 20,
+				"max_edit_size":     int64(cfg.MaxEditSize) << 10,
+			},
+		},
+	})
+}
+
+// DownloadContainerFile 下载容器文件
+// @Summary 下载容器文件
+// @Description 通过节点下载容器内文件，超过下载大小限制的文件会被拒绝
+// @Tags 文件管理
+// @Produce octet-stream
+// @Param name path string true "容器名称"
+// @Param node_id query int true "节点ID"
+// @Param path query string true "文件路径"
+// @Success 200 {file} file "文件内容"
+// @Failure 400 {object} map[string]interface{} "路径不允许访问或文件过大"
+// @Router /api/containers/{name}/files/download [get]
+func DownloadContainerFile(c *gin.Context) {
+	node, ok := fileNode(c)
+	if !ok {
+		return
+	}
+	name := c.Param("name")
+	p, err := services.CheckFilePath(c.Query("path"), false)
+	if err != nil {
+		fileError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	limit := int64(config.AppConfig.FileManager.MaxDownloadSize) << 20
+	body, size, err := services.OpenContainerFile(node, name, p, limit)
+	if err != nil {
+		fileError(c, http.StatusBadRequest, err)
+		return
+	}
+	defer body.Close()
+
+	recordOperation(c, "file_download", "container", node.ID, gin.H{
+		"hostname": name,
+		"path":     p,
+		"size":     size,
+	})
+
+	c.DataFromReader(http.StatusOK, size, "application/octet-stream", body, map[string]string{
+		"Content-Disposition": fmt.Sprintf(`attachment; filename="%s"`, strings.ReplaceAll(path.Base(p), `"`, "")),
+	})
+}
+
+// GetContainerFileContent 读取容器文本文件
+// @Summary 读取容器文本文件
+// @Description 读取容器内文本文件用于在线编辑，超过编辑大小限制或非文本文件会被拒绝
+// @Tags 文件管理
+// @Produce json
+// @Param name path string true "容器名称"
+// @Param node_id query int true "节点ID"
+// @Param path query string true "文件路径"
+// @Success 200 {object} map[string]interface{} "成功返回文件内容"
+// @Failure 400 {object} map[string]interface{} "路径不允许访问、文件过大或非文本文件"
+// @Router /api/containers/{name}/files/content [get]
+func GetContainerFileContent(c *gin.Context) {
+	node, ok := fileNode(c)
+	if !ok {
+		return
+	}
+	p, err := services.CheckFilePath(c.Query("path"), false)
+	if err != nil {
+		fileError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	limit := int64(config.AppConfig.FileManager.MaxEditSize) << 10
+	body, _, err := services.OpenContainerFile(node, c.Param("name"), p, limit)
+	if err != nil {
+		fileError(c, http.StatusBadRequest, err)
+		return
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(body, limit+1))
+	if err != nil {
+		fileError(c, http.StatusBadGateway, err)
+		return
+	}
+	if int64(len(data)) > limit {
+		fileError(c, http.StatusBadRequest, errors.New("文件超过在线编辑大小限制"))
+		return
+	}
+	if bytes.IndexByte(data, 0) >= 0 || !utf8.Valid(data) {
+		fileError(c, http.StatusBadRequest, errors.New("不是文本文件，无法在线编辑"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "success",
+		"data": gin.H{
+			"path":    p,
+			"content": string(data),
+		},
+	})
+}
+
+// SaveContainerFileContent 保存容器文本文件
+// @Summary 保存容器文本文件
+// @Description 将在线编辑的内容写回容器文件，受只读路径和编辑大小限制
+// @Tags 文件管理
+// @Accept json
+// @Produce json
+// @Param name path string true "容器名称"
+// @Param node_id query int true "节点ID"
+// @Param body body models.FileContentRequest true "文件路径和内容"
+// @Success 200 {object} map[string]interface{} "保存成功"
+// @Failure 400 {object} map[string]interface{} "参数错误或路径只读"
+// @Failure 502 {object} map[string]interface{} "节点写入失败"
+// @Router /api/containers/{name}/files/content [put]
+func SaveContainerFileContent(c *gin.Context) {
+	node, ok := fileNode(c)
+	if !ok {
+		return
+	}
+	var req models.FileContentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "参数错误: " + err.Error(),
+		})
+		return
+	}
+	name := c.Param("name")
+	details := gin.H{"path": req.Path, "size": len(req.Content)}
+
+	p, err := services.CheckFilePath(req.Path, true)
+	if err == nil && int64(len(req.Content)) > int64(config.AppConfig.FileManager.MaxEditSize)<<10 {
+		err = errors.New("内容超过在线编辑大小限制")
+	}
+	if err != nil {
+		recordFileOperation(c, "file_edit", node, name, details, err)
+		fileError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	err = services.WriteContainerFile(node, name, p, strings.NewReader(req.Content), int64(len(req.Content)), "")
+	recordFileOperation(c, "file_edit", node, name, details, err)
+	if err != nil {
+		fileError(c, http.StatusBadGateway, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "保存成功",
+	})
+}
+
+// UploadContainerFile 上传文件到容器
+// @Summary 上传文件到容器
+// @Description 以 multipart 表单上传文件到容器内指定目录，受上传大小和只读路径限制
+// @Tags 文件管理
+// @Accept multipart/form-data
+// @Produce json
+// @Param name path string true "容器名称"
+// @Param node_id query int true "节点ID"
+// @Param path formData string true "目标目录"
+// @Param file formData file true "上传的文件"
+// @Success 200 {object} map[string]interface{} "上传成功"
+// @Failure 400 {object} map[string]interface{} "参数错误或路径只读"
+// @Failure 413 {object} map[string]interface{} "文件过大"
+// @Failure 502 {object} map[string]interface{} "节点写入失败"
+// @Router /api/containers/{name}/files/upload [post]
+func UploadContainerFile(c *gin.Context) {
+	node, ok := fileNode(c)
+	if !ok {
+		return
+	}
+	name := c.Param("name")
+	limit := int64(config.AppConfig.FileManager.MaxUploadSize) << 20
+
+	// 预留 1MB 给 multipart 表单头部
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit+1<<20)
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			fileError(c, http.StatusRequestEntityTooLarge, fmt.Errorf("文件超过上传大小限制 %dMB", config.AppConfig.FileManager.MaxUploadSize))
+			return
+		}
+		fileError(c, http.StatusBadRequest, errors.New("请选择要上传的文件"))
+		return
+	}
+
+	details := gin.H{"path": path.Join(c.PostForm("path"), fileHeader.Filename), "size": fileHeader.Size}
+	if fileHeader.Size > limit {
+		err := fmt.Errorf("文件超过上传大小限制 %dMB", config.AppConfig.FileManager.MaxUploadSize)
+		recordFileOperation(c, "file_upload", node, name, details, err)
+		fileError(c, http.StatusRequestEntityTooLarge, err)
+		return
+	}
+	filename := path.Base(fileHeader.Filename)
+	if filename == "." || filename == "/" || filename == ".." {
+		fileError(c, http.StatusBadRequest, errors.New("文件名无效"))
+		return
+	}
+	p, err := services.CheckFilePath(path.Join(c.PostForm("path"), filename), true)
+	if err != nil {
+		recordFileOperation(c, "file_upload", node, name, details, err)
+		fileError(c, http.StatusBadRequest, err)
+		return
+	}
+	details["path"] = p
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		fileError(c, http.StatusBadRequest, err)
+		return
+	}
+	defer file.Close()
+
+	err = services.WriteContainerFile(node, name, p, file, fileHeader.Size, "")
+	recordFileOperation(c, "file_upload", node, name, details, err)
+	if err != nil {
+		fileError(c, http.StatusBadGateway, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "上传成功",
+		"data": gin.H{"path": p},
+	})
+}
+
+// DeleteContainerFile 删除容器文件
+// @Summary 删除容器文件
+// @Description 删除容器内文件，删除目录需要指定 recursive
+// @Tags 文件管理
+// @Accept json
+// @Produce json
+// @Param name path string true "容器名称"
+// @Param node_id query int true "节点ID"
+// @Param body body models.FileDeleteRequest true "文件路径"
+// @Success 200 {object} map[string]interface{} "删除成功"
+// @Failure 400 {object} map[string]interface{} "参数错误或路径只读"
+// @Failure 502 {object} map[string]interface{} "节点删除失败"
+// @Router /api/containers/{name}/files/delete [post]
+func DeleteContainerFile(c *gin.Context) {
+	node, ok := fileNode(c)
+	if !ok {
+		return
+	}
+	var req models.FileDeleteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "参数错误: " + err.Error(),
+		})
+		return
+	}
+	name := c.Param("name")
+	details := gin.H{"path": req.Path, "recursive": req.Recursive}
+
+	p, err := services.CheckFilePath(req.Path, true)
+	if err != nil {
+		recordFileOperation(c, "file_delete", node, name, details, err)
+		fileError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	err = services.DeleteContainerFile(node, name, p, req.Recursive)
+	recordFileOperation(c, "file_delete", node, name, details, err)
+	if err != nil {
+		fileError(c, http.StatusBadGateway, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "删除成功",
+	})
+}
+
+// MakeContainerDir 在容器内创建目录
+// @Summary 创建容器目录
+// @Description 在容器内创建目录，上级目录不存在时一并创建
+// @Tags 文件管理
+// @Accept json
+// @Produce json
+// @Param name path string true "容器名称"
+// @Param node_id query int true "节点ID"
+// @Param body body models.FileMkdirRequest true "目录路径"
+// @Success 200 {object} map[string]interface{} "创建成功"
+// @Failure 400 {object} map[string]interface{} "参数错误或路径只读"
+// @Failure 502 {object} map[string]interface{} "节点创建失败"
+// @Router /api/containers/{name}/files/mkdir [post]
+func MakeContainerDir(c *gin.Context) {
+	node, ok := fileNode(c)
+	if !ok {
+		return
+	}
+	var req models.FileMkdirRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "参数错误: " + err.Error(),
+		})
+		return
+	}
+	name := c.Param("name")
+	details := gin.H{"path": req.Path}
+
+	p, err := services.CheckFilePath(req.Path, true)
+	if err != nil {
+		recordFileOperation(c, "file_mkdir", node, name, details, err)
+		fileError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	err = services.MakeContainerDir(node, name, p)
+	recordFileOperation(c, "file_mkdir", node, name, details, err)
+	if err != nil {
+		fileError(c, http.StatusBadGateway, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "创建成功",
+	})
+}
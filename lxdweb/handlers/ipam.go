@@ -0,0 +1,538 @@
+package handlers
+
+import (
+	"errors"
+	"lxdweb/database"
+	"lxdweb/models"
+	"lxdweb/services"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// NodeIPAMPage 节点地址池页面
+func NodeIPAMPage(c *gin.Context) {
+	session := sessions.Default(c)
+	username := session.Get("username")
+	nodeID := c.Param("id")
+
+	c.HTML(http.StatusOK, "node_ipam.html", gin.H{
+		"title":    "地址池 - LXD管理后台",
+		"username": username,
+		"node_id":  nodeID,
+	})
+}
+
+// GetIPPools 获取地址池列表
+// @Summary 获取地址池列表
+// @Description 查询地址池及其使用率，支持按节点和地址族过滤
+// @Tags 地址池管理
+// @Produce json
+// @Param node_id query string false "节点ID"
+// @Param family query string false "地址族(ipv4/ipv6)"
+// @Success 200 {object} map[string]interface{} "成功返回地址池列表"
+// @Failure 500 {object} map[string]interface{} "查询失败"
+// @Router /api/ipam/pools [get]
+func GetIPPools(c *gin.Context) {
+	var pools []models.IPPool
+	query := database.DB.Order("node_id, family, id")
+
+	if nodeID := c.Query("node_id"); nodeID != "" {
+		query = query.Where("node_id = ?", nodeID)
+	}
+	if family := c.Query("family"); family != "" {
+		query = query.Where("family = ?", family)
+	}
+
+	if err := query.Find(&pools).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "查询失败: " + err.Error(),
+		})
+		return
+	}
+
+	list := make([]gin.H, 0, len(pools))
+	for _, pool := range pools {
+		list = append(list, gin.H{
+			"pool":  pool,
+			"usage": services.IPPoolUtilization(pool),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "success",
+		"data": list,
+	})
+}
+
+// CreateIPPool 创建地址池
+// @Summary 创建地址池
+// @Description 为节点创建IPv4地址段或IPv6前缀地址池，同节点同地址族的地址池范围不能重叠
+// @Tags 地址池管理
+// @Accept json
+// @Produce json
+// @Param body body models.IPPoolRequest true "地址池参数"
+// @Success 200 {object} map[string]interface{} "创建成功"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Failure 404 {object} map[string]interface{} "节点不存在"
+// @Router /api/ipam/pools [post]
+func CreateIPPool(c *gin.Context) {
+	var req models.IPPoolRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	var node models.Node
+	if err := database.DB.First(&node, req.NodeID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "节点不存在",
+		})
+		return
+	}
+
+	pool := models.IPPool{NodeID: node.ID, Enabled: true}
+	applyIPPoolRequest(&pool, req)
+	if err := services.NormalizeIPPool(&pool); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  err.Error(),
+		})
+		return
+	}
+
+	if err := database.DB.Create(&pool).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "创建失败: " + err.Error(),
+		})
+		return
+	}
+	if !pool.Enabled {
+		database.DB.Model(&pool).Update("enabled", false)
+	}
+	services.ReassignIPPoolAddresses(pool.NodeID, pool.Family)
+	services.ReconcileIPAM(pool.NodeID, pool.Family)
+
+	recordOperation(c, "ip_pool_create", "ip_pool", pool.ID, pool)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "创建成功",
+		"data": pool,
+	})
+}
+
+// UpdateIPPool 更新地址池
+// @Summary 更新地址池
+// @Description 修改地址池范围、网关和启用状态，已登记的地址会重新归属到对应地址池
+// @Tags 地址池管理
+// @Accept json
+// @Produce json
+// @Param id path string true "地址池ID"
+// @Param body body models.IPPoolRequest true "地址池参数"
+// @Success 200 {object} map[string]interface{} "更新成功"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Failure 404 {object} map[string]interface{} "地址池不存在"
+// @Router /api/ipam/pools/{id} [put]
+func UpdateIPPool(c *gin.Context) {
+	var pool models.IPPool
+	if err := database.DB.First(&pool, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "地址池不存在",
+		})
+		return
+	}
+
+	var req models.IPPoolRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "参数错误: " + err.Error(),
+		})
+		return
+	}
+	if req.Family != pool.Family {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "不能修改地址池的地址族",
+		})
+		return
+	}
+
+	applyIPPoolRequest(&pool, req)
+	if err := services.NormalizeIPPool(&pool); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  err.Error(),
+		})
+		return
+	}
+
+	if err := database.DB.Save(&pool).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "更新失败: " + err.Error(),
+		})
+		return
+	}
+	services.ReassignIPPoolAddresses(pool.NodeID, pool.Family)
+
+	recordOperation(c, "ip_pool_update", "ip_pool", pool.ID, pool)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "更新成功",
+		"data": pool,
+	})
+}
+
+// DeleteIPPool 删除地址池
+// @Summary 删除地址池
+// @Description 删除地址池及其预留、黑名单记录，池内仍有已分配地址时拒绝删除
+// @Tags 地址池管理
+// @Produce json
+// @Param id path string true "地址池ID"
+// @Success 200 {object} map[string]interface{} "删除成功"
+// @Failure 400 {object} map[string]interface{} "地址池仍在使用"
+// @Failure 404 {object} map[string]interface{} "地址池不存在"
+// @Router /api/ipam/pools/{id} [delete]
+func DeleteIPPool(c *gin.Context) {
+	var pool models.IPPool
+	if err := database.DB.First(&pool, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "地址池不存在",
+		})
+		return
+	}
+
+	var allocated int64
+	database.DB.Model(&models.IPAddress{}).
+		Where("pool_id = ? AND status = ?", pool.ID, models.IPStatusAllocated).
+		Count(&allocated)
+	if allocated > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "地址池内仍有已分配的地址，请先删除相关绑定",
+		})
+		return
+	}
+
+	database.DB.Where("pool_id = ?", pool.ID).Delete(&models.IPAddress{})
+	if err := database.DB.Delete(&pool).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "删除失败: " + err.Error(),
+		})
+		return
+	}
+
+	recordOperation(c, "ip_pool_delete", "ip_pool", pool.ID, pool)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "删除成功",
+	})
+}
+
+func applyIPPoolRequest(pool *models.IPPool, req models.IPPoolRequest) {
+	pool.Name = req.Name
+	pool.Family = req.Family
+	pool.CIDR = req.CIDR
+	pool.RangeStart = req.RangeStart
+	pool.RangeEnd = req.RangeEnd
+	pool.Gateway = req.Gateway
+	pool.Description = req.Description
+	if req.Enabled != nil {
+		pool.Enabled = *req.Enabled
+	}
+}
+
+// GetIPPoolAddresses 获取地址池地址记录
+// @Summary 获取地址池地址记录
+// @Description 查询地址池内已分配、预留和拉黑的地址
+// @Tags 地址池管理
+// @Produce json
+// @Param id path string true "地址池ID"
+// @Param status query string false "地址状态(allocated/reserved/blacklisted)"
+// @Success 200 {object} map[string]interface{} "成功返回地址列表"
+// @Failure 404 {object} map[string]interface{} "地址池不存在"
+// @Router /api/ipam/pools/{id}/addresses [get]
+func GetIPPoolAddresses(c *gin.Context) {
+	var pool models.IPPool
+	if err := database.DB.First(&pool, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "地址池不存在",
+		})
+		return
+	}
+
+	var addresses []models.IPAddress
+	query := database.DB.Where("pool_id = ?", pool.ID).Order("address")
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	query.Find(&addresses)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "success",
+		"data": addresses,
+	})
+}
+
+// MarkIPPoolAddresses 预留或拉黑地址
+// @Summary 预留或拉黑地址
+// @Description 将地址池内的地址标记为预留或黑名单，支持 起始-结束 形式的地址段
+// @Tags 地址池管理
+// @Accept json
+// @Produce json
+// @Param id path string true "地址池ID"
+// @Param body body models.IPAddressRequest true "地址参数"
+// @Success 200 {object} map[string]interface{} "返回每个地址的处理结果"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Failure 404 {object} map[string]interface{} "地址池不存在"
+// @Router /api/ipam/pools/{id}/addresses [post]
+func MarkIPPoolAddresses(c *gin.Context) {
+	var pool models.IPPool
+	if err := database.DB.First(&pool, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "地址池不存在",
+		})
+		return
+	}
+
+	var req models.IPAddressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	var addresses []string
+	for _, input := range req.Addresses {
+		expanded, err := services.ExpandIPAddressInput(input)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code": 400,
+				"msg":  err.Error(),
+			})
+			return
+		}
+		addresses = append(addresses, expanded...)
+	}
+
+	results := make([]gin.H, 0, len(addresses))
+	success := 0
+	for _, address := range addresses {
+		if _, err := services.MarkIPAddress(pool, address, req.Status, req.Hostname, req.Note); err != nil {
+			results = append(results, gin.H{"address": address, "success": false, "msg": err.Error()})
+			continue
+		}
+		success++
+		results = append(results, gin.H{"address": address, "success": true})
+	}
+
+	recordOperation(c, "ip_address_"+req.Status, "ip_pool", pool.ID, gin.H{
+		"addresses": req.Addresses,
+		"hostname":  req.Hostname,
+		"note":      req.Note,
+		"success":   success,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "处理完成",
+		"data": gin.H{
+			"total":   len(addresses),
+			"success": success,
+			"results": results,
+		},
+	})
+}
+
+// DeleteIPAddress 删除地址记录
+// @Summary 删除地址记录
+// @Description 取消地址的预留或黑名单状态；已分配的地址需通过删除绑定释放
+// @Tags 地址池管理
+// @Produce json
+// @Param id path string true "地址记录ID"
+// @Success 200 {object} map[string]interface{} "删除成功"
+// @Failure 400 {object} map[string]interface{} "地址已分配"
+// @Failure 404 {object} map[string]interface{} "地址记录不存在"
+// @Router /api/ipam/addresses/{id} [delete]
+func DeleteIPAddress(c *gin.Context) {
+	var address models.IPAddress
+	if err := database.DB.First(&address, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "地址记录不存在",
+		})
+		return
+	}
+
+	if address.Status == models.IPStatusAllocated && c.Query("force") != "true" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "地址已分配给 " + address.Hostname + "，请先删除绑定",
+		})
+		return
+	}
+
+	if err := database.DB.Delete(&address).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "删除失败: " + err.Error(),
+		})
+		return
+	}
+
+	recordOperation(c, "ip_address_release", "ip_pool", address.PoolID, address)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "删除成功",
+	})
+}
+
+// GetIPAMUtilization 获取地址池使用率汇总
+// @Summary 获取地址池使用率汇总
+// @Description 按节点和地址族汇总地址池总量、已分配、预留、黑名单和空闲数量
+// @Tags 地址池管理
+// @Produce json
+// @Param node_id query string false "节点ID"
+// @Success 200 {object} map[string]interface{} "成功返回使用率"
+// @Router /api/ipam/utilization [get]
+func GetIPAMUtilization(c *gin.Context) {
+	var pools []models.IPPool
+	query := database.DB.Order("node_id, family, id")
+	if nodeID := c.Query("node_id"); nodeID != "" {
+		query = query.Where("node_id = ?", nodeID)
+	}
+	query.Find(&pools)
+
+	summary := make(map[string]*services.IPPoolUsage)
+	for _, family := range []string{models.IPFamilyV4, models.IPFamilyV6} {
+		summary[family] = &services.IPPoolUsage{}
+	}
+
+	poolUsages := make([]gin.H, 0, len(pools))
+	for _, pool := range pools {
+		usage := services.IPPoolUtilization(pool)
+		poolUsages = append(poolUsages, gin.H{
+			"pool_id": pool.ID,
+			"node_id": pool.NodeID,
+			"name":    pool.Name,
+			"family":  pool.Family,
+			"cidr":    pool.CIDR,
+			"usage":   usage,
+		})
+
+		total, ok := summary[pool.Family]
+		if !ok {
+			continue
+		}
+		total.Total += usage.Total
+		total.Allocated += usage.Allocated
+		total.Reserved += usage.Reserved
+		total.Blacklisted += usage.Blacklisted
+		total.Free += usage.Free
+	}
+
+	for _, total := range summary {
+		if total.Total > 0 {
+			used := float64(total.Allocated + total.Reserved + total.Blacklisted)
+			total.UsagePercent = math.Round(used/total.Total*10000) / 100
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "success",
+		"data": gin.H{
+			"summary": summary,
+			"pools":   poolUsages,
+		},
+	})
+}
+
+// GetIPConflicts 获取地址冲突
+// @Summary 获取地址冲突
+// @Description 对比节点同步到的IPv4/IPv6绑定与地址池记录，列出重复绑定、黑名单占用、预留占用、归属不一致和池外地址
+// @Tags 地址池管理
+// @Produce json
+// @Param node_id query string false "节点ID"
+// @Success 200 {object} map[string]interface{} "成功返回冲突列表"
+// @Router /api/ipam/conflicts [get]
+func GetIPConflicts(c *gin.Context) {
+	nodeID, _ := strconv.ParseUint(c.Query("node_id"), 10, 32)
+	conflicts := services.DetectIPConflicts(uint(nodeID))
+	if conflicts == nil {
+		conflicts = []services.IPConflict{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "success",
+		"data": conflicts,
+	})
+}
+
+// allocateBindingIP 创建绑定前从地址池取得地址；指定地址时检查唯一性并登记，节点未配置地址池时返回空串由节点自行选址
+func allocateBindingIP(nodeID uint, family, hostname, address string) (string, error) {
+	if address != "" {
+		if conflict := services.CheckIPUnique(nodeID, address, hostname); conflict != nil {
+			return "", conflict
+		}
+		row, err := services.ClaimIP(nodeID, address, hostname, models.IPSourceBinding)
+		if err != nil {
+			return "", err
+		}
+		return row.Address, nil
+	}
+
+	row, err := services.AllocateIP(nodeID, family, hostname)
+	if errors.Is(err, services.ErrNoIPPool) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return row.Address, nil
+}
+
+// recordBindingIP 节点返回的实际地址与预分配不一致时，以节点为准更新地址池记录
+func recordBindingIP(nodeID uint, hostname, allocated string, data interface{}, key string) {
+	item, ok := data.(map[string]interface{})
+	if !ok {
+		return
+	}
+	actual, _ := item[key].(string)
+	if actual == "" {
+		return
+	}
+	addr, err := services.ParseIPAddress(actual)
+	if err != nil || addr.String() == allocated {
+		return
+	}
+
+	if allocated != "" {
+		services.ReleaseIP(nodeID, allocated)
+	}
+	services.RecordBindingIP(nodeID, addr.String(), hostname)
+}
@@ -147,7 +147,7 @@ func GetIPv6BindingsFromCache(c *gin.Context) {
 	var bindings []models.IPv6BindingCache
 	query := database.DB.
 		Joins("JOIN nodes ON nodes.id = ipv6_binding_caches.node_id").
-		Where("nodes.status = ?", "active").
+		Where("nodes.status IN ?", models.OnlineNodeStatuses).
 		Order("ipv6_binding_caches.last_sync desc")
 
 	if nodeID := c.Query("node_id"); nodeID != "" {
@@ -193,7 +193,7 @@ func SyncAllIPv6(c *gin.Context) {
 	}
 
 	var nodes []models.Node
-	if err := database.DB.Where("status = ?", "active").Find(&nodes).Error; err != nil {
+	if err := database.DB.Where("status IN ? AND maintenance = ?", models.OnlineNodeStatuses, false).Find(&nodes).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code": 500,
 			"msg":  "查询节点失败",
@@ -273,7 +273,7 @@ func GetIPv6SyncStatus(c *gin.Context) {
 
 // CreateIPv6Binding 创建IPv6绑定
 // @Summary 创建IPv6绑定
-// @Description 为指定容器创建新的IPv6地址绑定
+// @Description 为指定容器创建新的IPv6地址绑定，节点配置了地址池时由地址池分配地址
 // @Tags IPv6管理
 // @Accept json
 // @Produce json
@@ -281,6 +281,7 @@ func GetIPv6SyncStatus(c *gin.Context) {
 // @Success 200 {object} map[string]interface{} "创建成功"
 // @Failure 400 {object} map[string]interface{} "参数错误"
 // @Failure 404 {object} map[string]interface{} "节点不存在"
+// @Failure 409 {object} map[string]interface{} "地址已被占用，conflict 为占用者信息"
 // @Router /api/ipv6 [post]
 func CreateIPv6Binding(c *gin.Context) {
 	var req models.CreateIPv6Request
@@ -301,19 +302,43 @@ func CreateIPv6Binding(c *gin.Context) {
 		return
 	}
 
+	allocated, err := allocateBindingIP(node.ID, models.IPFamilyV6, req.ContainerHostname, req.Address)
+	if conflict := resourceConflict(err); conflict != nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"code":     409,
+			"msg":      "地址分配失败: " + conflict.Error(),
+			"conflict": conflict,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "地址分配失败: " + err.Error(),
+		})
+		return
+	}
+
 	ipv6Data := map[string]interface{}{
 		"hostname":    req.ContainerHostname,
 		"description": req.Description,
 	}
+	if allocated != "" {
+		ipv6Data["public_ipv6"] = allocated
+	}
 
 	result := callNodeAPIForIPv6Mgmt(node, "POST", "/api/ipv6/add", ipv6Data)
 	if result["code"] != float64(200) {
+		if allocated != "" {
+			services.ReleaseIP(node.ID, allocated)
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code": 500,
 			"msg":  result["msg"],
 		})
 		return
 	}
+	recordBindingIP(node.ID, req.ContainerHostname, allocated, result["data"], "public_ipv6")
 
 	time.Sleep(1 * time.Second)
 	go services.SyncNodeIPv6Bindings(node.ID)
@@ -367,6 +392,7 @@ func DeleteIPv6Binding(c *gin.Context) {
 		})
 		return
 	}
+	services.ReleaseIP(node.ID, binding.IPv6Address)
 
 	if err := database.DB.Unscoped().Delete(&binding).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -385,9 +411,9 @@ func DeleteIPv6Binding(c *gin.Context) {
 func callNodeAPIForIPv6Mgmt(node models.Node, method, path string, data interface{}) map[string]interface{} {
 	client := &http.Client{
 		Timeout: 30 * time.Second,
-		Transport: &http.Transport{
+		Transport: services.NodeAPITransport(node, &http.Transport{
 			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		},
+		}),
 	}
 
 	var body io.Reader
@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"fmt"
+	"lxdweb/database"
+	"lxdweb/models"
+	"lxdweb/pkg/logger"
+	"lxdweb/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// EnterNodeMaintenance 节点进入维护模式
+// @Summary 节点进入维护模式
+// @Description 将节点标记为维护中，维护期间不参与容器调度、自动同步和告警，可选停止或迁移节点上的容器
+// @Tags 节点管理
+// @Accept json
+// @Produce json
+// @Param id path string true "节点ID"
+// @Param body body models.MaintenanceRequest true "维护参数"
+// @Success 200 {object} map[string]interface{} "已进入维护模式"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Failure 404 {object} map[string]interface{} "节点不存在"
+// @Router /api/nodes/{id}/maintenance [post]
+func EnterNodeMaintenance(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := c.Param("id")
+
+	var node models.Node
+	if err := database.DB.First(&node, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "节点不存在",
+		})
+		return
+	}
+
+	var req models.MaintenanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	task, err := services.EnterMaintenance(node, req, currentAdminID(c), c.ClientIP())
+	if err != nil {
+		logger.Global.Warn(ctx, "节点进入维护模式失败",
+			zap.Uint("node_id", node.ID),
+			zap.Error(err),
+			zap.String("action", "enter_maintenance"))
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  err.Error(),
+		})
+		return
+	}
+
+	logger.Global.Info(ctx, "节点进入维护模式",
+		zap.Uint("node_id", node.ID),
+		zap.String("reason", req.Reason),
+		zap.String("drain", req.Drain),
+		zap.String("action", "enter_maintenance"))
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "节点已进入维护模式",
+		"data": gin.H{
+			"drain_task": task,
+		},
+	})
+}
+
+// ExitNodeMaintenance 节点退出维护模式
+// @Summary 节点退出维护模式
+// @Description 结束节点维护窗口并写入审计日志
+// @Tags 节点管理
+// @Produce json
+// @Param id path string true "节点ID"
+// @Success 200 {object} map[string]interface{} "已退出维护模式"
+// @Failure 400 {object} map[string]interface{} "节点未处于维护模式"
+// @Failure 404 {object} map[string]interface{} "节点不存在"
+// @Router /api/nodes/{id}/maintenance [delete]
+func ExitNodeMaintenance(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := c.Param("id")
+
+	var node models.Node
+	if err := database.DB.First(&node, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "节点不存在",
+		})
+		return
+	}
+
+	if err := services.ExitMaintenance(node, currentAdminID(c), c.ClientIP()); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  err.Error(),
+		})
+		return
+	}
+
+	logger.Global.Info(ctx, "节点退出维护模式",
+		zap.Uint("node_id", node.ID),
+		zap.String("action", "exit_maintenance"))
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "节点已退出维护模式",
+	})
+}
+
+// SetGroupMaintenance 按分组批量切换维护模式
+// @Summary 按分组批量切换维护模式
+// @Description 按分组、地域或标签筛选节点，批量进入或退出维护模式
+// @Tags 节点管理
+// @Accept json
+// @Produce json
+// @Param body body models.GroupMaintenanceRequest true "维护参数"
+// @Success 200 {object} map[string]interface{} "处理完成"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Failure 404 {object} map[string]interface{} "没有符合条件的节点"
+// @Router /api/nodes/maintenance/group [post]
+func SetGroupMaintenance(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req models.GroupMaintenanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	sel := services.NodeSelector{Group: req.Group, Region: req.Region, Tags: req.Tags}
+	if sel.IsEmpty() {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "请至少指定分组、地域或标签之一",
+		})
+		return
+	}
+	if req.Enabled && req.Reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "进入维护模式必须填写原因",
+		})
+		return
+	}
+
+	nodes, err := services.FindNodes(sel, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "查询节点失败",
+		})
+		return
+	}
+	if len(nodes) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "没有符合条件的节点",
+		})
+		return
+	}
+
+	adminID := currentAdminID(c)
+	successCount := 0
+	failedCount := 0
+	var failedErrors []string
+	var drainTasks []*models.BatchTask
+
+	for _, node := range nodes {
+		var err error
+		if req.Enabled {
+			var task *models.BatchTask
+			task, err = services.EnterMaintenance(node, models.MaintenanceRequest{
+				Reason:       req.Reason,
+				Until:        req.Until,
+				Drain:        req.Drain,
+				TargetNodeID: req.TargetNodeID,
+			}, adminID, c.ClientIP())
+			if task != nil {
+				drainTasks = append(drainTasks, task)
+			}
+		} else {
+			err = services.ExitMaintenance(node, adminID, c.ClientIP())
+		}
+
+		if err != nil {
+			failedCount++
+			failedErrors = append(failedErrors, fmt.Sprintf("节点 %s: %s", node.Name, err.Error()))
+		} else {
+			successCount++
+		}
+	}
+
+	logger.Global.Info(ctx, "按分组切换维护模式完成",
+		zap.Bool("enabled", req.Enabled),
+		zap.String("selector", sel.String()),
+		zap.Int("success", successCount),
+		zap.Int("failed", failedCount),
+		zap.String("action", "group_maintenance"))
+
+	response := gin.H{
+		"code":          200,
+		"msg":           fmt.Sprintf("处理完成：成功 %d 个，失败 %d 个", successCount, failedCount),
+		"success_count": successCount,
+		"failed_count":  failedCount,
+		"drain_tasks":   drainTasks,
+	}
+	if len(failedErrors) > 0 {
+		response["errors"] = failedErrors
+	}
+
+	c.JSON(http.StatusOK, response)
+}
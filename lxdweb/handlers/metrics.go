@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"lxdweb/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Metrics Prometheus 指标
+// @Summary Prometheus 指标
+// @Description 以 Prometheus 文本格式输出节点、容器指标以及同步耗时、节点API延迟和任务队列等自身指标，需令牌或 IP 白名单
+// @Tags 系统管理
+// @Produce plain
+// @Success 200 {string} string "指标数据"
+// @Failure 403 {string} string "无权访问"
+// @Router /metrics [get]
+func Metrics(c *gin.Context) {
+	c.Status(http.StatusOK)
+	c.Header("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := services.WriteMetrics(c.Writer); err != nil {
+		c.Error(err)
+	}
+}
@@ -3,7 +3,10 @@ import (
 	"fmt"
 	"lxdweb/database"
 	"lxdweb/models"
+	"lxdweb/services"
 	"net/http"
+	"strconv"
+	"strings"
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
 )
@@ -77,7 +80,7 @@ func GetNATRule(c *gin.Context) {
 }
 // CreateNATRule 创建NAT规则
 // @Summary 创建NAT规则
-// @Description 创建新的NAT端口转发规则
+// @Description 创建新的NAT端口转发规则，支持端口段和tcp+udp双协议，未指定外部端口时从节点端口池自动分配
 // @Tags NAT管理
 // @Accept json
 // @Produce json
@@ -85,7 +88,7 @@ func GetNATRule(c *gin.Context) {
 // @Success 200 {object} map[string]interface{} "创建成功"
 // @Failure 400 {object} map[string]interface{} "参数错误"
 // @Failure 404 {object} map[string]interface{} "节点不存在"
-// @Failure 409 {object} map[string]interface{} "端口已被占用"
+// @Failure 409 {object} map[string]interface{} "端口已被占用，conflict 为占用者信息"
 // @Router /api/nat [post]
 func CreateNATRule(c *gin.Context) {
 	var req models.CreateNATRequest
@@ -104,53 +107,76 @@ func CreateNATRule(c *gin.Context) {
 		})
 		return
 	}
-	var existingRule models.NATRule
-	if err := database.DB.Where("node_id = ? AND external_port = ? AND protocol = ?", 
-		req.NodeID, req.ExternalPort, req.Protocol).First(&existingRule).Error; err == nil {
-		c.JSON(http.StatusConflict, gin.H{
-			"code": 409,
-			"msg":  "该端口已被占用",
-		})
+	rule, status, err := createNATRule(node, req)
+	if err != nil {
+		resp := gin.H{
+			"code": status,
+			"msg":  err.Error(),
+		}
+		if conflict := resourceConflict(err); conflict != nil {
+			resp["conflict"] = conflict
+		}
+		c.JSON(status, resp)
 		return
 	}
-	natData := map[string]interface{}{
-		"hostname": req.ContainerHostname,
-		"dport":    req.ExternalPort,
-		"sport":    req.InternalPort,
-		"dtype":    req.Protocol,
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "NAT规则创建成功",
+		"data": rule,
+	})
+}
+// createNATRule 校验端口段、分配或检查外部端口和配额后推送到节点并保存，出错时返回对应的HTTP状态码
+func createNATRule(node models.Node, req models.CreateNATRequest) (models.NATRule, int, error) {
+	externalEnd, count, err := services.NormalizeNATRange(req.ExternalPort, req.ExternalPortEnd, req.InternalPort, req.InternalPortEnd)
+	if err != nil {
+		return models.NATRule{}, http.StatusBadRequest, err
+	}
+	req.ExternalPortEnd = externalEnd
+	if req.ExternalPort == 0 {
+		port, err := services.AllocateNATPort(node.ID, req.ContainerHostname, req.Protocol, count)
+		if err != nil {
+			return models.NATRule{}, http.StatusConflict, err
+		}
+		req.ExternalPort = port
+		if req.InternalPortEnd > 0 {
+			req.ExternalPortEnd = port + count - 1
+		}
+		defer services.ReleaseNATPortReservation(node.ID, req.Protocol, req.ExternalPort, req.ExternalPortEnd)
+	} else {
+		start, end := services.NATPortSpan(req.ExternalPort, req.ExternalPortEnd)
+		if err := services.CheckNATPortRangeAvailable(node.ID, req.Protocol, start, end); err != nil {
+			return models.NATRule{}, http.StatusConflict, fmt.Errorf("该端口已被占用: %w", err)
+		}
+		if err := services.CheckNATQuota(node.ID, req.ContainerHostname, services.NATPortList(req.ExternalPort, req.ExternalPortEnd)); err != nil {
+			return models.NATRule{}, http.StatusConflict, err
+		}
+	}
+	natData := natNodePayload(req.ContainerHostname, req.Protocol, req.ExternalPort, req.ExternalPortEnd, req.InternalPort, req.InternalPortEnd)
+	if req.Protocol == models.NATProtocolBoth {
+		natData["dtype"] = "both"
 	}
 	if req.Description != "" {
 		natData["description"] = req.Description
 	}
 	result := callNodeAPI(node, "POST", "/api/addport", natData)
 	if result["code"] != float64(200) {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code": 500,
-			"msg":  result["msg"],
-		})
-		return
+		return models.NATRule{}, http.StatusInternalServerError, fmt.Errorf("%v", result["msg"])
 	}
 	rule := models.NATRule{
-		NodeID:            req.NodeID,
+		NodeID:            node.ID,
 		ContainerHostname: req.ContainerHostname,
 		ExternalPort:      req.ExternalPort,
 		InternalPort:      req.InternalPort,
+		ExternalPortEnd:   req.ExternalPortEnd,
+		InternalPortEnd:   req.InternalPortEnd,
 		Protocol:          req.Protocol,
 		Description:       req.Description,
 		Status:            "active",
 	}
 	if err := database.DB.Create(&rule).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code": 500,
-			"msg":  "保存失败: " + err.Error(),
-		})
-		return
+		return models.NATRule{}, http.StatusInternalServerError, fmt.Errorf("保存失败: %v", err)
 	}
-	c.JSON(http.StatusOK, gin.H{
-		"code": 200,
-		"msg":  "NAT规则创建成功",
-		"data": rule,
-	})
+	return rule, http.StatusOK, nil
 }
 // UpdateNATRule 更新NAT规则
 // @Summary 更新NAT规则
@@ -228,17 +254,28 @@ func DeleteNATRule(c *gin.Context) {
 		})
 		return
 	}
-	natData := map[string]interface{}{
-		"hostname": rule.ContainerHostname,
-		"dport":    rule.ExternalPort,
-		"sport":    rule.InternalPort,
-		"dtype":    rule.Protocol,
+	// 节点删除接口按单一协议处理，tcp+udp 规则需分别删除；部分失败时保留未删除的协议
+	var remaining []string
+	var errMsgs []string
+	for _, proto := range services.NATProtocols(rule.Protocol) {
+		natData := natNodePayload(rule.ContainerHostname, proto, rule.ExternalPort, rule.ExternalPortEnd, rule.InternalPort, rule.InternalPortEnd)
+		result := callNodeAPI(node, "POST", "/api/delport", natData)
+		if result["code"] != float64(200) {
+			remaining = append(remaining, proto)
+			errMsgs = append(errMsgs, fmt.Sprintf("%s: %v", proto, result["msg"]))
+			continue
+		}
+		database.DB.Unscoped().Where("node_id = ? AND container_hostname = ? AND external_port = ? AND LOWER(protocol) = ?",
+			rule.NodeID, rule.ContainerHostname, rule.ExternalPort, proto).
+			Delete(&models.NATRuleCache{})
 	}
-	result := callNodeAPI(node, "POST", "/api/delport", natData)
-	if result["code"] != float64(200) {
+	if len(remaining) > 0 {
+		if len(remaining) < len(services.NATProtocols(rule.Protocol)) {
+			database.DB.Model(&rule).Update("protocol", remaining[0])
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code": 500,
-			"msg":  result["msg"],
+			"msg":  "删除失败: " + strings.Join(errMsgs, "; "),
 		})
 		return
 	}
@@ -250,15 +287,25 @@ func DeleteNATRule(c *gin.Context) {
 		return
 	}
 
-	database.DB.Unscoped().Where("node_id = ? AND container_hostname = ? AND external_port = ? AND protocol = ?",
-		rule.NodeID, rule.ContainerHostname, rule.ExternalPort, rule.Protocol).
-		Delete(&models.NATRuleCache{})
-
 	c.JSON(http.StatusOK, gin.H{
 		"code": 200,
 		"msg":  "删除成功",
 	})
 }
+// natNodePayload 构造节点 addport/delport 请求参数，端口段规则附带结束端口
+func natNodePayload(hostname, protocol string, dport, dportEnd, sport, sportEnd int) map[string]interface{} {
+	natData := map[string]interface{}{
+		"hostname": hostname,
+		"dport":    dport,
+		"sport":    sport,
+		"dtype":    protocol,
+	}
+	if dportEnd > 0 && sportEnd > 0 {
+		natData["dport_end"] = dportEnd
+		natData["sport_end"] = sportEnd
+	}
+	return natData
+}
 // SyncNATRules 同步NAT规则
 // @Summary 同步NAT规则
 // @Description 同步指定节点的NAT规则信息
@@ -303,13 +350,14 @@ func SyncNATRules(c *gin.Context) {
 
 // CheckNATPort 检查NAT端口是否可用
 // @Summary 检查NAT端口是否可用
-// @Description 检查指定端口和协议是否可用于NAT转发
+// @Description 检查指定端口（或端口段）和协议是否可用于NAT转发，按配置的唯一性范围同时检查其他节点
 // @Tags NAT管理
 // @Produce json
 // @Param node_id query string true "节点ID"
 // @Param hostname query string true "容器名称"
-// @Param protocol query string true "协议类型(tcp/udp)"
+// @Param protocol query string true "协议类型(tcp/udp/tcp+udp)"
 // @Param port query int true "端口号"
+// @Param port_end query int false "端口段结束端口"
 // @Success 200 {object} map[string]interface{} "检查结果"
 // @Failure 400 {object} map[string]interface{} "参数错误"
 // @Failure 404 {object} map[string]interface{} "节点不存在"
@@ -345,6 +393,26 @@ func CheckNATPort(c *gin.Context) {
 		return
 	}
 
+	if portNum, err := strconv.Atoi(port); err == nil {
+		portEnd, _ := strconv.Atoi(c.Query("port_end"))
+		start, end := services.NATPortSpan(portNum, portEnd)
+		if err := services.CheckNATPortRangeAvailable(node.ID, protocol, start, end); err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"code": 200,
+				"msg":  "success",
+				"data": map[string]interface{}{
+					"available": false,
+					"reason":    err.Error(),
+					"conflict":  resourceConflict(err),
+				},
+			})
+			return
+		}
+	}
+
+	if protocol == models.NATProtocolBoth {
+		protocol = "both"
+	}
 	result := callNodeAPI(node, "GET", fmt.Sprintf("/api/nat/check?hostname=%s&protocol=%s&port=%s", hostname, protocol, port), nil)
 	c.JSON(http.StatusOK, result)
 }
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"lxdweb/database"
+	"lxdweb/models"
+	"lxdweb/services"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportNATRules 导出NAT规则
+// @Summary 导出NAT规则
+// @Description 按节点或容器导出NAT规则为CSV或JSON，合并规则表与同步缓存，导出内容可直接用于批量导入
+// @Tags NAT管理
+// @Produce json
+// @Produce text/csv
+// @Param node_id query string true "节点ID"
+// @Param hostname query string false "容器名称，为空时导出整个节点"
+// @Param format query string false "导出格式(csv/json)，默认csv"
+// @Success 200 {file} file "导出文件"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Failure 404 {object} map[string]interface{} "节点不存在"
+// @Router /api/nat/export [get]
+func ExportNATRules(c *gin.Context) {
+	var node models.Node
+	if err := database.DB.First(&node, c.Query("node_id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "节点不存在",
+		})
+		return
+	}
+
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "json" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "不支持的导出格式: " + format,
+		})
+		return
+	}
+
+	hostname := c.Query("hostname")
+	rows := services.NATExportRows(node.ID, hostname)
+
+	name := node.Name
+	if hostname != "" {
+		name += "-" + hostname
+	}
+	filename := fmt.Sprintf("nat-%s-%s.%s", name, time.Now().Format("20060102"), format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	if format == "json" {
+		c.JSON(http.StatusOK, rows)
+		return
+	}
+
+	var buf bytes.Buffer
+	// 写入UTF-8 BOM，便于Excel正确识别中文描述
+	buf.WriteString("\ufeff")
+	if err := services.WriteNATRowsCSV(&buf, rows); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "导出失败: " + err.Error(),
+		})
+		return
+	}
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+}
+
+// ImportNATRules 批量导入NAT规则
+// @Summary 批量导入NAT规则
+// @Description 从CSV文本或JSON规则列表批量导入NAT规则。dry_run 为 true 时只校验并返回冲突报告；否则通过节点API逐行创建，校验未通过的行跳过，返回每行结果
+// @Tags NAT管理
+// @Accept json
+// @Produce json
+// @Param body body models.NATImportRequest true "导入参数"
+// @Success 200 {object} map[string]interface{} "导入结果"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Failure 404 {object} map[string]interface{} "节点不存在"
+// @Router /api/nat/import [post]
+func ImportNATRules(c *gin.Context) {
+	var req models.NATImportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	var node models.Node
+	if err := database.DB.First(&node, req.NodeID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "节点不存在",
+		})
+		return
+	}
+
+	rows := req.Rules
+	if strings.TrimSpace(req.CSV) != "" {
+		parsed, err := services.ParseNATRowsCSV(strings.NewReader(req.CSV))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code": 400,
+				"msg":  err.Error(),
+			})
+			return
+		}
+		rows = parsed
+	}
+	if len(rows) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "没有可导入的规则",
+		})
+		return
+	}
+	if len(rows) > services.NATImportMaxRows {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  fmt.Sprintf("单次最多导入 %d 条规则", services.NATImportMaxRows),
+		})
+		return
+	}
+
+	results := services.ValidateNATImport(node.ID, rows)
+	if !req.DryRun {
+		for i := range results {
+			if results[i].Status != services.NATImportOK {
+				continue
+			}
+			row := results[i].Rule
+			rule, _, err := createNATRule(node, models.CreateNATRequest{
+				NodeID:            node.ID,
+				ContainerHostname: row.ContainerHostname,
+				ExternalPort:      row.ExternalPort,
+				ExternalPortEnd:   row.ExternalPortEnd,
+				InternalPort:      row.InternalPort,
+				InternalPortEnd:   row.InternalPortEnd,
+				Protocol:          row.Protocol,
+				Description:       row.Description,
+			})
+			if err != nil {
+				results[i].Status = services.NATImportFailed
+				results[i].Message = err.Error()
+				continue
+			}
+			results[i].Status = services.NATImportCreated
+			results[i].Message = ""
+			results[i].Rule.ExternalPort = rule.ExternalPort
+			results[i].Rule.ExternalPortEnd = rule.ExternalPortEnd
+		}
+	}
+
+	summary := make(map[string]int)
+	for _, r := range results {
+		summary[r.Status]++
+	}
+
+	if !req.DryRun {
+		recordOperation(c, "nat_import", "node", node.ID, gin.H{
+			"total":   len(results),
+			"summary": summary,
+		})
+	}
+
+	msg := fmt.Sprintf("校验完成：共 %d 条，可导入 %d 条，冲突 %d 条，无效 %d 条",
+		len(results), summary[services.NATImportOK], summary[services.NATImportConflict], summary[services.NATImportInvalid])
+	if !req.DryRun {
+		msg = fmt.Sprintf("导入完成：成功 %d 条，失败 %d 条，跳过 %d 条",
+			summary[services.NATImportCreated], summary[services.NATImportFailed],
+			summary[services.NATImportConflict]+summary[services.NATImportInvalid])
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  msg,
+		"data": gin.H{
+			"dry_run": req.DryRun,
+			"summary": summary,
+			"results": results,
+		},
+	})
+}
@@ -0,0 +1,354 @@
+package handlers
+
+import (
+	"lxdweb/database"
+	"lxdweb/models"
+	"lxdweb/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetNATPortPools 获取NAT端口池列表
+// @Summary 获取NAT端口池列表
+// @Description 查询节点外部端口池及其使用率
+// @Tags NAT管理
+// @Produce json
+// @Param node_id query string false "节点ID"
+// @Success 200 {object} map[string]interface{} "成功返回端口池列表"
+// @Failure 500 {object} map[string]interface{} "查询失败"
+// @Router /api/nat-ports/pools [get]
+func GetNATPortPools(c *gin.Context) {
+	var pools []models.NATPortPool
+	query := database.DB.Order("node_id, port_start")
+	if nodeID := c.Query("node_id"); nodeID != "" {
+		query = query.Where("node_id = ?", nodeID)
+	}
+
+	if err := query.Find(&pools).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "查询失败: " + err.Error(),
+		})
+		return
+	}
+
+	list := make([]gin.H, 0, len(pools))
+	for _, pool := range pools {
+		list = append(list, gin.H{
+			"pool":  pool,
+			"usage": services.NATPortPoolUtilization(pool),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "success",
+		"data": list,
+	})
+}
+
+// CreateNATPortPool 创建NAT端口池
+// @Summary 创建NAT端口池
+// @Description 为节点配置外部端口范围，可设置单个容器在本池中的端口配额
+// @Tags NAT管理
+// @Accept json
+// @Produce json
+// @Param body body models.NATPortPoolRequest true "端口池参数"
+// @Success 200 {object} map[string]interface{} "创建成功"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Failure 404 {object} map[string]interface{} "节点不存在"
+// @Router /api/nat-ports/pools [post]
+func CreateNATPortPool(c *gin.Context) {
+	var req models.NATPortPoolRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	var node models.Node
+	if err := database.DB.First(&node, req.NodeID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "节点不存在",
+		})
+		return
+	}
+
+	pool := models.NATPortPool{NodeID: node.ID, Enabled: true}
+	applyNATPortPoolRequest(&pool, req)
+	if err := services.ValidateNATPortPool(pool); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  err.Error(),
+		})
+		return
+	}
+
+	if err := database.DB.Create(&pool).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "创建失败: " + err.Error(),
+		})
+		return
+	}
+	if !pool.Enabled {
+		database.DB.Model(&pool).Update("enabled", false)
+	}
+
+	recordOperation(c, "nat_port_pool_create", "nat_port_pool", pool.ID, pool)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "创建成功",
+		"data": pool,
+	})
+}
+
+// UpdateNATPortPool 更新NAT端口池
+// @Summary 更新NAT端口池
+// @Description 修改端口池范围、配额和启用状态，已创建的规则不受影响
+// @Tags NAT管理
+// @Accept json
+// @Produce json
+// @Param id path string true "端口池ID"
+// @Param body body models.NATPortPoolRequest true "端口池参数"
+// @Success 200 {object} map[string]interface{} "更新成功"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Failure 404 {object} map[string]interface{} "端口池不存在"
+// @Router /api/nat-ports/pools/{id} [put]
+func UpdateNATPortPool(c *gin.Context) {
+	var pool models.NATPortPool
+	if err := database.DB.First(&pool, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "端口池不存在",
+		})
+		return
+	}
+
+	var req models.NATPortPoolRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	applyNATPortPoolRequest(&pool, req)
+	if err := services.ValidateNATPortPool(pool); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  err.Error(),
+		})
+		return
+	}
+
+	if err := database.DB.Save(&pool).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "更新失败: " + err.Error(),
+		})
+		return
+	}
+
+	recordOperation(c, "nat_port_pool_update", "nat_port_pool", pool.ID, pool)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "更新成功",
+		"data": pool,
+	})
+}
+
+// DeleteNATPortPool 删除NAT端口池
+// @Summary 删除NAT端口池
+// @Description 删除端口池配置，池内已创建的NAT规则保留
+// @Tags NAT管理
+// @Produce json
+// @Param id path string true "端口池ID"
+// @Success 200 {object} map[string]interface{} "删除成功"
+// @Failure 404 {object} map[string]interface{} "端口池不存在"
+// @Router /api/nat-ports/pools/{id} [delete]
+func DeleteNATPortPool(c *gin.Context) {
+	var pool models.NATPortPool
+	if err := database.DB.First(&pool, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "端口池不存在",
+		})
+		return
+	}
+
+	if err := database.DB.Delete(&pool).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "删除失败: " + err.Error(),
+		})
+		return
+	}
+
+	recordOperation(c, "nat_port_pool_delete", "nat_port_pool", pool.ID, pool)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "删除成功",
+	})
+}
+
+func applyNATPortPoolRequest(pool *models.NATPortPool, req models.NATPortPoolRequest) {
+	pool.Name = req.Name
+	pool.PortStart = req.PortStart
+	pool.PortEnd = req.PortEnd
+	pool.ContainerQuota = req.ContainerQuota
+	pool.Description = req.Description
+	if req.Enabled != nil {
+		pool.Enabled = *req.Enabled
+	}
+}
+
+// GetNATPortQuotas 获取容器NAT端口配额
+// @Summary 获取容器NAT端口配额
+// @Description 查询节点上单独设置了NAT端口配额的容器及其已占用的外部端口数
+// @Tags NAT管理
+// @Produce json
+// @Param node_id query string true "节点ID"
+// @Success 200 {object} map[string]interface{} "成功返回配额列表"
+// @Failure 400 {object} map[string]interface{} "缺少参数"
+// @Router /api/nat-ports/quotas [get]
+func GetNATPortQuotas(c *gin.Context) {
+	nodeID := c.Query("node_id")
+	if nodeID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "缺少node_id参数",
+		})
+		return
+	}
+
+	var quotas []models.NATPortQuota
+	database.DB.Where("node_id = ?", nodeID).Order("hostname").Find(&quotas)
+
+	list := make([]gin.H, 0, len(quotas))
+	for _, quota := range quotas {
+		list = append(list, gin.H{
+			"quota": quota,
+			"used":  services.ContainerNATPortCount(quota.NodeID, quota.Hostname),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "success",
+		"data": list,
+	})
+}
+
+// SetNATPortQuota 设置容器NAT端口配额
+// @Summary 设置容器NAT端口配额
+// @Description 设置容器在节点上最多可占用的外部端口数，端口段规则按端口个数计，max_ports 为0表示不限制
+// @Tags NAT管理
+// @Accept json
+// @Produce json
+// @Param body body models.NATPortQuotaRequest true "配额参数"
+// @Success 200 {object} map[string]interface{} "设置成功"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Router /api/nat-ports/quotas [put]
+func SetNATPortQuota(c *gin.Context) {
+	var req models.NATPortQuotaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	var quota models.NATPortQuota
+	database.DB.Where("node_id = ? AND hostname = ?", req.NodeID, req.Hostname).First(&quota)
+	quota.NodeID = req.NodeID
+	quota.Hostname = req.Hostname
+	quota.MaxPorts = req.MaxPorts
+	if err := database.DB.Save(&quota).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "保存失败: " + err.Error(),
+		})
+		return
+	}
+
+	recordOperation(c, "nat_port_quota_set", "nat_port_quota", quota.ID, quota)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "设置成功",
+		"data": quota,
+	})
+}
+
+// DeleteNATPortQuota 删除容器NAT端口配额
+// @Summary 删除容器NAT端口配额
+// @Description 删除容器的单独配额设置，之后仅受端口池配额限制
+// @Tags NAT管理
+// @Produce json
+// @Param id path string true "配额ID"
+// @Success 200 {object} map[string]interface{} "删除成功"
+// @Failure 404 {object} map[string]interface{} "配额不存在"
+// @Router /api/nat-ports/quotas/{id} [delete]
+func DeleteNATPortQuota(c *gin.Context) {
+	var quota models.NATPortQuota
+	if err := database.DB.First(&quota, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "配额不存在",
+		})
+		return
+	}
+
+	database.DB.Delete(&quota)
+	recordOperation(c, "nat_port_quota_delete", "nat_port_quota", quota.ID, quota)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "删除成功",
+	})
+}
+
+// GetNATFreePorts 获取空闲外部端口
+// @Summary 获取空闲外部端口
+// @Description 从节点端口池中列出若干个未被NAT规则和同步缓存占用的外部端口
+// @Tags NAT管理
+// @Produce json
+// @Param node_id query string true "节点ID"
+// @Param protocol query string false "协议类型(tcp/udp/tcp+udp)，默认tcp"
+// @Param count query int false "返回数量，默认10，最大100"
+// @Success 200 {object} map[string]interface{} "成功返回端口列表"
+// @Failure 400 {object} map[string]interface{} "缺少参数"
+// @Router /api/nat-ports/free [get]
+func GetNATFreePorts(c *gin.Context) {
+	nodeID, err := strconv.ParseUint(c.Query("node_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "缺少node_id参数",
+		})
+		return
+	}
+
+	protocol := c.DefaultQuery("protocol", "tcp")
+	count, _ := strconv.Atoi(c.DefaultQuery("count", "10"))
+	if count <= 0 || count > 100 {
+		count = 10
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "success",
+		"data": services.FindFreeNATPorts(uint(nodeID), protocol, count),
+	})
+}
@@ -211,7 +211,7 @@ func GetNATRulesFromCache(c *gin.Context) {
 	var rules []models.NATRuleCache
 	result := database.DB.
 		Joins("JOIN nodes ON nodes.id = nat_rule_cache.node_id").
-		Where("nodes.status = ?", "active").
+		Where("nodes.status IN ?", models.OnlineNodeStatuses).
 		Order("nat_rule_cache.node_id ASC, nat_rule_cache.external_port ASC").
 		Find(&rules)
 	
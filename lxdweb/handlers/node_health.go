@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"lxdweb/database"
+	"lxdweb/models"
+	"lxdweb/services"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetNodeHealth 获取节点健康状态
+// @Summary 获取节点健康状态
+// @Description 返回节点24小时/7天/30天可用率、最近检查记录和状态变化记录
+// @Tags 节点管理
+// @Produce json
+// @Param id path string true "节点ID"
+// @Param limit query int false "最近检查记录条数，默认60"
+// @Success 200 {object} map[string]interface{} "成功返回健康状态"
+// @Failure 404 {object} map[string]interface{} "节点不存在"
+// @Router /api/nodes/{id}/health [get]
+func GetNodeHealth(c *gin.Context) {
+	id := c.Param("id")
+	var node models.Node
+	if err := database.DB.First(&node, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "节点不存在",
+		})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "60"))
+	if limit <= 0 || limit > 500 {
+		limit = 60
+	}
+
+	var recent []models.NodeStatusHistory
+	database.DB.Where("node_id = ?", node.ID).
+		Order("checked_at desc").
+		Limit(limit).
+		Find(&recent)
+
+	var transitions []models.NodeStatusHistory
+	database.DB.Where("node_id = ? AND status != prev_status", node.ID).
+		Order("checked_at desc").
+		Limit(20).
+		Find(&transitions)
+
+	now := time.Now()
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "success",
+		"data": gin.H{
+			"node_id":    node.ID,
+			"status":     node.Status,
+			"last_check": node.LastCheck,
+			"uptime": gin.H{
+				"24h": services.NodeUptime(node.ID, now.Add(-24*time.Hour)),
+				"7d":  services.NodeUptime(node.ID, now.AddDate(0, 0, -7)),
+				"30d": services.NodeUptime(node.ID, now.AddDate(0, 0, -30)),
+			},
+			"recent":      recent,
+			"transitions": transitions,
+		},
+	})
+}
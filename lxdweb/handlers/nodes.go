@@ -1,6 +1,5 @@
 package handlers
 import (
-	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"lxdweb/database"
@@ -9,7 +8,6 @@ import (
 	"lxdweb/services"
 	"net/http"
 	"strconv"
-	"time"
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -121,6 +119,19 @@ func NodeIPv6Page(c *gin.Context) {
 	})
 }
 
+// NodeIPv4Page 节点独立IPv4列表页面
+func NodeIPv4Page(c *gin.Context) {
+	session := sessions.Default(c)
+	username := session.Get("username")
+	nodeID := c.Param("id")
+
+	c.HTML(http.StatusOK, "node_ipv4.html", gin.H{
+		"title":    "IPv4绑定 - LXD管理后台",
+		"username": username,
+		"node_id":  nodeID,
+	})
+}
+
 // NodeProxyPage 节点代理列表页面
 func NodeProxyPage(c *gin.Context) {
 	session := sessions.Default(c)
@@ -135,16 +146,29 @@ func NodeProxyPage(c *gin.Context) {
 }
 // GetNodes 获取节点列表
 // @Summary 获取节点列表
-// @Description 查询所有LXD节点及其系统信息，支持按状态过滤
+// @Description 查询所有LXD节点及其系统信息，支持按状态、分组、地域和标签过滤
 // @Tags 节点管理
 // @Produce json
-// @Param status query string false "节点状态(active/inactive)"
+// @Param status query string false "节点状态(active/degraded/down/inactive)"
+// @Param group query string false "节点分组"
+// @Param region query string false "节点地域"
+// @Param tags query string false "节点标签，逗号分隔，需全部匹配"
 // @Success 200 {object} map[string]interface{} "成功返回节点列表"
 // @Failure 500 {object} map[string]interface{} "查询失败"
 // @Router /api/nodes [get]
 func GetNodes(c *gin.Context) {
 	var nodes []models.Node
-	if err := database.DB.Order("created_at desc").Find(&nodes).Error; err != nil {
+	query := database.DB.Order("created_at desc")
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if group := c.Query("group"); group != "" {
+		query = query.Where("node_group = ?", group)
+	}
+	if region := c.Query("region"); region != "" {
+		query = query.Where("region = ?", region)
+	}
+	if err := query.Find(&nodes).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code": 500,
 			"msg":  "查询失败",
@@ -159,18 +183,29 @@ func GetNodes(c *gin.Context) {
 		cacheMap[cache.NodeID] = cache
 	}
 	
+	tags := models.SplitTags(c.Query("tags"))
+
 	result := make([]map[string]interface{}, 0, len(nodes))
 	for _, node := range nodes {
+		if !node.HasTags(tags) {
+			continue
+		}
 		nodeData := map[string]interface{}{
-			"id":          node.ID,
-			"name":        node.Name,
-			"description": node.Description,
-			"address":     node.Address,
-			"api_key":     node.APIKey,
-			"status":      node.Status,
-			"last_check":  node.LastCheck,
-			"created_at":  node.CreatedAt,
-			"updated_at":  node.UpdatedAt,
+			"id":                 node.ID,
+			"name":               node.Name,
+			"description":        node.Description,
+			"address":            node.Address,
+			"api_key":            node.APIKey,
+			"status":             node.Status,
+			"group":              node.Group,
+			"region":             node.Region,
+			"tags":               node.TagList(),
+			"maintenance":        node.Maintenance,
+			"maintenance_reason": node.MaintenanceReason,
+			"maintenance_until":  node.MaintenanceUntil,
+			"last_check":         node.LastCheck,
+			"created_at":         node.CreatedAt,
+			"updated_at":         node.UpdatedAt,
 		}
 
 		if cache, ok := cacheMap[node.ID]; ok {
@@ -178,6 +213,10 @@ func GetNodes(c *gin.Context) {
 			if err := json.Unmarshal([]byte(cache.SystemInfo), &sysInfo); err == nil {
 				nodeData["system_info"] = sysInfo
 			}
+			if cache.LastError != "" {
+				nodeData["cache_error"] = cache.LastError
+				nodeData["cache_error_at"] = cache.LastErrorAt
+			}
 		}
 		
 		result = append(result, nodeData)
@@ -284,6 +323,9 @@ func CreateNode(c *gin.Context) {
 		Address:       req.Address,
 		APIKey:        req.APIKey,
 		Status:        "inactive",
+		Group:         req.Group,
+		Region:        req.Region,
+		Tags:          models.JoinTags(req.Tags),
 		SyncPreset:    syncPreset,
 		BatchSize:     batchSize,
 		BatchInterval: batchInterval,
@@ -390,6 +432,15 @@ func UpdateNode(c *gin.Context) {
 	if req.APIKey != "" {
 		updates["api_key"] = req.APIKey
 	}
+	if req.Group != nil {
+		updates["node_group"] = *req.Group
+	}
+	if req.Region != nil {
+		updates["region"] = *req.Region
+	}
+	if req.Tags != nil {
+		updates["tags"] = models.JoinTags(req.Tags)
+	}
 	
 	if req.SyncPreset != "" {
 		updates["sync_preset"] = req.SyncPreset
@@ -480,14 +531,38 @@ func DeleteNode(c *gin.Context) {
 			return fmt.Errorf("删除NAT缓存失败: %w", err)
 		}
 		
+		if err := tx.Where("node_id = ?", nodeID).Delete(&models.NATPortPool{}).Error; err != nil {
+			return fmt.Errorf("删除NAT端口池失败: %w", err)
+		}
+		
+		if err := tx.Where("node_id = ?", nodeID).Delete(&models.NATPortQuota{}).Error; err != nil {
+			return fmt.Errorf("删除NAT端口配额失败: %w", err)
+		}
+		
 		if err := tx.Unscoped().Where("node_id = ?", nodeID).Delete(&models.IPv6BindingCache{}).Error; err != nil {
 			return fmt.Errorf("删除IPv6缓存失败: %w", err)
 		}
 		
+		if err := tx.Unscoped().Where("node_id = ?", nodeID).Delete(&models.IPv4BindingCache{}).Error; err != nil {
+			return fmt.Errorf("删除IPv4缓存失败: %w", err)
+		}
+		
+		if err := tx.Where("node_id = ?", nodeID).Delete(&models.IPAddress{}).Error; err != nil {
+			return fmt.Errorf("删除地址池记录失败: %w", err)
+		}
+		
+		if err := tx.Where("node_id = ?", nodeID).Delete(&models.IPPool{}).Error; err != nil {
+			return fmt.Errorf("删除地址池失败: %w", err)
+		}
+		
 		if err := tx.Unscoped().Where("node_id = ?", nodeID).Delete(&models.ProxyConfigCache{}).Error; err != nil {
 			return fmt.Errorf("删除代理缓存失败: %w", err)
 		}
 		
+		if err := tx.Where("node_id = ?", nodeID).Delete(&models.ProxyCertificate{}).Error; err != nil {
+			return fmt.Errorf("删除代理证书失败: %w", err)
+		}
+		
 		if err := tx.Unscoped().Where("node_id = ?", nodeID).Delete(&models.NodeInfoCache{}).Error; err != nil {
 			return fmt.Errorf("删除节点缓存失败: %w", err)
 		}
@@ -504,6 +579,10 @@ func DeleteNode(c *gin.Context) {
 			return fmt.Errorf("删除IPv6同步任务失败: %w", err)
 		}
 		
+		if err := tx.Unscoped().Where("node_id = ?", nodeID).Delete(&models.IPv4SyncTask{}).Error; err != nil {
+			return fmt.Errorf("删除IPv4同步任务失败: %w", err)
+		}
+		
 		if err := tx.Unscoped().Where("node_id = ?", nodeID).Delete(&models.ProxySyncTask{}).Error; err != nil {
 			return fmt.Errorf("删除代理同步任务失败: %w", err)
 		}
@@ -548,7 +627,6 @@ func DeleteNode(c *gin.Context) {
 // @Router /api/nodes/{id}/test [post]
 func TestNode(c *gin.Context) {
 	id := c.Param("id")
-	idInt, _ := strconv.ParseUint(id, 10, 32)
 	var node models.Node
 	if err := database.DB.First(&node, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
@@ -557,48 +635,20 @@ func TestNode(c *gin.Context) {
 		})
 		return
 	}
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		},
-	}
-	req, err := http.NewRequest("GET", node.Address+"/api/check", nil)
-	if err != nil {
-		updateNodeStatus(uint(idInt), "error")
-		c.JSON(http.StatusOK, gin.H{
-			"code": 500,
-			"msg":  "连接失败: " + err.Error(),
-		})
-		return
-	}
-	if node.APIKey != "" {
-		req.Header.Set("apikey", node.APIKey)
-	}
-	resp, err := client.Do(req)
+	history, err := services.CheckNodeHealth(node, true)
 	if err != nil {
-		updateNodeStatus(uint(idInt), "error")
 		c.JSON(http.StatusOK, gin.H{
 			"code": 500,
 			"msg":  "连接失败: " + err.Error(),
+			"data": history,
 		})
 		return
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode == 200 {
-		updateNodeStatus(uint(idInt), "active")
-		go services.RefreshNodeCache(uint(idInt))
-		c.JSON(http.StatusOK, gin.H{
-			"code": 200,
-			"msg":  "连接成功",
-		})
-	} else {
-		updateNodeStatus(uint(idInt), "error")
-		c.JSON(http.StatusOK, gin.H{
-			"code": 500,
-			"msg":  fmt.Sprintf("连接失败: HTTP %d", resp.StatusCode),
-		})
-	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  fmt.Sprintf("连接成功 (%dms)", history.LatencyMs),
+		"data": history,
+	})
 }
 // RefreshNodeCache 刷新节点缓存
 // @Summary 刷新节点缓存
@@ -631,14 +681,6 @@ func RefreshNodeCache(c *gin.Context) {
 	})
 }
 
-func updateNodeStatus(nodeID uint, status string) {
-	now := time.Now()
-	database.DB.Model(&models.Node{}).Where("id = ?", nodeID).Updates(map[string]interface{}{
-		"status":     status,
-		"last_check": now,
-	})
-}
-
 func ExportNodes(c *gin.Context) {
 	var nodes []models.Node
 	if err := database.DB.Find(&nodes).Error; err != nil {
@@ -656,6 +698,9 @@ func ExportNodes(c *gin.Context) {
 			"address":        node.Address,
 			"api_key":        node.APIKey,
 			"description":    node.Description,
+			"group":          node.Group,
+			"region":         node.Region,
+			"tags":           node.TagList(),
 			"sync_preset":    node.SyncPreset,
 			"batch_size":     node.BatchSize,
 			"batch_interval": node.BatchInterval,
@@ -731,6 +776,20 @@ func ImportNodes(c *gin.Context) {
 
 		apiKey, _ := nodeData["api_key"].(string)
 		description, _ := nodeData["description"].(string)
+		group, _ := nodeData["group"].(string)
+		region, _ := nodeData["region"].(string)
+
+		var tags []string
+		switch v := nodeData["tags"].(type) {
+		case string:
+			tags = models.SplitTags(v)
+		case []interface{}:
+			for _, t := range v {
+				if tag, ok := t.(string); ok {
+					tags = append(tags, tag)
+				}
+			}
+		}
 
 		node := models.Node{
 			Name:          name,
@@ -738,6 +797,9 @@ func ImportNodes(c *gin.Context) {
 			APIKey:        apiKey,
 			Description:   description,
 			Status:        "inactive",
+			Group:         group,
+			Region:        region,
+			Tags:          models.JoinTags(tags),
 			SyncPreset:    syncPreset,
 			BatchSize:     int(batchSize),
 			BatchInterval: int(batchInterval),
@@ -809,14 +871,38 @@ func BatchDeleteNodes(c *gin.Context) {
 				return fmt.Errorf("删除NAT缓存失败: %w", err)
 			}
 			
+			if err := tx.Where("node_id = ?", nodeID).Delete(&models.NATPortPool{}).Error; err != nil {
+				return fmt.Errorf("删除NAT端口池失败: %w", err)
+			}
+			
+			if err := tx.Where("node_id = ?", nodeID).Delete(&models.NATPortQuota{}).Error; err != nil {
+				return fmt.Errorf("删除NAT端口配额失败: %w", err)
+			}
+			
 			if err := tx.Unscoped().Where("node_id = ?", nodeID).Delete(&models.IPv6BindingCache{}).Error; err != nil {
 				return fmt.Errorf("删除IPv6缓存失败: %w", err)
 			}
 			
+			if err := tx.Unscoped().Where("node_id = ?", nodeID).Delete(&models.IPv4BindingCache{}).Error; err != nil {
+				return fmt.Errorf("删除IPv4缓存失败: %w", err)
+			}
+			
+			if err := tx.Where("node_id = ?", nodeID).Delete(&models.IPAddress{}).Error; err != nil {
+				return fmt.Errorf("删除地址池记录失败: %w", err)
+			}
+			
+			if err := tx.Where("node_id = ?", nodeID).Delete(&models.IPPool{}).Error; err != nil {
+				return fmt.Errorf("删除地址池失败: %w", err)
+			}
+			
 			if err := tx.Unscoped().Where("node_id = ?", nodeID).Delete(&models.ProxyConfigCache{}).Error; err != nil {
 				return fmt.Errorf("删除代理缓存失败: %w", err)
 			}
 			
+			if err := tx.Where("node_id = ?", nodeID).Delete(&models.ProxyCertificate{}).Error; err != nil {
+				return fmt.Errorf("删除代理证书失败: %w", err)
+			}
+			
 			if err := tx.Unscoped().Where("node_id = ?", nodeID).Delete(&models.NodeInfoCache{}).Error; err != nil {
 				return fmt.Errorf("删除节点缓存失败: %w", err)
 			}
@@ -833,6 +919,10 @@ func BatchDeleteNodes(c *gin.Context) {
 				return fmt.Errorf("删除IPv6同步任务失败: %w", err)
 			}
 			
+			if err := tx.Unscoped().Where("node_id = ?", nodeID).Delete(&models.IPv4SyncTask{}).Error; err != nil {
+				return fmt.Errorf("删除IPv4同步任务失败: %w", err)
+			}
+			
 			if err := tx.Unscoped().Where("node_id = ?", nodeID).Delete(&models.ProxySyncTask{}).Error; err != nil {
 				return fmt.Errorf("删除代理同步任务失败: %w", err)
 			}
@@ -877,3 +967,47 @@ func BatchDeleteNodes(c *gin.Context) {
 	
 	c.JSON(http.StatusOK, response)
 }
+
+// GetNodeGroups 获取节点分组、地域和标签汇总
+// @Summary 获取节点分组汇总
+// @Description 汇总所有节点的分组、地域和标签及对应节点数量
+// @Tags 节点管理
+// @Produce json
+// @Success 200 {object} map[string]interface{} "成功返回汇总信息"
+// @Failure 500 {object} map[string]interface{} "查询失败"
+// @Router /api/nodes/groups [get]
+func GetNodeGroups(c *gin.Context) {
+	var nodes []models.Node
+	if err := database.DB.Find(&nodes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "查询失败",
+		})
+		return
+	}
+
+	groups := make(map[string]int)
+	regions := make(map[string]int)
+	tags := make(map[string]int)
+	for _, node := range nodes {
+		if node.Group != "" {
+			groups[node.Group]++
+		}
+		if node.Region != "" {
+			regions[node.Region]++
+		}
+		for _, tag := range node.TagList() {
+			tags[tag]++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "success",
+		"data": gin.H{
+			"groups":  groups,
+			"regions": regions,
+			"tags":    tags,
+		},
+	})
+}
@@ -5,8 +5,10 @@ import (
 	"crypto/tls"
 	"encoding/json"
 	"io"
+	"lxdweb/config"
 	"lxdweb/database"
 	"lxdweb/models"
+	"lxdweb/pkg/logger"
 	"lxdweb/services"
 	"net/http"
 	"strconv"
@@ -14,6 +16,7 @@ import (
 
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
 // ProxyPage Proxy管理页面
@@ -135,7 +138,7 @@ func GetProxySyncTasks(c *gin.Context) {
 
 // GetProxyConfigsFromCache 从缓存获取Proxy配置
 // @Summary 从缓存获取反向代理配置
-// @Description 直接从本地缓存数据库读取反向代理配置信息
+// @Description 直接从本地缓存数据库读取反向代理配置信息，启用SSL的配置附带证书到期、签发者和域名匹配检查结果
 // @Tags 反向代理管理
 // @Produce json
 // @Param node_id query string false "节点ID"
@@ -147,7 +150,7 @@ func GetProxyConfigsFromCache(c *gin.Context) {
 	var configs []models.ProxyConfigCache
 	query := database.DB.
 		Joins("JOIN nodes ON nodes.id = proxy_config_caches.node_id").
-		Where("nodes.status = ?", "active").
+		Where("nodes.status IN ?", models.OnlineNodeStatuses).
 		Order("proxy_config_caches.last_sync desc")
 
 	if nodeID := c.Query("node_id"); nodeID != "" {
@@ -168,7 +171,7 @@ func GetProxyConfigsFromCache(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"code": 200,
 		"msg":  "success",
-		"data": configs,
+		"data": services.ProxyConfigCacheViews(configs),
 	})
 }
 
@@ -193,7 +196,7 @@ func SyncAllProxy(c *gin.Context) {
 	}
 
 	var nodes []models.Node
-	if err := database.DB.Where("status = ?", "active").Find(&nodes).Error; err != nil {
+	if err := database.DB.Where("status IN ? AND maintenance = ?", models.OnlineNodeStatuses, false).Find(&nodes).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code": 500,
 			"msg":  "查询节点失败",
@@ -273,14 +276,16 @@ func GetProxySyncStatus(c *gin.Context) {
 
 // CreateProxyConfig 创建反向代理配置
 // @Summary 创建反向代理配置
-// @Description 为指定容器创建新的反向代理配置
+// @Description 为指定容器创建新的反向代理配置，支持HTTP跳转HTTPS、WebSocket、自定义请求头、请求体大小、Basic Auth、按路径转发和路径跳转
 // @Tags 反向代理管理
 // @Accept json
 // @Produce json
 // @Param body body models.CreateProxyRequest true "反向代理配置参数"
 // @Success 200 {object} map[string]interface{} "创建成功"
 // @Failure 400 {object} map[string]interface{} "参数错误"
+// @Failure 403 {object} map[string]interface{} "域名未验证"
 // @Failure 404 {object} map[string]interface{} "节点不存在"
+// @Failure 409 {object} map[string]interface{} "域名已被其他代理使用，conflict 为占用者信息"
 // @Router /api/proxy-configs [post]
 func CreateProxyConfig(c *gin.Context) {
 	var req models.CreateProxyRequest
@@ -301,20 +306,95 @@ func CreateProxyConfig(c *gin.Context) {
 		return
 	}
 
-	proxyData := map[string]interface{}{
-		"hostname":       req.ContainerHostname,
-		"domain":         req.Domain,
-		"container_port": req.ContainerPort,
-		"description":    req.Description,
-		"ssl_enabled":    req.SSLEnabled,
-		"ssl_type":       req.SSLType,
+	if config.AppConfig.DomainVerify.Required && !services.IsDomainVerified(req.Domain) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"code": 403,
+			"msg":  "域名未通过所有权验证，请先完成域名验证",
+		})
+		return
+	}
+
+	if conflict := services.CheckDomainUnique(node.ID, req.Domain); conflict != nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"code":     409,
+			"msg":      conflict.Error(),
+			"conflict": conflict,
+		})
+		return
+	}
+
+	useACME := req.SSLEnabled && req.SSLType == models.CertSourceACME
+	if useACME && !config.AppConfig.ACME.Enabled {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "ACME自动签发未启用",
+		})
+		return
+	}
+
+	if req.SSLEnabled && req.SSLType == models.CertSourceCustom {
+		if _, err := services.ValidateCertificatePair(req.SSLCert, req.SSLKey); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code": 400,
+				"msg":  err.Error(),
+			})
+			return
+		}
+	}
+
+	if err := services.NormalizeProxyOptions(&req.ProxyOptions, req.SSLEnabled); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  err.Error(),
+		})
+		return
+	}
+	for _, loc := range req.Locations {
+		if loc.Hostname == "" || loc.Hostname == req.ContainerHostname {
+			continue
+		}
+		var count int64
+		database.DB.Model(&models.ContainerCache{}).
+			Where("node_id = ? AND hostname = ?", node.ID, loc.Hostname).
+			Count(&count)
+		if count == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code": 400,
+				"msg":  "路径 " + loc.Path + " 的后端容器不存在: " + loc.Hostname,
+			})
+			return
+		}
 	}
 
-	if req.SSLEnabled && req.SSLType == "custom" {
+	var authHash string
+	if req.BasicAuth != nil {
+		hash, err := services.ProxyHTPasswdHash(req.BasicAuth.Password)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code": 500,
+				"msg":  "生成密码哈希失败: " + err.Error(),
+			})
+			return
+		}
+		authHash = hash
+	}
+
+	proxyData := services.ProxyNodePayload(req.ContainerHostname, req.Domain, req.ContainerPort, req.Description, req.ProxyOptions, authHash)
+	proxyData["ssl_enabled"] = req.SSLEnabled
+	proxyData["ssl_type"] = req.SSLType
+
+	if req.SSLEnabled && req.SSLType == models.CertSourceCustom {
 		proxyData["ssl_cert"] = req.SSLCert
 		proxyData["ssl_key"] = req.SSLKey
 	}
 
+	// ACME 证书需先以HTTP方式提供站点完成验证，签发成功后再切换为HTTPS
+	if useACME {
+		proxyData["ssl_enabled"] = false
+		proxyData["ssl_type"] = "none"
+		proxyData["force_https"] = false
+	}
+
 	result := callNodeAPIForProxyMgmt(node, "POST", "/api/proxy/add", proxyData)
 	if result["code"] != float64(200) {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -324,8 +404,32 @@ func CreateProxyConfig(c *gin.Context) {
 		return
 	}
 
+	sslType := req.SSLType
+	if !req.SSLEnabled || useACME {
+		sslType = "none"
+	}
+	if err := services.SaveProxyOptions(node, req.ContainerHostname, req.Domain, req.ContainerPort, req.SSLEnabled && !useACME, sslType, req.ProxyOptions, authHash); err != nil {
+		logger.Global.Warn(c.Request.Context(), "保存反向代理高级选项失败",
+			zap.Uint("node_id", node.ID),
+			zap.String("domain", req.Domain),
+			zap.Error(err))
+	}
+
+	if req.SSLEnabled && req.SSLType == models.CertSourceCustom {
+		if _, err := services.RecordCustomCertificate(node.ID, req.ContainerHostname, req.Domain, req.SSLCert, req.SSLKey); err != nil {
+			logger.Global.Warn(c.Request.Context(), "记录代理证书失败",
+				zap.Uint("node_id", node.ID),
+				zap.String("domain", req.Domain),
+				zap.Error(err))
+		}
+	}
+
 	time.Sleep(1 * time.Second)
-	go services.SyncNodeProxyConfigs(node.ID)
+	if useACME {
+		go services.IssueNewProxyCertificate(node.ID, req.ContainerHostname, req.Domain)
+	} else {
+		go services.SyncNodeProxyConfigs(node.ID)
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"code": 200,
@@ -388,6 +492,7 @@ func DeleteProxyConfig(c *gin.Context) {
 		})
 		return
 	}
+	services.DeleteProxyCertificate(config.NodeID, config.Domain)
 
 	c.JSON(http.StatusOK, gin.H{
 		"code": 200,
@@ -397,7 +502,7 @@ func DeleteProxyConfig(c *gin.Context) {
 
 // CheckProxyDomain 检查域名是否可用
 // @Summary 检查反向代理域名是否可用
-// @Description 检查指定域名是否可用于反向代理配置
+// @Description 检查指定域名是否可用于反向代理配置，先按配置的唯一性范围检查所有节点的代理缓存，返回的 verified 表示域名是否已通过所有权验证
 // @Tags 反向代理管理
 // @Produce json
 // @Param node_id query string true "节点ID"
@@ -435,16 +540,38 @@ func CheckProxyDomain(c *gin.Context) {
 		return
 	}
 
+	if conflict := services.CheckDomainUnique(node.ID, domain); conflict != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"code": 200,
+			"msg":  "success",
+			"data": map[string]interface{}{
+				"available": false,
+				"reason":    conflict.Error(),
+				"conflict":  conflict,
+				"verified":  services.IsDomainVerified(domain),
+			},
+		})
+		return
+	}
+
 	result := callNodeAPIForProxyMgmt(node, "GET", "/api/proxy/check?domain="+domain, nil)
+	if data, ok := result["data"].(map[string]interface{}); ok {
+		verified := services.IsDomainVerified(domain)
+		data["verified"] = verified
+		if config.AppConfig.DomainVerify.Required && !verified {
+			data["available"] = false
+			data["reason"] = "域名未通过所有权验证"
+		}
+	}
 	c.JSON(http.StatusOK, result)
 }
 
 func callNodeAPIForProxyMgmt(node models.Node, method, path string, data interface{}) map[string]interface{} {
 	client := &http.Client{
 		Timeout: 30 * time.Second,
-		Transport: &http.Transport{
+		Transport: services.NodeAPITransport(node, &http.Transport{
 			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		},
+		}),
 	}
 
 	var body io.Reader
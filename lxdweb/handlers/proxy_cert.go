@@ -0,0 +1,290 @@
+package handlers
+
+import (
+	"lxdweb/config"
+	"lxdweb/database"
+	"lxdweb/models"
+	"lxdweb/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetProxyCertificates 获取反向代理证书列表
+// @Summary 获取反向代理证书列表
+// @Description 查询反向代理证书的来源、签发者、有效期和续期状态
+// @Tags 反向代理管理
+// @Produce json
+// @Param node_id query string false "节点ID"
+// @Param status query string false "证书状态(pending/issuing/valid/expiring/expired/failed/unknown)"
+// @Success 200 {object} map[string]interface{} "成功返回证书列表"
+// @Failure 500 {object} map[string]interface{} "查询失败"
+// @Router /api/proxy-certs [get]
+func GetProxyCertificates(c *gin.Context) {
+	var certs []models.ProxyCertificate
+	query := database.DB.Order("node_id, domain")
+	if nodeID := c.Query("node_id"); nodeID != "" {
+		query = query.Where("node_id = ?", nodeID)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	if err := query.Find(&certs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "查询失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "success",
+		"data": certs,
+		"acme": gin.H{
+			"enabled":           config.AppConfig.ACME.Enabled,
+			"directory_url":     config.AppConfig.ACME.DirectoryURL,
+			"renew_before_days": config.AppConfig.ACME.RenewBeforeDays,
+		},
+	})
+}
+
+// IssueProxyCertificate 签发或续期反向代理证书
+// @Summary 签发或续期反向代理证书
+// @Description 通过ACME HTTP-01为反向代理域名签发证书，签发在后台执行，成功后自动部署到节点并启用HTTPS
+// @Tags 反向代理管理
+// @Produce json
+// @Param id path string true "代理配置ID"
+// @Success 200 {object} map[string]interface{} "已开始签发"
+// @Failure 400 {object} map[string]interface{} "ACME未启用或正在签发"
+// @Failure 404 {object} map[string]interface{} "代理配置不存在"
+// @Router /api/proxy-configs/{id}/cert/issue [post]
+func IssueProxyCertificate(c *gin.Context) {
+	if !config.AppConfig.ACME.Enabled {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "ACME自动签发未启用",
+		})
+		return
+	}
+
+	var proxy models.ProxyConfigCache
+	if err := database.DB.First(&proxy, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "代理配置不存在",
+		})
+		return
+	}
+
+	cert, err := services.RequestACMECertificate(proxy.NodeID, proxy.Hostname, proxy.Domain)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "创建证书记录失败: " + err.Error(),
+		})
+		return
+	}
+	if cert.Status == models.CertStatusIssuing {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "证书正在签发中",
+		})
+		return
+	}
+	if cert.ProxyConfigID != proxy.ID {
+		database.DB.Model(cert).Update("proxy_config_id", proxy.ID)
+	}
+
+	go services.IssueProxyCertificate(cert.ID)
+
+	recordOperation(c, "proxy_cert_issue", "proxy_certificate", cert.ID, gin.H{
+		"node_id": proxy.NodeID,
+		"domain":  proxy.Domain,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "已开始签发证书",
+		"data": cert,
+	})
+}
+
+// UploadProxyCertificate 上传自定义证书
+// @Summary 上传反向代理自定义证书
+// @Description 校验证书与私钥后部署到节点，并解析证书的签发者和有效期
+// @Tags 反向代理管理
+// @Accept json
+// @Produce json
+// @Param id path string true "代理配置ID"
+// @Param body body models.UploadCertificateRequest true "证书与私钥(PEM)"
+// @Success 200 {object} map[string]interface{} "上传成功"
+// @Failure 400 {object} map[string]interface{} "证书无效"
+// @Failure 404 {object} map[string]interface{} "代理配置不存在"
+// @Router /api/proxy-configs/{id}/cert [put]
+func UploadProxyCertificate(c *gin.Context) {
+	var proxy models.ProxyConfigCache
+	if err := database.DB.First(&proxy, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "代理配置不存在",
+		})
+		return
+	}
+
+	var req models.UploadCertificateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	leaf, err := services.ValidateCertificatePair(req.SSLCert, req.SSLKey)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  err.Error(),
+		})
+		return
+	}
+	if !services.CertificateCoversDomain(leaf, proxy.Domain) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "证书不包含域名 " + proxy.Domain,
+		})
+		return
+	}
+
+	var existing models.ProxyCertificate
+	database.DB.Where("node_id = ? AND domain = ?", proxy.NodeID, proxy.Domain).First(&existing)
+	if existing.NodeID == 0 {
+		existing = models.ProxyCertificate{NodeID: proxy.NodeID, Domain: proxy.Domain}
+	}
+
+	if err := services.DeployProxyCertificate(existing, req.SSLCert, req.SSLKey); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  err.Error(),
+		})
+		return
+	}
+
+	cert, err := services.RecordCustomCertificate(proxy.NodeID, proxy.Hostname, proxy.Domain, req.SSLCert, req.SSLKey)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "保存证书失败: " + err.Error(),
+		})
+		return
+	}
+
+	recordOperation(c, "proxy_cert_upload", "proxy_certificate", cert.ID, gin.H{
+		"node_id":     cert.NodeID,
+		"domain":      cert.Domain,
+		"issuer":      cert.Issuer,
+		"fingerprint": cert.Fingerprint,
+		"not_after":   cert.NotAfter,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "证书已部署",
+		"data": cert,
+	})
+}
+
+// UpdateProxyCertificate 更新证书设置
+// @Summary 更新反向代理证书设置
+// @Description 开启或关闭ACME证书的自动续期
+// @Tags 反向代理管理
+// @Accept json
+// @Produce json
+// @Param id path string true "证书ID"
+// @Param body body models.UpdateCertificateRequest true "证书设置"
+// @Success 200 {object} map[string]interface{} "更新成功"
+// @Failure 404 {object} map[string]interface{} "证书不存在"
+// @Router /api/proxy-certs/{id} [put]
+func UpdateProxyCertificate(c *gin.Context) {
+	var cert models.ProxyCertificate
+	if err := database.DB.First(&cert, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "证书不存在",
+		})
+		return
+	}
+
+	var req models.UpdateCertificateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	if req.AutoRenew != nil {
+		cert.AutoRenew = *req.AutoRenew
+		database.DB.Model(&cert).Update("auto_renew", cert.AutoRenew)
+	}
+
+	recordOperation(c, "proxy_cert_update", "proxy_certificate", cert.ID, req)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "更新成功",
+		"data": cert,
+	})
+}
+
+// DeleteProxyCertificate 删除证书记录
+// @Summary 删除反向代理证书记录
+// @Description 删除lxdweb中的证书记录，不影响节点上已部署的证书；仍启用SSL的代理会在下次同步时重新登记
+// @Tags 反向代理管理
+// @Produce json
+// @Param id path string true "证书ID"
+// @Success 200 {object} map[string]interface{} "删除成功"
+// @Failure 404 {object} map[string]interface{} "证书不存在"
+// @Router /api/proxy-certs/{id} [delete]
+func DeleteProxyCertificate(c *gin.Context) {
+	var cert models.ProxyCertificate
+	if err := database.DB.First(&cert, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "证书不存在",
+		})
+		return
+	}
+
+	database.DB.Delete(&cert)
+	recordOperation(c, "proxy_cert_delete", "proxy_certificate", cert.ID, gin.H{
+		"node_id": cert.NodeID,
+		"domain":  cert.Domain,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "删除成功",
+	})
+}
+
+// ACMEChallenge 响应ACME HTTP-01验证请求（standalone 模式，无需登录）
+// @Summary ACME HTTP-01验证
+// @Description 返回验证令牌对应的 key authorization，供CA校验域名控制权
+// @Tags 反向代理管理
+// @Produce plain
+// @Param token path string true "验证令牌"
+// @Success 200 {string} string "key authorization"
+// @Failure 404 {string} string "令牌不存在"
+// @Router /.well-known/acme-challenge/{token} [get]
+func ACMEChallenge(c *gin.Context) {
+	keyAuth, ok := services.ACMEChallengeResponse(c.Param("token"))
+	if !ok {
+		c.String(http.StatusNotFound, "not found")
+		return
+	}
+	c.String(http.StatusOK, keyAuth)
+}
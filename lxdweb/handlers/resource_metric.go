@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"lxdweb/models"
+	"lxdweb/services"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// metricRanges 资源历史支持的查询跨度
+var metricRanges = map[string]time.Duration{
+	"1h":   time.Hour,
+	"6h":   6 * time.Hour,
+	"24h":  24 * time.Hour,
+	"7d":   7 * 24 * time.Hour,
+	"30d":  30 * 24 * time.Hour,
+	"365d": 365 * 24 * time.Hour,
+}
+
+// respondResourceMetrics 解析 range/resolution 参数并返回指定对象的资源历史
+func respondResourceMetrics(c *gin.Context, scope string, nodeID uint, hostname string) {
+	rangeKey := c.DefaultQuery("range", "24h")
+	span, ok := metricRanges[rangeKey]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "时间范围只能是 1h/6h/24h/7d/30d/365d",
+		})
+		return
+	}
+
+	resolution := c.Query("resolution")
+	switch resolution {
+	case "":
+		resolution = services.MetricResolutionFor(span)
+	case models.MetricResolutionRaw, models.MetricResolution5Min, models.MetricResolutionHourly, models.MetricResolutionDaily:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "精度只能是 raw/5m/1h/1d",
+		})
+		return
+	}
+
+	to := time.Now()
+	from := to.Add(-span)
+	points := services.QueryResourceMetrics(scope, nodeID, hostname, resolution, from, to)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "success",
+		"data": gin.H{
+			"range":      rangeKey,
+			"resolution": resolution,
+			"from":       from,
+			"to":         to,
+			"points":     points,
+		},
+	})
+}
+
+// GetContainerResourceMetrics 获取容器资源使用历史
+// @Summary 获取容器资源使用历史
+// @Description 返回容器CPU、内存、硬盘和流量的历史数据，按时间范围自动选择原始、5分钟、小时或天精度
+// @Tags 容器管理
+// @Produce json
+// @Param name path string true "容器名称"
+// @Param node_id query int true "节点ID"
+// @Param range query string false "时间范围(1h/6h/24h/7d/30d/365d)，默认24h"
+// @Param resolution query string false "指定精度(raw/5m/1h/1d)，默认按时间范围选择"
+// @Success 200 {object} map[string]interface{} "成功返回资源历史"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Router /api/metrics/containers/{name} [get]
+func GetContainerResourceMetrics(c *gin.Context) {
+	nodeID, err := strconv.ParseUint(c.Query("node_id"), 10, 64)
+	if err != nil || nodeID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "缺少节点ID",
+		})
+		return
+	}
+
+	respondResourceMetrics(c, models.MetricScopeContainer, uint(nodeID), c.Param("name"))
+}
+
+// GetNodeResourceMetrics 获取节点资源使用历史
+// @Summary 获取节点资源使用历史
+// @Description 返回节点所有容器汇总的CPU、内存、硬盘、流量和运行容器数历史数据
+// @Tags 节点管理
+// @Produce json
+// @Param id path int true "节点ID"
+// @Param range query string false "时间范围(1h/6h/24h/7d/30d/365d)，默认24h"
+// @Param resolution query string false "指定精度(raw/5m/1h/1d)，默认按时间范围选择"
+// @Success 200 {object} map[string]interface{} "成功返回资源历史"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Router /api/metrics/nodes/{id} [get]
+func GetNodeResourceMetrics(c *gin.Context) {
+	nodeID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || nodeID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "无效的节点ID",
+		})
+		return
+	}
+
+	respondResourceMetrics(c, models.MetricScopeNode, uint(nodeID), "")
+}
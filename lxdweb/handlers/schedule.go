@@ -0,0 +1,305 @@
+package handlers
+
+import (
+	"lxdweb/database"
+	"lxdweb/models"
+	"lxdweb/services"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// SchedulesPage 计划任务页面
+func SchedulesPage(c *gin.Context) {
+	session := sessions.Default(c)
+	username := session.Get("username")
+	c.HTML(http.StatusOK, "schedules.html", gin.H{
+		"title":    "计划任务 - LXD管理后台",
+		"username": username,
+	})
+}
+
+// GetScheduledTasks 获取计划任务列表
+// @Summary 获取计划任务列表
+// @Description 返回计划任务，可按节点和容器过滤
+// @Tags 计划任务
+// @Produce json
+// @Param node_id query int false "节点ID"
+// @Param hostname query string false "容器名称"
+// @Success 200 {object} map[string]interface{} "成功返回任务列表"
+// @Router /api/schedules [get]
+func GetScheduledTasks(c *gin.Context) {
+	query := database.DB.Order("id")
+	if nodeID := c.Query("node_id"); nodeID != "" {
+		query = query.Where("node_id = ?", nodeID)
+	}
+	if hostname := c.Query("hostname"); hostname != "" {
+		query = query.Where("hostname = ?", hostname)
+	}
+
+	var tasks []models.ScheduledTask
+	query.Find(&tasks)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "success",
+		"data": tasks,
+	})
+}
+
+// CreateScheduledTask 创建计划任务
+// @Summary 创建计划任务
+// @Description 按 cron 表达式定期对单个容器或节点分组内全部容器执行启动、停止、重启、暂停、重置流量或快照
+// @Tags 计划任务
+// @Accept json
+// @Produce json
+// @Param body body models.ScheduledTaskRequest true "任务参数"
+// @Success 200 {object} map[string]interface{} "创建成功"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Router /api/schedules [post]
+func CreateScheduledTask(c *gin.Context) {
+	var req models.ScheduledTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	task := models.ScheduledTask{Enabled: true, CreatedBy: currentAdminID(c)}
+	applyScheduledTaskRequest(&task, req)
+	if err := services.PrepareScheduledTask(&task); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  err.Error(),
+		})
+		return
+	}
+	if err := database.DB.Create(&task).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "创建失败: " + err.Error(),
+		})
+		return
+	}
+
+	recordOperation(c, "schedule_create", "scheduled_task", task.ID, task)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "创建成功",
+		"data": task,
+	})
+}
+
+// UpdateScheduledTask 更新计划任务
+// @Summary 更新计划任务
+// @Description 更新计划任务并重新计算下次执行时间
+// @Tags 计划任务
+// @Accept json
+// @Produce json
+// @Param id path string true "任务ID"
+// @Param body body models.ScheduledTaskRequest true "任务参数"
+// @Success 200 {object} map[string]interface{} "更新成功"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Failure 404 {object} map[string]interface{} "任务不存在"
+// @Router /api/schedules/{id} [put]
+func UpdateScheduledTask(c *gin.Context) {
+	var task models.ScheduledTask
+	if err := database.DB.First(&task, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "任务不存在",
+		})
+		return
+	}
+
+	var req models.ScheduledTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	applyScheduledTaskRequest(&task, req)
+	if err := services.PrepareScheduledTask(&task); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  err.Error(),
+		})
+		return
+	}
+	if err := database.DB.Save(&task).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "更新失败: " + err.Error(),
+		})
+		return
+	}
+
+	recordOperation(c, "schedule_update", "scheduled_task", task.ID, task)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "更新成功",
+		"data": task,
+	})
+}
+
+// DeleteScheduledTask 删除计划任务
+// @Summary 删除计划任务
+// @Description 删除计划任务及其执行记录，正在执行的任务会继续执行完成
+// @Tags 计划任务
+// @Produce json
+// @Param id path string true "任务ID"
+// @Success 200 {object} map[string]interface{} "删除成功"
+// @Failure 404 {object} map[string]interface{} "任务不存在"
+// @Router /api/schedules/{id} [delete]
+func DeleteScheduledTask(c *gin.Context) {
+	var task models.ScheduledTask
+	if err := database.DB.First(&task, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "任务不存在",
+		})
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("task_id = ?", task.ID).Delete(&models.ScheduledTaskRun{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&task).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "删除失败: " + err.Error(),
+		})
+		return
+	}
+
+	recordOperation(c, "schedule_delete", "scheduled_task", task.ID, gin.H{"name": task.Name})
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "删除成功",
+	})
+}
+
+// RunScheduledTaskNow 立即执行计划任务
+// @Summary 立即执行计划任务
+// @Description 手动触发一次计划任务，不影响下次计划执行时间
+// @Tags 计划任务
+// @Produce json
+// @Param id path string true "任务ID"
+// @Success 200 {object} map[string]interface{} "已开始执行"
+// @Failure 404 {object} map[string]interface{} "任务不存在"
+// @Router /api/schedules/{id}/run [post]
+func RunScheduledTaskNow(c *gin.Context) {
+	var task models.ScheduledTask
+	if err := database.DB.First(&task, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "任务不存在",
+		})
+		return
+	}
+
+	run := services.RunScheduledTask(task, models.ScheduleTriggerManual, time.Now())
+	recordOperation(c, "schedule_trigger", "scheduled_task", task.ID, gin.H{
+		"name":   task.Name,
+		"run_id": run.ID,
+		"status": run.Status,
+	})
+
+	msg := "任务已开始执行"
+	if run.Status == "skipped" {
+		msg = run.ErrorMessage
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  msg,
+		"data": run,
+	})
+}
+
+// GetScheduledTaskRuns 获取计划任务执行记录
+// @Summary 获取计划任务执行记录
+// @Description 返回计划任务最近的执行记录
+// @Tags 计划任务
+// @Produce json
+// @Param id path string true "任务ID"
+// @Param limit query int false "返回条数，默认50，最大500"
+// @Success 200 {object} map[string]interface{} "成功返回执行记录"
+// @Router /api/schedules/{id}/runs [get]
+func GetScheduledTaskRuns(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+
+	var runs []models.ScheduledTaskRun
+	database.DB.Where("task_id = ?", c.Param("id")).Order("id DESC").Limit(limit).Find(&runs)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "success",
+		"data": runs,
+	})
+}
+
+// PreviewSchedule 预览 cron 表达式的执行时间
+// @Summary 预览cron表达式
+// @Description 校验 cron 表达式和时区，返回接下来5次执行时间
+// @Tags 计划任务
+// @Produce json
+// @Param cron query string true "cron表达式（分 时 日 月 周）"
+// @Param timezone query string false "时区，如 Asia/Shanghai，默认使用配置时区"
+// @Success 200 {object} map[string]interface{} "成功返回执行时间"
+// @Failure 400 {object} map[string]interface{} "表达式无效"
+// @Router /api/schedules/preview [get]
+func PreviewSchedule(c *gin.Context) {
+	runs, err := services.NextScheduleRuns(c.Query("cron"), c.Query("timezone"), time.Now(), 5)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "success",
+		"data": runs,
+	})
+}
+
+func applyScheduledTaskRequest(task *models.ScheduledTask, req models.ScheduledTaskRequest) {
+	task.Name = req.Name
+	task.Action = req.Action
+	task.CronExpr = req.CronExpr
+	task.Timezone = req.Timezone
+	task.TargetType = req.TargetType
+	task.MissedPolicy = req.MissedPolicy
+
+	task.NodeID, task.Hostname, task.NodeGroup = 0, "", ""
+	if req.TargetType == models.ScheduleTargetGroup {
+		task.NodeGroup = req.NodeGroup
+	} else {
+		task.NodeID = req.NodeID
+		task.Hostname = req.Hostname
+	}
+
+	if req.Enabled != nil {
+		task.Enabled = *req.Enabled
+	}
+}
@@ -0,0 +1,339 @@
+package handlers
+
+import (
+	"errors"
+	"lxdweb/database"
+	"lxdweb/models"
+	"lxdweb/services"
+	"net/http"
+	"strings"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// SSHKeysPage SSH 公钥库页面
+func SSHKeysPage(c *gin.Context) {
+	session := sessions.Default(c)
+	username := session.Get("username")
+	c.HTML(http.StatusOK, "ssh_keys.html", gin.H{
+		"title":    "SSH公钥 - LXD管理后台",
+		"username": username,
+	})
+}
+
+// GetSSHKeys 获取 SSH 公钥列表
+// @Summary 获取 SSH 公钥列表
+// @Description 获取公钥库中的全部 SSH 公钥及指纹
+// @Tags SSH公钥
+// @Produce json
+// @Success 200 {object} map[string]interface{} "成功返回公钥列表"
+// @Router /api/ssh-keys [get]
+func GetSSHKeys(c *gin.Context) {
+	var keys []models.SSHKey
+	database.DB.Order("name").Find(&keys)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "success",
+		"data": keys,
+	})
+}
+
+// CreateSSHKey 添加 SSH 公钥
+// @Summary 添加 SSH 公钥
+// @Description 添加命名 SSH 公钥到公钥库，名称和指纹不可重复
+// @Tags SSH公钥
+// @Accept json
+// @Produce json
+// @Param body body models.SSHKeyRequest true "名称和公钥"
+// @Success 200 {object} map[string]interface{} "添加成功"
+// @Failure 400 {object} map[string]interface{} "参数错误、公钥无效或已存在"
+// @Router /api/ssh-keys [post]
+func CreateSSHKey(c *gin.Context) {
+	var req models.SSHKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "参数错误: " + err.Error(),
+		})
+		return
+	}
+	key, err := services.ParseSSHPublicKey(req.PublicKey)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  err.Error(),
+		})
+		return
+	}
+	key.Name = strings.TrimSpace(req.Name)
+	key.CreatedBy = currentAdminID(c)
+	if err := database.DB.Create(&key).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "添加失败，名称或公钥已存在",
+		})
+		return
+	}
+
+	recordOperation(c, "ssh_key_create", "ssh_key", key.ID, gin.H{
+		"name":        key.Name,
+		"fingerprint": key.Fingerprint,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "添加成功",
+		"data": key,
+	})
+}
+
+// UpdateSSHKey 更新 SSH 公钥
+// @Summary 更新 SSH 公钥
+// @Description 修改公钥名称或替换公钥内容，已写入容器的 authorized_keys 不受影响
+// @Tags SSH公钥
+// @Accept json
+// @Produce json
+// @Param id path string true "公钥ID"
+// @Param body body models.SSHKeyRequest true "名称和公钥"
+// @Success 200 {object} map[string]interface{} "更新成功"
+// @Failure 400 {object} map[string]interface{} "参数错误、公钥无效或已存在"
+// @Failure 404 {object} map[string]interface{} "公钥不存在"
+// @Router /api/ssh-keys/{id} [put]
+func UpdateSSHKey(c *gin.Context) {
+	var key models.SSHKey
+	if err := database.DB.First(&key, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "公钥不存在",
+		})
+		return
+	}
+	var req models.SSHKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "参数错误: " + err.Error(),
+		})
+		return
+	}
+	parsed, err := services.ParseSSHPublicKey(req.PublicKey)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  err.Error(),
+		})
+		return
+	}
+
+	oldFingerprint := key.Fingerprint
+	key.Name = strings.TrimSpace(req.Name)
+	key.PublicKey = parsed.PublicKey
+	key.KeyType = parsed.KeyType
+	key.Fingerprint = parsed.Fingerprint
+	key.Comment = parsed.Comment
+	if err := database.DB.Save(&key).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "更新失败，名称或公钥已存在",
+		})
+		return
+	}
+
+	recordOperation(c, "ssh_key_update", "ssh_key", key.ID, gin.H{
+		"name":            key.Name,
+		"fingerprint":     key.Fingerprint,
+		"old_fingerprint": oldFingerprint,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "更新成功",
+		"data": key,
+	})
+}
+
+// DeleteSSHKey 删除 SSH 公钥
+// @Summary 删除 SSH 公钥
+// @Description 从公钥库删除公钥，不会从已写入的容器中移除
+// @Tags SSH公钥
+// @Produce json
+// @Param id path string true "公钥ID"
+// @Success 200 {object} map[string]interface{} "删除成功"
+// @Failure 404 {object} map[string]interface{} "公钥不存在"
+// @Router /api/ssh-keys/{id} [delete]
+func DeleteSSHKey(c *gin.Context) {
+	var key models.SSHKey
+	if err := database.DB.First(&key, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "公钥不存在",
+		})
+		return
+	}
+	database.DB.Delete(&key)
+
+	recordOperation(c, "ssh_key_delete", "ssh_key", key.ID, gin.H{
+		"name":        key.Name,
+		"fingerprint": key.Fingerprint,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "删除成功",
+	})
+}
+
+// SetContainerSSHKeys 写入容器 SSH 公钥
+// @Summary 写入容器 SSH 公钥
+// @Description 通过节点将公钥库或直接提交的公钥追加或替换到容器 root 用户的 authorized_keys，可同时禁用密码登录
+// @Tags SSH公钥
+// @Accept json
+// @Produce json
+// @Param name path string true "容器名称"
+// @Param body body models.ContainerSSHKeysRequest true "节点、公钥和写入方式"
+// @Success 200 {object} map[string]interface{} "写入成功"
+// @Failure 400 {object} map[string]interface{} "参数错误或公钥无效"
+// @Failure 404 {object} map[string]interface{} "节点不存在"
+// @Failure 502 {object} map[string]interface{} "节点写入失败"
+// @Router /api/containers/{name}/ssh-keys [post]
+func SetContainerSSHKeys(c *gin.Context) {
+	name := c.Param("name")
+
+	var req models.ContainerSSHKeysRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "参数错误: " + err.Error(),
+		})
+		return
+	}
+	if req.Mode == "" {
+		req.Mode = models.SSHKeyModeAdd
+	}
+
+	var node models.Node
+	if err := database.DB.First(&node, req.NodeID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "节点不存在",
+		})
+		return
+	}
+
+	keys, err := services.ResolveSSHKeys(req.KeyIDs, req.Keys)
+	if err == nil && len(keys) == 0 {
+		err = errors.New("请至少指定一个 SSH 公钥")
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  err.Error(),
+		})
+		return
+	}
+
+	fingerprints := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if parsed, err := services.ParseSSHPublicKey(k); err == nil {
+			fingerprints = append(fingerprints, parsed.Fingerprint)
+		}
+	}
+	details := gin.H{
+		"hostname":         name,
+		"mode":             req.Mode,
+		"fingerprints":     fingerprints,
+		"disable_password": req.DisablePassword,
+	}
+
+	err = services.SetContainerSSHKeys(node, name, keys, req.Mode)
+	// 公钥写入成功后才禁用密码登录，避免容器失去所有登录方式
+	if err == nil && req.DisablePassword {
+		err = services.SetContainerPasswordAuth(node, name, false)
+	}
+	services.RecordOperation(services.AuditEntry{
+		AdminID:       currentAdminID(c),
+		OperationType: "container_ssh_keys",
+		TargetType:    "container",
+		TargetID:      node.ID,
+		Details:       details,
+		IPAddress:     c.ClientIP(),
+		Err:           err,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"code": 502,
+			"msg":  "写入失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "SSH公钥已写入",
+		"data": gin.H{"fingerprints": fingerprints},
+	})
+}
+
+// SetContainerPasswordAuth 设置容器 SSH 密码登录
+// @Summary 设置容器 SSH 密码登录
+// @Description 通过节点启用或禁用容器 SSH 密码登录，禁用前请确认容器中已写入可用的公钥
+// @Tags SSH公钥
+// @Accept json
+// @Produce json
+// @Param name path string true "容器名称"
+// @Param body body models.ContainerPasswordAuthRequest true "节点和是否启用"
+// @Success 200 {object} map[string]interface{} "设置成功"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Failure 404 {object} map[string]interface{} "节点不存在"
+// @Failure 502 {object} map[string]interface{} "节点设置失败"
+// @Router /api/containers/{name}/ssh-password-auth [post]
+func SetContainerPasswordAuth(c *gin.Context) {
+	name := c.Param("name")
+
+	var req models.ContainerPasswordAuthRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	var node models.Node
+	if err := database.DB.First(&node, req.NodeID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "节点不存在",
+		})
+		return
+	}
+
+	err := services.SetContainerPasswordAuth(node, name, req.Enabled)
+	services.RecordOperation(services.AuditEntry{
+		AdminID:       currentAdminID(c),
+		OperationType: "container_password_auth",
+		TargetType:    "container",
+		TargetID:      node.ID,
+		Details:       gin.H{"hostname": name, "enabled": req.Enabled},
+		IPAddress:     c.ClientIP(),
+		Err:           err,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"code": 502,
+			"msg":  "设置失败: " + err.Error(),
+		})
+		return
+	}
+
+	msg := "已禁用密码登录"
+	if req.Enabled {
+		msg = "已启用密码登录"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  msg,
+	})
+}
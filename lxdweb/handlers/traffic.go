@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"lxdweb/config"
+	"lxdweb/database"
+	"lxdweb/models"
+	"lxdweb/services"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetContainerTrafficHistory 获取容器流量历史
+// @Summary 获取容器流量历史
+// @Description 按小时或天汇总容器的入站、出站流量增量，数据来自每次同步的流量采样
+// @Tags 容器管理
+// @Produce json
+// @Param name path string true "容器名称"
+// @Param node_id query int true "节点ID"
+// @Param hours query int false "查询最近多少小时，默认24，最多保留天数*24"
+// @Param interval query string false "汇总粒度(hour/day)，默认hour"
+// @Success 200 {object} map[string]interface{} "成功返回流量历史"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Router /api/containers/{name}/traffic/history [get]
+func GetContainerTrafficHistory(c *gin.Context) {
+	nodeID, err := strconv.ParseUint(c.Query("node_id"), 10, 64)
+	if err != nil || nodeID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "缺少节点ID",
+		})
+		return
+	}
+
+	hours, _ := strconv.Atoi(c.DefaultQuery("hours", "24"))
+	if hours <= 0 {
+		hours = 24
+	}
+	if maxHours := config.AppConfig.Traffic.SampleRetention * 24; hours > maxHours {
+		hours = maxHours
+	}
+	interval := c.DefaultQuery("interval", "hour")
+	if interval != "hour" && interval != "day" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "汇总粒度只能是 hour 或 day",
+		})
+		return
+	}
+
+	to := time.Now()
+	from := to.Add(-time.Duration(hours) * time.Hour)
+	buckets := services.TrafficHistory(uint(nodeID), c.Param("name"), from, to, interval == "day")
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "success",
+		"data": gin.H{
+			"interval": interval,
+			"from":     from,
+			"to":       to,
+			"points":   buckets,
+		},
+	})
+}
+
+// GetContainerTrafficPeriods 获取容器流量账期
+// @Summary 获取容器流量账期
+// @Description 返回容器的流量账期汇总（当前账期在前）和账期起始日
+// @Tags 容器管理
+// @Produce json
+// @Param name path string true "容器名称"
+// @Param node_id query int true "节点ID"
+// @Param limit query int false "返回账期数量，默认12"
+// @Success 200 {object} map[string]interface{} "成功返回账期列表"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Router /api/containers/{name}/traffic/periods [get]
+func GetContainerTrafficPeriods(c *gin.Context) {
+	nodeID, err := strconv.ParseUint(c.Query("node_id"), 10, 64)
+	if err != nil || nodeID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "缺少节点ID",
+		})
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "12"))
+	if limit <= 0 || limit > 120 {
+		limit = 12
+	}
+
+	name := c.Param("name")
+	var periods []models.TrafficPeriod
+	database.DB.Where("node_id = ? AND hostname = ?", nodeID, name).
+		Order("period_start DESC").Limit(limit).Find(&periods)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "success",
+		"data": gin.H{
+			"anchor_day": services.BillingAnchorDay(uint(nodeID), name),
+			"periods":    periods,
+		},
+	})
+}
+
+// UpdateContainerBillingCycle 设置容器账期起始日
+// @Summary 设置容器账期起始日
+// @Description 修改容器每月的账期起始日，当前账期在修改时关闭，新账期从现在开始到下一个起始日
+// @Tags 容器管理
+// @Accept json
+// @Produce json
+// @Param name path string true "容器名称"
+// @Param body body models.UpdateBillingCycleRequest true "账期设置"
+// @Success 200 {object} map[string]interface{} "设置成功"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Failure 500 {object} map[string]interface{} "保存失败"
+// @Router /api/containers/{name}/traffic/billing [put]
+func UpdateContainerBillingCycle(c *gin.Context) {
+	var req models.UpdateBillingCycleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	name := c.Param("name")
+	if err := services.SetBillingAnchorDay(req.NodeID, name, req.AnchorDay); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "保存失败: " + err.Error(),
+		})
+		return
+	}
+
+	recordOperation(c, "traffic_billing_update", "container", req.NodeID, gin.H{
+		"hostname":   name,
+		"anchor_day": req.AnchorDay,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "账期起始日已更新",
+	})
+}
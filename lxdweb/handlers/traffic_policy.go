@@ -0,0 +1,413 @@
+package handlers
+
+import (
+	"lxdweb/database"
+	"lxdweb/models"
+	"lxdweb/services"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetTrafficPolicies 获取流量策略列表
+// @Summary 获取流量策略列表
+// @Description 返回所有流量超额策略及绑定的容器数量
+// @Tags 流量策略
+// @Produce json
+// @Success 200 {object} map[string]interface{} "成功返回策略列表"
+// @Router /api/traffic-policies [get]
+func GetTrafficPolicies(c *gin.Context) {
+	var policies []models.TrafficPolicy
+	database.DB.Order("id").Find(&policies)
+
+	type bindingCount struct {
+		PolicyID uint
+		Count    int
+	}
+	var counts []bindingCount
+	database.DB.Model(&models.TrafficPolicyBinding{}).
+		Select("policy_id, COUNT(*) AS count").Group("policy_id").Scan(&counts)
+	countMap := make(map[uint]int)
+	for _, bc := range counts {
+		countMap[bc.PolicyID] = bc.Count
+	}
+
+	list := make([]gin.H, 0, len(policies))
+	for _, p := range policies {
+		list = append(list, gin.H{
+			"policy":   p,
+			"bindings": countMap[p.ID],
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "success",
+		"data": list,
+	})
+}
+
+// CreateTrafficPolicy 创建流量策略
+// @Summary 创建流量策略
+// @Description 创建流量超额策略，动作可以是仅通知、限速或暂停容器；设为默认时取消其他策略的默认标记
+// @Tags 流量策略
+// @Accept json
+// @Produce json
+// @Param body body models.TrafficPolicyRequest true "策略参数"
+// @Success 200 {object} map[string]interface{} "创建成功"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Router /api/traffic-policies [post]
+func CreateTrafficPolicy(c *gin.Context) {
+	var req models.TrafficPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	policy := models.TrafficPolicy{Enabled: true}
+	if msg := applyTrafficPolicyRequest(&policy, req); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  msg,
+		})
+		return
+	}
+	if err := saveTrafficPolicy(&policy); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "创建失败: " + err.Error(),
+		})
+		return
+	}
+
+	recordOperation(c, "traffic_policy_create", "traffic_policy", policy.ID, policy)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "创建成功",
+		"data": policy,
+	})
+}
+
+// UpdateTrafficPolicy 更新流量策略
+// @Summary 更新流量策略
+// @Description 更新流量超额策略，已执行的超额处理按执行时的动作恢复，不受修改影响
+// @Tags 流量策略
+// @Accept json
+// @Produce json
+// @Param id path string true "策略ID"
+// @Param body body models.TrafficPolicyRequest true "策略参数"
+// @Success 200 {object} map[string]interface{} "更新成功"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Failure 404 {object} map[string]interface{} "策略不存在"
+// @Router /api/traffic-policies/{id} [put]
+func UpdateTrafficPolicy(c *gin.Context) {
+	var policy models.TrafficPolicy
+	if err := database.DB.First(&policy, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "策略不存在",
+		})
+		return
+	}
+
+	var req models.TrafficPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	if msg := applyTrafficPolicyRequest(&policy, req); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  msg,
+		})
+		return
+	}
+	if err := saveTrafficPolicy(&policy); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "更新失败: " + err.Error(),
+		})
+		return
+	}
+
+	recordOperation(c, "traffic_policy_update", "traffic_policy", policy.ID, policy)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "更新成功",
+		"data": policy,
+	})
+}
+
+// DeleteTrafficPolicy 删除流量策略
+// @Summary 删除流量策略
+// @Description 删除流量策略及其容器绑定，已执行的超额处理仍会在账期结束时恢复
+// @Tags 流量策略
+// @Produce json
+// @Param id path string true "策略ID"
+// @Success 200 {object} map[string]interface{} "删除成功"
+// @Failure 404 {object} map[string]interface{} "策略不存在"
+// @Router /api/traffic-policies/{id} [delete]
+func DeleteTrafficPolicy(c *gin.Context) {
+	var policy models.TrafficPolicy
+	if err := database.DB.First(&policy, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "策略不存在",
+		})
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("policy_id = ?", policy.ID).Delete(&models.TrafficPolicyBinding{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&policy).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "删除失败: " + err.Error(),
+		})
+		return
+	}
+
+	recordOperation(c, "traffic_policy_delete", "traffic_policy", policy.ID, gin.H{"name": policy.Name})
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "删除成功",
+	})
+}
+
+func applyTrafficPolicyRequest(policy *models.TrafficPolicy, req models.TrafficPolicyRequest) string {
+	if req.Action == models.TrafficActionThrottle && req.ThrottleIngress == "" && req.ThrottleEgress == "" {
+		return "限速策略需要设置入站或出站限速"
+	}
+
+	policy.Name = req.Name
+	policy.Description = req.Description
+	policy.Action = req.Action
+	policy.ThresholdPercent = req.ThresholdPercent
+	if policy.ThresholdPercent <= 0 {
+		policy.ThresholdPercent = 100
+	}
+	policy.ThrottleIngress = req.ThrottleIngress
+	policy.ThrottleEgress = req.ThrottleEgress
+	policy.IsDefault = req.IsDefault
+
+	ids := make([]string, 0, len(req.ChannelIDs))
+	for _, id := range req.ChannelIDs {
+		ids = append(ids, strconv.FormatUint(uint64(id), 10))
+	}
+	policy.ChannelIDs = strings.Join(ids, ",")
+
+	if req.Enabled != nil {
+		policy.Enabled = *req.Enabled
+	}
+	return ""
+}
+
+// saveTrafficPolicy 保存策略，设为默认时同时取消其他策略的默认标记
+func saveTrafficPolicy(policy *models.TrafficPolicy) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if policy.IsDefault {
+			if err := tx.Model(&models.TrafficPolicy{}).Where("is_default = ? AND id <> ?", true, policy.ID).
+				Update("is_default", false).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Save(policy).Error
+	})
+}
+
+// GetContainerTrafficPolicy 获取容器流量策略
+// @Summary 获取容器流量策略
+// @Description 返回容器绑定的流量策略、当前生效的策略和最近的超额处理记录
+// @Tags 流量策略
+// @Produce json
+// @Param name path string true "容器名称"
+// @Param node_id query int true "节点ID"
+// @Success 200 {object} map[string]interface{} "成功返回策略信息"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Router /api/containers/{name}/traffic/policy [get]
+func GetContainerTrafficPolicy(c *gin.Context) {
+	nodeID, err := strconv.ParseUint(c.Query("node_id"), 10, 64)
+	if err != nil || nodeID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "缺少节点ID",
+		})
+		return
+	}
+
+	name := c.Param("name")
+	var binding models.TrafficPolicyBinding
+	boundID := uint(0)
+	if database.DB.Where("node_id = ? AND hostname = ?", nodeID, name).First(&binding).Error == nil {
+		boundID = binding.PolicyID
+	}
+
+	var enforcements []models.TrafficEnforcement
+	database.DB.Where("node_id = ? AND hostname = ?", nodeID, name).Order("id DESC").Limit(10).Find(&enforcements)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "success",
+		"data": gin.H{
+			"bound_policy_id": boundID,
+			"effective":       services.TrafficPolicyFor(uint(nodeID), name),
+			"enforcements":    enforcements,
+		},
+	})
+}
+
+// BindContainerTrafficPolicy 设置容器流量策略
+// @Summary 设置容器流量策略
+// @Description 为容器绑定流量策略，policy_id 为0时解除绑定并使用默认策略
+// @Tags 流量策略
+// @Accept json
+// @Produce json
+// @Param name path string true "容器名称"
+// @Param body body models.BindTrafficPolicyRequest true "绑定参数"
+// @Success 200 {object} map[string]interface{} "设置成功"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Failure 404 {object} map[string]interface{} "策略不存在"
+// @Router /api/containers/{name}/traffic/policy [put]
+func BindContainerTrafficPolicy(c *gin.Context) {
+	var req models.BindTrafficPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	name := c.Param("name")
+	if req.PolicyID == 0 {
+		database.DB.Where("node_id = ? AND hostname = ?", req.NodeID, name).Delete(&models.TrafficPolicyBinding{})
+	} else {
+		var policy models.TrafficPolicy
+		if err := database.DB.First(&policy, req.PolicyID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"code": 404,
+				"msg":  "策略不存在",
+			})
+			return
+		}
+
+		binding := models.TrafficPolicyBinding{NodeID: req.NodeID, Hostname: name}
+		database.DB.Where("node_id = ? AND hostname = ?", req.NodeID, name).First(&binding)
+		binding.PolicyID = req.PolicyID
+		if err := database.DB.Save(&binding).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code": 500,
+				"msg":  "保存失败: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	recordOperation(c, "traffic_policy_bind", "container", req.NodeID, gin.H{
+		"hostname":  name,
+		"policy_id": req.PolicyID,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "流量策略已更新",
+	})
+}
+
+// GetTrafficEnforcements 获取超额处理记录
+// @Summary 获取超额处理记录
+// @Description 查询流量超额处理记录，支持按节点、容器和状态过滤
+// @Tags 流量策略
+// @Produce json
+// @Param node_id query int false "节点ID"
+// @Param hostname query string false "容器名称"
+// @Param status query string false "状态(active/failed/restored)"
+// @Param limit query int false "返回条数，默认100，最大1000"
+// @Success 200 {object} map[string]interface{} "成功返回处理记录"
+// @Router /api/traffic-enforcements [get]
+func GetTrafficEnforcements(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+
+	query := database.DB.Order("id DESC").Limit(limit)
+	if nodeID := c.Query("node_id"); nodeID != "" {
+		query = query.Where("node_id = ?", nodeID)
+	}
+	if hostname := c.Query("hostname"); hostname != "" {
+		query = query.Where("hostname = ?", hostname)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var list []models.TrafficEnforcement
+	query.Find(&list)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "success",
+		"data": list,
+	})
+}
+
+// ReleaseTrafficEnforcement 手动解除超额处理
+// @Summary 手动解除超额处理
+// @Description 恢复容器限速或暂停前的状态，本账期内不再自动执行超额动作
+// @Tags 流量策略
+// @Produce json
+// @Param id path string true "处理记录ID"
+// @Success 200 {object} map[string]interface{} "解除成功"
+// @Failure 404 {object} map[string]interface{} "记录不存在"
+// @Failure 500 {object} map[string]interface{} "恢复失败"
+// @Router /api/traffic-enforcements/{id}/release [post]
+func ReleaseTrafficEnforcement(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "无效的记录ID",
+		})
+		return
+	}
+
+	enforcement, err := services.ReleaseTrafficEnforcement(uint(id), currentAdminID(c), c.ClientIP())
+	if enforcement == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "记录不存在",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "恢复失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "超额处理已解除",
+		"data": enforcement,
+	})
+}
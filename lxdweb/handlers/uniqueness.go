@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"errors"
+	"lxdweb/config"
+	"lxdweb/database"
+	"lxdweb/models"
+	"lxdweb/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// resourceConflict 从错误中取出唯一性冲突信息，不是冲突错误时返回 nil
+func resourceConflict(err error) *services.ResourceConflict {
+	var conflict *services.ResourceConflict
+	if errors.As(err, &conflict) {
+		return conflict
+	}
+	return nil
+}
+
+// CheckResourceUnique 检查资源唯一性
+// @Summary 检查资源唯一性
+// @Description 按配置的唯一性范围检查域名、公网地址或NAT外部端口是否已被占用，冲突时返回占用者所在节点和容器
+// @Tags 节点管理
+// @Produce json
+// @Param node_id query int true "节点ID"
+// @Param type query string true "资源类型(domain/ip/port)"
+// @Param value query string true "域名、地址或端口"
+// @Param protocol query string false "端口协议(tcp/udp/tcp+udp)，type=port 时使用，默认tcp"
+// @Param hostname query string false "容器名称，type=ip 时忽略该容器自身的绑定"
+// @Success 200 {object} map[string]interface{} "返回是否可用及冲突信息"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Failure 404 {object} map[string]interface{} "节点不存在"
+// @Router /api/uniqueness/check [get]
+func CheckResourceUnique(c *gin.Context) {
+	value := c.Query("value")
+	if value == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "缺少必要参数",
+		})
+		return
+	}
+
+	var node models.Node
+	if err := database.DB.First(&node, c.Query("node_id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "节点不存在",
+		})
+		return
+	}
+
+	var conflict *services.ResourceConflict
+	var checkErr error
+	switch c.Query("type") {
+	case services.ConflictTypeDomain:
+		conflict = services.CheckDomainUnique(node.ID, value)
+	case services.ConflictTypeIP:
+		if _, err := services.ParseIPAddress(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code": 400,
+				"msg":  err.Error(),
+			})
+			return
+		}
+		conflict = services.CheckIPUnique(node.ID, value, c.Query("hostname"))
+	case services.ConflictTypePort:
+		port, err := strconv.Atoi(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code": 400,
+				"msg":  "端口格式错误",
+			})
+			return
+		}
+		protocol := c.DefaultQuery("protocol", "tcp")
+		checkErr = services.CheckNATPortAvailable(node.ID, protocol, port)
+		conflict = resourceConflict(checkErr)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "资源类型只能是 domain、ip 或 port",
+		})
+		return
+	}
+
+	if conflict != nil {
+		checkErr = conflict
+	}
+	data := gin.H{
+		"available": checkErr == nil,
+		"conflict":  conflict,
+		"scope": gin.H{
+			"domain": config.AppConfig.Uniqueness.DomainScope,
+			"ip":     config.AppConfig.Uniqueness.IPScope,
+			"port":   config.AppConfig.Uniqueness.PortScope,
+		},
+	}
+	if checkErr != nil {
+		data["reason"] = checkErr.Error()
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "success",
+		"data": data,
+	})
+}
@@ -0,0 +1,369 @@
+package handlers
+
+import (
+	"lxdweb/database"
+	"lxdweb/models"
+	"lxdweb/services"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetWebhooks 获取 webhook 列表
+// @Summary 获取webhook列表
+// @Description 返回全部 webhook 订阅，密钥不返回，只返回是否已设置
+// @Tags Webhook
+// @Produce json
+// @Success 200 {object} map[string]interface{} "成功返回webhook列表"
+// @Router /api/webhooks [get]
+func GetWebhooks(c *gin.Context) {
+	var hooks []models.Webhook
+	database.DB.Order("id").Find(&hooks)
+
+	data := make([]gin.H, 0, len(hooks))
+	for _, h := range hooks {
+		var pending, failed int64
+		database.DB.Model(&models.WebhookDelivery{}).Where("webhook_id = ? AND status = ?", h.ID, models.WebhookDeliveryPending).Count(&pending)
+		database.DB.Model(&models.WebhookDelivery{}).Where("webhook_id = ? AND status = ?", h.ID, models.WebhookDeliveryFailed).Count(&failed)
+		data = append(data, gin.H{
+			"webhook":    h,
+			"has_secret": h.Secret != "",
+			"pending":    pending,
+			"failed":     failed,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "success",
+		"data": data,
+	})
+}
+
+// GetWebhookEvents 获取可订阅的事件类型
+// @Summary 获取webhook事件类型
+// @Tags Webhook
+// @Produce json
+// @Success 200 {object} map[string]interface{} "成功返回事件类型"
+// @Router /api/webhooks/events [get]
+func GetWebhookEvents(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "success",
+		"data": models.WebhookEvents,
+	})
+}
+
+// CreateWebhook 创建 webhook
+// @Summary 创建webhook
+// @Description 创建事件订阅，events 为空表示订阅全部事件；未指定密钥时自动生成，密钥只在创建时返回一次
+// @Tags Webhook
+// @Accept json
+// @Produce json
+// @Param body body models.WebhookRequest true "webhook参数"
+// @Success 200 {object} map[string]interface{} "创建成功"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Router /api/webhooks [post]
+func CreateWebhook(c *gin.Context) {
+	var req models.WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "参数错误: " + err.Error(),
+		})
+		return
+	}
+	events, ok := validWebhookEvents(req.Events)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "不支持的事件类型: " + events,
+		})
+		return
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		var err error
+		if secret, err = services.GenerateWebhookSecret(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code": 500,
+				"msg":  "生成密钥失败: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	hook := models.Webhook{
+		Name:        req.Name,
+		URL:         req.URL,
+		Secret:      secret,
+		Events:      events,
+		Description: req.Description,
+		Enabled:     true,
+	}
+	if req.Enabled != nil {
+		hook.Enabled = *req.Enabled
+	}
+	if err := database.DB.Create(&hook).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "创建失败: " + err.Error(),
+		})
+		return
+	}
+
+	recordOperation(c, "webhook_create", "webhook", hook.ID, gin.H{"name": hook.Name, "url": hook.URL, "events": hook.Events})
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "创建成功",
+		"data": gin.H{
+			"webhook": hook,
+			"secret":  secret,
+		},
+	})
+}
+
+// UpdateWebhook 更新 webhook
+// @Summary 更新webhook
+// @Description 更新事件订阅，secret 为空时保留原密钥
+// @Tags Webhook
+// @Accept json
+// @Produce json
+// @Param id path string true "webhook ID"
+// @Param body body models.WebhookRequest true "webhook参数"
+// @Success 200 {object} map[string]interface{} "更新成功"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Failure 404 {object} map[string]interface{} "webhook不存在"
+// @Router /api/webhooks/{id} [put]
+func UpdateWebhook(c *gin.Context) {
+	var hook models.Webhook
+	if err := database.DB.First(&hook, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "webhook不存在",
+		})
+		return
+	}
+
+	var req models.WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "参数错误: " + err.Error(),
+		})
+		return
+	}
+	events, ok := validWebhookEvents(req.Events)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "不支持的事件类型: " + events,
+		})
+		return
+	}
+
+	hook.Name = req.Name
+	hook.URL = req.URL
+	hook.Events = events
+	hook.Description = req.Description
+	if req.Secret != "" {
+		hook.Secret = req.Secret
+	}
+	if req.Enabled != nil {
+		hook.Enabled = *req.Enabled
+	}
+	if err := database.DB.Save(&hook).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "更新失败: " + err.Error(),
+		})
+		return
+	}
+
+	recordOperation(c, "webhook_update", "webhook", hook.ID, gin.H{
+		"name":           hook.Name,
+		"url":            hook.URL,
+		"events":         hook.Events,
+		"enabled":        hook.Enabled,
+		"secret_changed": req.Secret != "",
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "更新成功",
+		"data": hook,
+	})
+}
+
+// DeleteWebhook 删除 webhook
+// @Summary 删除webhook
+// @Description 删除事件订阅及其投递记录
+// @Tags Webhook
+// @Produce json
+// @Param id path string true "webhook ID"
+// @Success 200 {object} map[string]interface{} "删除成功"
+// @Failure 404 {object} map[string]interface{} "webhook不存在"
+// @Router /api/webhooks/{id} [delete]
+func DeleteWebhook(c *gin.Context) {
+	var hook models.Webhook
+	if err := database.DB.First(&hook, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "webhook不存在",
+		})
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("webhook_id = ?", hook.ID).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&hook).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "删除失败: " + err.Error(),
+		})
+		return
+	}
+
+	recordOperation(c, "webhook_delete", "webhook", hook.ID, gin.H{"name": hook.Name, "url": hook.URL})
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "删除成功",
+	})
+}
+
+// TestWebhook 发送测试事件
+// @Summary 测试webhook
+// @Description 向 webhook 投递一条 ping 事件，结果见投递记录
+// @Tags Webhook
+// @Produce json
+// @Param id path string true "webhook ID"
+// @Success 200 {object} map[string]interface{} "已加入投递队列"
+// @Failure 404 {object} map[string]interface{} "webhook不存在"
+// @Router /api/webhooks/{id}/test [post]
+func TestWebhook(c *gin.Context) {
+	var hook models.Webhook
+	if err := database.DB.First(&hook, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "webhook不存在",
+		})
+		return
+	}
+
+	if err := services.SendWebhookPing(hook); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "发送失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "测试事件已加入投递队列",
+	})
+}
+
+// GetWebhookDeliveries 获取投递记录
+// @Summary 获取webhook投递记录
+// @Description 按 webhook、事件类型和状态查询投递记录
+// @Tags Webhook
+// @Produce json
+// @Param webhook_id query int false "webhook ID"
+// @Param event query string false "事件类型"
+// @Param status query string false "状态: pending/success/failed"
+// @Param limit query int false "返回条数，默认100，最大500"
+// @Success 200 {object} map[string]interface{} "成功返回投递记录"
+// @Router /api/webhooks/deliveries [get]
+func GetWebhookDeliveries(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+
+	query := database.DB.Order("id DESC").Limit(limit)
+	if webhookID := c.Query("webhook_id"); webhookID != "" {
+		query = query.Where("webhook_id = ?", webhookID)
+	}
+	if event := c.Query("event"); event != "" {
+		query = query.Where("event = ?", event)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var deliveries []models.WebhookDelivery
+	query.Find(&deliveries)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "success",
+		"data": deliveries,
+	})
+}
+
+// RedeliverWebhookDelivery 重新投递
+// @Summary 重新投递webhook事件
+// @Description 以原事件内容和事件ID创建一条新的投递记录并立即投递
+// @Tags Webhook
+// @Produce json
+// @Param id path string true "投递记录ID"
+// @Success 200 {object} map[string]interface{} "已加入投递队列"
+// @Failure 404 {object} map[string]interface{} "投递记录不存在"
+// @Router /api/webhooks/deliveries/{id}/redeliver [post]
+func RedeliverWebhookDelivery(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	delivery, err := services.RedeliverWebhook(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "投递记录不存在: " + err.Error(),
+		})
+		return
+	}
+
+	recordOperation(c, "webhook_redeliver", "webhook", delivery.WebhookID, gin.H{
+		"delivery_id":   delivery.ID,
+		"redelivery_of": delivery.RedeliveryOf,
+		"event":         delivery.Event,
+		"event_id":      delivery.EventID,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "已加入投递队列",
+		"data": delivery,
+	})
+}
+
+// validWebhookEvents 校验事件类型，返回逗号拼接的结果；校验失败时返回不支持的事件
+func validWebhookEvents(events []string) (string, bool) {
+	valid := make(map[string]bool, len(models.WebhookEvents))
+	for _, e := range models.WebhookEvents {
+		valid[e] = true
+	}
+	seen := make(map[string]bool)
+	var list []string
+	for _, e := range events {
+		e = strings.TrimSpace(e)
+		if e == "" || seen[e] {
+			continue
+		}
+		if !valid[e] {
+			return e, false
+		}
+		seen[e] = true
+		list = append(list, e)
+	}
+	return strings.Join(list, ","), true
+}
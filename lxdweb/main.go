@@ -78,6 +78,15 @@ func startWebServer() {
 	go services.StartNATSyncService()
 	go services.StartAutoSyncService()
 	go services.StartNodeCacheService()
+	go services.StartMaintenanceService()
+	go services.StartHealthCheckService()
+	go services.StartAlertService()
+	go services.StartACMEService()
+	go services.StartTrafficService()
+	go services.StartResourceMetricService()
+	go services.StartSchedulerService()
+	go services.StartWebhookService()
+	go services.StartConsoleService()
 	
 	gin.SetMode(config.AppConfig.Server.Mode)
 	r := gin.Default()
@@ -92,6 +101,8 @@ func startWebServer() {
 	r.POST("/login", handlers.Login)
 	r.GET("/logout", handlers.Logout)
 	r.GET("/api/captcha", handlers.GetCaptcha)
+	r.GET("/metrics", middleware.MetricsAuth(), handlers.Metrics)
+	r.GET("/.well-known/acme-challenge/:token", handlers.ACMEChallenge)
 	auth := r.Group("/")
 	auth.Use(middleware.AuthRequired())
 	{
@@ -102,14 +113,26 @@ func startWebServer() {
 		auth.GET("/nodes/:id/containers/:name", handlers.ContainerDetailPage)
 		auth.GET("/nodes/:id/nat", handlers.NodeNATPage)
 		auth.GET("/nodes/:id/ipv6", handlers.NodeIPv6Page)
+		auth.GET("/nodes/:id/ipv4", handlers.NodeIPv4Page)
+		auth.GET("/nodes/:id/ipam", handlers.NodeIPAMPage)
 		auth.GET("/nodes/:id/proxy", handlers.NodeProxyPage)
+		auth.GET("/alerts", handlers.AlertsPage)
+		auth.GET("/schedules", handlers.SchedulesPage)
+		auth.GET("/ssh-keys", handlers.SSHKeysPage)
+		auth.GET("/console/:token", handlers.ConsolePage)
+		auth.GET("/console/:token/ws", handlers.ConsoleWebSocket)
 		auth.GET("/api/nodes", handlers.GetNodes)
+		auth.GET("/api/nodes/groups", handlers.GetNodeGroups)
 		auth.GET("/api/nodes/:id", handlers.GetNode)
 		auth.POST("/api/nodes", handlers.CreateNode)
 		auth.PUT("/api/nodes/:id", handlers.UpdateNode)
 		auth.DELETE("/api/nodes/:id", handlers.DeleteNode)
 		auth.POST("/api/nodes/:id/test", handlers.TestNode)
 		auth.POST("/api/nodes/:id/refresh", handlers.RefreshNodeCache)
+		auth.GET("/api/nodes/:id/health", handlers.GetNodeHealth)
+		auth.POST("/api/nodes/:id/maintenance", handlers.EnterNodeMaintenance)
+		auth.DELETE("/api/nodes/:id/maintenance", handlers.ExitNodeMaintenance)
+		auth.POST("/api/nodes/maintenance/group", handlers.SetGroupMaintenance)
 		auth.GET("/api/nodes/export/all", handlers.ExportNodes)
 		auth.POST("/api/nodes/import/batch", handlers.ImportNodes)
 		auth.POST("/api/nodes/delete/batch", handlers.BatchDeleteNodes)
@@ -125,22 +148,92 @@ func startWebServer() {
 		auth.POST("/api/containers/:name/refresh", handlers.RefreshSingleContainer)
 		auth.POST("/api/containers/:name/reinstall", handlers.ReinstallContainer)
 		auth.POST("/api/containers/:name/password", handlers.ResetContainerPassword)
+		auth.POST("/api/containers/:name/ssh-keys", handlers.SetContainerSSHKeys)
+		auth.POST("/api/containers/:name/ssh-password-auth", handlers.SetContainerPasswordAuth)
 		auth.POST("/api/containers/:name/suspend", handlers.SuspendContainer)
 		auth.POST("/api/containers/:name/unsuspend", handlers.UnsuspendContainer)
 		auth.POST("/api/containers/:name/traffic/reset", handlers.ResetContainerTraffic)
+		auth.GET("/api/containers/:name/traffic/history", handlers.GetContainerTrafficHistory)
+		auth.GET("/api/containers/:name/traffic/periods", handlers.GetContainerTrafficPeriods)
+		auth.PUT("/api/containers/:name/traffic/billing", handlers.UpdateContainerBillingCycle)
+		auth.GET("/api/containers/:name/traffic/policy", handlers.GetContainerTrafficPolicy)
+		auth.PUT("/api/containers/:name/traffic/policy", handlers.BindContainerTrafficPolicy)
+		auth.GET("/api/containers/:name/files", handlers.ListContainerFiles)
+		auth.GET("/api/containers/:name/files/download", handlers.DownloadContainerFile)
+		auth.GET("/api/containers/:name/files/content", handlers.GetContainerFileContent)
+		auth.PUT("/api/containers/:name/files/content", handlers.SaveContainerFileContent)
+		auth.POST("/api/containers/:name/files/upload", handlers.UploadContainerFile)
+		auth.POST("/api/containers/:name/files/delete", handlers.DeleteContainerFile)
+		auth.POST("/api/containers/:name/files/mkdir", handlers.MakeContainerDir)
+		auth.GET("/api/metrics/containers/:name", handlers.GetContainerResourceMetrics)
+		auth.GET("/api/metrics/nodes/:id", handlers.GetNodeResourceMetrics)
 		auth.POST("/api/containers/create", handlers.CreateContainer)
+		auth.POST("/api/containers/batch", handlers.BatchContainerAction)
+		auth.POST("/api/containers/exec", handlers.ExecContainers)
+		auth.POST("/api/cloud-init/validate", handlers.ValidateCloudInit)
+		auth.GET("/api/user-data-templates", handlers.GetUserDataTemplates)
+		auth.POST("/api/user-data-templates", handlers.CreateUserDataTemplate)
+		auth.PUT("/api/user-data-templates/:id", handlers.UpdateUserDataTemplate)
+		auth.DELETE("/api/user-data-templates/:id", handlers.DeleteUserDataTemplate)
+		auth.GET("/api/ssh-keys", handlers.GetSSHKeys)
+		auth.POST("/api/ssh-keys", handlers.CreateSSHKey)
+		auth.PUT("/api/ssh-keys/:id", handlers.UpdateSSHKey)
+		auth.DELETE("/api/ssh-keys/:id", handlers.DeleteSSHKey)
+		auth.GET("/api/batch/tasks", handlers.GetBatchTasks)
+		auth.GET("/api/batch/tasks/:id", handlers.GetBatchTask)
+		// 告警API
+		auth.GET("/api/alerts/events", handlers.GetAlertEvents)
+		auth.GET("/api/alerts/rules", handlers.GetAlertRules)
+		auth.POST("/api/alerts/rules", handlers.CreateAlertRule)
+		auth.PUT("/api/alerts/rules/:id", handlers.UpdateAlertRule)
+		auth.DELETE("/api/alerts/rules/:id", handlers.DeleteAlertRule)
+		auth.GET("/api/alerts/channels", handlers.GetAlertChannels)
+		auth.POST("/api/alerts/channels", handlers.CreateAlertChannel)
+		auth.PUT("/api/alerts/channels/:id", handlers.UpdateAlertChannel)
+		auth.DELETE("/api/alerts/channels/:id", handlers.DeleteAlertChannel)
+		auth.POST("/api/alerts/channels/:id/test", handlers.TestAlertChannel)
+		auth.GET("/api/alerts/silences", handlers.GetAlertSilences)
+		auth.POST("/api/alerts/silences", handlers.CreateAlertSilence)
+		auth.DELETE("/api/alerts/silences/:id", handlers.DeleteAlertSilence)
+		// 流量超额策略
+		auth.GET("/api/traffic-policies", handlers.GetTrafficPolicies)
+		auth.POST("/api/traffic-policies", handlers.CreateTrafficPolicy)
+		auth.PUT("/api/traffic-policies/:id", handlers.UpdateTrafficPolicy)
+		auth.DELETE("/api/traffic-policies/:id", handlers.DeleteTrafficPolicy)
+		auth.GET("/api/traffic-enforcements", handlers.GetTrafficEnforcements)
+		auth.POST("/api/traffic-enforcements/:id/release", handlers.ReleaseTrafficEnforcement)
+		auth.GET("/api/schedules", handlers.GetScheduledTasks)
+		auth.POST("/api/schedules", handlers.CreateScheduledTask)
+		auth.GET("/api/schedules/preview", handlers.PreviewSchedule)
+		auth.PUT("/api/schedules/:id", handlers.UpdateScheduledTask)
+		auth.DELETE("/api/schedules/:id", handlers.DeleteScheduledTask)
+		auth.POST("/api/schedules/:id/run", handlers.RunScheduledTaskNow)
+		auth.GET("/api/schedules/:id/runs", handlers.GetScheduledTaskRuns)
+		auth.GET("/api/webhooks", handlers.GetWebhooks)
+		auth.POST("/api/webhooks", handlers.CreateWebhook)
+		auth.GET("/api/webhooks/events", handlers.GetWebhookEvents)
+		auth.GET("/api/webhooks/deliveries", handlers.GetWebhookDeliveries)
+		auth.POST("/api/webhooks/deliveries/:id/redeliver", handlers.RedeliverWebhookDelivery)
+		auth.PUT("/api/webhooks/:id", handlers.UpdateWebhook)
+		auth.DELETE("/api/webhooks/:id", handlers.DeleteWebhook)
+		auth.POST("/api/webhooks/:id/test", handlers.TestWebhook)
 		// NAT API（保留API接口，但去掉全局页面入口）
 		auth.GET("/api/nat", handlers.GetNATRules)
 		auth.GET("/api/nat/:id", handlers.GetNATRule)
 		auth.GET("/api/nat/check", handlers.CheckNATPort)
+		auth.GET("/api/nat/export", handlers.ExportNATRules)
+		auth.POST("/api/nat/import", handlers.ImportNATRules)
 		auth.POST("/api/nat", handlers.CreateNATRule)
 		auth.PUT("/api/nat/:id", handlers.UpdateNATRule)
 		auth.DELETE("/api/nat/:id", handlers.DeleteNATRule)
 		auth.POST("/api/nat/sync", handlers.SyncNATRules)
 		auth.POST("/api/console/create-token", handlers.CreateConsoleToken)
+		auth.GET("/api/console/sessions", handlers.GetConsoleSessions)
+		auth.POST("/api/console/sessions/:id/terminate", handlers.TerminateConsoleSession)
 
 		auth.POST("/api/sync/all", handlers.SyncAllNodes)
 		auth.POST("/api/sync/node/:id", handlers.SyncNode)
+		auth.POST("/api/sync/group", handlers.SyncNodeGroup)
 		auth.GET("/api/sync/tasks", handlers.GetSyncTasks)
 		auth.GET("/api/sync/status", handlers.GetSyncStatus)
 
@@ -149,6 +242,14 @@ func startWebServer() {
 		auth.GET("/api/nat-sync/tasks", handlers.GetNATSyncTasks)
 		auth.GET("/api/nat-sync/status", handlers.GetNATSyncStatus)
 		auth.GET("/api/nat/cache", handlers.GetNATRulesFromCache)
+		auth.GET("/api/nat-ports/pools", handlers.GetNATPortPools)
+		auth.POST("/api/nat-ports/pools", handlers.CreateNATPortPool)
+		auth.PUT("/api/nat-ports/pools/:id", handlers.UpdateNATPortPool)
+		auth.DELETE("/api/nat-ports/pools/:id", handlers.DeleteNATPortPool)
+		auth.GET("/api/nat-ports/quotas", handlers.GetNATPortQuotas)
+		auth.PUT("/api/nat-ports/quotas", handlers.SetNATPortQuota)
+		auth.DELETE("/api/nat-ports/quotas/:id", handlers.DeleteNATPortQuota)
+		auth.GET("/api/nat-ports/free", handlers.GetNATFreePorts)
 
 		// IPv6 API（保留API接口，但去掉全局页面入口）
 		auth.GET("/api/ipv6", handlers.GetIPv6Bindings)
@@ -160,6 +261,28 @@ func startWebServer() {
 		auth.GET("/api/ipv6-sync/status", handlers.GetIPv6SyncStatus)
 		auth.GET("/api/ipv6-sync/tasks", handlers.GetIPv6SyncTasks)
 
+		// 独立IPv4 API
+		auth.GET("/api/ipv4", handlers.GetIPv4Bindings)
+		auth.POST("/api/ipv4", handlers.CreateIPv4Binding)
+		auth.DELETE("/api/ipv4/:id", handlers.DeleteIPv4Binding)
+		auth.POST("/api/ipv4/sync", handlers.SyncIPv4Bindings)
+		auth.GET("/api/ipv4/cache", handlers.GetIPv4BindingsFromCache)
+		auth.POST("/api/ipv4-sync/all", handlers.SyncAllIPv4)
+		auth.GET("/api/ipv4-sync/status", handlers.GetIPv4SyncStatus)
+		auth.GET("/api/ipv4-sync/tasks", handlers.GetIPv4SyncTasks)
+
+		// 地址池（IPAM）API
+		auth.GET("/api/ipam/pools", handlers.GetIPPools)
+		auth.POST("/api/ipam/pools", handlers.CreateIPPool)
+		auth.PUT("/api/ipam/pools/:id", handlers.UpdateIPPool)
+		auth.DELETE("/api/ipam/pools/:id", handlers.DeleteIPPool)
+		auth.GET("/api/ipam/pools/:id/addresses", handlers.GetIPPoolAddresses)
+		auth.POST("/api/ipam/pools/:id/addresses", handlers.MarkIPPoolAddresses)
+		auth.DELETE("/api/ipam/addresses/:id", handlers.DeleteIPAddress)
+		auth.GET("/api/ipam/utilization", handlers.GetIPAMUtilization)
+		auth.GET("/api/ipam/conflicts", handlers.GetIPConflicts)
+		auth.GET("/api/uniqueness/check", handlers.CheckResourceUnique)
+
 		// 反向代理 API（保留API接口，但去掉全局页面入口）
 		auth.GET("/api/proxy-configs", handlers.GetProxyConfigs)
 		auth.GET("/api/proxy/check", handlers.CheckProxyDomain)
@@ -170,6 +293,17 @@ func startWebServer() {
 		auth.POST("/api/proxy-sync/all", handlers.SyncAllProxy)
 		auth.GET("/api/proxy-sync/status", handlers.GetProxySyncStatus)
 		auth.GET("/api/proxy-sync/tasks", handlers.GetProxySyncTasks)
+		auth.GET("/api/proxy-certs", handlers.GetProxyCertificates)
+		auth.PUT("/api/proxy-certs/:id", handlers.UpdateProxyCertificate)
+		auth.DELETE("/api/proxy-certs/:id", handlers.DeleteProxyCertificate)
+		auth.POST("/api/proxy-configs/:id/cert/issue", handlers.IssueProxyCertificate)
+		auth.GET("/api/domain-verifications", handlers.GetDomainVerifications)
+		auth.POST("/api/domain-verifications", handlers.CreateDomainVerification)
+		auth.POST("/api/domain-verifications/:id/check", handlers.CheckDomainVerification)
+		auth.DELETE("/api/domain-verifications/:id", handlers.DeleteDomainVerification)
+		auth.PUT("/api/proxy-configs/:id/cert", handlers.UploadProxyCertificate)
+
+		auth.GET("/api/audit-logs", handlers.GetOperationLogs)
 
 		auth.GET("/api/auto-sync/status", handlers.GetAutoSyncStatus)
 		auth.POST("/api/auto-sync/enable", handlers.EnableAutoSync)
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"lxdweb/config"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MetricsAuth 校验 /metrics 访问权限：令牌正确或来源 IP 在白名单内即可访问
+func MetricsAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cfg := config.AppConfig.Metrics
+		if !cfg.Enabled {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+
+		if cfg.Token != "" {
+			token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+			if token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(cfg.Token)) == 1 {
+				c.Next()
+				return
+			}
+		}
+
+		// 只按 TCP 连接的来源地址校验，X-Forwarded-For 等请求头可由客户端伪造
+		if ipAllowed(c.RemoteIP(), cfg.AllowIPs) {
+			c.Next()
+			return
+		}
+
+		c.AbortWithStatus(http.StatusForbidden)
+	}
+}
+
+func ipAllowed(clientIP string, allowList []string) bool {
+	ip := net.ParseIP(clientIP)
+	if ip == nil {
+		return false
+	}
+	for _, entry := range allowList {
+		if strings.Contains(entry, "/") {
+			if _, cidr, err := net.ParseCIDR(entry); err == nil && cidr.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if allowed := net.ParseIP(entry); allowed != nil && allowed.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,210 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 告警指标
+const (
+	AlertMetricNodeDown      = "node_down"       // 节点离线，离线时值为1
+	AlertMetricNodeDegraded  = "node_degraded"   // 节点降级，降级时值为1
+	AlertMetricSyncFailed    = "sync_failed"     // 节点最近一次同步失败的任务类型数量
+	AlertMetricTrafficUsage  = "traffic_usage"   // 容器流量使用率（%）
+	AlertMetricDiskUsage     = "disk_usage"      // 容器磁盘使用率（%）
+	AlertMetricMemoryUsage   = "memory_usage"    // 容器内存使用率（%）
+	AlertMetricCPUUsage      = "cpu_usage"       // 容器CPU使用率（%）
+	AlertMetricProxySSLError = "proxy_ssl_error" // 启用SSL的反向代理同步异常，异常时值为1
+	AlertMetricCertDaysLeft  = "cert_days_left"  // 反向代理证书剩余有效天数
+	AlertMetricCertInvalid   = "cert_invalid"    // 反向代理证书已过期或与域名不匹配，异常时值为1
+)
+
+// 告警事件状态
+const (
+	AlertStatusPending  = "pending"
+	AlertStatusFiring   = "firing"
+	AlertStatusResolved = "resolved"
+)
+
+// AlertRule 告警规则表
+type AlertRule struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	Name           string    `json:"name" gorm:"size:200;not null"`
+	Description    string    `json:"description" gorm:"type:text"`
+	Metric         string    `json:"metric" gorm:"size:50;not null;index"`
+	Operator       string    `json:"operator" gorm:"size:10;default:'gte'"`
+	Threshold      float64   `json:"threshold"`
+	Duration       int       `json:"duration"`
+	RepeatInterval int       `json:"repeat_interval"`
+	Severity       string    `json:"severity" gorm:"size:20;default:'warning'"`
+	NodeID         uint      `json:"node_id" gorm:"index"`
+	NodeGroup      string    `json:"node_group" gorm:"size:100"`
+	ChannelIDs     string    `json:"channel_ids" gorm:"size:500"`
+	Enabled        bool      `json:"enabled" gorm:"default:true"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// ChannelIDList 解析规则关联的通知渠道ID
+func (r AlertRule) ChannelIDList() []uint {
+	var ids []uint
+	for _, part := range strings.Split(r.ChannelIDs, ",") {
+		id, err := strconv.ParseUint(strings.TrimSpace(part), 10, 32)
+		if err == nil && id > 0 {
+			ids = append(ids, uint(id))
+		}
+	}
+	return ids
+}
+
+// Compare 判断指标值是否满足规则条件
+func (r AlertRule) Compare(value float64) bool {
+	switch r.Operator {
+	case "gt":
+		return value > r.Threshold
+	case "lt":
+		return value < r.Threshold
+	case "lte":
+		return value <= r.Threshold
+	case "eq":
+		return value == r.Threshold
+	default:
+		return value >= r.Threshold
+	}
+}
+
+// AlertChannel 告警通知渠道表，Config 为渠道类型对应的 JSON 配置
+type AlertChannel struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"size:200;not null"`
+	Type      string    `json:"type" gorm:"size:50;not null"`
+	Config    string    `json:"config" gorm:"type:text"`
+	Enabled   bool      `json:"enabled" gorm:"default:true"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// AlertChannelConfig 通知渠道配置
+type AlertChannelConfig struct {
+	// webhook
+	URL     string            `json:"url,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// smtp
+	Host     string   `json:"host,omitempty"`
+	Port     int      `json:"port,omitempty"`
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	From     string   `json:"from,omitempty"`
+	To       []string `json:"to,omitempty"`
+	TLS      bool     `json:"tls,omitempty"`
+
+	// telegram
+	APIBase  string `json:"api_base,omitempty"`
+	BotToken string `json:"bot_token,omitempty"`
+	ChatID   string `json:"chat_id,omitempty"`
+
+	// webhook / telegram 跳过 TLS 证书校验，仅用于自签名证书的内网地址
+	InsecureSkipVerify bool `json:"insecure_skip_verify,omitempty"`
+}
+
+// AlertEvent 告警事件表，同一规则同一对象同时只有一条未恢复事件
+type AlertEvent struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	RuleID         uint       `json:"rule_id" gorm:"index"`
+	RuleName       string     `json:"rule_name" gorm:"size:200"`
+	Metric         string     `json:"metric" gorm:"size:50"`
+	Severity       string     `json:"severity" gorm:"size:20"`
+	Target         string     `json:"target" gorm:"size:300;index"`
+	NodeID         uint       `json:"node_id" gorm:"index"`
+	NodeName       string     `json:"node_name" gorm:"size:200"`
+	Hostname       string     `json:"hostname" gorm:"size:200"`
+	Status         string     `json:"status" gorm:"size:20;index"`
+	Value          float64    `json:"value"`
+	Threshold      float64    `json:"threshold"`
+	Message        string     `json:"message" gorm:"type:text"`
+	Silenced       bool       `json:"silenced"`
+	NotifyCount    int        `json:"notify_count"`
+	NotifyError    string     `json:"notify_error" gorm:"type:text"`
+	StartedAt      time.Time  `json:"started_at"`
+	FiredAt        *time.Time `json:"fired_at"`
+	ResolvedAt     *time.Time `json:"resolved_at"`
+	LastNotifiedAt *time.Time `json:"last_notified_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// AlertSilence 告警静默表，RuleID/NodeID 为0、Hostname 为空表示匹配全部
+type AlertSilence struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	RuleID    uint      `json:"rule_id" gorm:"index"`
+	NodeID    uint      `json:"node_id" gorm:"index"`
+	Hostname  string    `json:"hostname" gorm:"size:200"`
+	Reason    string    `json:"reason" gorm:"type:text"`
+	StartsAt  time.Time `json:"starts_at"`
+	EndsAt    time.Time `json:"ends_at" gorm:"index"`
+	CreatedBy uint      `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Matches 判断静默是否覆盖指定事件
+func (s AlertSilence) Matches(ruleID, nodeID uint, hostname string, at time.Time) bool {
+	if at.Before(s.StartsAt) || !at.Before(s.EndsAt) {
+		return false
+	}
+	if s.RuleID != 0 && s.RuleID != ruleID {
+		return false
+	}
+	if s.NodeID != 0 && s.NodeID != nodeID {
+		return false
+	}
+	return s.Hostname == "" || s.Hostname == hostname
+}
+
+type AlertRuleRequest struct {
+	Name           string  `json:"name" binding:"required"`
+	Description    string  `json:"description"`
+	Metric         string  `json:"metric" binding:"required,oneof=node_down node_degraded sync_failed traffic_usage disk_usage memory_usage cpu_usage proxy_ssl_error cert_days_left cert_invalid"`
+	Operator       string  `json:"operator" binding:"omitempty,oneof=gt gte lt lte eq"`
+	Threshold      float64 `json:"threshold"`
+	Duration       int     `json:"duration" binding:"min=0"`
+	RepeatInterval int     `json:"repeat_interval" binding:"min=0"`
+	Severity       string  `json:"severity" binding:"omitempty,oneof=info warning critical"`
+	NodeID         uint    `json:"node_id"`
+	NodeGroup      string  `json:"node_group"`
+	ChannelIDs     []uint  `json:"channel_ids"`
+	Enabled        *bool   `json:"enabled"`
+}
+
+type AlertChannelRequest struct {
+	Name    string             `json:"name" binding:"required"`
+	Type    string             `json:"type" binding:"required,oneof=webhook smtp telegram log"`
+	Config  AlertChannelConfig `json:"config"`
+	Enabled *bool              `json:"enabled"`
+}
+
+type AlertSilenceRequest struct {
+	RuleID   uint       `json:"rule_id"`
+	NodeID   uint       `json:"node_id"`
+	Hostname string     `json:"hostname"`
+	Reason   string     `json:"reason" binding:"required"`
+	StartsAt *time.Time `json:"starts_at"`
+	EndsAt   time.Time  `json:"ends_at" binding:"required"`
+}
+
+func (AlertRule) TableName() string {
+	return "alert_rules"
+}
+
+func (AlertChannel) TableName() string {
+	return "alert_channels"
+}
+
+func (AlertEvent) TableName() string {
+	return "alert_events"
+}
+
+func (AlertSilence) TableName() string {
+	return "alert_silences"
+}
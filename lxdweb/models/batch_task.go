@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+)
+
+// BatchTask 批量操作任务表
+type BatchTask struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	Action       string     `json:"action" gorm:"size:50;index"`
+	Selector     string     `json:"selector" gorm:"type:text"`
+	Status       string     `json:"status" gorm:"size:50;default:'pending';index"`
+	TotalCount   int        `json:"total_count"`
+	SuccessCount int        `json:"success_count"`
+	FailedCount  int        `json:"failed_count"`
+	Results      string     `json:"results" gorm:"type:text"`
+	ErrorMessage string     `json:"error_message" gorm:"type:text"`
+	StartTime    *time.Time `json:"start_time"`
+	EndTime      *time.Time `json:"end_time"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// BatchItemResult 批量操作单项结果
+type BatchItemResult struct {
+	NodeID   uint        `json:"node_id"`
+	NodeName string      `json:"node_name"`
+	Hostname string      `json:"hostname"`
+	Success  bool        `json:"success"`
+	Message  string      `json:"message"`
+	Output   *ExecOutput `json:"output,omitempty"` // 远程执行命令的输出
+}
+
+type BatchContainerRequest struct {
+	Action    string   `json:"action" binding:"required,oneof=start stop restart suspend unsuspend"`
+	NodeIDs   []uint   `json:"node_ids"`
+	Hostnames []string `json:"hostnames"`
+	Group     string   `json:"group"`
+	Region    string   `json:"region"`
+	Tags      []string `json:"tags"`
+}
+
+func (BatchTask) TableName() string {
+	return "batch_tasks"
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+)
+
+// UserDataTemplate 可复用的 cloud-init user-data 模板
+type UserDataTemplate struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Name        string    `json:"name" gorm:"size:100;uniqueIndex;not null"`
+	Description string    `json:"description" gorm:"size:500"`
+	Content     string    `json:"content" gorm:"type:text"`
+	CreatedBy   uint      `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// CloudInitOptions 创建和重装容器时的 cloud-init 参数，
+// SSHKeyIDs 指定的公钥库公钥与 SSHKeys 合并，UserData 为空时使用 UserDataTemplateID 指定的模板
+type CloudInitOptions struct {
+	SSHKeys             []string `json:"ssh_keys"`
+	SSHKeyIDs           []uint   `json:"ssh_key_ids"`
+	DisablePasswordAuth bool     `json:"disable_password_auth"`
+	UserData            string   `json:"user_data"`
+	UserDataTemplateID  uint     `json:"user_data_template_id"`
+	VendorData          string   `json:"vendor_data"`
+}
+
+type UserDataTemplateRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	Content     string `json:"content" binding:"required"`
+}
+
+func (UserDataTemplate) TableName() string {
+	return "user_data_templates"
+}
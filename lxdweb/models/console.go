@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+)
+
+// 控制台会话状态
+const (
+	ConsoleSessionPending = "pending" // 已签发令牌，等待建立连接
+	ConsoleSessionActive  = "active"
+	ConsoleSessionClosed  = "closed"
+	ConsoleSessionExpired = "expired" // 令牌过期未使用
+)
+
+// ConsoleSession 控制台会话表，浏览器只使用 Token 连接 lxdweb，
+// 节点令牌 NodeToken 只在 lxdweb 与节点之间使用
+type ConsoleSession struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	Token       string     `json:"-" gorm:"size:64;uniqueIndex"`
+	NodeToken   string     `json:"-" gorm:"size:200"`
+	AdminID     uint       `json:"admin_id" gorm:"index"`
+	NodeID      uint       `json:"node_id" gorm:"index"`
+	NodeName    string     `json:"node_name" gorm:"size:200"`
+	Hostname    string     `json:"hostname" gorm:"size:200"`
+	ClientIP    string     `json:"client_ip" gorm:"size:64"`
+	Status      string     `json:"status" gorm:"size:20;index"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	StartedAt   *time.Time `json:"started_at"`
+	EndedAt     *time.Time `json:"ended_at"`
+	BytesIn     int64      `json:"bytes_in"`
+	BytesOut    int64      `json:"bytes_out"`
+	CloseReason string     `json:"close_reason" gorm:"size:200"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+func (ConsoleSession) TableName() string {
+	return "console_sessions"
+}
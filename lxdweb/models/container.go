@@ -20,13 +20,17 @@ type Container struct {
 	DeletedAt  gorm.DeletedAt `json:"-" gorm:"index"`
 	Node Node `json:"node" gorm:"foreignKey:NodeID"`
 }
+// NATProtocolBoth 同时转发TCP和UDP的规则协议，节点API中对应 dtype=both
+const NATProtocolBoth = "tcp+udp"
 type NATRule struct {
 	ID                uint           `json:"id" gorm:"primaryKey"`
 	NodeID            uint           `json:"node_id" gorm:"not null;index:idx_nat_unique"`
 	ContainerHostname string         `json:"container_hostname" gorm:"size:200;not null"`
 	ExternalPort      int            `json:"external_port" gorm:"not null;index:idx_nat_unique"`
 	InternalPort      int            `json:"internal_port" gorm:"not null"`
-	Protocol          string         `json:"protocol" gorm:"size:10;default:'tcp';index:idx_nat_unique"` 
+	ExternalPortEnd   int            `json:"external_port_end"` // 端口段结束端口，0为单端口
+	InternalPortEnd   int            `json:"internal_port_end"`
+	Protocol          string         `json:"protocol" gorm:"size:10;default:'tcp';index:idx_nat_unique"` // tcp/udp/tcp+udp
 	Status            string         `json:"status" gorm:"size:50;default:'active'"`
 	Description       string         `json:"description" gorm:"type:text"`
 	CreatedAt         time.Time      `json:"created_at"`
@@ -62,29 +66,60 @@ type Image struct {
 type CreateNATRequest struct {
 	NodeID            uint   `json:"node_id" binding:"required"`
 	ContainerHostname string `json:"container_hostname" binding:"required"`
-	ExternalPort      int    `json:"external_port" binding:"required"`
+	ExternalPort      int    `json:"external_port" binding:"omitempty,min=1,max=65535"` // 留空时从端口池自动分配
 	InternalPort      int    `json:"internal_port" binding:"required"`
-	Protocol          string `json:"protocol" binding:"required,oneof=tcp udp"`
+	ExternalPortEnd   int    `json:"external_port_end" binding:"omitempty,min=1,max=65535"` // 端口段，留空时按内部端口数量推算
+	InternalPortEnd   int    `json:"internal_port_end" binding:"omitempty,min=1,max=65535"`
+	Protocol          string `json:"protocol" binding:"required,oneof=tcp udp tcp+udp"`
 	Description       string `json:"description"`
 }
 type UpdateNATRequest struct {
 	ExternalPort int    `json:"external_port"`
 	InternalPort int    `json:"internal_port"`
-	Protocol     string `json:"protocol" binding:"omitempty,oneof=tcp udp"`
+	Protocol     string `json:"protocol" binding:"omitempty,oneof=tcp udp tcp+udp"`
 	Description  string `json:"description"`
 	Status       string `json:"status" binding:"omitempty,oneof=active inactive"`
 }
+// NATImportRow NAT规则导入导出的单行数据，CSV列名与JSON字段一致
+type NATImportRow struct {
+	ContainerHostname string `json:"container_hostname"`
+	Protocol          string `json:"protocol"`
+	ExternalPort      int    `json:"external_port"`
+	ExternalPortEnd   int    `json:"external_port_end"`
+	InternalPort      int    `json:"internal_port"`
+	InternalPortEnd   int    `json:"internal_port_end"`
+	Description       string `json:"description"`
+}
+// NATImportRequest 批量导入NAT规则请求，csv 与 rules 二选一
+type NATImportRequest struct {
+	NodeID uint           `json:"node_id" binding:"required"`
+	DryRun bool           `json:"dry_run"`
+	CSV    string         `json:"csv"`
+	Rules  []NATImportRow `json:"rules"`
+}
 
 type CreateIPv6Request struct {
 	NodeID            uint   `json:"node_id" binding:"required"`
 	ContainerHostname string `json:"container_hostname" binding:"required"`
 	Description       string `json:"description"`
+	Address           string `json:"address"`
 }
 
 type DeleteIPv6Request struct {
 	PublicIPv6 string `json:"public_ipv6" binding:"required"`
 }
 
+type CreateIPv4Request struct {
+	NodeID            uint   `json:"node_id" binding:"required"`
+	ContainerHostname string `json:"container_hostname" binding:"required"`
+	Description       string `json:"description"`
+	Address           string `json:"address"`
+}
+
+type DeleteIPv4Request struct {
+	PublicIPv4 string `json:"public_ipv4" binding:"required"`
+}
+
 type CreateProxyRequest struct {
 	NodeID            uint   `json:"node_id" binding:"required"`
 	ContainerHostname string `json:"container_hostname" binding:"required"`
@@ -95,6 +130,7 @@ type CreateProxyRequest struct {
 	SSLType           string `json:"ssl_type"`
 	SSLCert           string `json:"ssl_cert"`
 	SSLKey            string `json:"ssl_key"`
+	ProxyOptions
 }
 
 type DeleteProxyRequest struct {
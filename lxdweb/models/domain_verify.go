@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+)
+
+// 域名验证方式
+const (
+	DomainVerifyMethodTXT  = "txt"
+	DomainVerifyMethodHTTP = "http"
+)
+
+// 域名验证状态
+const (
+	DomainVerifyStatusPending  = "pending"
+	DomainVerifyStatusVerified = "verified"
+	DomainVerifyStatusFailed   = "failed"
+	DomainVerifyStatusExpired  = "expired"
+)
+
+// DomainVerification 域名所有权验证表，TXT 方式验证通过的域名同时覆盖其子域名
+type DomainVerification struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	Domain      string     `json:"domain" gorm:"size:500;not null;uniqueIndex"`
+	Method      string     `json:"method" gorm:"size:20"`
+	Token       string     `json:"token" gorm:"size:100"`
+	Status      string     `json:"status" gorm:"size:20;index"`
+	TokenExpiry time.Time  `json:"token_expiry"`
+	VerifiedAt  *time.Time `json:"verified_at"`
+	ExpiresAt   *time.Time `json:"expires_at"` // 验证结果过期时间，为空表示长期有效
+	LastCheckAt *time.Time `json:"last_check_at"`
+	LastError   string     `json:"last_error" gorm:"type:text"`
+	AdminID     uint       `json:"admin_id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// CreateDomainVerificationRequest 申请域名验证请求
+type CreateDomainVerificationRequest struct {
+	Domain string `json:"domain" binding:"required"`
+	Method string `json:"method" binding:"required,oneof=txt http"`
+}
+
+func (DomainVerification) TableName() string {
+	return "domain_verifications"
+}
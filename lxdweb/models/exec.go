@@ -0,0 +1,23 @@
+package models
+
+// ExecOutput 容器内命令执行结果，保存在批量任务的单项结果中
+type ExecOutput struct {
+	ExitCode   int    `json:"exit_code"`
+	Stdout     string `json:"stdout"`
+	Stderr     string `json:"stderr"`
+	Truncated  bool   `json:"truncated"` // 输出超过保存上限被截断
+	TimedOut   bool   `json:"timed_out"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+type ExecRequest struct {
+	Command   string            `json:"command" binding:"required"`
+	Env       map[string]string `json:"env"`
+	Cwd       string            `json:"cwd"`
+	Timeout   int               `json:"timeout"`
+	NodeIDs   []uint            `json:"node_ids"`
+	Hostnames []string          `json:"hostnames"`
+	Group     string            `json:"group"`
+	Region    string            `json:"region"`
+	Tags      []string          `json:"tags"`
+}
@@ -0,0 +1,34 @@
+package models
+
+// ContainerFile 容器内文件或目录，由节点 /api/files/list 返回
+type ContainerFile struct {
+	Name    string `json:"name"`
+	Path    string `json:"path"`
+	Type    string `json:"type"` // file / dir / symlink
+	Size    int64  `json:"size"`
+	Mode    string `json:"mode"`
+	ModTime string `json:"mod_time"`
+	Target  string `json:"target,omitempty"` // 符号链接指向
+}
+
+// FileAccessPolicy 随每个文件请求发送给节点的访问策略，节点在解析符号链接后的真实路径上校验，
+// 约定见 docs/node_api.md
+type FileAccessPolicy struct {
+	DeniedPaths    []string `json:"denied_paths"`
+	ReadOnlyPaths  []string `json:"read_only_paths"`
+	FollowSymlinks bool     `json:"follow_symlinks"`
+}
+
+type FileContentRequest struct {
+	Path    string `json:"path" binding:"required"`
+	Content string `json:"content"`
+}
+
+type FileDeleteRequest struct {
+	Path      string `json:"path" binding:"required"`
+	Recursive bool   `json:"recursive"`
+}
+
+type FileMkdirRequest struct {
+	Path string `json:"path" binding:"required"`
+}
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"time"
+)
+
+// 地址族
+const (
+	IPFamilyV4 = "ipv4"
+	IPFamilyV6 = "ipv6"
+)
+
+// 地址状态
+const (
+	IPStatusAllocated   = "allocated"   // 已分配给容器
+	IPStatusReserved    = "reserved"    // 预留，不参与自动分配
+	IPStatusBlacklisted = "blacklisted" // 黑名单，禁止使用
+)
+
+// 地址来源
+const (
+	IPSourceManual  = "manual"  // 管理员手动录入
+	IPSourceBinding = "binding" // 创建绑定时分配
+	IPSourceSync    = "sync"    // 同步时从节点发现
+)
+
+// IPPool 公网地址池表，IPv4为地址段，IPv6为前缀
+type IPPool struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	NodeID      uint      `json:"node_id" gorm:"not null;index"`
+	Name        string    `json:"name" gorm:"size:200;not null"`
+	Family      string    `json:"family" gorm:"size:10;not null;index"`
+	CIDR        string    `json:"cidr" gorm:"size:64;not null"`
+	RangeStart  string    `json:"range_start" gorm:"size:64"`
+	RangeEnd    string    `json:"range_end" gorm:"size:64"`
+	Gateway     string    `json:"gateway" gorm:"size:64"`
+	Description string    `json:"description" gorm:"type:text"`
+	Enabled     bool      `json:"enabled" gorm:"default:true"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// IPAddress 地址池中已占用（分配、预留、黑名单）的地址表，未出现在表中的地址视为空闲
+type IPAddress struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	PoolID      uint       `json:"pool_id" gorm:"index"`
+	NodeID      uint       `json:"node_id" gorm:"not null;uniqueIndex:idx_unique_ip_address"`
+	Family      string     `json:"family" gorm:"size:10"`
+	Address     string     `json:"address" gorm:"size:64;not null;uniqueIndex:idx_unique_ip_address"`
+	Status      string     `json:"status" gorm:"size:20;not null;index"`
+	Hostname    string     `json:"hostname" gorm:"size:200;index"`
+	Source      string     `json:"source" gorm:"size:20"`
+	Note        string     `json:"note" gorm:"size:500"`
+	AllocatedAt *time.Time `json:"allocated_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// IPPoolRequest 创建/更新地址池请求
+type IPPoolRequest struct {
+	NodeID      uint   `json:"node_id"`
+	Name        string `json:"name" binding:"required"`
+	Family      string `json:"family" binding:"required,oneof=ipv4 ipv6"`
+	CIDR        string `json:"cidr" binding:"required"`
+	RangeStart  string `json:"range_start"`
+	RangeEnd    string `json:"range_end"`
+	Gateway     string `json:"gateway"`
+	Description string `json:"description"`
+	Enabled     *bool  `json:"enabled"`
+}
+
+// IPAddressRequest 预留/拉黑地址请求
+type IPAddressRequest struct {
+	Addresses []string `json:"addresses" binding:"required,min=1"`
+	Status    string   `json:"status" binding:"required,oneof=reserved blacklisted"`
+	Hostname  string   `json:"hostname"`
+	Note      string   `json:"note"`
+}
+
+func (IPPool) TableName() string {
+	return "ip_pools"
+}
+
+func (IPAddress) TableName() string {
+	return "ip_addresses"
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// IPv4BindingCache 独立IPv4绑定缓存表
+type IPv4BindingCache struct {
+	ID          uint           `json:"id" gorm:"primaryKey"`
+	NodeID      uint           `json:"node_id" gorm:"not null;uniqueIndex:idx_unique_ipv4_cache"`
+	NodeName    string         `json:"node_name" gorm:"size:200"`
+	Hostname    string         `json:"hostname" gorm:"size:200;not null;uniqueIndex:idx_unique_ipv4_cache"`
+	IPv4Address string         `json:"ipv4_address" gorm:"size:50;uniqueIndex:idx_unique_ipv4_cache"`
+	Interface   string         `json:"interface" gorm:"size:50"`
+	Status      string         `json:"status" gorm:"size:50"`
+	LastSync    time.Time      `json:"last_sync"`
+	SyncError   string         `json:"sync_error" gorm:"type:text"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// IPv4SyncTask IPv4同步任务表
+type IPv4SyncTask struct {
+	ID           uint           `json:"id" gorm:"primaryKey"`
+	NodeID       uint           `json:"node_id" gorm:"index"`
+	NodeName     string         `json:"node_name" gorm:"size:200"`
+	Status       string         `json:"status" gorm:"size:50"`
+	TotalCount   int            `json:"total_count"`
+	SuccessCount int            `json:"success_count"`
+	FailedCount  int            `json:"failed_count"`
+	ErrorMessage string         `json:"error_message" gorm:"type:text"`
+	StartTime    *time.Time     `json:"start_time"`
+	EndTime      *time.Time     `json:"end_time"`
+	CreatedAt    time.Time      `json:"created_at"`
+	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+func (IPv4BindingCache) TableName() string {
+	return "ipv4_binding_caches"
+}
+
+func (IPv4SyncTask) TableName() string {
+	return "ipv4_sync_tasks"
+}
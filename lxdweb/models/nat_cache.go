@@ -12,6 +12,8 @@ type NATRuleCache struct {
 	ContainerHostname string         `json:"container_hostname" gorm:"size:200;not null;uniqueIndex:idx_unique_nat_cache"`
 	ExternalPort      int            `json:"external_port" gorm:"not null;uniqueIndex:idx_unique_nat_cache"`
 	InternalPort      int            `json:"internal_port" gorm:"not null"`
+	ExternalPortEnd   int            `json:"external_port_end"`
+	InternalPortEnd   int            `json:"internal_port_end"`
 	Protocol          string         `json:"protocol" gorm:"size:10;default:'tcp';uniqueIndex:idx_unique_nat_cache"`
 	Description       string         `json:"description" gorm:"type:text"`
 	Status            string         `json:"status" gorm:"size:50;default:'active'"`
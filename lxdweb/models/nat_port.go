@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+)
+
+// NATPortPool 节点外部端口池表，创建NAT规则未指定外部端口时从池中分配
+type NATPortPool struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	NodeID         uint      `json:"node_id" gorm:"not null;index"`
+	Name           string    `json:"name" gorm:"size:200;not null"`
+	PortStart      int       `json:"port_start" gorm:"not null"`
+	PortEnd        int       `json:"port_end" gorm:"not null"`
+	ContainerQuota int       `json:"container_quota"` // 单个容器最多占用本池端口数，0为不限
+	Description    string    `json:"description" gorm:"type:text"`
+	Enabled        bool      `json:"enabled" gorm:"default:true"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// NATPortQuota 容器NAT端口配额表，限制容器在节点上占用的外部端口总数（端口段按端口个数计）
+type NATPortQuota struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	NodeID    uint      `json:"node_id" gorm:"not null;uniqueIndex:idx_unique_nat_quota"`
+	Hostname  string    `json:"hostname" gorm:"size:200;not null;uniqueIndex:idx_unique_nat_quota"`
+	MaxPorts  int       `json:"max_ports"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NATPortPoolRequest 创建/更新端口池请求
+type NATPortPoolRequest struct {
+	NodeID         uint   `json:"node_id"`
+	Name           string `json:"name" binding:"required"`
+	PortStart      int    `json:"port_start" binding:"required,min=1,max=65535"`
+	PortEnd        int    `json:"port_end" binding:"required,min=1,max=65535"`
+	ContainerQuota int    `json:"container_quota" binding:"min=0"`
+	Description    string `json:"description"`
+	Enabled        *bool  `json:"enabled"`
+}
+
+// NATPortQuotaRequest 设置容器端口配额请求
+type NATPortQuotaRequest struct {
+	NodeID   uint   `json:"node_id" binding:"required"`
+	Hostname string `json:"hostname" binding:"required"`
+	MaxPorts int    `json:"max_ports" binding:"min=0"`
+}
+
+func (NATPortPool) TableName() string {
+	return "nat_port_pools"
+}
+
+func (NATPortQuota) TableName() string {
+	return "nat_port_quotas"
+}
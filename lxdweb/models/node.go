@@ -1,5 +1,6 @@
 package models
 import (
+	"strings"
 	"time"
 	"gorm.io/gorm"
 )
@@ -10,6 +11,13 @@ type Node struct {
 	Address        string         `json:"address" gorm:"size:500;not null"` 
 	APIKey         string         `json:"api_key" gorm:"size:500"`          
 	Status         string         `json:"status" gorm:"size:50;default:'inactive'"` 
+	Group          string         `json:"group" gorm:"column:node_group;size:100;index"`
+	Region         string         `json:"region" gorm:"size:100;index"`
+	Tags           string         `json:"tags" gorm:"size:500"`
+	Maintenance       bool       `json:"maintenance" gorm:"default:false;index"`
+	MaintenanceReason string     `json:"maintenance_reason" gorm:"type:text"`
+	MaintenanceStart  *time.Time `json:"maintenance_start"`
+	MaintenanceUntil  *time.Time `json:"maintenance_until"`
 	LastCheck      *time.Time     `json:"last_check"`
 	SyncPreset     string         `json:"sync_preset" gorm:"size:50;default:'medium'"`
 	BatchSize      int            `json:"batch_size" gorm:"default:5"`
@@ -19,20 +27,94 @@ type Node struct {
 	DeletedAt      gorm.DeletedAt `json:"-" gorm:"index"`
 }
 type CreateNodeRequest struct {
-	Name          string `json:"name" binding:"required"`
-	Description   string `json:"description"`
-	Address       string `json:"address" binding:"required"`
-	APIKey        string `json:"api_key"`
-	SyncPreset    string `json:"sync_preset"`
-	BatchSize     int    `json:"batch_size"`
-	BatchInterval int    `json:"batch_interval"`
+	Name          string   `json:"name" binding:"required"`
+	Description   string   `json:"description"`
+	Address       string   `json:"address" binding:"required"`
+	APIKey        string   `json:"api_key"`
+	Group         string   `json:"group"`
+	Region        string   `json:"region"`
+	Tags          []string `json:"tags"`
+	SyncPreset    string   `json:"sync_preset"`
+	BatchSize     int      `json:"batch_size"`
+	BatchInterval int      `json:"batch_interval"`
 }
 type UpdateNodeRequest struct {
-	Name          string `json:"name"`
-	Description   string `json:"description"`
-	Address       string `json:"address"`
-	APIKey        string `json:"api_key"`
-	SyncPreset    string `json:"sync_preset"`
-	BatchSize     int    `json:"batch_size"`
-	BatchInterval int    `json:"batch_interval"`
+	Name          string   `json:"name"`
+	Description   string   `json:"description"`
+	Address       string   `json:"address"`
+	APIKey        string   `json:"api_key"`
+	Group         *string  `json:"group"`
+	Region        *string  `json:"region"`
+	Tags          []string `json:"tags"`
+	SyncPreset    string   `json:"sync_preset"`
+	BatchSize     int      `json:"batch_size"`
+	BatchInterval int      `json:"batch_interval"`
+}
+
+// MaintenanceRequest 节点进入维护模式参数
+// Drain: none 不处理容器, stop 停止节点上所有容器, migrate 迁移到其他节点
+type MaintenanceRequest struct {
+	Reason       string     `json:"reason" binding:"required"`
+	Until        *time.Time `json:"until"`
+	Drain        string     `json:"drain" binding:"omitempty,oneof=none stop migrate"`
+	TargetNodeID uint       `json:"target_node_id"`
+}
+
+// GroupMaintenanceRequest 按分组批量切换维护模式参数
+type GroupMaintenanceRequest struct {
+	Enabled      bool       `json:"enabled"`
+	Group        string     `json:"group"`
+	Region       string     `json:"region"`
+	Tags         []string   `json:"tags"`
+	Reason       string     `json:"reason"`
+	Until        *time.Time `json:"until"`
+	Drain        string     `json:"drain" binding:"omitempty,oneof=none stop migrate"`
+	TargetNodeID uint       `json:"target_node_id"`
+}
+
+// TagList 返回节点标签列表
+func (n Node) TagList() []string {
+	return SplitTags(n.Tags)
+}
+
+// HasTags 判断节点是否包含全部指定标签
+func (n Node) HasTags(tags []string) bool {
+	if len(tags) == 0 {
+		return true
+	}
+	owned := make(map[string]bool)
+	for _, t := range n.TagList() {
+		owned[t] = true
+	}
+	for _, t := range tags {
+		if !owned[strings.ToLower(strings.TrimSpace(t))] {
+			return false
+		}
+	}
+	return true
+}
+
+// SplitTags 将逗号分隔的标签字符串拆分为去重后的小写列表
+func SplitTags(s string) []string {
+	return NormalizeTags(strings.Split(s, ","))
+}
+
+// NormalizeTags 去除空白、统一小写并去重
+func NormalizeTags(tags []string) []string {
+	seen := make(map[string]bool)
+	result := make([]string, 0, len(tags))
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" || seen[t] {
+			continue
+		}
+		seen[t] = true
+		result = append(result, t)
+	}
+	return result
+}
+
+// JoinTags 将标签列表存储为逗号分隔字符串
+func JoinTags(tags []string) string {
+	return strings.Join(NormalizeTags(tags), ",")
 }
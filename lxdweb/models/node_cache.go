@@ -12,6 +12,8 @@ type NodeInfoCache struct {
 	SystemInfo     string         `json:"system_info" gorm:"type:text"`
 
 	LastSync       time.Time      `json:"last_sync"`
+	LastError      string         `json:"last_error" gorm:"type:text"`
+	LastErrorAt    *time.Time     `json:"last_error_at"`
 	
 	CreatedAt      time.Time      `json:"created_at"`
 	UpdatedAt      time.Time      `json:"updated_at"`
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+)
+
+// 节点健康状态
+const (
+	NodeStatusActive   = "active"
+	NodeStatusDegraded = "degraded"
+	NodeStatusDown     = "down"
+	NodeStatusInactive = "inactive"
+)
+
+// OnlineNodeStatuses 可正常同步的节点状态
+var OnlineNodeStatuses = []string{NodeStatusActive, NodeStatusDegraded}
+
+// NodeStatusHistory 节点健康检查历史表
+type NodeStatusHistory struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	NodeID     uint      `json:"node_id" gorm:"not null;index:idx_node_status_history"`
+	Status     string    `json:"status" gorm:"size:50"`
+	PrevStatus string    `json:"prev_status" gorm:"size:50"`
+	Success    bool      `json:"success"`
+	LatencyMs  int64     `json:"latency_ms"`
+	Error      string    `json:"error" gorm:"type:text"`
+	Manual     bool      `json:"manual"`
+	CheckedAt  time.Time `json:"checked_at" gorm:"index:idx_node_status_history"`
+}
+
+func (NodeStatusHistory) TableName() string {
+	return "node_status_history"
+}
@@ -16,6 +16,8 @@ type ProxyConfigCache struct {
 	BackendPort int            `json:"backend_port"`
 	SSLEnabled  bool           `json:"ssl_enabled"`
 	SSLType     string         `json:"ssl_type" gorm:"size:50;default:'none'"`
+	Options     string         `json:"options" gorm:"type:text"` // 高级选项，ProxyOptions 的 JSON
+	AuthHash    string         `json:"-" gorm:"size:200"`        // Basic Auth 密码的 htpasswd 哈希
 	Status      string         `json:"status" gorm:"size:50"`
 	LastSync    time.Time      `json:"last_sync"`
 	SyncError   string         `json:"sync_error" gorm:"type:text"`
@@ -48,3 +50,40 @@ func (ProxySyncTask) TableName() string {
 	return "proxy_sync_tasks"
 }
 
+// ProxyOptions 反向代理高级选项，随 /api/proxy/add 下发到节点并渲染到 nginx 模板
+type ProxyOptions struct {
+	ForceHTTPS        *bool           `json:"force_https,omitempty"` // 启用SSL时HTTP跳转HTTPS，默认开启
+	WebSocket         bool            `json:"websocket"`
+	ClientMaxBodySize string          `json:"client_max_body_size,omitempty"` // 如 100M，默认 100M
+	Headers           []ProxyHeader   `json:"headers,omitempty"`
+	BasicAuth         *ProxyBasicAuth `json:"basic_auth,omitempty"`
+	Locations         []ProxyLocation `json:"locations,omitempty"`
+	Redirects         []ProxyRedirect `json:"redirects,omitempty"`
+}
+
+// ProxyHeader 转发到后端的自定义请求头
+type ProxyHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// ProxyBasicAuth Basic Auth 访问保护，密码只在创建时提交，不会保存明文
+type ProxyBasicAuth struct {
+	Username string `json:"username"`
+	Password string `json:"password,omitempty"`
+}
+
+// ProxyLocation 按路径转发的附加后端，Hostname 为空时使用代理所属容器
+type ProxyLocation struct {
+	Path        string `json:"path"`
+	Hostname    string `json:"hostname,omitempty"`
+	Port        int    `json:"port"`
+	StripPrefix bool   `json:"strip_prefix"`
+}
+
+// ProxyRedirect 路径跳转规则
+type ProxyRedirect struct {
+	Path   string `json:"path"`
+	Target string `json:"target"`
+	Code   int    `json:"code"`
+}
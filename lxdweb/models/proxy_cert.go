@@ -0,0 +1,97 @@
+package models
+
+import (
+	"time"
+)
+
+// 证书来源
+const (
+	CertSourceCustom     = "custom"
+	CertSourceACME       = "acme"
+	CertSourceSelfSigned = "self-signed"
+)
+
+// 证书状态
+const (
+	CertStatusPending  = "pending"
+	CertStatusIssuing  = "issuing"
+	CertStatusValid    = "valid"
+	CertStatusExpiring = "expiring"
+	CertStatusExpired  = "expired"
+	CertStatusFailed   = "failed"
+	CertStatusUnknown  = "unknown"
+)
+
+// ProxyCertificate 反向代理证书表，每个节点上的域名对应一条记录
+type ProxyCertificate struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	NodeID         uint       `json:"node_id" gorm:"not null;uniqueIndex:idx_unique_proxy_cert"`
+	ProxyConfigID  uint       `json:"proxy_config_id" gorm:"index"`
+	Hostname       string     `json:"hostname" gorm:"size:200"`
+	Domain         string     `json:"domain" gorm:"size:500;not null;uniqueIndex:idx_unique_proxy_cert"`
+	Source         string     `json:"source" gorm:"size:20"`
+	Status         string     `json:"status" gorm:"size:20;index"`
+	AutoRenew      bool       `json:"auto_renew" gorm:"default:true"`
+	Issuer         string     `json:"issuer" gorm:"size:500"`
+	Subject        string     `json:"subject" gorm:"size:500"`
+	SerialNumber   string     `json:"serial_number" gorm:"size:100"`
+	DNSNames       string     `json:"dns_names" gorm:"type:text"`
+	Fingerprint    string     `json:"fingerprint" gorm:"size:100"` // SHA-256
+	NotBefore      *time.Time `json:"not_before"`
+	NotAfter       *time.Time `json:"not_after" gorm:"index"`
+	DomainMismatch bool       `json:"domain_mismatch"` // 证书SAN不包含代理域名
+	CertPEM        string     `json:"-" gorm:"type:text"`
+	KeyPEM         string     `json:"-" gorm:"type:text"`
+	LastError      string     `json:"last_error" gorm:"type:text"`
+	LastAttemptAt  *time.Time `json:"last_attempt_at"`
+	IssuedAt       *time.Time `json:"issued_at"`
+	CheckedAt      *time.Time `json:"checked_at"`                   // 最近一次检查节点实际提供证书的时间
+	CheckError     string     `json:"check_error" gorm:"type:text"` // 检查失败原因，失败时使用已保存的证书
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// ACMEAccount ACME账户表，按目录地址区分，便于在正式CA与测试CA之间切换
+type ACMEAccount struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	DirectoryURL string    `json:"directory_url" gorm:"size:500;uniqueIndex"`
+	Email        string    `json:"email" gorm:"size:200"`
+	URI          string    `json:"uri" gorm:"size:500"`
+	KeyPEM       string    `json:"-" gorm:"type:text"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// ProxyConfigCacheView 反向代理缓存及其证书检查结果
+type ProxyConfigCacheView struct {
+	ProxyConfigCache
+	CertStatus         string     `json:"cert_status"`
+	CertIssuer         string     `json:"cert_issuer"`
+	CertDNSNames       string     `json:"cert_dns_names"`
+	CertNotAfter       *time.Time `json:"cert_not_after"`
+	CertDaysLeft       *int       `json:"cert_days_left"`
+	CertExpired        bool       `json:"cert_expired"`
+	CertExpiring       bool       `json:"cert_expiring"`
+	CertDomainMismatch bool       `json:"cert_domain_mismatch"`
+	CertCheckedAt      *time.Time `json:"cert_checked_at"`
+	CertCheckError     string     `json:"cert_check_error"`
+}
+
+// UploadCertificateRequest 上传自定义证书请求
+type UploadCertificateRequest struct {
+	SSLCert string `json:"ssl_cert" binding:"required"`
+	SSLKey  string `json:"ssl_key" binding:"required"`
+}
+
+// UpdateCertificateRequest 更新证书设置请求
+type UpdateCertificateRequest struct {
+	AutoRenew *bool `json:"auto_renew"`
+}
+
+func (ProxyCertificate) TableName() string {
+	return "proxy_certificates"
+}
+
+func (ACMEAccount) TableName() string {
+	return "acme_accounts"
+}
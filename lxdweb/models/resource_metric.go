@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+)
+
+// 资源指标对象类型
+const (
+	MetricScopeContainer = "container"
+	MetricScopeNode      = "node"
+)
+
+// 资源指标精度，原始采样逐级汇总为 5 分钟、小时和天
+const (
+	MetricResolutionRaw    = "raw"
+	MetricResolution5Min   = "5m"
+	MetricResolutionHourly = "1h"
+	MetricResolutionDaily  = "1d"
+)
+
+// ResourceMetric 容器和节点资源使用时间序列表。
+// 节点指标为该节点所有容器缓存的汇总；汇总精度下使用率为区间平均值，CPUMax 为区间最大值
+type ResourceMetric struct {
+	ID           uint      `json:"-" gorm:"primaryKey"`
+	Scope        string    `json:"-" gorm:"size:20;not null;uniqueIndex:idx_unique_resource_metric"`
+	NodeID       uint      `json:"-" gorm:"not null;uniqueIndex:idx_unique_resource_metric"`
+	Hostname     string    `json:"-" gorm:"size:200;uniqueIndex:idx_unique_resource_metric"`
+	Resolution   string    `json:"-" gorm:"size:10;not null;uniqueIndex:idx_unique_resource_metric;index:idx_resource_metric_time"`
+	Timestamp    time.Time `json:"timestamp" gorm:"not null;uniqueIndex:idx_unique_resource_metric;index:idx_resource_metric_time"`
+	CPUUsage     float64   `json:"cpu_usage"`
+	CPUMax       float64   `json:"cpu_max"`
+	MemoryUsage  uint64    `json:"memory_usage"`
+	MemoryTotal  uint64    `json:"memory_total"`
+	DiskUsage    uint64    `json:"disk_usage"`
+	DiskTotal    uint64    `json:"disk_total"`
+	TrafficTotal uint64    `json:"traffic_total"`
+	TrafficLimit int       `json:"traffic_limit"`
+	Containers   int       `json:"containers,omitempty"` // 节点指标：容器总数
+	Running      int       `json:"running,omitempty"`    // 节点指标：运行中的容器数
+	Samples      int       `json:"samples"`              // 参与汇总的原始采样数
+}
+
+func (ResourceMetric) TableName() string {
+	return "resource_metrics"
+}
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"time"
+)
+
+// 计划任务动作
+const (
+	ScheduleActionStart        = "start"
+	ScheduleActionStop         = "stop"
+	ScheduleActionRestart      = "restart"
+	ScheduleActionSuspend      = "suspend"
+	ScheduleActionTrafficReset = "traffic_reset"
+	ScheduleActionSnapshot     = "snapshot"
+)
+
+// 计划任务目标类型
+const (
+	ScheduleTargetContainer = "container" // 单个容器
+	ScheduleTargetGroup     = "group"     // 节点分组内的全部容器
+)
+
+// 错过执行时间的处理策略
+const (
+	ScheduleMissedSkip    = "skip"     // 跳过，等待下次执行
+	ScheduleMissedRunOnce = "run_once" // 在补执行窗口内补执行一次
+)
+
+// 执行触发方式
+const (
+	ScheduleTriggerSchedule = "schedule"
+	ScheduleTriggerCatchup  = "catchup"
+	ScheduleTriggerManual   = "manual"
+)
+
+// ScheduledTask 容器计划任务表
+type ScheduledTask struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	Name         string     `json:"name" gorm:"size:200;not null"`
+	Action       string     `json:"action" gorm:"size:50;not null"`
+	CronExpr     string     `json:"cron_expr" gorm:"size:100;not null"`
+	Timezone     string     `json:"timezone" gorm:"size:64"`
+	TargetType   string     `json:"target_type" gorm:"size:20;not null"`
+	NodeID       uint       `json:"node_id" gorm:"index"`
+	Hostname     string     `json:"hostname" gorm:"size:200"`
+	NodeGroup    string     `json:"node_group" gorm:"size:100"`
+	MissedPolicy string     `json:"missed_policy" gorm:"size:20"`
+	Enabled      bool       `json:"enabled"`
+	NextRunAt    *time.Time `json:"next_run_at" gorm:"index"`
+	LastRunAt    *time.Time `json:"last_run_at"`
+	LastStatus   string     `json:"last_status" gorm:"size:20"`
+	CreatedBy    uint       `json:"created_by"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// ScheduledTaskRun 计划任务执行记录，Results 为各容器 BatchItemResult 的 JSON
+type ScheduledTaskRun struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	TaskID       uint       `json:"task_id" gorm:"index"`
+	Trigger      string     `json:"trigger" gorm:"size:20"`
+	Status       string     `json:"status" gorm:"size:20;index"`
+	ScheduledAt  time.Time  `json:"scheduled_at"`
+	StartTime    *time.Time `json:"start_time"`
+	EndTime      *time.Time `json:"end_time"`
+	TotalCount   int        `json:"total_count"`
+	SuccessCount int        `json:"success_count"`
+	FailedCount  int        `json:"failed_count"`
+	Results      string     `json:"results" gorm:"type:text"`
+	ErrorMessage string     `json:"error_message" gorm:"type:text"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+type ScheduledTaskRequest struct {
+	Name         string `json:"name" binding:"required"`
+	Action       string `json:"action" binding:"required,oneof=start stop restart suspend traffic_reset snapshot"`
+	CronExpr     string `json:"cron_expr" binding:"required"`
+	Timezone     string `json:"timezone"`
+	TargetType   string `json:"target_type" binding:"required,oneof=container group"`
+	NodeID       uint   `json:"node_id"`
+	Hostname     string `json:"hostname"`
+	NodeGroup    string `json:"node_group"`
+	MissedPolicy string `json:"missed_policy" binding:"omitempty,oneof=skip run_once"`
+	Enabled      *bool  `json:"enabled"`
+}
+
+func (ScheduledTask) TableName() string {
+	return "scheduled_tasks"
+}
+
+func (ScheduledTaskRun) TableName() string {
+	return "scheduled_task_runs"
+}
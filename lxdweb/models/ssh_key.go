@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+)
+
+// 容器 authorized_keys 写入方式
+const (
+	SSHKeyModeAdd     = "add"     // 追加到现有 authorized_keys，已存在的公钥不重复写入
+	SSHKeyModeReplace = "replace" // 用提交的公钥替换整个 authorized_keys
+)
+
+// SSHKey SSH 公钥库，Fingerprint 为 OpenSSH SHA256 指纹
+type SSHKey struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Name        string    `json:"name" gorm:"size:100;uniqueIndex;not null"`
+	PublicKey   string    `json:"public_key" gorm:"type:text;not null"`
+	KeyType     string    `json:"key_type" gorm:"size:50"`
+	Fingerprint string    `json:"fingerprint" gorm:"size:100;uniqueIndex"`
+	Comment     string    `json:"comment" gorm:"size:200"`
+	CreatedBy   uint      `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type SSHKeyRequest struct {
+	Name      string `json:"name" binding:"required"`
+	PublicKey string `json:"public_key" binding:"required"`
+}
+
+// ContainerSSHKeysRequest 写入容器 root 用户 authorized_keys，KeyIDs 为公钥库中的公钥
+type ContainerSSHKeysRequest struct {
+	NodeID          uint     `json:"node_id" binding:"required"`
+	KeyIDs          []uint   `json:"key_ids"`
+	Keys            []string `json:"keys"`
+	Mode            string   `json:"mode" binding:"omitempty,oneof=add replace"`
+	DisablePassword bool     `json:"disable_password"`
+}
+
+type ContainerPasswordAuthRequest struct {
+	NodeID  uint `json:"node_id" binding:"required"`
+	Enabled bool `json:"enabled"`
+}
+
+func (SSHKey) TableName() string {
+	return "ssh_keys"
+}
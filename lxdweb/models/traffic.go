@@ -0,0 +1,78 @@
+package models
+
+import (
+	"time"
+)
+
+// 账期状态
+const (
+	TrafficPeriodOpen   = "open"
+	TrafficPeriodClosed = "closed"
+)
+
+// 账期结束原因
+const (
+	TrafficCloseRollover = "rollover" // 到达账期起始日自动结转
+	TrafficCloseReset    = "reset"    // 手动重置流量
+	TrafficCloseAnchor   = "anchor"   // 修改账期起始日
+)
+
+// TrafficSample 容器流量采样表，每次同步记录一次节点计数器和与上次采样的差值
+type TrafficSample struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	NodeID       uint      `json:"node_id" gorm:"not null;index:idx_traffic_sample"`
+	Hostname     string    `json:"hostname" gorm:"size:200;not null;index:idx_traffic_sample"`
+	SampledAt    time.Time `json:"sampled_at" gorm:"index:idx_traffic_sample"`
+	CounterTotal uint64    `json:"counter_total"`
+	CounterIn    uint64    `json:"counter_in"`
+	CounterOut   uint64    `json:"counter_out"`
+	DeltaTotal   uint64    `json:"delta_total"`
+	DeltaIn      uint64    `json:"delta_in"`
+	DeltaOut     uint64    `json:"delta_out"`
+}
+
+// TrafficPeriod 容器流量账期汇总表，每个容器同一时间只有一个 open 账期
+type TrafficPeriod struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	NodeID       uint       `json:"node_id" gorm:"not null;index:idx_traffic_period"`
+	Hostname     string     `json:"hostname" gorm:"size:200;not null;index:idx_traffic_period"`
+	PeriodStart  time.Time  `json:"period_start"`
+	PeriodEnd    time.Time  `json:"period_end"` // 计划结束时间，提前关闭时为实际关闭时间
+	TrafficTotal uint64     `json:"traffic_total"`
+	TrafficIn    uint64     `json:"traffic_in"`
+	TrafficOut   uint64     `json:"traffic_out"`
+	TrafficLimit int        `json:"traffic_limit"` // 账期内的流量限额(GB)，0为不限
+	Status       string     `json:"status" gorm:"size:20;index"`
+	CloseReason  string     `json:"close_reason" gorm:"size:20"`
+	ClosedAt     *time.Time `json:"closed_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// TrafficBillingCycle 容器账期设置表，未设置的容器使用配置中的默认起始日
+type TrafficBillingCycle struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	NodeID    uint      `json:"node_id" gorm:"not null;uniqueIndex:idx_unique_billing_cycle"`
+	Hostname  string    `json:"hostname" gorm:"size:200;not null;uniqueIndex:idx_unique_billing_cycle"`
+	AnchorDay int       `json:"anchor_day"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// UpdateBillingCycleRequest 设置账期起始日请求
+type UpdateBillingCycleRequest struct {
+	NodeID    uint `json:"node_id" binding:"required"`
+	AnchorDay int  `json:"anchor_day" binding:"required,min=1,max=31"`
+}
+
+func (TrafficSample) TableName() string {
+	return "traffic_samples"
+}
+
+func (TrafficPeriod) TableName() string {
+	return "traffic_periods"
+}
+
+func (TrafficBillingCycle) TableName() string {
+	return "traffic_billing_cycles"
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"time"
+)
+
+// 超额处理动作
+const (
+	TrafficActionNotify   = "notify"   // 仅通知
+	TrafficActionThrottle = "throttle" // 限速
+	TrafficActionSuspend  = "suspend"  // 暂停容器
+)
+
+// 超额处理记录状态
+const (
+	TrafficEnforcementActive   = "active"
+	TrafficEnforcementFailed   = "failed"
+	TrafficEnforcementRestored = "restored"
+)
+
+// TrafficPolicy 流量超额策略表，可作为套餐策略绑定到多个容器；
+// IsDefault 的策略用于未单独绑定策略的容器，同时只有一个默认策略
+type TrafficPolicy struct {
+	ID               uint      `json:"id" gorm:"primaryKey"`
+	Name             string    `json:"name" gorm:"size:200;not null"`
+	Description      string    `json:"description" gorm:"type:text"`
+	Action           string    `json:"action" gorm:"size:20;not null"`
+	ThresholdPercent float64   `json:"threshold_percent"`
+	ThrottleIngress  string    `json:"throttle_ingress" gorm:"size:50"`
+	ThrottleEgress   string    `json:"throttle_egress" gorm:"size:50"`
+	ChannelIDs       string    `json:"channel_ids" gorm:"size:500"`
+	IsDefault        bool      `json:"is_default"`
+	Enabled          bool      `json:"enabled"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// ChannelIDList 解析策略关联的通知渠道ID
+func (p TrafficPolicy) ChannelIDList() []uint {
+	return AlertRule{ChannelIDs: p.ChannelIDs}.ChannelIDList()
+}
+
+// TrafficPolicyBinding 容器与流量策略的绑定关系
+type TrafficPolicyBinding struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	NodeID    uint      `json:"node_id" gorm:"not null;uniqueIndex:idx_unique_traffic_policy_binding"`
+	Hostname  string    `json:"hostname" gorm:"size:200;not null;uniqueIndex:idx_unique_traffic_policy_binding"`
+	PolicyID  uint      `json:"policy_id" gorm:"not null;index"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TrafficEnforcement 流量超额处理记录，每个账期每个容器最多一条，
+// 账期结束或手动解除时恢复限速或暂停前的状态；执行或恢复失败时按 NextRetryAt 退避重试
+type TrafficEnforcement struct {
+	ID              uint       `json:"id" gorm:"primaryKey"`
+	NodeID          uint       `json:"node_id" gorm:"not null;index:idx_traffic_enforcement_container"`
+	Hostname        string     `json:"hostname" gorm:"size:200;not null;index:idx_traffic_enforcement_container"`
+	PeriodID        uint       `json:"period_id" gorm:"index"`
+	PolicyID        uint       `json:"policy_id" gorm:"index"`
+	PolicyName      string     `json:"policy_name" gorm:"size:200"`
+	Action          string     `json:"action" gorm:"size:20"`
+	Status          string     `json:"status" gorm:"size:20;index"`
+	UsagePercent    float64    `json:"usage_percent"`
+	OriginalIngress string     `json:"original_ingress" gorm:"size:50"`
+	OriginalEgress  string     `json:"original_egress" gorm:"size:50"`
+	Error           string     `json:"error" gorm:"type:text"`
+	Attempts        int        `json:"attempts"`
+	NextRetryAt     *time.Time `json:"next_retry_at"`
+	AppliedAt       time.Time  `json:"applied_at"`
+	RestoredAt      *time.Time `json:"restored_at"`
+	RestoreReason   string     `json:"restore_reason" gorm:"size:50"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+type TrafficPolicyRequest struct {
+	Name             string  `json:"name" binding:"required"`
+	Description      string  `json:"description"`
+	Action           string  `json:"action" binding:"required,oneof=notify throttle suspend"`
+	ThresholdPercent float64 `json:"threshold_percent" binding:"min=0"`
+	ThrottleIngress  string  `json:"throttle_ingress"`
+	ThrottleEgress   string  `json:"throttle_egress"`
+	ChannelIDs       []uint  `json:"channel_ids"`
+	IsDefault        bool    `json:"is_default"`
+	Enabled          *bool   `json:"enabled"`
+}
+
+type BindTrafficPolicyRequest struct {
+	NodeID   uint `json:"node_id" binding:"required"`
+	PolicyID uint `json:"policy_id"` // 0 表示解除绑定，使用默认策略
+}
+
+func (TrafficPolicy) TableName() string {
+	return "traffic_policies"
+}
+
+func (TrafficPolicyBinding) TableName() string {
+	return "traffic_policy_bindings"
+}
+
+func (TrafficEnforcement) TableName() string {
+	return "traffic_enforcements"
+}
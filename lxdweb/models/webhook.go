@@ -0,0 +1,109 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Webhook 事件类型
+const (
+	WebhookEventContainerCreated   = "container.created"
+	WebhookEventContainerDeleted   = "container.deleted"
+	WebhookEventContainerSuspended = "container.suspended"
+	WebhookEventTrafficExceeded    = "container.traffic_exceeded"
+	WebhookEventNodeDown           = "node.down"
+	WebhookEventNodeUp             = "node.up"
+	WebhookEventPing               = "ping" // 测试投递，只发送给被测试的 webhook
+)
+
+// WebhookEvents 可订阅的事件类型
+var WebhookEvents = []string{
+	WebhookEventContainerCreated,
+	WebhookEventContainerDeleted,
+	WebhookEventContainerSuspended,
+	WebhookEventTrafficExceeded,
+	WebhookEventNodeDown,
+	WebhookEventNodeUp,
+}
+
+// 投递状态
+const (
+	WebhookDeliveryPending = "pending" // 等待投递或等待重试
+	WebhookDeliverySuccess = "success"
+	WebhookDeliveryFailed  = "failed" // 超过最大投递次数
+)
+
+// Webhook 事件订阅表，Events 为逗号分隔的事件类型，为空表示订阅全部事件
+type Webhook struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Name        string    `json:"name" gorm:"size:200;not null"`
+	URL         string    `json:"url" gorm:"size:500;not null"`
+	Secret      string    `json:"-" gorm:"size:200"`
+	Events      string    `json:"events" gorm:"size:500"`
+	Description string    `json:"description" gorm:"type:text"`
+	Enabled     bool      `json:"enabled"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// EventList 解析订阅的事件类型
+func (w Webhook) EventList() []string {
+	var events []string
+	for _, part := range strings.Split(w.Events, ",") {
+		if e := strings.TrimSpace(part); e != "" {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// Subscribes 判断是否订阅了指定事件
+func (w Webhook) Subscribes(event string) bool {
+	events := w.EventList()
+	if len(events) == 0 {
+		return true
+	}
+	for _, e := range events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery 投递记录表，同一事件投递给多个 webhook 时共用 EventID，
+// 手动重新投递会创建新记录并保留原 EventID，便于接收方去重
+type WebhookDelivery struct {
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	WebhookID     uint       `json:"webhook_id" gorm:"index"`
+	EventID       string     `json:"event_id" gorm:"size:64;index"`
+	Event         string     `json:"event" gorm:"size:50;index"`
+	Payload       string     `json:"payload" gorm:"type:text"`
+	Status        string     `json:"status" gorm:"size:20;index"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt *time.Time `json:"next_attempt_at" gorm:"index"`
+	LastAttemptAt *time.Time `json:"last_attempt_at"`
+	ResponseCode  int        `json:"response_code"`
+	ResponseBody  string     `json:"response_body" gorm:"type:text"`
+	Error         string     `json:"error" gorm:"type:text"`
+	DeliveredAt   *time.Time `json:"delivered_at"`
+	RedeliveryOf  uint       `json:"redelivery_of"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+type WebhookRequest struct {
+	Name        string   `json:"name" binding:"required"`
+	URL         string   `json:"url" binding:"required,url"`
+	Secret      string   `json:"secret"`
+	Events      []string `json:"events"`
+	Description string   `json:"description"`
+	Enabled     *bool    `json:"enabled"`
+}
+
+func (Webhook) TableName() string {
+	return "webhooks"
+}
+
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
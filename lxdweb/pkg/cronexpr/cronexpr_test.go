@@ -0,0 +1,175 @@
+package cronexpr
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("加载时区 %s 失败: %v", name, err)
+	}
+	return loc
+}
+
+func TestNext(t *testing.T) {
+	ny := mustLoad(t, "America/New_York")
+	utc := time.UTC
+
+	tests := []struct {
+		name string
+		expr string
+		from time.Time
+		want []time.Time
+	}{
+		{
+			name: "夏令时开始当天的周日04:00",
+			expr: "0 4 * * 0",
+			from: time.Date(2026, 3, 7, 12, 0, 0, 0, ny),
+			want: []time.Time{
+				time.Date(2026, 3, 8, 4, 0, 0, 0, ny),
+				time.Date(2026, 3, 15, 4, 0, 0, 0, ny),
+			},
+		},
+		{
+			name: "跳过的02:30当天不执行",
+			expr: "30 2 * * *",
+			from: time.Date(2026, 3, 7, 12, 0, 0, 0, ny),
+			want: []time.Time{
+				time.Date(2026, 3, 9, 2, 30, 0, 0, ny),
+			},
+		},
+		{
+			name: "夏令时结束时重复的01:30只执行一次",
+			expr: "30 1 * * *",
+			from: time.Date(2026, 10, 31, 12, 0, 0, 0, ny),
+			want: []time.Time{
+				time.Date(2026, 11, 1, 5, 30, 0, 0, utc).In(ny), // 01:30 EDT
+				time.Date(2026, 11, 2, 1, 30, 0, 0, ny),
+			},
+		},
+		{
+			name: "每小时任务在重复的小时内不重复执行",
+			expr: "0 * * * *",
+			from: time.Date(2026, 11, 1, 0, 30, 0, 0, ny),
+			want: []time.Time{
+				time.Date(2026, 11, 1, 5, 0, 0, 0, utc).In(ny), // 01:00 EDT
+				time.Date(2026, 11, 1, 7, 0, 0, 0, utc).In(ny), // 02:00 EST
+			},
+		},
+		{
+			name: "日和星期同时限定时满足其一即可",
+			expr: "0 0 13 * 5",
+			from: time.Date(2026, 2, 1, 0, 0, 0, 0, utc),
+			want: []time.Time{
+				time.Date(2026, 2, 6, 0, 0, 0, 0, utc),
+				time.Date(2026, 2, 13, 0, 0, 0, 0, utc),
+				time.Date(2026, 2, 20, 0, 0, 0, 0, utc),
+			},
+		},
+		{
+			name: "星期为*时只按日匹配",
+			expr: "0 0 13 * *",
+			from: time.Date(2026, 2, 1, 0, 0, 0, 0, utc),
+			want: []time.Time{
+				time.Date(2026, 2, 13, 0, 0, 0, 0, utc),
+				time.Date(2026, 3, 13, 0, 0, 0, 0, utc),
+			},
+		},
+		{
+			name: "星期7等同于周日",
+			expr: "0 12 * * 7",
+			from: time.Date(2026, 2, 1, 13, 0, 0, 0, utc),
+			want: []time.Time{
+				time.Date(2026, 2, 8, 12, 0, 0, 0, utc),
+			},
+		},
+		{
+			name: "分钟步长",
+			expr: "*/20 * * * *",
+			from: time.Date(2026, 2, 1, 10, 5, 0, 0, utc),
+			want: []time.Time{
+				time.Date(2026, 2, 1, 10, 20, 0, 0, utc),
+				time.Date(2026, 2, 1, 10, 40, 0, 0, utc),
+				time.Date(2026, 2, 1, 11, 0, 0, 0, utc),
+			},
+		},
+		{
+			name: "范围加步长",
+			expr: "0 8-18/5 * * *",
+			from: time.Date(2026, 2, 1, 9, 0, 0, 0, utc),
+			want: []time.Time{
+				time.Date(2026, 2, 1, 13, 0, 0, 0, utc),
+				time.Date(2026, 2, 1, 18, 0, 0, 0, utc),
+				time.Date(2026, 2, 2, 8, 0, 0, 0, utc),
+			},
+		},
+		{
+			name: "单值加步长到最大值",
+			expr: "0 0 1 10/2 *",
+			from: time.Date(2026, 2, 1, 0, 0, 0, 0, utc),
+			want: []time.Time{
+				time.Date(2026, 10, 1, 0, 0, 0, 0, utc),
+				time.Date(2026, 12, 1, 0, 0, 0, 0, utc),
+				time.Date(2027, 10, 1, 0, 0, 0, 0, utc),
+			},
+		},
+		{
+			name: "严格晚于起始时间",
+			expr: "@daily",
+			from: time.Date(2026, 2, 1, 0, 0, 0, 0, utc),
+			want: []time.Time{
+				time.Date(2026, 2, 2, 0, 0, 0, 0, utc),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.expr, err)
+			}
+			from := tt.from
+			for i, want := range tt.want {
+				got := s.Next(from)
+				if !got.Equal(want) {
+					t.Fatalf("第 %d 次 Next(%s) = %s，期望 %s", i+1, from, got, want)
+				}
+				from = got
+			}
+		})
+	}
+}
+
+func TestNextNoMatch(t *testing.T) {
+	s, err := Parse("0 0 30 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Next(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)); !got.IsZero() {
+		t.Fatalf("2月30日不应匹配，得到 %s", got)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+	} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q) 应返回错误", expr)
+		}
+	}
+}
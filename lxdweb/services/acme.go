@@ -0,0 +1,514 @@
+package services
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"lxdweb/config"
+	"lxdweb/database"
+	"lxdweb/models"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/acme"
+)
+
+const (
+	ACMEChallengeModeNode       = "node"
+	ACMEChallengeModeStandalone = "standalone"
+
+	// acmeRetryBackoff 签发失败后距离下次自动重试的最短间隔
+	acmeRetryBackoff = 1 * time.Hour
+)
+
+var (
+	acmeChallenges   = make(map[string]string)
+	acmeChallengesMu sync.RWMutex
+
+	acmeIssuing   = make(map[uint]bool)
+	acmeIssuingMu sync.Mutex
+)
+
+// StartACMEService 启动证书检查、状态刷新与ACME自动续期服务
+func StartACMEService() {
+	cfg := config.AppConfig.ACME
+	interval := time.Duration(cfg.CheckInterval) * time.Second
+
+	// 进程重启前未完成的签发任务重新排队
+	database.DB.Model(&models.ProxyCertificate{}).
+		Where("status = ?", models.CertStatusIssuing).
+		Update("status", models.CertStatusPending)
+
+	if cfg.Enabled {
+		log.Printf("[ACME] 证书自动签发服务启动，目录 %s，验证方式 %s，检查间隔 %v", cfg.DirectoryURL, cfg.ChallengeMode, interval)
+		if cfg.ChallengeMode == ACMEChallengeModeStandalone && cfg.HTTPAddress != "" {
+			go startACMEChallengeServer(cfg.HTTPAddress)
+		}
+	} else {
+		log.Printf("[ACME] 自动签发未启用，仅检查证书状态，间隔 %v", interval)
+	}
+
+	go checkProxyCertificates()
+
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			checkProxyCertificates()
+		}
+	}()
+}
+
+func checkProxyCertificates() {
+	CheckProxyCertificates()
+	RefreshCertificateStatuses()
+	if !config.AppConfig.ACME.Enabled {
+		return
+	}
+
+	for _, cert := range dueACMECertificates(time.Now()) {
+		log.Printf("[ACME] 证书 %s 需要签发/续期 (状态: %s)", cert.Domain, cert.Status)
+		if err := IssueProxyCertificate(cert.ID); err != nil {
+			log.Printf("[ACME] 证书 %s 签发失败: %v", cert.Domain, err)
+		}
+	}
+}
+
+// dueACMECertificates 查询待签发或即将到期、且不在失败退避期内的ACME证书
+func dueACMECertificates(now time.Time) []models.ProxyCertificate {
+	renewBefore := now.Add(time.Duration(config.AppConfig.ACME.RenewBeforeDays) * 24 * time.Hour)
+	retryAfter := now.Add(-acmeRetryBackoff)
+
+	var certs []models.ProxyCertificate
+	database.DB.Where("source = ? AND auto_renew = ? AND status <> ?", models.CertSourceACME, true, models.CertStatusIssuing).
+		Where("not_after IS NULL OR not_after < ?", renewBefore).
+		Where("last_attempt_at IS NULL OR last_attempt_at < ?", retryAfter).
+		Find(&certs)
+	return certs
+}
+
+// ACMEChallengeResponse 返回HTTP-01验证令牌对应的 key authorization
+func ACMEChallengeResponse(token string) (string, bool) {
+	acmeChallengesMu.RLock()
+	defer acmeChallengesMu.RUnlock()
+	keyAuth, ok := acmeChallenges[token]
+	return keyAuth, ok
+}
+
+func startACMEChallengeServer(address string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/acme-challenge/", func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.URL.Path, "/.well-known/acme-challenge/")
+		keyAuth, ok := ACMEChallengeResponse(token)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(keyAuth))
+	})
+
+	log.Printf("[ACME] HTTP-01 验证服务监听 %s", address)
+	if err := http.ListenAndServe(address, mux); err != nil {
+		log.Printf("[ACME] HTTP-01 验证服务启动失败: %v", err)
+	}
+}
+
+// publishACMEChallenge 发布验证内容：standalone 模式保存在本地，node 模式推送到节点由 nginx 提供
+func publishACMEChallenge(node models.Node, domain, token, keyAuth string) error {
+	acmeChallengesMu.Lock()
+	acmeChallenges[token] = keyAuth
+	acmeChallengesMu.Unlock()
+
+	if config.AppConfig.ACME.ChallengeMode != ACMEChallengeModeNode {
+		return nil
+	}
+
+	result := callNodeAPIForProxy(node, "POST", "/api/proxy/acme-challenge", map[string]interface{}{
+		"domain":            domain,
+		"token":             token,
+		"key_authorization": keyAuth,
+	})
+	if result["code"] != float64(200) {
+		return fmt.Errorf("推送验证文件到节点失败: %v", result["msg"])
+	}
+	return nil
+}
+
+func withdrawACMEChallenge(node models.Node, domain, token string) {
+	acmeChallengesMu.Lock()
+	delete(acmeChallenges, token)
+	acmeChallengesMu.Unlock()
+
+	if config.AppConfig.ACME.ChallengeMode != ACMEChallengeModeNode {
+		return
+	}
+
+	result := callNodeAPIForProxy(node, "DELETE", "/api/proxy/acme-challenge", map[string]interface{}{
+		"domain": domain,
+		"token":  token,
+	})
+	if result["code"] != float64(200) {
+		log.Printf("[ACME] 清理节点 %s 验证文件失败: %v", node.Name, result["msg"])
+	}
+}
+
+// acmeHTTPClient 构造访问ACME目录的HTTP客户端，支持信任本地测试CA
+func acmeHTTPClient() (*http.Client, error) {
+	cfg := config.AppConfig.ACME
+	tlsConfig := &tls.Config{InsecureSkipVerify: cfg.InsecureSkipVerify}
+
+	if cfg.CACertFile != "" {
+		caPEM, err := os.ReadFile(cfg.CACertFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取CA证书失败: %v", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("CA证书格式错误: %s", cfg.CACertFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return &http.Client{
+		Timeout:   30 * time.Second,
+		Transport: &http.Transport{TLSClientConfig: tlsConfig},
+	}, nil
+}
+
+// newACMEClient 加载或注册当前目录地址对应的ACME账户
+func newACMEClient(ctx context.Context) (*acme.Client, error) {
+	cfg := config.AppConfig.ACME
+	httpClient, err := acmeHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+
+	var account models.ACMEAccount
+	database.DB.Where("directory_url = ?", cfg.DirectoryURL).First(&account)
+
+	var key crypto.Signer
+	if account.KeyPEM != "" {
+		key, err = parseECPrivateKeyPEM(account.KeyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("ACME账户私钥损坏: %v", err)
+		}
+	} else {
+		ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		keyPEM, err := encodeECPrivateKeyPEM(ecKey)
+		if err != nil {
+			return nil, err
+		}
+		key = ecKey
+		account.DirectoryURL = cfg.DirectoryURL
+		account.KeyPEM = keyPEM
+	}
+
+	client := &acme.Client{
+		Key:          key,
+		HTTPClient:   httpClient,
+		DirectoryURL: cfg.DirectoryURL,
+		UserAgent:    "lxdweb",
+	}
+
+	if account.URI == "" {
+		acct := &acme.Account{}
+		if cfg.Email != "" {
+			acct.Contact = []string{"mailto:" + cfg.Email}
+		}
+		registered, err := client.Register(ctx, acct, acme.AcceptTOS)
+		if errors.Is(err, acme.ErrAccountAlreadyExists) {
+			// 私钥已在CA注册过（如上次注册后保存失败），取回已有账户地址
+			registered, err = client.GetReg(ctx, "")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("注册ACME账户失败: %v", err)
+		}
+		if registered.URI == "" {
+			return nil, errors.New("注册ACME账户失败: CA未返回账户地址")
+		}
+		account.URI = registered.URI
+		account.Email = cfg.Email
+		if err := database.DB.Save(&account).Error; err != nil {
+			return nil, err
+		}
+		log.Printf("[ACME] 已注册账户 %s", account.URI)
+	} else {
+		client.KID = acme.KeyID(account.URI)
+	}
+
+	return client, nil
+}
+
+// IssueProxyCertificate 通过ACME HTTP-01 为证书记录对应的域名签发证书，并部署到节点
+func IssueProxyCertificate(certID uint) error {
+	acmeIssuingMu.Lock()
+	if acmeIssuing[certID] {
+		acmeIssuingMu.Unlock()
+		return fmt.Errorf("证书正在签发中")
+	}
+	acmeIssuing[certID] = true
+	acmeIssuingMu.Unlock()
+	defer func() {
+		acmeIssuingMu.Lock()
+		delete(acmeIssuing, certID)
+		acmeIssuingMu.Unlock()
+	}()
+
+	var cert models.ProxyCertificate
+	if err := database.DB.First(&cert, certID).Error; err != nil {
+		return fmt.Errorf("证书记录不存在: %v", err)
+	}
+
+	var node models.Node
+	if err := database.DB.First(&node, cert.NodeID).Error; err != nil {
+		return fmt.Errorf("节点不存在: %v", err)
+	}
+
+	now := time.Now()
+	database.DB.Model(&cert).Updates(map[string]interface{}{
+		"status":          models.CertStatusIssuing,
+		"last_attempt_at": now,
+	})
+
+	certPEM, keyPEM, err := obtainACMECertificate(node, cert.Domain)
+	if err == nil {
+		err = DeployProxyCertificate(cert, certPEM, keyPEM)
+	}
+	if err != nil {
+		status := models.CertStatusFailed
+		if cert.NotAfter != nil {
+			// 保留旧证书的有效期状态，仅记录错误
+			status = certificateStatus(cert.NotAfter, time.Now())
+		}
+		database.DB.Model(&cert).Updates(map[string]interface{}{
+			"status":     status,
+			"last_error": err.Error(),
+		})
+		return err
+	}
+
+	leaf, err := ParseCertificatePEM(certPEM)
+	if err != nil {
+		return err
+	}
+	issuedAt := time.Now()
+	cert.Source = models.CertSourceACME
+	cert.CertPEM = certPEM
+	cert.KeyPEM = keyPEM
+	cert.LastError = ""
+	cert.LastAttemptAt = &now
+	cert.IssuedAt = &issuedAt
+	applyCertificateInfo(&cert, leaf)
+	if err := database.DB.Save(&cert).Error; err != nil {
+		return err
+	}
+
+	log.Printf("[ACME] 证书 %s 签发成功，有效期至 %s", cert.Domain, leaf.NotAfter.Format("2006-01-02 15:04:05"))
+	return nil
+}
+
+// IssueNewProxyCertificate 为新建的反向代理同步配置缓存、创建证书记录并签发
+func IssueNewProxyCertificate(nodeID uint, hostname, domain string) {
+	SyncNodeProxyConfigs(nodeID)
+
+	cert, err := RequestACMECertificate(nodeID, hostname, domain)
+	if err != nil {
+		log.Printf("[ACME] 创建证书记录失败 %s: %v", domain, err)
+		return
+	}
+	if err := IssueProxyCertificate(cert.ID); err != nil {
+		log.Printf("[ACME] 证书 %s 签发失败: %v", domain, err)
+	}
+}
+
+// obtainACMECertificate 完成订单、HTTP-01验证和CSR提交，返回PEM格式的证书链和私钥
+func obtainACMECertificate(node models.Node, domain string) (string, string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(config.AppConfig.ACME.Timeout)*time.Second)
+	defer cancel()
+
+	client, err := newACMEClient(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	order, err := client.AuthorizeOrder(ctx, acme.DomainIDs(domain))
+	if err != nil {
+		return "", "", fmt.Errorf("创建订单失败: %v", err)
+	}
+
+	for _, authzURL := range order.AuthzURLs {
+		if err := completeHTTP01(ctx, client, node, domain, authzURL); err != nil {
+			return "", "", err
+		}
+	}
+
+	order, err = client.WaitOrder(ctx, order.URI)
+	if err != nil {
+		return "", "", fmt.Errorf("订单未就绪: %v", err)
+	}
+
+	certKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: domain},
+		DNSNames: []string{domain},
+	}, certKey)
+	if err != nil {
+		return "", "", err
+	}
+
+	chain, _, err := client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return "", "", fmt.Errorf("获取证书失败: %v", err)
+	}
+
+	var certPEM strings.Builder
+	for _, der := range chain {
+		pem.Encode(&certPEM, &pem.Block{Type: "CERTIFICATE", Bytes: der})
+	}
+	keyPEM, err := encodeECPrivateKeyPEM(certKey)
+	if err != nil {
+		return "", "", err
+	}
+
+	return certPEM.String(), keyPEM, nil
+}
+
+func completeHTTP01(ctx context.Context, client *acme.Client, node models.Node, domain, authzURL string) error {
+	authz, err := client.GetAuthorization(ctx, authzURL)
+	if err != nil {
+		return fmt.Errorf("获取授权失败: %v", err)
+	}
+	if authz.Status == acme.StatusValid {
+		return nil
+	}
+
+	var chal *acme.Challenge
+	for _, c := range authz.Challenges {
+		if c.Type == "http-01" {
+			chal = c
+			break
+		}
+	}
+	if chal == nil {
+		return fmt.Errorf("CA未提供 http-01 验证方式")
+	}
+
+	keyAuth, err := client.HTTP01ChallengeResponse(chal.Token)
+	if err != nil {
+		return err
+	}
+	if err := publishACMEChallenge(node, domain, chal.Token, keyAuth); err != nil {
+		return err
+	}
+	defer withdrawACMEChallenge(node, domain, chal.Token)
+
+	if _, err := client.Accept(ctx, chal); err != nil {
+		return fmt.Errorf("提交验证失败: %v", err)
+	}
+	if _, err := client.WaitAuthorization(ctx, authz.URI); err != nil {
+		return fmt.Errorf("域名验证失败: %v", err)
+	}
+	return nil
+}
+
+// DeployProxyCertificate 以自定义证书方式重建节点上的反向代理，失败时尝试恢复原配置
+func DeployProxyCertificate(cert models.ProxyCertificate, certPEM, keyPEM string) error {
+	var node models.Node
+	if err := database.DB.First(&node, cert.NodeID).Error; err != nil {
+		return fmt.Errorf("节点不存在: %v", err)
+	}
+
+	var proxy models.ProxyConfigCache
+	if err := database.DB.Where("node_id = ? AND domain = ?", cert.NodeID, cert.Domain).First(&proxy).Error; err != nil {
+		return fmt.Errorf("反向代理配置不存在: %s", cert.Domain)
+	}
+
+	result := callNodeAPIForProxy(node, "POST", "/api/proxy/delete", map[string]interface{}{
+		"hostname": proxy.Hostname,
+		"domain":   proxy.Domain,
+	})
+	if result["code"] != float64(200) {
+		return fmt.Errorf("移除旧代理配置失败: %v", result["msg"])
+	}
+
+	data := ProxyCachePayload(proxy)
+	data["ssl_enabled"] = true
+	data["ssl_type"] = models.CertSourceCustom
+	data["ssl_cert"] = certPEM
+	data["ssl_key"] = keyPEM
+	if proxy.Options == "" {
+		// 没有保存高级选项的代理保持启用SSL即跳转HTTPS的默认行为
+		data["force_https"] = true
+	}
+
+	result = callNodeAPIForProxy(node, "POST", "/api/proxy/add", data)
+	if result["code"] != float64(200) {
+		restoreProxyConfig(node, proxy, cert)
+		return fmt.Errorf("部署证书失败: %v", result["msg"])
+	}
+
+	database.DB.Model(&proxy).Updates(map[string]interface{}{
+		"ssl_enabled": true,
+		"ssl_type":    models.CertSourceCustom,
+	})
+	log.Printf("[CERT] 证书已部署到节点 %s: %s", node.Name, proxy.Domain)
+	return nil
+}
+
+// restoreProxyConfig 按部署前的缓存配置重建代理，原证书不可用时退回HTTP
+func restoreProxyConfig(node models.Node, proxy models.ProxyConfigCache, cert models.ProxyCertificate) {
+	data := ProxyCachePayload(proxy)
+	data["ssl_enabled"] = proxy.SSLEnabled
+	data["ssl_type"] = proxy.SSLType
+	if proxy.SSLEnabled && proxy.SSLType == models.CertSourceCustom {
+		if cert.CertPEM != "" && cert.KeyPEM != "" {
+			data["ssl_cert"] = cert.CertPEM
+			data["ssl_key"] = cert.KeyPEM
+		} else {
+			data["ssl_enabled"] = false
+			data["ssl_type"] = "none"
+		}
+	}
+
+	result := callNodeAPIForProxy(node, "POST", "/api/proxy/add", data)
+	if result["code"] != float64(200) {
+		log.Printf("[CERT] 恢复节点 %s 代理配置 %s 失败: %v", node.Name, proxy.Domain, result["msg"])
+	}
+}
+
+func encodeECPrivateKeyPEM(key *ecdsa.PrivateKey) (string, error) {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})), nil
+}
+
+func parseECPrivateKeyPEM(keyPEM string) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(keyPEM))
+	if block == nil {
+		return nil, errors.New("无效的PEM私钥")
+	}
+	return x509.ParseECPrivateKey(block.Bytes)
+}
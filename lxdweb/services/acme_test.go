@@ -0,0 +1,378 @@
+package services
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"lxdweb/config"
+	"lxdweb/database"
+	"lxdweb/models"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/acme"
+)
+
+// fakeACMEServer 最小化的 RFC 8555 CA 替身，只解析 JWS 内容不校验签名，
+// HTTP-01 验证直接读取 lxdweb 本地保存的 key authorization
+type fakeACMEServer struct {
+	mu       sync.Mutex
+	server   *httptest.Server
+	accounts map[string]string // jwk -> 账户地址
+	created  int               // 新建账户次数
+	lookups  int               // onlyReturnExisting 查询次数
+	orders   map[string]*fakeACMEOrder
+	caKey    *ecdsa.PrivateKey
+	caCert   *x509.Certificate
+	nextID   int
+}
+
+type fakeACMEOrder struct {
+	domain  string
+	token   string
+	status  string // pending / ready / valid
+	authz   string // pending / valid
+	certPEM []byte
+}
+
+type fakeJWS struct {
+	Protected string `json:"protected"`
+	Payload   string `json:"payload"`
+}
+
+func newFakeACMEServer(t *testing.T) *fakeACMEServer {
+	t.Helper()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "lxdweb fake ACME CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caCert, _ := x509.ParseCertificate(der)
+
+	f := &fakeACMEServer{
+		accounts: make(map[string]string),
+		orders:   make(map[string]*fakeACMEOrder),
+		caKey:    caKey,
+		caCert:   caCert,
+	}
+	f.server = httptest.NewTLSServer(http.HandlerFunc(f.handle))
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+func (f *fakeACMEServer) url(p string) string {
+	return f.server.URL + p
+}
+
+func (f *fakeACMEServer) reply(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func (f *fakeACMEServer) handle(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.nextID++
+	w.Header().Set("Replay-Nonce", fmt.Sprintf("nonce-%d", f.nextID))
+
+	if r.URL.Path == "/dir" {
+		f.reply(w, http.StatusOK, map[string]string{
+			"newNonce":   f.url("/nonce"),
+			"newAccount": f.url("/acct"),
+			"newOrder":   f.url("/order"),
+			"revokeCert": f.url("/revoke"),
+		})
+		return
+	}
+	if r.URL.Path == "/nonce" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	var jws fakeJWS
+	json.NewDecoder(r.Body).Decode(&jws)
+	protectedJSON, _ := base64.RawURLEncoding.DecodeString(jws.Protected)
+	payload, _ := base64.RawURLEncoding.DecodeString(jws.Payload)
+	var protected struct {
+		JWK json.RawMessage `json:"jwk"`
+	}
+	json.Unmarshal(protectedJSON, &protected)
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch parts[0] {
+	case "acct":
+		f.handleAccount(w, string(protected.JWK), payload)
+	case "order":
+		f.handleOrder(w, parts, payload)
+	case "authz":
+		o := f.orders[parts[1]]
+		f.reply(w, http.StatusOK, f.authzJSON(parts[1], o))
+	case "chal":
+		o := f.orders[parts[1]]
+		// 以 lxdweb 发布的 key authorization 作为 HTTP-01 验证结果
+		if keyAuth, ok := ACMEChallengeResponse(o.token); ok && strings.HasPrefix(keyAuth, o.token+".") {
+			o.authz = "valid"
+			o.status = "ready"
+		}
+		f.reply(w, http.StatusOK, f.authzJSON(parts[1], o)["challenges"].([]map[string]string)[0])
+	case "finalize":
+		f.handleFinalize(w, parts[1], payload)
+	case "cert":
+		w.Header().Set("Content-Type", "application/pem-certificate-chain")
+		w.Write(f.orders[parts[1]].certPEM)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakeACMEServer) handleAccount(w http.ResponseWriter, jwk string, payload []byte) {
+	var req struct {
+		OnlyReturnExisting bool `json:"onlyReturnExisting"`
+	}
+	json.Unmarshal(payload, &req)
+
+	uri, exists := f.accounts[jwk]
+	if req.OnlyReturnExisting {
+		f.lookups++
+		if !exists {
+			f.reply(w, http.StatusBadRequest, map[string]string{"type": "urn:ietf:params:acme:error:accountDoesNotExist"})
+			return
+		}
+	}
+	status := http.StatusOK
+	if !exists {
+		f.created++
+		uri = f.url(fmt.Sprintf("/acct/%d", len(f.accounts)+1))
+		f.accounts[jwk] = uri
+		status = http.StatusCreated
+	}
+	w.Header().Set("Location", uri)
+	f.reply(w, status, map[string]string{"status": "valid"})
+}
+
+func (f *fakeACMEServer) handleOrder(w http.ResponseWriter, parts []string, payload []byte) {
+	if len(parts) == 1 {
+		var req struct {
+			Identifiers []struct {
+				Value string `json:"value"`
+			} `json:"identifiers"`
+		}
+		json.Unmarshal(payload, &req)
+		id := fmt.Sprint(len(f.orders) + 1)
+		f.orders[id] = &fakeACMEOrder{
+			domain: req.Identifiers[0].Value,
+			token:  "token-" + id,
+			status: "pending",
+			authz:  "pending",
+		}
+		w.Header().Set("Location", f.url("/order/"+id))
+		f.reply(w, http.StatusCreated, f.orderJSON(id))
+		return
+	}
+	f.reply(w, http.StatusOK, f.orderJSON(parts[1]))
+}
+
+func (f *fakeACMEServer) handleFinalize(w http.ResponseWriter, id string, payload []byte) {
+	o := f.orders[id]
+	var req struct {
+		CSR string `json:"csr"`
+	}
+	json.Unmarshal(payload, &req)
+	der, _ := base64.RawURLEncoding.DecodeString(req.CSR)
+	csr, err := x509.ParseCertificateRequest(der)
+	if o.status != "ready" || err != nil || len(csr.DNSNames) != 1 || csr.DNSNames[0] != o.domain {
+		f.reply(w, http.StatusForbidden, map[string]string{"type": "urn:ietf:params:acme:error:orderNotReady"})
+		return
+	}
+
+	leaf := &x509.Certificate{
+		SerialNumber: big.NewInt(int64(f.nextID) + 100),
+		Subject:      pkix.Name{CommonName: o.domain},
+		DNSNames:     csr.DNSNames,
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(90 * 24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leaf, f.caCert, csr.PublicKey, f.caKey)
+	if err != nil {
+		f.reply(w, http.StatusInternalServerError, map[string]string{"type": "urn:ietf:params:acme:error:serverInternal"})
+		return
+	}
+	o.certPEM = append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leafDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: f.caCert.Raw})...)
+	o.status = "valid"
+	f.reply(w, http.StatusOK, f.orderJSON(id))
+}
+
+func (f *fakeACMEServer) orderJSON(id string) map[string]interface{} {
+	o := f.orders[id]
+	v := map[string]interface{}{
+		"status":         o.status,
+		"identifiers":    []map[string]string{{"type": "dns", "value": o.domain}},
+		"authorizations": []string{f.url("/authz/" + id)},
+		"finalize":       f.url("/finalize/" + id),
+	}
+	if o.status == "valid" {
+		v["certificate"] = f.url("/cert/" + id)
+	}
+	return v
+}
+
+func (f *fakeACMEServer) authzJSON(id string, o *fakeACMEOrder) map[string]interface{} {
+	return map[string]interface{}{
+		"status":     o.authz,
+		"identifier": map[string]string{"type": "dns", "value": o.domain},
+		"challenges": []map[string]string{{
+			"type":   "http-01",
+			"url":    f.url("/chal/" + id),
+			"token":  o.token,
+			"status": o.authz,
+		}},
+	}
+}
+
+// setupACMEConfig 默认使用本地 CA 替身；设置 LXDWEB_TEST_ACME_DIRECTORY 时改为连接 Pebble
+// （需以 PEBBLE_VA_ALWAYS_VALID=1 启动，LXDWEB_TEST_ACME_CA 为 Pebble 的 CA 证书文件）
+func setupACMEConfig(t *testing.T) *fakeACMEServer {
+	t.Helper()
+	old := config.AppConfig
+	config.AppConfig = &config.Config{}
+	config.AppConfig.ACME.Enabled = true
+	config.AppConfig.ACME.ChallengeMode = ACMEChallengeModeStandalone
+	config.AppConfig.ACME.Timeout = 60
+	config.AppConfig.ACME.Email = "ops@example.com"
+	t.Cleanup(func() { config.AppConfig = old })
+
+	if dir := os.Getenv("LXDWEB_TEST_ACME_DIRECTORY"); dir != "" {
+		config.AppConfig.ACME.DirectoryURL = dir
+		config.AppConfig.ACME.CACertFile = os.Getenv("LXDWEB_TEST_ACME_CA")
+		config.AppConfig.ACME.InsecureSkipVerify = config.AppConfig.ACME.CACertFile == ""
+		return nil
+	}
+
+	f := newFakeACMEServer(t)
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: f.server.Certificate().Raw})
+	if err := os.WriteFile(caFile, caPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	config.AppConfig.ACME.DirectoryURL = f.url("/dir")
+	config.AppConfig.ACME.CACertFile = caFile
+	return f
+}
+
+func TestNewACMEClientRecoversExistingAccount(t *testing.T) {
+	f := setupACMEConfig(t)
+	setupTestDB(t, &models.ACMEAccount{})
+	ctx := context.Background()
+	dirURL := config.AppConfig.ACME.DirectoryURL
+
+	// 私钥已在CA注册，但本地没有保存账户地址
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpClient, err := acmeHTTPClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pre := &acme.Client{Key: key, HTTPClient: httpClient, DirectoryURL: dirURL}
+	existing, err := pre.Register(ctx, &acme.Account{}, acme.AcceptTOS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPEM, _ := encodeECPrivateKeyPEM(key)
+	database.DB.Create(&models.ACMEAccount{DirectoryURL: dirURL, KeyPEM: keyPEM})
+
+	if _, err := newACMEClient(ctx); err != nil {
+		t.Fatal(err)
+	}
+	var account models.ACMEAccount
+	database.DB.Where("directory_url = ?", dirURL).First(&account)
+	if account.URI == "" || account.URI != existing.URI {
+		t.Fatalf("账户地址 = %q，期望 %q", account.URI, existing.URI)
+	}
+
+	// 已保存账户地址后不再重复注册
+	if _, err := newACMEClient(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if f != nil && (f.created != 1 || f.lookups != 1) {
+		t.Errorf("新建账户 %d 次、查询已有账户 %d 次，期望各 1 次", f.created, f.lookups)
+	}
+}
+
+func TestIssueProxyCertificate(t *testing.T) {
+	setupACMEConfig(t)
+	setupTestDB(t, &models.ACMEAccount{}, &models.Node{}, &models.ProxyConfigCache{}, &models.ProxyCertificate{})
+
+	var deployed []map[string]interface{}
+	nodeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		if r.URL.Path == "/api/proxy/add" {
+			deployed = append(deployed, body)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"code": 200, "msg": "success"})
+	}))
+	defer nodeServer.Close()
+
+	domain := "app.lxdweb.test"
+	node := models.Node{Name: "node-1", Address: nodeServer.URL, Status: models.NodeStatusActive}
+	database.DB.Create(&node)
+	database.DB.Create(&models.ProxyConfigCache{NodeID: node.ID, Hostname: "c1", Domain: domain, BackendPort: 80})
+	cert := models.ProxyCertificate{NodeID: node.ID, Hostname: "c1", Domain: domain,
+		Source: models.CertSourceACME, Status: models.CertStatusPending, AutoRenew: true}
+	database.DB.Create(&cert)
+
+	if err := IssueProxyCertificate(cert.ID); err != nil {
+		t.Fatalf("签发失败: %v", err)
+	}
+
+	database.DB.First(&cert, cert.ID)
+	if cert.Status != models.CertStatusValid || cert.CertPEM == "" || cert.KeyPEM == "" || cert.LastError != "" {
+		t.Fatalf("证书记录 = status %s, error %q", cert.Status, cert.LastError)
+	}
+	if !strings.Contains(cert.DNSNames, domain) || cert.DomainMismatch {
+		t.Errorf("证书域名 = %q", cert.DNSNames)
+	}
+	if len(deployed) != 1 || deployed[0]["ssl_cert"] != cert.CertPEM || deployed[0]["ssl_key"] != cert.KeyPEM {
+		t.Fatalf("节点应收到一次携带新证书的 /api/proxy/add，实际 %d 次", len(deployed))
+	}
+
+	acmeChallengesMu.RLock()
+	left := len(acmeChallenges)
+	acmeChallengesMu.RUnlock()
+	if left != 0 {
+		t.Errorf("签发完成后应清理验证内容，剩余 %d 条", left)
+	}
+}
@@ -0,0 +1,485 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"lxdweb/config"
+	"lxdweb/database"
+	"lxdweb/models"
+	"strings"
+	"sync"
+	"time"
+)
+
+var alertMetricNames = map[string]string{
+	models.AlertMetricNodeDown:      "节点离线",
+	models.AlertMetricNodeDegraded:  "节点降级",
+	models.AlertMetricSyncFailed:    "同步失败",
+	models.AlertMetricTrafficUsage:  "流量使用率(%)",
+	models.AlertMetricDiskUsage:     "磁盘使用率(%)",
+	models.AlertMetricMemoryUsage:   "内存使用率(%)",
+	models.AlertMetricCPUUsage:      "CPU使用率(%)",
+	models.AlertMetricProxySSLError: "反向代理SSL异常",
+	models.AlertMetricCertDaysLeft:  "证书剩余天数",
+	models.AlertMetricCertInvalid:   "证书过期或域名不匹配",
+}
+
+var alertOperatorSymbols = map[string]string{
+	"gt":  ">",
+	"gte": ">=",
+	"lt":  "<",
+	"lte": "<=",
+	"eq":  "=",
+}
+
+// alertSample 单个监控对象的指标采样
+type alertSample struct {
+	Target   string
+	NodeID   uint
+	NodeName string
+	Hostname string
+	Detail   string
+	Value    float64
+}
+
+var alertEvalMutex sync.Mutex
+
+func StartAlertService() {
+	interval := time.Duration(config.AppConfig.Alert.EvalInterval) * time.Second
+	log.Printf("[ALERT] 告警评估服务启动，间隔 %v", interval)
+
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			EvaluateAlerts()
+		}
+	}()
+
+	cleanupTicker := time.NewTicker(1 * time.Hour)
+	go func() {
+		for range cleanupTicker.C {
+			cleanupAlertEvents()
+		}
+	}()
+}
+
+// EvaluateAlerts 评估所有启用的告警规则
+func EvaluateAlerts() {
+	alertEvalMutex.Lock()
+	defer alertEvalMutex.Unlock()
+
+	now := time.Now()
+
+	var rules []models.AlertRule
+	database.DB.Where("enabled = ?", true).Find(&rules)
+
+	var silences []models.AlertSilence
+	database.DB.Where("starts_at <= ? AND ends_at > ?", now, now).Find(&silences)
+
+	enabledIDs := make([]uint, 0, len(rules))
+	for _, rule := range rules {
+		enabledIDs = append(enabledIDs, rule.ID)
+		evaluateAlertRule(rule, silences, now)
+	}
+
+	// 规则被禁用或删除后，未恢复的事件直接关闭
+	query := database.DB.Model(&models.AlertEvent{}).
+		Where("status IN ?", []string{models.AlertStatusPending, models.AlertStatusFiring})
+	if len(enabledIDs) > 0 {
+		query = query.Where("rule_id NOT IN ?", enabledIDs)
+	}
+	query.Updates(map[string]interface{}{
+		"status":      models.AlertStatusResolved,
+		"resolved_at": now,
+	})
+}
+
+func evaluateAlertRule(rule models.AlertRule, silences []models.AlertSilence, now time.Time) {
+	samples, err := collectAlertSamples(rule)
+	if err != nil {
+		log.Printf("[ALERT] 规则 %s 采集指标失败: %v", rule.Name, err)
+		return
+	}
+
+	var openEvents []models.AlertEvent
+	database.DB.Where("rule_id = ? AND status IN ?", rule.ID,
+		[]string{models.AlertStatusPending, models.AlertStatusFiring}).Find(&openEvents)
+	open := make(map[string]*models.AlertEvent, len(openEvents))
+	for i := range openEvents {
+		open[openEvents[i].Target] = &openEvents[i]
+	}
+
+	seen := make(map[string]bool, len(samples))
+	for _, sample := range samples {
+		seen[sample.Target] = true
+		event, exists := open[sample.Target]
+
+		if !rule.Compare(sample.Value) {
+			if exists {
+				event.Value = sample.Value
+				resolveAlertEvent(rule, event, silences, now, true)
+			}
+			continue
+		}
+
+		if !exists {
+			event = &models.AlertEvent{
+				RuleID:    rule.ID,
+				RuleName:  rule.Name,
+				Metric:    rule.Metric,
+				Severity:  rule.Severity,
+				Target:    sample.Target,
+				NodeID:    sample.NodeID,
+				NodeName:  sample.NodeName,
+				Hostname:  sample.Hostname,
+				Status:    models.AlertStatusPending,
+				Threshold: rule.Threshold,
+				StartedAt: now,
+			}
+		}
+		event.Value = sample.Value
+		event.Message = alertEventMessage(rule, sample)
+
+		switch event.Status {
+		case models.AlertStatusPending:
+			if now.Sub(event.StartedAt) >= time.Duration(rule.Duration)*time.Second {
+				event.Status = models.AlertStatusFiring
+				event.FiredAt = &now
+				notifyAlertEvent(rule, event, silences, now)
+			}
+		case models.AlertStatusFiring:
+			if alertRepeatDue(rule, event, now) {
+				notifyAlertEvent(rule, event, silences, now)
+			}
+		}
+
+		database.DB.Save(event)
+	}
+
+	// 对象已消失（容器删除、节点进入维护等），关闭对应事件
+	for target, event := range open {
+		if seen[target] {
+			continue
+		}
+		resolveAlertEvent(rule, event, silences, now, !IsNodeInMaintenance(event.NodeID))
+	}
+}
+
+func resolveAlertEvent(rule models.AlertRule, event *models.AlertEvent, silences []models.AlertSilence, now time.Time, notify bool) {
+	wasNotified := event.Status == models.AlertStatusFiring && event.LastNotifiedAt != nil
+	event.Status = models.AlertStatusResolved
+	event.ResolvedAt = &now
+	if notify && wasNotified {
+		notifyAlertEvent(rule, event, silences, now)
+	}
+	database.DB.Save(event)
+}
+
+func alertRepeatDue(rule models.AlertRule, event *models.AlertEvent, now time.Time) bool {
+	if event.LastNotifiedAt == nil {
+		return true
+	}
+	repeat := rule.RepeatInterval
+	if repeat <= 0 {
+		repeat = config.AppConfig.Alert.RepeatInterval
+	}
+	return now.Sub(*event.LastNotifiedAt) >= time.Duration(repeat)*time.Minute
+}
+
+func isAlertSilenced(silences []models.AlertSilence, ruleID, nodeID uint, hostname string, at time.Time) bool {
+	for _, silence := range silences {
+		if silence.Matches(ruleID, nodeID, hostname, at) {
+			return true
+		}
+	}
+	return false
+}
+
+func notifyAlertEvent(rule models.AlertRule, event *models.AlertEvent, silences []models.AlertSilence, now time.Time) {
+	event.Silenced = isAlertSilenced(silences, rule.ID, event.NodeID, event.Hostname, now)
+	if event.Silenced {
+		return
+	}
+
+	errs := dispatchAlert(rule.ChannelIDList(), buildAlertMessage(rule, event, now))
+	event.NotifyCount++
+	event.LastNotifiedAt = &now
+	event.NotifyError = strings.Join(errs, "; ")
+}
+
+// dispatchAlert 向多个渠道发送告警，返回失败信息
+func dispatchAlert(channelIDs []uint, msg AlertMessage) []string {
+	if len(channelIDs) == 0 {
+		return nil
+	}
+
+	var channels []models.AlertChannel
+	database.DB.Where("id IN ? AND enabled = ?", channelIDs, true).Find(&channels)
+
+	var errs []string
+	for _, ch := range channels {
+		if err := SendAlertToChannel(ch, msg); err != nil {
+			log.Printf("[ALERT] 渠道 %s 发送失败: %v", ch.Name, err)
+			errs = append(errs, fmt.Sprintf("%s: %v", ch.Name, err))
+		}
+	}
+	return errs
+}
+
+// SendAlertToChannel 通过指定渠道发送告警消息
+func SendAlertToChannel(ch models.AlertChannel, msg AlertMessage) error {
+	notifier, err := NewAlertNotifier(ch)
+	if err != nil {
+		return err
+	}
+	return notifier.Send(msg)
+}
+
+func alertTargetLabel(nodeName, hostname string) string {
+	if hostname == "" {
+		return nodeName
+	}
+	return nodeName + "/" + hostname
+}
+
+func alertEventMessage(rule models.AlertRule, sample alertSample) string {
+	msg := fmt.Sprintf("%s %s 当前值 %.2f，阈值 %s %.2f",
+		alertTargetLabel(sample.NodeName, sample.Hostname),
+		alertMetricNames[rule.Metric], sample.Value,
+		alertOperatorSymbols[rule.Operator], rule.Threshold)
+	if sample.Detail != "" {
+		msg += "（" + sample.Detail + "）"
+	}
+	return msg
+}
+
+func buildAlertMessage(rule models.AlertRule, event *models.AlertEvent, now time.Time) AlertMessage {
+	status := strings.ToUpper(event.Status)
+	target := alertTargetLabel(event.NodeName, event.Hostname)
+
+	lines := []string{
+		"规则: " + rule.Name,
+		"指标: " + alertMetricNames[rule.Metric],
+		"对象: " + target,
+		fmt.Sprintf("当前值: %.2f (阈值 %s %.2f)", event.Value, alertOperatorSymbols[rule.Operator], rule.Threshold),
+		"开始时间: " + event.StartedAt.Format("2006-01-02 15:04:05"),
+	}
+	if event.ResolvedAt != nil {
+		lines = append(lines, "恢复时间: "+event.ResolvedAt.Format("2006-01-02 15:04:05"))
+	}
+	if event.Message != "" {
+		lines = append(lines, "详情: "+event.Message)
+	}
+
+	return AlertMessage{
+		Title:     fmt.Sprintf("[%s][%s] %s - %s", status, event.Severity, rule.Name, target),
+		Text:      strings.Join(lines, "\n"),
+		Status:    event.Status,
+		Severity:  event.Severity,
+		RuleID:    rule.ID,
+		RuleName:  rule.Name,
+		Metric:    rule.Metric,
+		NodeID:    event.NodeID,
+		NodeName:  event.NodeName,
+		Hostname:  event.Hostname,
+		Value:     event.Value,
+		Threshold: rule.Threshold,
+		StartedAt: event.StartedAt,
+		Time:      now,
+	}
+}
+
+// collectAlertSamples 按规则指标从缓存数据中采集样本，维护中的节点不参与告警
+func collectAlertSamples(rule models.AlertRule) ([]alertSample, error) {
+	query := database.DB.Where("maintenance = ?", false)
+	if rule.NodeID != 0 {
+		query = query.Where("id = ?", rule.NodeID)
+	}
+	if rule.NodeGroup != "" {
+		query = query.Where("node_group = ?", rule.NodeGroup)
+	}
+	var nodes []models.Node
+	if err := query.Find(&nodes).Error; err != nil {
+		return nil, err
+	}
+	if len(nodes) == 0 {
+		return nil, nil
+	}
+
+	nodeIDs := make([]uint, 0, len(nodes))
+	nodeMap := make(map[uint]models.Node, len(nodes))
+	for _, node := range nodes {
+		nodeIDs = append(nodeIDs, node.ID)
+		nodeMap[node.ID] = node
+	}
+
+	var samples []alertSample
+	switch rule.Metric {
+	case models.AlertMetricNodeDown, models.AlertMetricNodeDegraded:
+		status := models.NodeStatusDown
+		if rule.Metric == models.AlertMetricNodeDegraded {
+			status = models.NodeStatusDegraded
+		}
+		for _, node := range nodes {
+			value := 0.0
+			if node.Status == status {
+				value = 1
+			}
+			samples = append(samples, nodeAlertSample(node, value, ""))
+		}
+
+	case models.AlertMetricSyncFailed:
+		for _, node := range nodes {
+			failed := failedSyncKinds(node.ID)
+			samples = append(samples, nodeAlertSample(node, float64(len(failed)), strings.Join(failed, ",")))
+		}
+
+	case models.AlertMetricTrafficUsage, models.AlertMetricDiskUsage,
+		models.AlertMetricMemoryUsage, models.AlertMetricCPUUsage:
+		var containers []models.ContainerCache
+		database.DB.Where("node_id IN ?", nodeIDs).Find(&containers)
+		for _, c := range containers {
+			value, ok := containerUsagePercent(rule.Metric, c)
+			if !ok {
+				continue
+			}
+			samples = append(samples, alertSample{
+				Target:   fmt.Sprintf("container:%d:%s", c.NodeID, c.Hostname),
+				NodeID:   c.NodeID,
+				NodeName: nodeMap[c.NodeID].Name,
+				Hostname: c.Hostname,
+				Value:    value,
+			})
+		}
+
+	case models.AlertMetricProxySSLError:
+		var proxies []models.ProxyConfigCache
+		database.DB.Where("node_id IN ? AND ssl_enabled = ?", nodeIDs, true).Find(&proxies)
+		for _, p := range proxies {
+			value := 0.0
+			if p.SyncError != "" || p.Status == "error" {
+				value = 1
+			}
+			samples = append(samples, alertSample{
+				Target:   fmt.Sprintf("proxy:%d:%s", p.NodeID, p.Domain),
+				NodeID:   p.NodeID,
+				NodeName: nodeMap[p.NodeID].Name,
+				Hostname: p.Hostname,
+				Detail:   p.Domain,
+				Value:    value,
+			})
+		}
+
+	case models.AlertMetricCertDaysLeft, models.AlertMetricCertInvalid:
+		var certs []models.ProxyCertificate
+		database.DB.Joins("JOIN proxy_config_caches ON proxy_config_caches.node_id = proxy_certificates.node_id AND proxy_config_caches.domain = proxy_certificates.domain").
+			Where("proxy_certificates.node_id IN ? AND proxy_config_caches.ssl_enabled = ? AND proxy_config_caches.deleted_at IS NULL", nodeIDs, true).
+			Find(&certs)
+		now := time.Now()
+		for _, cert := range certs {
+			sample := alertSample{
+				Target:   fmt.Sprintf("cert:%d:%s", cert.NodeID, cert.Domain),
+				NodeID:   cert.NodeID,
+				NodeName: nodeMap[cert.NodeID].Name,
+				Hostname: cert.Hostname,
+			}
+			if rule.Metric == models.AlertMetricCertDaysLeft {
+				if cert.NotAfter == nil {
+					continue
+				}
+				sample.Value = float64(CertificateDaysLeft(*cert.NotAfter, now))
+				sample.Detail = fmt.Sprintf("%s 到期 %s", cert.Domain, cert.NotAfter.Format("2006-01-02"))
+			} else {
+				var reasons []string
+				if cert.NotAfter != nil && now.After(*cert.NotAfter) {
+					reasons = append(reasons, "已过期")
+				}
+				if cert.DomainMismatch {
+					reasons = append(reasons, "域名不匹配")
+				}
+				sample.Detail = cert.Domain
+				if len(reasons) > 0 {
+					sample.Value = 1
+					sample.Detail += " " + strings.Join(reasons, ",")
+				}
+			}
+			samples = append(samples, sample)
+		}
+
+	default:
+		return nil, fmt.Errorf("未知指标: %s", rule.Metric)
+	}
+
+	return samples, nil
+}
+
+func nodeAlertSample(node models.Node, value float64, detail string) alertSample {
+	return alertSample{
+		Target:   fmt.Sprintf("node:%d", node.ID),
+		NodeID:   node.ID,
+		NodeName: node.Name,
+		Detail:   detail,
+		Value:    value,
+	}
+}
+
+// containerUsagePercent 计算容器资源使用率，缺少总量时返回 false
+func containerUsagePercent(metric string, c models.ContainerCache) (float64, bool) {
+	switch metric {
+	case models.AlertMetricTrafficUsage:
+		if c.TrafficLimit <= 0 {
+			return 0, false
+		}
+		limit := float64(c.TrafficLimit) * 1024 * 1024 * 1024
+		return float64(c.TrafficTotal) * 100 / limit, true
+	case models.AlertMetricDiskUsage:
+		if c.DiskTotal == 0 {
+			return 0, false
+		}
+		return float64(c.DiskUsage) * 100 / float64(c.DiskTotal), true
+	case models.AlertMetricMemoryUsage:
+		if c.MemoryTotal == 0 {
+			return 0, false
+		}
+		return float64(c.MemoryUsage) * 100 / float64(c.MemoryTotal), true
+	case models.AlertMetricCPUUsage:
+		return c.CPUUsage, true
+	}
+	return 0, false
+}
+
+// failedSyncKinds 返回节点最近一次同步失败的任务类型
+func failedSyncKinds(nodeID uint) []string {
+	kinds := []struct {
+		name  string
+		model interface{}
+	}{
+		{"container", &models.SyncTask{}},
+		{"nat", &models.NATSyncTask{}},
+		{"ipv6", &models.IPv6SyncTask{}},
+		{"ipv4", &models.IPv4SyncTask{}},
+		{"proxy", &models.ProxySyncTask{}},
+	}
+
+	var failed []string
+	for _, kind := range kinds {
+		var statuses []string
+		database.DB.Model(kind.model).
+			Where("node_id = ? AND status IN ?", nodeID, []string{"completed", "failed"}).
+			Order("id desc").
+			Limit(1).
+			Pluck("status", &statuses)
+		if len(statuses) > 0 && statuses[0] == "failed" {
+			failed = append(failed, kind.name)
+		}
+	}
+	return failed
+}
+
+func cleanupAlertEvents() {
+	cutoff := time.Now().AddDate(0, 0, -config.AppConfig.Alert.RetentionDays)
+	result := database.DB.Where("status = ? AND resolved_at < ?", models.AlertStatusResolved, cutoff).Delete(&models.AlertEvent{})
+	if result.RowsAffected > 0 {
+		log.Printf("[ALERT] 清理过期告警事件 %d 条", result.RowsAffected)
+	}
+}
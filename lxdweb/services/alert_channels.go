@@ -0,0 +1,229 @@
+package services
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"log"
+	"lxdweb/config"
+	"lxdweb/models"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// AlertMessage 告警通知内容
+type AlertMessage struct {
+	Title     string    `json:"title"`
+	Text      string    `json:"text"`
+	Status    string    `json:"status"`
+	Severity  string    `json:"severity"`
+	RuleID    uint      `json:"rule_id"`
+	RuleName  string    `json:"rule_name"`
+	Metric    string    `json:"metric"`
+	NodeID    uint      `json:"node_id"`
+	NodeName  string    `json:"node_name"`
+	Hostname  string    `json:"hostname"`
+	Value     float64   `json:"value"`
+	Threshold float64   `json:"threshold"`
+	StartedAt time.Time `json:"started_at"`
+	Time      time.Time `json:"time"`
+}
+
+// AlertNotifier 告警通知渠道
+type AlertNotifier interface {
+	Send(msg AlertMessage) error
+}
+
+// NewAlertNotifier 根据渠道类型创建通知器
+func NewAlertNotifier(ch models.AlertChannel) (AlertNotifier, error) {
+	var cfg models.AlertChannelConfig
+	if ch.Config != "" {
+		if err := json.Unmarshal([]byte(ch.Config), &cfg); err != nil {
+			return nil, fmt.Errorf("渠道配置解析失败: %v", err)
+		}
+	}
+
+	switch ch.Type {
+	case "webhook":
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("webhook 渠道未配置 url")
+		}
+		return &webhookNotifier{cfg: cfg}, nil
+	case "smtp":
+		if cfg.Host == "" || cfg.From == "" || len(cfg.To) == 0 {
+			return nil, fmt.Errorf("smtp 渠道需要配置 host、from 和 to")
+		}
+		return &smtpNotifier{cfg: cfg}, nil
+	case "telegram":
+		if cfg.BotToken == "" || cfg.ChatID == "" {
+			return nil, fmt.Errorf("telegram 渠道需要配置 bot_token 和 chat_id")
+		}
+		return &telegramNotifier{cfg: cfg}, nil
+	case "log":
+		return &logNotifier{name: ch.Name}, nil
+	}
+	return nil, fmt.Errorf("不支持的渠道类型: %s", ch.Type)
+}
+
+// alertHTTPClient 默认校验证书，仅在渠道显式配置 insecure_skip_verify 时跳过
+func alertHTTPClient(insecure bool) *http.Client {
+	client := &http.Client{
+		Timeout: time.Duration(config.AppConfig.Alert.NotifyTimeout) * time.Second,
+	}
+	if insecure {
+		client.Transport = &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}
+	}
+	return client
+}
+
+func postJSON(client *http.Client, url string, headers map[string]string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// webhookNotifier 以 JSON 形式 POST 告警内容到指定地址
+type webhookNotifier struct {
+	cfg models.AlertChannelConfig
+}
+
+func (n *webhookNotifier) Send(msg AlertMessage) error {
+	return postJSON(alertHTTPClient(n.cfg.InsecureSkipVerify), n.cfg.URL, n.cfg.Headers, msg)
+}
+
+// smtpNotifier 通过 SMTP 发送告警邮件，tls 为 true 时使用 SMTPS 直连
+type smtpNotifier struct {
+	cfg models.AlertChannelConfig
+}
+
+func (n *smtpNotifier) Send(msg AlertMessage) error {
+	port := n.cfg.Port
+	if port == 0 {
+		port = 25
+	}
+	addr := net.JoinHostPort(n.cfg.Host, fmt.Sprintf("%d", port))
+
+	// 标题可能包含容器名等外部输入，去掉换行防止注入邮件头
+	subject := strings.NewReplacer("\r", " ", "\n", " ").Replace(msg.Title)
+
+	var body strings.Builder
+	body.WriteString("From: " + n.cfg.From + "\r\n")
+	body.WriteString("To: " + strings.Join(n.cfg.To, ", ") + "\r\n")
+	body.WriteString("Subject: " + subject + "\r\n")
+	body.WriteString("MIME-Version: 1.0\r\n")
+	body.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	body.WriteString("\r\n")
+	body.WriteString(msg.Text + "\r\n")
+
+	var auth smtp.Auth
+	if n.cfg.Username != "" {
+		auth = smtp.PlainAuth("", n.cfg.Username, n.cfg.Password, n.cfg.Host)
+	}
+
+	// 告警评估持有 alertEvalMutex 时发送，连接和读写都必须有超时，避免一个无响应的 SMTP 服务阻塞所有告警
+	timeout := time.Duration(config.AppConfig.Alert.NotifyTimeout) * time.Second
+	var conn net.Conn
+	var err error
+	if n.cfg.TLS {
+		conn, err = tls.DialWithDialer(&net.Dialer{Timeout: timeout}, "tcp", addr, &tls.Config{ServerName: n.cfg.Host})
+	} else {
+		conn, err = net.DialTimeout("tcp", addr, timeout)
+	}
+	if err != nil {
+		return err
+	}
+	if timeout > 0 {
+		conn.SetDeadline(time.Now().Add(timeout))
+	}
+	client, err := smtp.NewClient(conn, n.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	// 与 smtp.SendMail 一致，明文连接上服务端支持时升级到 STARTTLS
+	if !n.cfg.TLS {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(&tls.Config{ServerName: n.cfg.Host}); err != nil {
+				return err
+			}
+		}
+	}
+	if auth != nil {
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(n.cfg.From); err != nil {
+		return err
+	}
+	for _, to := range n.cfg.To {
+		if err := client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write([]byte(body.String())); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// telegramNotifier 通过 Bot API 的 sendMessage 发送告警，api_base 可指向兼容的本地服务
+type telegramNotifier struct {
+	cfg models.AlertChannelConfig
+}
+
+func (n *telegramNotifier) Send(msg AlertMessage) error {
+	apiBase := strings.TrimRight(n.cfg.APIBase, "/")
+	if apiBase == "" {
+		apiBase = "https://api.telegram.org"
+	}
+	return postJSON(alertHTTPClient(n.cfg.InsecureSkipVerify), fmt.Sprintf("%s/bot%s/sendMessage", apiBase, n.cfg.BotToken), nil, map[string]interface{}{
+		"chat_id": n.cfg.ChatID,
+		"text":    msg.Title + "\n\n" + msg.Text,
+	})
+}
+
+// logNotifier 仅写入日志，用于本地调试
+type logNotifier struct {
+	name string
+}
+
+func (n *logNotifier) Send(msg AlertMessage) error {
+	log.Printf("[ALERT] [%s] %s | %s", n.name, msg.Title, strings.ReplaceAll(msg.Text, "\n", " | "))
+	return nil
+}
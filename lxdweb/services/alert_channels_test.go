@@ -0,0 +1,283 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"lxdweb/config"
+	"lxdweb/models"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingServer 记录收到的请求，作为告警渠道的本地替身
+type recordingServer struct {
+	mu      sync.Mutex
+	paths   []string
+	headers []http.Header
+	bodies  [][]byte
+	status  int
+	server  *httptest.Server
+}
+
+func newRecordingServer(t *testing.T, tlsServer bool) *recordingServer {
+	t.Helper()
+	rs := &recordingServer{status: http.StatusOK}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		rs.mu.Lock()
+		rs.paths = append(rs.paths, r.URL.Path)
+		rs.headers = append(rs.headers, r.Header.Clone())
+		rs.bodies = append(rs.bodies, body)
+		status := rs.status
+		rs.mu.Unlock()
+		w.WriteHeader(status)
+	})
+	if tlsServer {
+		rs.server = httptest.NewTLSServer(handler)
+	} else {
+		rs.server = httptest.NewServer(handler)
+	}
+	t.Cleanup(rs.server.Close)
+	return rs
+}
+
+func (rs *recordingServer) count() int {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	return len(rs.bodies)
+}
+
+func (rs *recordingServer) body(i int) []byte {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	return rs.bodies[i]
+}
+
+func setupAlertConfig(t *testing.T) {
+	t.Helper()
+	old := config.AppConfig
+	config.AppConfig = &config.Config{}
+	config.AppConfig.Alert.NotifyTimeout = 5
+	config.AppConfig.Alert.RepeatInterval = 240
+	t.Cleanup(func() { config.AppConfig = old })
+}
+
+func alertChannel(t *testing.T, typ string, cfg models.AlertChannelConfig) models.AlertChannel {
+	t.Helper()
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return models.AlertChannel{Name: typ, Type: typ, Config: string(data), Enabled: true}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	setupAlertConfig(t)
+	rs := newRecordingServer(t, false)
+
+	ch := alertChannel(t, "webhook", models.AlertChannelConfig{
+		URL:     rs.server.URL + "/alert",
+		Headers: map[string]string{"Authorization": "Bearer test"},
+	})
+	msg := AlertMessage{Title: "标题", Text: "内容", Status: models.AlertStatusFiring, RuleID: 7}
+	if err := SendAlertToChannel(ch, msg); err != nil {
+		t.Fatalf("发送失败: %v", err)
+	}
+
+	if rs.count() != 1 {
+		t.Fatalf("期望收到 1 个请求，实际 %d", rs.count())
+	}
+	if rs.paths[0] != "/alert" {
+		t.Errorf("请求路径 %s", rs.paths[0])
+	}
+	if got := rs.headers[0].Get("Authorization"); got != "Bearer test" {
+		t.Errorf("自定义请求头 Authorization = %q", got)
+	}
+	if got := rs.headers[0].Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	var got AlertMessage
+	if err := json.Unmarshal(rs.body(0), &got); err != nil {
+		t.Fatalf("请求体不是 JSON: %v", err)
+	}
+	if got.Title != msg.Title || got.Status != msg.Status || got.RuleID != msg.RuleID {
+		t.Errorf("请求体 = %+v", got)
+	}
+
+	rs.status = http.StatusInternalServerError
+	if err := SendAlertToChannel(ch, msg); err == nil {
+		t.Error("非 2xx 响应应返回错误")
+	}
+}
+
+func TestWebhookNotifierVerifiesTLS(t *testing.T) {
+	setupAlertConfig(t)
+	rs := newRecordingServer(t, true)
+	msg := AlertMessage{Title: "标题"}
+
+	ch := alertChannel(t, "webhook", models.AlertChannelConfig{URL: rs.server.URL})
+	if err := SendAlertToChannel(ch, msg); err == nil {
+		t.Fatal("默认应校验证书，自签名证书应发送失败")
+	}
+	if rs.count() != 0 {
+		t.Fatal("证书校验失败时不应发出请求")
+	}
+
+	ch = alertChannel(t, "webhook", models.AlertChannelConfig{URL: rs.server.URL, InsecureSkipVerify: true})
+	if err := SendAlertToChannel(ch, msg); err != nil {
+		t.Fatalf("显式跳过校验后应发送成功: %v", err)
+	}
+	if rs.count() != 1 {
+		t.Fatalf("期望收到 1 个请求，实际 %d", rs.count())
+	}
+}
+
+func TestTelegramNotifier(t *testing.T) {
+	setupAlertConfig(t)
+	rs := newRecordingServer(t, false)
+
+	ch := alertChannel(t, "telegram", models.AlertChannelConfig{
+		APIBase:  rs.server.URL + "/",
+		BotToken: "123456:ABC",
+		ChatID:   "-100123",
+	})
+	if err := SendAlertToChannel(ch, AlertMessage{Title: "标题", Text: "内容"}); err != nil {
+		t.Fatalf("发送失败: %v", err)
+	}
+
+	if rs.count() != 1 {
+		t.Fatalf("期望收到 1 个请求，实际 %d", rs.count())
+	}
+	if rs.paths[0] != "/bot123456:ABC/sendMessage" {
+		t.Errorf("请求路径 %s", rs.paths[0])
+	}
+	var got map[string]string
+	if err := json.Unmarshal(rs.body(0), &got); err != nil {
+		t.Fatalf("请求体不是 JSON: %v", err)
+	}
+	if got["chat_id"] != "-100123" || got["text"] != "标题\n\n内容" {
+		t.Errorf("请求体 = %v", got)
+	}
+}
+
+// fakeSMTPServer 实现发送邮件所需的最少 SMTP 命令，记录 DATA 内容；hang 为 true 时接受连接后不再响应
+func fakeSMTPServer(t *testing.T, hang bool) (host string, port int, data <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	ch := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if hang {
+			io.Copy(io.Discard, conn)
+			return
+		}
+		r := bufio.NewReader(conn)
+		reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 localhost")
+			case cmd == "DATA":
+				reply("354 go ahead")
+				var msg strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if l == ".\r\n" {
+						break
+					}
+					msg.WriteString(l)
+				}
+				ch <- msg.String()
+				reply("250 ok")
+			case cmd == "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+	addr := ln.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port, ch
+}
+
+func TestSMTPNotifier(t *testing.T) {
+	setupAlertConfig(t)
+	host, port, data := fakeSMTPServer(t, false)
+
+	ch := alertChannel(t, "smtp", models.AlertChannelConfig{
+		Host: host,
+		Port: port,
+		From: "alert@example.com",
+		To:   []string{"ops@example.com"},
+	})
+	msg := AlertMessage{Title: "容器 web\r\nBcc: evil@example.com 告警", Text: "内容"}
+	if err := SendAlertToChannel(ch, msg); err != nil {
+		t.Fatalf("发送失败: %v", err)
+	}
+
+	got := <-data
+	if strings.Contains(got, "\r\nBcc:") {
+		t.Fatalf("标题中的换行应被去掉，邮件内容:\n%s", got)
+	}
+	if !strings.Contains(got, "Subject: 容器 web  Bcc: evil@example.com 告警\r\n") {
+		t.Errorf("Subject 头不正确，邮件内容:\n%s", got)
+	}
+}
+
+func TestSMTPNotifierTimeout(t *testing.T) {
+	setupAlertConfig(t)
+	config.AppConfig.Alert.NotifyTimeout = 1
+	host, port, _ := fakeSMTPServer(t, true)
+
+	ch := alertChannel(t, "smtp", models.AlertChannelConfig{
+		Host: host,
+		Port: port,
+		From: "alert@example.com",
+		To:   []string{"ops@example.com"},
+	})
+	start := time.Now()
+	if err := SendAlertToChannel(ch, AlertMessage{Title: "标题"}); err == nil {
+		t.Fatal("SMTP 服务无响应时应返回错误")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("无响应的 SMTP 服务阻塞了 %s，应在超时后返回", elapsed)
+	}
+}
+
+func TestNewAlertNotifierValidation(t *testing.T) {
+	for _, ch := range []models.AlertChannel{
+		{Type: "webhook", Config: `{}`},
+		{Type: "smtp", Config: `{"host":"127.0.0.1"}`},
+		{Type: "telegram", Config: `{"bot_token":"x"}`},
+		{Type: "unknown"},
+		{Type: "webhook", Config: `{`},
+	} {
+		if _, err := NewAlertNotifier(ch); err == nil {
+			t.Errorf("渠道 %s 配置 %q 应返回错误", ch.Type, ch.Config)
+		}
+	}
+}
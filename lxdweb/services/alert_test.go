@@ -0,0 +1,192 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"lxdweb/database"
+	"lxdweb/models"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	_ "modernc.org/sqlite"
+)
+
+// setupTestDB 使用内存数据库替换 database.DB，并迁移给定的模型
+func setupTestDB(t *testing.T, tables ...interface{}) {
+	t.Helper()
+	sqlDB, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 内存库每个连接相互独立，只保留一个连接
+	sqlDB.SetMaxOpenConns(1)
+	db, err := gorm.Open(sqlite.Dialector{Conn: sqlDB}, &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(tables...); err != nil {
+		t.Fatal(err)
+	}
+
+	old := database.DB
+	database.DB = db
+	t.Cleanup(func() {
+		database.DB = old
+		sqlDB.Close()
+	})
+}
+
+func setupAlertRule(t *testing.T, rs *recordingServer) (models.Node, models.AlertRule) {
+	t.Helper()
+	setupAlertConfig(t)
+	setupTestDB(t, &models.Node{}, &models.AlertRule{}, &models.AlertChannel{},
+		&models.AlertEvent{}, &models.AlertSilence{})
+
+	node := models.Node{Name: "node-1", Address: "https://127.0.0.1:8443", Status: models.NodeStatusDown}
+	database.DB.Create(&node)
+
+	ch := alertChannel(t, "webhook", models.AlertChannelConfig{URL: rs.server.URL})
+	database.DB.Create(&ch)
+
+	rule := models.AlertRule{
+		Name:       "节点离线",
+		Metric:     models.AlertMetricNodeDown,
+		Operator:   "gte",
+		Threshold:  1,
+		Severity:   "critical",
+		ChannelIDs: fmt.Sprint(ch.ID),
+		Enabled:    true,
+	}
+	database.DB.Create(&rule)
+	return node, rule
+}
+
+func lastAlertMessage(t *testing.T, rs *recordingServer) AlertMessage {
+	t.Helper()
+	var msg AlertMessage
+	if err := json.Unmarshal(rs.body(rs.count()-1), &msg); err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+func TestEvaluateAlertsFireAndResolve(t *testing.T) {
+	rs := newRecordingServer(t, false)
+	node, rule := setupAlertRule(t, rs)
+
+	EvaluateAlerts()
+	if rs.count() != 1 {
+		t.Fatalf("节点离线应发送 1 条告警，实际 %d", rs.count())
+	}
+	msg := lastAlertMessage(t, rs)
+	if msg.Status != models.AlertStatusFiring || msg.RuleID != rule.ID || msg.NodeID != node.ID {
+		t.Errorf("告警内容 = %+v", msg)
+	}
+
+	var event models.AlertEvent
+	database.DB.Where("rule_id = ?", rule.ID).First(&event)
+	if event.Status != models.AlertStatusFiring || event.NotifyCount != 1 || event.NotifyError != "" {
+		t.Errorf("告警事件 = %+v", event)
+	}
+
+	// 未到重复间隔不再发送
+	EvaluateAlerts()
+	if rs.count() != 1 {
+		t.Fatalf("重复间隔内不应再次发送，实际 %d", rs.count())
+	}
+
+	database.DB.Model(&node).Update("status", models.NodeStatusActive)
+	EvaluateAlerts()
+	if rs.count() != 2 {
+		t.Fatalf("恢复时应发送通知，实际 %d", rs.count())
+	}
+	if msg := lastAlertMessage(t, rs); msg.Status != models.AlertStatusResolved {
+		t.Errorf("恢复通知状态 = %s", msg.Status)
+	}
+	database.DB.First(&event, event.ID)
+	if event.Status != models.AlertStatusResolved || event.ResolvedAt == nil {
+		t.Errorf("告警事件 = %+v", event)
+	}
+}
+
+func TestEvaluateAlertsPendingDuration(t *testing.T) {
+	rs := newRecordingServer(t, false)
+	_, rule := setupAlertRule(t, rs)
+	database.DB.Model(&rule).Update("duration", 600)
+
+	EvaluateAlerts()
+	if rs.count() != 0 {
+		t.Fatalf("持续时间未到不应发送，实际 %d", rs.count())
+	}
+	var event models.AlertEvent
+	database.DB.Where("rule_id = ?", rule.ID).First(&event)
+	if event.Status != models.AlertStatusPending {
+		t.Fatalf("告警事件状态 = %s，期望 pending", event.Status)
+	}
+
+	database.DB.Model(&event).Update("started_at", time.Now().Add(-11*time.Minute))
+	EvaluateAlerts()
+	if rs.count() != 1 {
+		t.Fatalf("持续时间已到应发送告警，实际 %d", rs.count())
+	}
+}
+
+func TestEvaluateAlertsSilenced(t *testing.T) {
+	rs := newRecordingServer(t, false)
+	node, rule := setupAlertRule(t, rs)
+	database.DB.Create(&models.AlertSilence{
+		NodeID:   node.ID,
+		StartsAt: time.Now().Add(-time.Minute),
+		EndsAt:   time.Now().Add(time.Hour),
+	})
+
+	EvaluateAlerts()
+	if rs.count() != 0 {
+		t.Fatalf("静默期内不应发送，实际 %d", rs.count())
+	}
+	var event models.AlertEvent
+	database.DB.Where("rule_id = ?", rule.ID).First(&event)
+	if event.Status != models.AlertStatusFiring || !event.Silenced {
+		t.Errorf("告警事件 = %+v", event)
+	}
+}
+
+func TestEvaluateAlertsSkipsMaintenance(t *testing.T) {
+	rs := newRecordingServer(t, false)
+	node, _ := setupAlertRule(t, rs)
+	database.DB.Model(&node).Update("maintenance", true)
+
+	EvaluateAlerts()
+	if rs.count() != 0 {
+		t.Fatalf("维护中的节点不应告警，实际 %d", rs.count())
+	}
+}
+
+func TestAlertRuleCompare(t *testing.T) {
+	tests := []struct {
+		op    string
+		value float64
+		want  bool
+	}{
+		{"gt", 80, false},
+		{"gt", 81, true},
+		{"gte", 80, true},
+		{"", 80, true},
+		{"lt", 79, true},
+		{"lte", 80, true},
+		{"lte", 81, false},
+		{"eq", 80, true},
+	}
+	for _, tt := range tests {
+		rule := models.AlertRule{Operator: tt.op, Threshold: 80}
+		if got := rule.Compare(tt.value); got != tt.want {
+			t.Errorf("Compare(%q, %v) = %v，期望 %v", tt.op, tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"encoding/json"
+	"log"
+	"lxdweb/database"
+	"lxdweb/models"
+)
+
+// AuditEntry 审计日志记录参数
+type AuditEntry struct {
+	AdminID       uint
+	OperationType string
+	TargetType    string
+	TargetID      uint
+	Details       interface{}
+	IPAddress     string
+	Err           error
+}
+
+// RecordOperation 写入操作审计日志，Details 会被序列化为 JSON
+func RecordOperation(entry AuditEntry) {
+	opLog := models.OperationLog{
+		AdminID:       entry.AdminID,
+		OperationType: entry.OperationType,
+		TargetType:    entry.TargetType,
+		TargetID:      entry.TargetID,
+		IPAddress:     entry.IPAddress,
+		Status:        "success",
+	}
+
+	switch v := entry.Details.(type) {
+	case nil:
+	case string:
+		opLog.Details = v
+	default:
+		if data, err := json.Marshal(v); err == nil {
+			opLog.Details = string(data)
+		}
+	}
+
+	if entry.Err != nil {
+		opLog.Status = "failed"
+		opLog.ErrorMessage = entry.Err.Error()
+	}
+
+	if err := database.DB.Create(&opLog).Error; err != nil {
+		log.Printf("[AUDIT] 写入审计日志失败: %s %s#%d: %v", entry.OperationType, entry.TargetType, entry.TargetID, err)
+	}
+}
@@ -16,7 +16,7 @@ func SyncAllNodesFullAsync() {
 	log.Println("[AUTO-SYNC] 开始执行完整实时同步任务")
 
 	var nodes []models.Node
-	if err := database.DB.Where("status = ?", "active").Find(&nodes).Error; err != nil {
+	if err := database.DB.Where("status IN ? AND maintenance = ?", models.OnlineNodeStatuses, false).Find(&nodes).Error; err != nil {
 		log.Printf("[AUTO-SYNC] 查询节点失败: %v", err)
 		return
 	}
@@ -28,6 +28,32 @@ func SyncAllNodesFullAsync() {
 
 	log.Printf("[AUTO-SYNC] 找到 %d 个活跃节点，开始实时同步", len(nodes))
 
+	syncNodesFull(nodes)
+
+	log.Println("[AUTO-SYNC] 所有节点完整同步任务完成")
+}
+
+// SyncNodeGroupFullAsync 完整同步符合筛选条件的在线节点
+func SyncNodeGroupFullAsync(sel NodeSelector) {
+	nodes, err := FindNodes(sel, true)
+	if err != nil {
+		log.Printf("[AUTO-SYNC] 查询节点失败: %v", err)
+		return
+	}
+
+	if len(nodes) == 0 {
+		log.Printf("[AUTO-SYNC] 没有符合条件的活跃节点需要同步 (%s)", sel.String())
+		return
+	}
+
+	log.Printf("[AUTO-SYNC] 节点组 (%s) 共 %d 个活跃节点，开始实时同步", sel.String(), len(nodes))
+
+	syncNodesFull(nodes)
+
+	log.Printf("[AUTO-SYNC] 节点组 (%s) 完整同步任务完成", sel.String())
+}
+
+func syncNodesFull(nodes []models.Node) {
 	for i, node := range nodes {
 		log.Printf("[AUTO-SYNC] 处理节点 %d/%d: %s", i+1, len(nodes), node.Name)
 		syncNodeFull(node)
@@ -38,8 +64,6 @@ func SyncAllNodesFullAsync() {
 			time.Sleep(interval)
 		}
 	}
-
-	log.Println("[AUTO-SYNC] 所有节点完整同步任务完成")
 }
 
 // syncNodeFull 完整同步单个节点的所有数据类型
@@ -64,6 +88,12 @@ func syncNodeFull(n models.Node) {
 
 	time.Sleep(1 * time.Second)
 
+	if err := SyncNodeIPv4Bindings(n.ID); err != nil {
+		log.Printf("[AUTO-SYNC] 节点 %s IPv4绑定同步失败: %v", n.Name, err)
+	}
+
+	time.Sleep(1 * time.Second)
+
 	if err := SyncNodeProxyConfigs(n.ID); err != nil {
 		log.Printf("[AUTO-SYNC] 节点 %s Proxy配置同步失败: %v", n.Name, err)
 	}
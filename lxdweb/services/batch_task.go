@@ -0,0 +1,196 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"lxdweb/database"
+	"lxdweb/models"
+	"sync"
+	"time"
+)
+
+// BatchTarget 批量操作的目标容器
+type BatchTarget struct {
+	Node     models.Node
+	Hostname string
+}
+
+// BatchFunc 对单个目标执行操作，返回是否成功及说明
+type BatchFunc func(t BatchTarget) (bool, string)
+
+// BatchOutputFunc 与 BatchFunc 相同，额外返回命令输出，用于远程执行
+type BatchOutputFunc func(t BatchTarget) (bool, string, *models.ExecOutput)
+
+// withOutput 将 BatchFunc 适配为不返回输出的 BatchOutputFunc
+func (fn BatchFunc) withOutput() BatchOutputFunc {
+	return func(t BatchTarget) (bool, string, *models.ExecOutput) {
+		ok, msg := fn(t)
+		return ok, msg, nil
+	}
+}
+
+const batchConcurrency = 5
+
+// ContainerActionPaths 容器电源操作对应的节点接口
+var ContainerActionPaths = map[string]string{
+	"start":     "/api/boot",
+	"stop":      "/api/stop",
+	"restart":   "/api/reboot",
+	"suspend":   "/api/suspend",
+	"unsuspend": "/api/unsuspend",
+}
+
+// ResolveBatchTargets 根据节点筛选条件、节点ID和容器名从缓存解析目标容器，
+// 维护中的节点不参与，显式指定维护中的节点时返回 ErrNodeInMaintenance
+func ResolveBatchTargets(sel NodeSelector, nodeIDs []uint, hostnames []string) ([]BatchTarget, error) {
+	nodes, err := FindNodes(sel, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(nodeIDs) > 0 {
+		// 显式指定的节点处于维护模式时直接拒绝，而不是静默跳过
+		var maintained []models.Node
+		database.DB.Where("id IN ? AND maintenance = ?", nodeIDs, true).Find(&maintained)
+		if len(maintained) > 0 {
+			return nil, CheckNodeAvailable(maintained[0])
+		}
+
+		allowed := make(map[uint]bool)
+		for _, id := range nodeIDs {
+			allowed[id] = true
+		}
+		filtered := nodes[:0]
+		for _, node := range nodes {
+			if allowed[node.ID] {
+				filtered = append(filtered, node)
+			}
+		}
+		nodes = filtered
+	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("没有符合条件的在线节点")
+	}
+
+	nodeMap := make(map[uint]models.Node)
+	ids := make([]uint, 0, len(nodes))
+	for _, node := range nodes {
+		nodeMap[node.ID] = node
+		ids = append(ids, node.ID)
+	}
+
+	var containers []models.ContainerCache
+	query := database.DB.Where("node_id IN ?", ids).Order("node_id ASC, hostname ASC")
+	if len(hostnames) > 0 {
+		query = query.Where("hostname IN ?", hostnames)
+	}
+	if err := query.Find(&containers).Error; err != nil {
+		return nil, err
+	}
+
+	targets := make([]BatchTarget, 0, len(containers))
+	for _, container := range containers {
+		targets = append(targets, BatchTarget{
+			Node:     nodeMap[container.NodeID],
+			Hostname: container.Hostname,
+		})
+	}
+	return targets, nil
+}
+
+// StartBatchTask 创建批量任务并在后台执行
+func StartBatchTask(action, selector string, targets []BatchTarget, fn BatchFunc) (*models.BatchTask, error) {
+	return StartBatchOutputTask(action, selector, targets, fn.withOutput())
+}
+
+// StartBatchOutputTask 创建批量任务并在后台执行，每个目标的输出保存在任务结果中
+func StartBatchOutputTask(action, selector string, targets []BatchTarget, fn BatchOutputFunc) (*models.BatchTask, error) {
+	task := models.BatchTask{
+		Action:     action,
+		Selector:   selector,
+		Status:     "pending",
+		TotalCount: len(targets),
+	}
+	if err := database.DB.Create(&task).Error; err != nil {
+		return nil, err
+	}
+
+	go runBatchTask(task, targets, fn)
+	return &task, nil
+}
+
+func runBatchTask(task models.BatchTask, targets []BatchTarget, fn BatchOutputFunc) {
+	now := time.Now()
+	task.Status = "running"
+	task.StartTime = &now
+	database.DB.Save(&task)
+
+	log.Printf("[BATCH] 任务 %d 开始执行: %s, 共 %d 个目标", task.ID, task.Action, len(targets))
+
+	results := executeBatch(targets, fn)
+	for _, r := range results {
+		if r.Success {
+			task.SuccessCount++
+		} else {
+			task.FailedCount++
+		}
+	}
+
+	resultsJSON, _ := json.Marshal(results)
+	task.Results = string(resultsJSON)
+	task.Status = "completed"
+	if task.FailedCount > 0 && task.SuccessCount == 0 && task.TotalCount > 0 {
+		task.Status = "failed"
+	}
+	endTime := time.Now()
+	task.EndTime = &endTime
+	database.DB.Save(&task)
+
+	log.Printf("[BATCH] 任务 %d 执行完成: 成功 %d, 失败 %d", task.ID, task.SuccessCount, task.FailedCount)
+}
+
+// executeBatch 以固定并发数对所有目标执行操作，结果顺序与目标一致
+func executeBatch(targets []BatchTarget, fn BatchOutputFunc) []models.BatchItemResult {
+	results := make([]models.BatchItemResult, len(targets))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, batchConcurrency)
+
+	for i, target := range targets {
+		wg.Add(1)
+		go func(i int, t BatchTarget) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			ok, msg, output := fn(t)
+			results[i] = models.BatchItemResult{
+				NodeID:   t.Node.ID,
+				NodeName: t.Node.Name,
+				Hostname: t.Hostname,
+				Success:  ok,
+				Message:  msg,
+				Output:   output,
+			}
+		}(i, target)
+	}
+	wg.Wait()
+	return results
+}
+
+// ContainerActionFunc 返回执行容器电源操作的批量函数
+func ContainerActionFunc(action string) (BatchFunc, error) {
+	path, ok := ContainerActionPaths[action]
+	if !ok {
+		return nil, fmt.Errorf("不支持的操作: %s", action)
+	}
+	return func(t BatchTarget) (bool, string) {
+		result := callNodeAPI(t.Node, "GET", path+"?hostname="+t.Hostname, nil)
+		msg, _ := result["msg"].(string)
+		ok := result["code"] == float64(200)
+		if ok && action == "suspend" {
+			EmitContainerEvent(models.WebhookEventContainerSuspended, t.Node, t.Hostname, map[string]interface{}{"source": "batch"})
+		}
+		return ok, msg
+	}, nil
+}
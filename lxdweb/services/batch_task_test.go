@@ -0,0 +1,32 @@
+package services
+
+import (
+	"errors"
+	"lxdweb/database"
+	"lxdweb/models"
+	"testing"
+)
+
+func TestResolveBatchTargetsMaintenance(t *testing.T) {
+	setupTestDB(t, &models.Node{}, &models.ContainerCache{})
+
+	active := models.Node{Name: "active", Address: "https://10.0.0.1:8443", Status: models.NodeStatusActive}
+	maintained := models.Node{Name: "maintained", Address: "https://10.0.0.2:8443", Status: models.NodeStatusActive, Maintenance: true}
+	database.DB.Create(&active)
+	database.DB.Create(&maintained)
+	database.DB.Create(&models.ContainerCache{NodeID: active.ID, Hostname: "c1"})
+	database.DB.Create(&models.ContainerCache{NodeID: maintained.ID, Hostname: "c2"})
+
+	targets, err := ResolveBatchTargets(NodeSelector{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(targets) != 1 || targets[0].Hostname != "c1" {
+		t.Fatalf("按筛选条件应跳过维护中的节点，得到 %+v", targets)
+	}
+
+	_, err = ResolveBatchTargets(NodeSelector{}, []uint{active.ID, maintained.ID}, nil)
+	if !errors.Is(err, ErrNodeInMaintenance) {
+		t.Fatalf("显式指定维护中的节点应返回 ErrNodeInMaintenance，得到 %v", err)
+	}
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"fmt"
+	"lxdweb/database"
+	"lxdweb/models"
+	"sort"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	cloudInitMaxSize = 64 * 1024
+	sshKeysMax       = 20
+)
+
+// ValidateSSHKeys 校验 authorized_keys 格式的公钥，与公钥库一致不接受 command=、from= 等选项，
+// 返回去除空白和重复后的列表
+func ValidateSSHKeys(keys []string) ([]string, error) {
+	seen := make(map[string]bool)
+	result := make([]string, 0, len(keys))
+	for i, key := range keys {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		if strings.ContainsAny(key, "\r\n") {
+			return nil, fmt.Errorf("第 %d 个 SSH 公钥包含换行，每项只能填写一个公钥", i+1)
+		}
+		pub, _, options, _, err := ssh.ParseAuthorizedKey([]byte(key))
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 个 SSH 公钥格式错误: %v", i+1, err)
+		}
+		if len(options) > 0 {
+			return nil, fmt.Errorf("第 %d 个 SSH 公钥带有 authorized_keys 选项，不支持", i+1)
+		}
+		fingerprint := ssh.FingerprintSHA256(pub)
+		if seen[fingerprint] {
+			continue
+		}
+		seen[fingerprint] = true
+		result = append(result, key)
+	}
+	if len(result) > sshKeysMax {
+		return nil, fmt.Errorf("SSH 公钥最多 %d 个", sshKeysMax)
+	}
+	return result, nil
+}
+
+// ValidateUserData 校验 user-data / vendor-data，#cloud-config 必须是 YAML 映射，
+// #! 开头视为首次启动脚本原样传递，返回 cloud-config 的顶层配置项
+func ValidateUserData(data string) ([]string, error) {
+	if len(data) > cloudInitMaxSize {
+		return nil, fmt.Errorf("内容不能超过 %d KB", cloudInitMaxSize/1024)
+	}
+	firstLine, _, _ := strings.Cut(data, "\n")
+	firstLine = strings.TrimSpace(firstLine)
+	switch {
+	case strings.HasPrefix(firstLine, "#!"):
+		return nil, nil
+	case firstLine != "#cloud-config":
+		return nil, fmt.Errorf("首行必须是 #cloud-config 或以 #! 开头的脚本")
+	}
+
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal([]byte(data), &doc); err != nil {
+		return nil, fmt.Errorf("cloud-config YAML 格式错误: %v", err)
+	}
+	keys := make([]string, 0, len(doc))
+	for key := range doc {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// BuildCloudInit 校验 cloud-init 参数并解析模板，返回需要附加到节点创建或重装请求中的字段
+func BuildCloudInit(opts models.CloudInitOptions) (map[string]interface{}, error) {
+	fields := make(map[string]interface{})
+
+	keys, err := ResolveSSHKeys(opts.SSHKeyIDs, opts.SSHKeys)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) > 0 {
+		fields["ssh_keys"] = keys
+	}
+	if opts.DisablePasswordAuth {
+		if len(keys) == 0 {
+			return nil, fmt.Errorf("禁用密码登录时至少需要一个 SSH 公钥")
+		}
+		fields["disable_password_auth"] = true
+	}
+
+	userData := opts.UserData
+	if userData == "" && opts.UserDataTemplateID != 0 {
+		var tmpl models.UserDataTemplate
+		if err := database.DB.First(&tmpl, opts.UserDataTemplateID).Error; err != nil {
+			return nil, fmt.Errorf("user-data 模板不存在")
+		}
+		userData = tmpl.Content
+	}
+	if strings.TrimSpace(userData) != "" {
+		if _, err := ValidateUserData(userData); err != nil {
+			return nil, fmt.Errorf("user_data 无效: %v", err)
+		}
+		fields["user_data"] = userData
+	}
+
+	if strings.TrimSpace(opts.VendorData) != "" {
+		if _, err := ValidateUserData(opts.VendorData); err != nil {
+			return nil, fmt.Errorf("vendor_data 无效: %v", err)
+		}
+		fields["vendor_data"] = opts.VendorData
+	}
+	return fields, nil
+}
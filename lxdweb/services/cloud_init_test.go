@@ -0,0 +1,70 @@
+package services
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func testPublicKey(t *testing.T, comment string) string {
+	t.Helper()
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshPub))) + " " + comment
+}
+
+func TestValidateSSHKeys(t *testing.T) {
+	k1 := testPublicKey(t, "a@host")
+	k2 := testPublicKey(t, "b@host")
+
+	keys, err := ValidateSSHKeys([]string{" " + k1 + " ", "", k2, k1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || keys[0] != k1 || keys[1] != k2 {
+		t.Errorf("去空白去重后 = %q", keys)
+	}
+
+	for _, bad := range []string{
+		"ssh-ed25519 not-base64",
+		k1 + "\n" + k2,
+		`command="/bin/sh" ` + k1,
+		`from="10.0.0.1" ` + k1,
+		"no-pty " + k1,
+	} {
+		if _, err := ValidateSSHKeys([]string{bad}); err == nil {
+			t.Errorf("ValidateSSHKeys(%q) 应返回错误", bad)
+		}
+	}
+}
+
+func TestValidateUserData(t *testing.T) {
+	keys, err := ValidateUserData("#cloud-config\npackages:\n  - htop\nruncmd:\n  - echo ok\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(keys, ",") != "packages,runcmd" {
+		t.Errorf("顶层配置项 = %q", keys)
+	}
+	if _, err := ValidateUserData("#!/bin/sh\necho ok\n"); err != nil {
+		t.Errorf("脚本应原样接受: %v", err)
+	}
+	for _, bad := range []string{
+		"packages:\n  - htop\n",
+		"#cloud-config\npackages: [htop\n",
+		"#cloud-config\n" + strings.Repeat("#", cloudInitMaxSize),
+	} {
+		if _, err := ValidateUserData(bad); err == nil {
+			t.Errorf("ValidateUserData(%.30q) 应返回错误", bad)
+		}
+	}
+}
@@ -0,0 +1,338 @@
+package services
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"lxdweb/config"
+	"lxdweb/database"
+	"lxdweb/models"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CreateConsoleSession 返回的错误类型，分别对应会话数超限和节点申请令牌失败
+var (
+	ErrConsoleLimit = errors.New("控制台会话数已达上限")
+	ErrConsoleNode  = errors.New("节点创建控制台令牌失败")
+)
+
+var (
+	// consoleMu 串行化并发数检查与会话创建
+	consoleMu sync.Mutex
+
+	consoleConnsMu sync.Mutex
+	consoleConns   = make(map[uint]*consoleConn)
+)
+
+// StartConsoleService 启动控制台会话维护服务，标记过期未使用的令牌并清理历史会话
+func StartConsoleService() {
+	log.Println("[CONSOLE] 控制台会话服务启动")
+
+	// 服务重启前的连接已全部断开
+	now := time.Now()
+	database.DB.Model(&models.ConsoleSession{}).
+		Where("status IN ?", []string{models.ConsoleSessionPending, models.ConsoleSessionActive}).
+		Updates(map[string]interface{}{"status": models.ConsoleSessionClosed, "ended_at": now, "close_reason": "服务重启"})
+
+	ticker := time.NewTicker(1 * time.Minute)
+	go func() {
+		for range ticker.C {
+			expireConsoleSessions()
+		}
+	}()
+
+	cleanupTicker := time.NewTicker(1 * time.Hour)
+	go func() {
+		for range cleanupTicker.C {
+			cleanupConsoleSessions()
+		}
+	}()
+}
+
+// CreateConsoleSession 检查并发数限制，向节点申请控制台令牌并签发 lxdweb 自己的一次性令牌
+func CreateConsoleSession(node models.Node, hostname string, adminID uint, clientIP string) (*models.ConsoleSession, error) {
+	cfg := config.AppConfig.Console
+
+	consoleMu.Lock()
+	defer consoleMu.Unlock()
+
+	now := time.Now()
+	live := "(status = ? OR (status = ? AND expires_at > ?))"
+	var total, own int64
+	database.DB.Model(&models.ConsoleSession{}).
+		Where(live, models.ConsoleSessionActive, models.ConsoleSessionPending, now).Count(&total)
+	database.DB.Model(&models.ConsoleSession{}).
+		Where(live, models.ConsoleSessionActive, models.ConsoleSessionPending, now).
+		Where("admin_id = ?", adminID).Count(&own)
+	if total >= int64(cfg.MaxSessions) {
+		return nil, fmt.Errorf("%w %d", ErrConsoleLimit, cfg.MaxSessions)
+	}
+	if own >= int64(cfg.MaxSessionsPerAdmin) {
+		return nil, fmt.Errorf("当前管理员%w %d", ErrConsoleLimit, cfg.MaxSessionsPerAdmin)
+	}
+
+	result := callNodeAPI(node, "POST", "/api/console/create-token", map[string]interface{}{
+		"hostname":   hostname,
+		"user_id":    adminID,
+		"service_id": 0,
+		"server_ip":  clientIP,
+		"expires_in": cfg.SessionTTL,
+	})
+	if result["code"] != float64(200) {
+		return nil, fmt.Errorf("%w: %v", ErrConsoleNode, result["msg"])
+	}
+	data, _ := result["data"].(map[string]interface{})
+	nodeToken, _ := data["token"].(string)
+	if nodeToken == "" {
+		return nil, fmt.Errorf("%w: 未返回令牌", ErrConsoleNode)
+	}
+
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+
+	session := models.ConsoleSession{
+		Token:     hex.EncodeToString(buf),
+		NodeToken: nodeToken,
+		AdminID:   adminID,
+		NodeID:    node.ID,
+		NodeName:  node.Name,
+		Hostname:  hostname,
+		ClientIP:  clientIP,
+		Status:    models.ConsoleSessionPending,
+		ExpiresAt: now.Add(time.Duration(cfg.TokenTTL) * time.Second),
+	}
+	if err := database.DB.Create(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// FindConsoleSession 按令牌查找当前管理员的控制台会话
+func FindConsoleSession(token string, adminID uint) (*models.ConsoleSession, error) {
+	var session models.ConsoleSession
+	if err := database.DB.Where("token = ? AND admin_id = ?", token, adminID).First(&session).Error; err != nil {
+		return nil, errors.New("控制台令牌无效")
+	}
+	return &session, nil
+}
+
+// ClaimConsoleSession 使用令牌建立连接，令牌只能使用一次且必须在有效期内
+func ClaimConsoleSession(token string, adminID uint) (*models.ConsoleSession, error) {
+	now := time.Now()
+	result := database.DB.Model(&models.ConsoleSession{}).
+		Where("token = ? AND admin_id = ? AND status = ? AND expires_at > ?", token, adminID, models.ConsoleSessionPending, now).
+		Updates(map[string]interface{}{"status": models.ConsoleSessionActive, "started_at": now})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("控制台令牌无效、已使用或已过期")
+	}
+	return FindConsoleSession(token, adminID)
+}
+
+// consoleConn 统计浏览器连接的收发字节数，并在会话超时或被终止时关闭连接
+type consoleConn struct {
+	net.Conn
+	bytesIn    atomic.Int64
+	bytesOut   atomic.Int64
+	terminated atomic.Bool
+}
+
+func (c *consoleConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.bytesIn.Add(int64(n))
+	return n, err
+}
+
+func (c *consoleConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	c.bytesOut.Add(int64(n))
+	return n, err
+}
+
+// consoleResponseWriter 在 ReverseProxy 接管连接时替换为 consoleConn
+type consoleResponseWriter struct {
+	http.ResponseWriter
+	deadline time.Time
+	onHijack func(*consoleConn)
+}
+
+func (w *consoleResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("连接不支持升级")
+	}
+	conn, brw, err := hj.Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	conn.SetDeadline(w.deadline)
+	cc := &consoleConn{Conn: conn}
+	w.onHijack(cc)
+	return cc, brw, nil
+}
+
+// ProxyConsoleSession 将浏览器的 WebSocket 连接代理到节点控制台，阻塞到连接关闭
+func ProxyConsoleSession(w http.ResponseWriter, r *http.Request, session *models.ConsoleSession, clientIP string) {
+	cfg := config.AppConfig.Console
+
+	var node models.Node
+	if err := database.DB.First(&node, session.NodeID).Error; err != nil {
+		http.Error(w, "节点不存在", http.StatusNotFound)
+		closeConsoleSession(session, nil, "节点不存在", clientIP)
+		return
+	}
+	target, err := url.Parse(node.Address)
+	if err != nil {
+		http.Error(w, "节点地址无效", http.StatusBadGateway)
+		closeConsoleSession(session, nil, "节点地址无效", clientIP)
+		return
+	}
+
+	var conn *consoleConn
+	reason := ""
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(target)
+			pr.Out.URL.Path = cfg.NodeWSPath
+			pr.Out.URL.RawPath = ""
+			pr.Out.URL.RawQuery = "token=" + url.QueryEscape(session.NodeToken)
+			pr.Out.Header.Del("Cookie")
+			pr.Out.Header.Set("Origin", node.Address)
+			if node.APIKey != "" {
+				pr.Out.Header.Set("apikey", node.APIKey)
+			}
+		},
+		Transport: NodeAPITransport(node, &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}),
+		ModifyResponse: func(resp *http.Response) error {
+			if resp.StatusCode != http.StatusSwitchingProtocols {
+				reason = fmt.Sprintf("节点拒绝连接: HTTP %d", resp.StatusCode)
+			}
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			reason = "连接节点失败: " + err.Error()
+			w.WriteHeader(http.StatusBadGateway)
+		},
+	}
+
+	deadline := session.StartedAt.Add(time.Duration(cfg.SessionTTL) * time.Second)
+	rw := &consoleResponseWriter{
+		ResponseWriter: w,
+		deadline:       deadline,
+		onHijack: func(cc *consoleConn) {
+			conn = cc
+			consoleConnsMu.Lock()
+			consoleConns[session.ID] = cc
+			consoleConnsMu.Unlock()
+		},
+	}
+
+	log.Printf("[CONSOLE] 会话 %d 建立连接: %s/%s (管理员 %d)", session.ID, node.Name, session.Hostname, session.AdminID)
+	proxy.ServeHTTP(rw, r)
+
+	if conn != nil {
+		consoleConnsMu.Lock()
+		delete(consoleConns, session.ID)
+		consoleConnsMu.Unlock()
+	}
+	if reason == "" {
+		switch {
+		case conn != nil && conn.terminated.Load():
+			reason = "管理员终止"
+		case !time.Now().Before(deadline):
+			reason = "会话超时"
+		default:
+			reason = "连接关闭"
+		}
+	}
+	closeConsoleSession(session, conn, reason, clientIP)
+}
+
+func closeConsoleSession(session *models.ConsoleSession, conn *consoleConn, reason, clientIP string) {
+	now := time.Now()
+	session.Status = models.ConsoleSessionClosed
+	session.EndedAt = &now
+	session.CloseReason = reason
+	if conn != nil {
+		session.BytesIn = conn.bytesIn.Load()
+		session.BytesOut = conn.bytesOut.Load()
+	}
+	database.DB.Save(session)
+
+	var duration time.Duration
+	if session.StartedAt != nil {
+		duration = now.Sub(*session.StartedAt).Truncate(time.Second)
+	}
+	log.Printf("[CONSOLE] 会话 %d 结束: %s, 时长 %s", session.ID, reason, duration)
+
+	RecordOperation(AuditEntry{
+		AdminID:       session.AdminID,
+		OperationType: "console_session",
+		TargetType:    "container",
+		TargetID:      session.NodeID,
+		Details: map[string]interface{}{
+			"session_id": session.ID,
+			"hostname":   session.Hostname,
+			"duration":   int64(duration.Seconds()),
+			"bytes_in":   session.BytesIn,
+			"bytes_out":  session.BytesOut,
+			"reason":     reason,
+		},
+		IPAddress: clientIP,
+	})
+}
+
+// TerminateConsoleSession 断开正在进行的控制台会话，未连接的会话直接作废
+func TerminateConsoleSession(id uint) error {
+	consoleConnsMu.Lock()
+	conn := consoleConns[id]
+	consoleConnsMu.Unlock()
+	if conn != nil {
+		conn.terminated.Store(true)
+		return conn.Close()
+	}
+
+	now := time.Now()
+	result := database.DB.Model(&models.ConsoleSession{}).
+		Where("id = ? AND status = ?", id, models.ConsoleSessionPending).
+		Updates(map[string]interface{}{"status": models.ConsoleSessionClosed, "ended_at": now, "close_reason": "管理员终止"})
+	if result.RowsAffected == 0 {
+		return errors.New("会话不存在或已结束")
+	}
+	return nil
+}
+
+// expireConsoleSessions 标记超过有效期仍未连接的令牌
+func expireConsoleSessions() {
+	database.DB.Model(&models.ConsoleSession{}).
+		Where("status = ? AND expires_at <= ?", models.ConsoleSessionPending, time.Now()).
+		Update("status", models.ConsoleSessionExpired)
+}
+
+// cleanupConsoleSessions 删除超过保留天数的会话记录
+func cleanupConsoleSessions() {
+	cutoff := time.Now().AddDate(0, 0, -config.AppConfig.Console.SessionRetention)
+	result := database.DB.Where("created_at < ? AND status IN ?", cutoff,
+		[]string{models.ConsoleSessionClosed, models.ConsoleSessionExpired}).Delete(&models.ConsoleSession{})
+	if result.Error != nil {
+		log.Printf("[CONSOLE] 清理会话记录失败: %v", result.Error)
+	} else if result.RowsAffected > 0 {
+		log.Printf("[CONSOLE] 已清理 %d 条过期会话记录", result.RowsAffected)
+	}
+}
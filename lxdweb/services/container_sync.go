@@ -29,7 +29,7 @@ func StartContainerSyncService() {
 // SyncAllNodesAsync 同步所有活动节点的容器
 func SyncAllNodesAsync() {
 	var nodes []models.Node
-	database.DB.Where("status = ?", "active").Find(&nodes)
+	database.DB.Where("status IN ? AND maintenance = ?", models.OnlineNodeStatuses, false).Find(&nodes)
 	
 	log.Printf("[SYNC] 开始实时同步 %d 个活动节点", len(nodes))
 	
@@ -151,6 +151,7 @@ func RefreshNodeContainers(nodeID uint, manual bool) error {
 		if !existingHostnames[cached.Hostname] {
 			database.DB.Unscoped().Delete(&cached)
 			log.Printf("[REFRESH] 删除不存在的容器缓存: %s", cached.Hostname)
+			EmitContainerEvent(models.WebhookEventContainerDeleted, node, cached.Hostname, map[string]interface{}{"source": "sync"})
 		}
 	}
 
@@ -160,6 +161,7 @@ func RefreshNodeContainers(nodeID uint, manual bool) error {
 	endTime := time.Now()
 	task.EndTime = &endTime
 	database.DB.Save(&task)
+	RecordNodeMetric(node.ID)
 	
 	log.Printf("[REFRESH] 节点 %s 刷新完成: 成功 %d, 失败 %d, 总计 %d", 
 		node.Name, successCount, failedCount, task.TotalCount)
@@ -318,6 +320,7 @@ func SyncNodeContainers(nodeID uint, manual bool) error {
 	endTime := time.Now()
 	task.EndTime = &endTime
 	database.DB.Save(&task)
+	RecordNodeMetric(node.ID)
 	
 	log.Printf("[SYNC] 节点 %s 实时同步完成: 成功 %d, 失败 %d, 总计 %d", 
 		node.Name, successCount, failedCount, task.TotalCount)
@@ -420,10 +423,11 @@ func updateContainerCache(node models.Node, data map[string]interface{}) error {
 		updates["disk_total"] = uint64(diskTotal * 1024 * 1024) 
 	}
 
-	if trafficRaw, ok := data["traffic_usage_raw"].(float64); ok {
-		updates["traffic_total"] = uint64(trafficRaw)
-		updates["traffic_in"] = uint64(trafficRaw * 0.5)
-		updates["traffic_out"] = uint64(trafficRaw * 0.5)
+	counter, hasTraffic := ParseTrafficCounter(data)
+	if hasTraffic {
+		updates["traffic_total"] = counter.Total
+		updates["traffic_in"] = counter.In
+		updates["traffic_out"] = counter.Out
 	}
 
 	cache := models.ContainerCache{
@@ -505,16 +509,32 @@ func updateContainerCache(node models.Node, data map[string]interface{}) error {
 			"last_sync", "sync_error",
 		}),
 	}).Create(&cache)
-	
-	return result.Error
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if hasTraffic {
+		if err := RecordTrafficSample(node.ID, hostname, counter, cache.TrafficLimit); err != nil {
+			log.Printf("[SYNC] 记录容器 %s 流量采样失败: %v", hostname, err)
+		} else {
+			EvaluateTrafficQuota(node, cache)
+		}
+	}
+	RecordContainerMetric(cache)
+	return nil
 }
 
 func callNodeAPI(node models.Node, method, path string, data interface{}) map[string]interface{} {
+	return callNodeAPITimeout(node, method, path, data, 30*time.Second)
+}
+
+// callNodeAPITimeout 与 callNodeAPI 相同，用于耗时较长的节点接口
+func callNodeAPITimeout(node models.Node, method, path string, data interface{}, timeout time.Duration) map[string]interface{} {
 	client := &http.Client{
-		Timeout: 30 * time.Second,
-		Transport: &http.Transport{
+		Timeout: timeout,
+		Transport: NodeAPITransport(node, &http.Transport{
 			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		},
+		}),
 	}
 	
 	var body io.Reader
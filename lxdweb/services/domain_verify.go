@@ -0,0 +1,247 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"lxdweb/config"
+	"lxdweb/database"
+	"lxdweb/models"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DomainVerifyHTTPPath HTTP 验证文件路径前缀，文件内容为验证令牌
+const DomainVerifyHTTPPath = "/.well-known/lxdweb-verification/"
+
+var domainNamePattern = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z]{2,63}$`)
+
+// domainVerifyHTTPPort HTTP 验证访问的端口，测试时替换为本地服务端口
+var domainVerifyHTTPPort = "80"
+
+// NormalizeDomain 统一域名格式（小写、去掉末尾点），格式不合法时返回错误
+func NormalizeDomain(domain string) (string, error) {
+	domain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+	if len(domain) > 253 || !domainNamePattern.MatchString(domain) {
+		return "", fmt.Errorf("域名格式错误: %s", domain)
+	}
+	return domain, nil
+}
+
+// DomainVerifyInstructions 返回完成验证需要配置的内容
+func DomainVerifyInstructions(v models.DomainVerification) map[string]interface{} {
+	if v.Method == models.DomainVerifyMethodHTTP {
+		return map[string]interface{}{
+			"method":  v.Method,
+			"url":     "http://" + v.Domain + DomainVerifyHTTPPath + v.Token,
+			"content": v.Token,
+		}
+	}
+	return map[string]interface{}{
+		"method": v.Method,
+		"record": config.AppConfig.DomainVerify.TXTPrefix + "." + v.Domain,
+		"type":   "TXT",
+		"value":  v.Token,
+	}
+}
+
+// RequestDomainVerification 为域名生成新的验证令牌，已有记录时重置为待验证
+func RequestDomainVerification(domain, method string, adminID uint) (*models.DomainVerification, error) {
+	domain, err := NormalizeDomain(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+
+	var v models.DomainVerification
+	database.DB.Where("domain = ?", domain).First(&v)
+	v.Domain = domain
+	v.Method = method
+	v.Token = "lxdweb-verify=" + hex.EncodeToString(buf)
+	v.Status = models.DomainVerifyStatusPending
+	v.TokenExpiry = time.Now().Add(time.Duration(config.AppConfig.DomainVerify.TokenTTL) * time.Hour)
+	v.VerifiedAt = nil
+	v.ExpiresAt = nil
+	v.LastError = ""
+	v.AdminID = adminID
+
+	if err := database.DB.Save(&v).Error; err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// CheckDomainVerification 通过配置的解析器检查TXT记录或HTTP验证文件，并保存结果
+func CheckDomainVerification(v *models.DomainVerification) error {
+	now := time.Now()
+	v.LastCheckAt = &now
+
+	var err error
+	if now.After(v.TokenExpiry) && v.Status != models.DomainVerifyStatusVerified {
+		v.Status = models.DomainVerifyStatusExpired
+		err = errors.New("验证令牌已过期，请重新申请")
+	} else {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(config.AppConfig.DomainVerify.Timeout)*time.Second)
+		defer cancel()
+		if v.Method == models.DomainVerifyMethodHTTP {
+			err = checkDomainHTTPToken(ctx, v.Domain, v.Token)
+		} else {
+			err = checkDomainTXTToken(ctx, v.Domain, v.Token)
+		}
+
+		if err == nil {
+			v.Status = models.DomainVerifyStatusVerified
+			v.VerifiedAt = &now
+			v.ExpiresAt = nil
+			if days := config.AppConfig.DomainVerify.ValidDays; days > 0 {
+				expires := now.AddDate(0, 0, days)
+				v.ExpiresAt = &expires
+			}
+		} else {
+			v.Status = models.DomainVerifyStatusFailed
+		}
+	}
+
+	v.LastError = ""
+	if err != nil {
+		v.LastError = err.Error()
+	}
+	if saveErr := database.DB.Save(v).Error; saveErr != nil {
+		return saveErr
+	}
+	if err == nil {
+		log.Printf("[DOMAIN] 域名 %s 通过 %s 验证", v.Domain, v.Method)
+	}
+	return err
+}
+
+// IsDomainVerified 检查域名是否已验证；TXT 方式验证的上级域名同样视为已验证
+func IsDomainVerified(domain string) bool {
+	domain, err := NormalizeDomain(domain)
+	if err != nil {
+		return false
+	}
+
+	candidates := []string{domain}
+	labels := strings.Split(domain, ".")
+	for i := 1; i < len(labels)-1; i++ {
+		candidates = append(candidates, strings.Join(labels[i:], "."))
+	}
+
+	var list []models.DomainVerification
+	database.DB.Where("domain IN ? AND status = ?", candidates, models.DomainVerifyStatusVerified).Find(&list)
+	now := time.Now()
+	for _, v := range list {
+		if v.ExpiresAt != nil && now.After(*v.ExpiresAt) {
+			continue
+		}
+		if v.Domain == domain || v.Method == models.DomainVerifyMethodTXT {
+			return true
+		}
+	}
+	return false
+}
+
+// domainResolver 返回配置的DNS解析器，未配置时使用系统解析
+func domainResolver() *net.Resolver {
+	server := config.AppConfig.DomainVerify.Resolver
+	if server == "" {
+		return net.DefaultResolver
+	}
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, server)
+		},
+	}
+}
+
+func checkDomainTXTToken(ctx context.Context, domain, token string) error {
+	name := config.AppConfig.DomainVerify.TXTPrefix + "." + domain
+	records, err := domainResolver().LookupTXT(ctx, name)
+	if err != nil {
+		return fmt.Errorf("查询TXT记录 %s 失败: %v", name, err)
+	}
+	for _, record := range records {
+		if strings.TrimSpace(record) == token {
+			return nil
+		}
+	}
+	return fmt.Errorf("TXT记录 %s 中未找到验证令牌", name)
+}
+
+func checkDomainHTTPToken(ctx context.Context, domain, token string) error {
+	resolver := domainResolver()
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				host, port, err := net.SplitHostPort(addr)
+				if err != nil {
+					return nil, err
+				}
+				ips, err := resolver.LookupHost(ctx, host)
+				if err != nil {
+					return nil, err
+				}
+				var d net.Dialer
+				var lastErr error
+				for _, ip := range ips {
+					conn, err := d.DialContext(ctx, network, net.JoinHostPort(ip, port))
+					if err == nil {
+						return conn, nil
+					}
+					lastErr = err
+				}
+				return nil, lastErr
+			},
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 3 {
+				return errors.New("重定向次数过多")
+			}
+			return nil
+		},
+	}
+
+	host := domain
+	if domainVerifyHTTPPort != "80" {
+		host = net.JoinHostPort(domain, domainVerifyHTTPPort)
+	}
+	url := "http://" + host + DomainVerifyHTTPPath + token
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("访问 %s 失败: %v", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("访问 %s 返回状态码 %d", url, resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(string(body)) != token {
+		return fmt.Errorf("验证文件内容与令牌不一致")
+	}
+	return nil
+}
@@ -0,0 +1,263 @@
+package services
+
+import (
+	"lxdweb/config"
+	"lxdweb/database"
+	"lxdweb/models"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// fakeDNSServer 本地 UDP DNS 服务，作为 domain_verify.resolver 的替身
+type fakeDNSServer struct {
+	mu   sync.Mutex
+	txt  map[string][]string
+	a    map[string]net.IP
+	addr string
+}
+
+func newFakeDNSServer(t *testing.T) *fakeDNSServer {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	s := &fakeDNSServer{txt: map[string][]string{}, a: map[string]net.IP{}, addr: conn.LocalAddr().String()}
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, from, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if resp, ok := s.answer(buf[:n]); ok {
+				conn.WriteTo(resp, from)
+			}
+		}
+	}()
+	return s
+}
+
+func (s *fakeDNSServer) setTXT(name string, values ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.txt[name+"."] = values
+}
+
+func (s *fakeDNSServer) setA(name string, ip string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.a[name+"."] = net.ParseIP(ip).To4()
+}
+
+func (s *fakeDNSServer) answer(query []byte) ([]byte, bool) {
+	var p dnsmessage.Parser
+	header, err := p.Start(query)
+	if err != nil {
+		return nil, false
+	}
+	q, err := p.Question()
+	if err != nil {
+		return nil, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	name := strings.ToLower(q.Name.String())
+	txt, hasTXT := s.txt[name]
+	ip, hasA := s.a[name]
+
+	rcode := dnsmessage.RCodeSuccess
+	if !hasTXT && !hasA {
+		rcode = dnsmessage.RCodeNameError
+	}
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: header.ID, Response: true, Authoritative: true, RCode: rcode})
+	b.EnableCompression()
+	b.StartQuestions()
+	b.Question(q)
+	b.StartAnswers()
+	rh := dnsmessage.ResourceHeader{Name: q.Name, Type: q.Type, Class: dnsmessage.ClassINET, TTL: 60}
+	switch {
+	case q.Type == dnsmessage.TypeTXT && hasTXT:
+		// 每个值单独一条记录，同一条记录内的多个字符串会被拼接
+		for _, value := range txt {
+			b.TXTResource(rh, dnsmessage.TXTResource{TXT: []string{value}})
+		}
+	case q.Type == dnsmessage.TypeA && hasA:
+		var a [4]byte
+		copy(a[:], ip)
+		b.AResource(rh, dnsmessage.AResource{A: a})
+	}
+	resp, err := b.Finish()
+	return resp, err == nil
+}
+
+func setupDomainVerifyConfig(t *testing.T, resolver string) {
+	t.Helper()
+	old := config.AppConfig
+	config.AppConfig = &config.Config{}
+	config.AppConfig.DomainVerify.Resolver = resolver
+	config.AppConfig.DomainVerify.TXTPrefix = "_lxdweb-verify"
+	config.AppConfig.DomainVerify.TokenTTL = 72
+	config.AppConfig.DomainVerify.ValidDays = 30
+	config.AppConfig.DomainVerify.Timeout = 5
+	t.Cleanup(func() { config.AppConfig = old })
+}
+
+func TestCheckDomainVerificationTXT(t *testing.T) {
+	setupTestDB(t, &models.DomainVerification{})
+	dns := newFakeDNSServer(t)
+	setupDomainVerifyConfig(t, dns.addr)
+
+	v, err := RequestDomainVerification("Example.TEST.", models.DomainVerifyMethodTXT, 1)
+	if err != nil {
+		t.Fatalf("申请验证失败: %v", err)
+	}
+	if v.Domain != "example.test" {
+		t.Fatalf("域名应规范化为 example.test，实际 %s", v.Domain)
+	}
+
+	dns.setTXT("_lxdweb-verify.example.test", "other-value")
+	if err := CheckDomainVerification(v); err == nil {
+		t.Fatal("TXT 记录不含令牌时应验证失败")
+	}
+	if v.Status != models.DomainVerifyStatusFailed || v.LastError == "" {
+		t.Fatalf("验证失败后状态 %s，错误 %q", v.Status, v.LastError)
+	}
+	if IsDomainVerified("example.test") {
+		t.Fatal("验证失败的域名不应视为已验证")
+	}
+
+	dns.setTXT("_lxdweb-verify.example.test", "other-value", v.Token)
+	if err := CheckDomainVerification(v); err != nil {
+		t.Fatalf("验证失败: %v", err)
+	}
+	if v.Status != models.DomainVerifyStatusVerified || v.VerifiedAt == nil {
+		t.Fatalf("验证通过后状态 %s", v.Status)
+	}
+	if v.ExpiresAt == nil || v.ExpiresAt.Sub(*v.VerifiedAt) != 30*24*time.Hour {
+		t.Fatalf("ExpiresAt 应为验证时间后 30 天，实际 %v", v.ExpiresAt)
+	}
+
+	for domain, want := range map[string]bool{
+		"example.test":           true,
+		"app.example.test":       true,
+		"a.b.example.test":       true,
+		"example.test.evil.test": false,
+		"notexample.test":        false,
+	} {
+		if got := IsDomainVerified(domain); got != want {
+			t.Errorf("IsDomainVerified(%q) = %v，期望 %v", domain, got, want)
+		}
+	}
+}
+
+func TestCheckDomainVerificationHTTP(t *testing.T) {
+	setupTestDB(t, &models.DomainVerification{})
+	dns := newFakeDNSServer(t)
+	setupDomainVerifyConfig(t, dns.addr)
+
+	var mu sync.Mutex
+	content := "wrong"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, DomainVerifyHTTPPath) {
+			http.NotFound(w, r)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		w.Write([]byte(content + "\n"))
+	}))
+	t.Cleanup(srv.Close)
+	u, _ := url.Parse(srv.URL)
+	oldPort := domainVerifyHTTPPort
+	domainVerifyHTTPPort = u.Port()
+	t.Cleanup(func() { domainVerifyHTTPPort = oldPort })
+	dns.setA("site.example.test", "127.0.0.1")
+
+	v, err := RequestDomainVerification("site.example.test", models.DomainVerifyMethodHTTP, 1)
+	if err != nil {
+		t.Fatalf("申请验证失败: %v", err)
+	}
+	if err := CheckDomainVerification(v); err == nil {
+		t.Fatal("验证文件内容不一致时应验证失败")
+	}
+
+	mu.Lock()
+	content = v.Token
+	mu.Unlock()
+	if err := CheckDomainVerification(v); err != nil {
+		t.Fatalf("验证失败: %v", err)
+	}
+	if v.Status != models.DomainVerifyStatusVerified {
+		t.Fatalf("验证通过后状态 %s", v.Status)
+	}
+
+	if !IsDomainVerified("site.example.test") {
+		t.Error("HTTP 验证的域名本身应视为已验证")
+	}
+	if IsDomainVerified("api.site.example.test") {
+		t.Error("HTTP 验证只覆盖域名本身，不应覆盖子域名")
+	}
+}
+
+func TestCheckDomainVerificationTokenExpired(t *testing.T) {
+	setupTestDB(t, &models.DomainVerification{})
+	dns := newFakeDNSServer(t)
+	setupDomainVerifyConfig(t, dns.addr)
+
+	v, err := RequestDomainVerification("example.test", models.DomainVerifyMethodTXT, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dns.setTXT("_lxdweb-verify.example.test", v.Token)
+	v.TokenExpiry = time.Now().Add(-time.Minute)
+	if err := CheckDomainVerification(v); err == nil {
+		t.Fatal("令牌过期后应验证失败")
+	}
+	if v.Status != models.DomainVerifyStatusExpired {
+		t.Fatalf("令牌过期后状态应为 %s，实际 %s", models.DomainVerifyStatusExpired, v.Status)
+	}
+}
+
+func TestIsDomainVerifiedExpiresAt(t *testing.T) {
+	setupTestDB(t, &models.DomainVerification{})
+	setupDomainVerifyConfig(t, "")
+
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	for _, v := range []models.DomainVerification{
+		{Domain: "expired.test", Method: models.DomainVerifyMethodTXT, Status: models.DomainVerifyStatusVerified, ExpiresAt: &past},
+		{Domain: "valid.test", Method: models.DomainVerifyMethodTXT, Status: models.DomainVerifyStatusVerified, ExpiresAt: &future},
+		{Domain: "forever.test", Method: models.DomainVerifyMethodTXT, Status: models.DomainVerifyStatusVerified},
+		{Domain: "pending.test", Method: models.DomainVerifyMethodTXT, Status: models.DomainVerifyStatusPending},
+	} {
+		if err := database.DB.Create(&v).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for domain, want := range map[string]bool{
+		"expired.test":     false,
+		"www.expired.test": false,
+		"valid.test":       true,
+		"www.valid.test":   true,
+		"forever.test":     true,
+		"pending.test":     false,
+		"invalid domain":   false,
+	} {
+		if got := IsDomainVerified(domain); got != want {
+			t.Errorf("IsDomainVerified(%q) = %v，期望 %v", domain, got, want)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"fmt"
+	"lxdweb/config"
+	"lxdweb/models"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const execMaxCommandLength = 64 * 1024
+
+var execEnvKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// NormalizeExecRequest 校验远程执行参数并补全默认超时
+func NormalizeExecRequest(req *models.ExecRequest) error {
+	cfg := config.AppConfig.Exec
+	if strings.TrimSpace(req.Command) == "" {
+		return fmt.Errorf("命令不能为空")
+	}
+	if len(req.Command) > execMaxCommandLength {
+		return fmt.Errorf("命令长度不能超过 %d 字节", execMaxCommandLength)
+	}
+	for key := range req.Env {
+		if !execEnvKeyPattern.MatchString(key) {
+			return fmt.Errorf("环境变量名无效: %s", key)
+		}
+	}
+	if req.Cwd != "" {
+		cwd, err := CleanContainerPath(req.Cwd)
+		if err != nil {
+			return fmt.Errorf("工作目录无效: %s", req.Cwd)
+		}
+		req.Cwd = cwd
+	}
+	if req.Timeout <= 0 {
+		req.Timeout = cfg.DefaultTimeout
+	}
+	if req.Timeout > cfg.MaxTimeout {
+		return fmt.Errorf("超时时间不能超过 %d 秒", cfg.MaxTimeout)
+	}
+	return nil
+}
+
+// ExecFunc 返回在容器内执行命令的批量函数，命令通过 /bin/sh -c 执行，退出码为 0 视为成功
+func ExecFunc(req models.ExecRequest) BatchOutputFunc {
+	maxOutput := config.AppConfig.Exec.MaxOutputSize * 1024
+	payload := map[string]interface{}{
+		"command":    []string{"/bin/sh", "-c", req.Command},
+		"env":        req.Env,
+		"cwd":        req.Cwd,
+		"timeout":    req.Timeout,
+		"max_output": maxOutput,
+	}
+	// 节点在超时后终止命令，额外留出返回结果的时间
+	timeout := time.Duration(req.Timeout)*time.Second + 30*time.Second
+
+	return func(t BatchTarget) (bool, string, *models.ExecOutput) {
+		body := make(map[string]interface{}, len(payload)+1)
+		for k, v := range payload {
+			body[k] = v
+		}
+		body["hostname"] = t.Hostname
+
+		start := time.Now()
+		result := callNodeAPITimeout(t.Node, "POST", "/api/exec", body, timeout)
+		if result["code"] != float64(200) {
+			return false, fmt.Sprintf("执行失败: %v", result["msg"]), nil
+		}
+		data, _ := result["data"].(map[string]interface{})
+		exitCode, _ := data["exit_code"].(float64)
+		stdout, _ := data["stdout"].(string)
+		stderr, _ := data["stderr"].(string)
+		timedOut, _ := data["timed_out"].(bool)
+		truncated, _ := data["truncated"].(bool)
+
+		output := &models.ExecOutput{
+			ExitCode:   int(exitCode),
+			TimedOut:   timedOut,
+			DurationMs: time.Since(start).Milliseconds(),
+		}
+		var cut bool
+		output.Stdout, cut = truncateOutput(stdout, maxOutput)
+		truncated = truncated || cut
+		output.Stderr, cut = truncateOutput(stderr, maxOutput)
+		output.Truncated = truncated || cut
+
+		switch {
+		case timedOut:
+			return false, fmt.Sprintf("执行超时（%d 秒）", req.Timeout), output
+		case output.ExitCode != 0:
+			return false, fmt.Sprintf("退出码 %d", output.ExitCode), output
+		}
+		return true, "退出码 0", output
+	}
+}
+
+// truncateOutput 截断超过上限的输出，保证结果仍是合法 UTF-8
+func truncateOutput(s string, limit int) (string, bool) {
+	if len(s) <= limit {
+		return s, false
+	}
+	return strings.ToValidUTF8(s[:limit], ""), true
+}
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"encoding/json"
+	"lxdweb/config"
+	"lxdweb/models"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf8"
+)
+
+// fakeExecNode 模拟节点 /api/exec，记录请求体并返回预设的执行结果
+type fakeExecNode struct {
+	mu     sync.Mutex
+	bodies []map[string]interface{}
+	code   int
+	data   map[string]interface{}
+	server *httptest.Server
+}
+
+func newFakeExecNode(t *testing.T) *fakeExecNode {
+	t.Helper()
+	n := &fakeExecNode{code: 200}
+	n.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		if r.URL.Path == "/api/exec" {
+			n.bodies = append(n.bodies, body)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"code": n.code, "msg": "节点返回失败", "data": n.data})
+	}))
+	t.Cleanup(n.server.Close)
+	return n
+}
+
+func (n *fakeExecNode) respond(code int, data map[string]interface{}) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.code = code
+	n.data = data
+}
+
+func setupExecConfig(t *testing.T) {
+	t.Helper()
+	old := config.AppConfig
+	config.AppConfig = &config.Config{}
+	config.AppConfig.Exec.DefaultTimeout = 60
+	config.AppConfig.Exec.MaxTimeout = 1800
+	config.AppConfig.Exec.MaxOutputSize = 1
+	t.Cleanup(func() { config.AppConfig = old })
+}
+
+func TestNormalizeExecRequest(t *testing.T) {
+	setupExecConfig(t)
+
+	req := models.ExecRequest{Command: "uptime", Cwd: "/root/../tmp/"}
+	if err := NormalizeExecRequest(&req); err != nil {
+		t.Fatalf("合法请求返回错误: %v", err)
+	}
+	if req.Timeout != 60 {
+		t.Errorf("未指定超时时应使用默认值 60，实际 %d", req.Timeout)
+	}
+	if req.Cwd != "/tmp" {
+		t.Errorf("工作目录应规范化为 /tmp，实际 %s", req.Cwd)
+	}
+
+	for name, req := range map[string]models.ExecRequest{
+		"空命令":     {Command: "  \n"},
+		"命令过长":    {Command: strings.Repeat("a", execMaxCommandLength+1)},
+		"环境变量名无效": {Command: "env", Env: map[string]string{"A-B": "1"}},
+		"相对工作目录":  {Command: "ls", Cwd: "tmp"},
+		"超时超过上限":  {Command: "sleep 1", Timeout: 1801},
+	} {
+		if err := NormalizeExecRequest(&req); err == nil {
+			t.Errorf("%s: 应返回错误", name)
+		}
+	}
+}
+
+func TestExecFunc(t *testing.T) {
+	setupExecConfig(t)
+	fn := newFakeExecNode(t)
+	target := BatchTarget{Node: models.Node{Name: "node-1", Address: fn.server.URL}, Hostname: "c1"}
+
+	req := models.ExecRequest{Command: "echo $A", Env: map[string]string{"A": "1"}, Cwd: "/root", Timeout: 5}
+	exec := ExecFunc(req)
+
+	fn.respond(200, map[string]interface{}{"exit_code": 0, "stdout": "1\n", "stderr": ""})
+	ok, msg, out := exec(target)
+	if !ok || out == nil || out.Stdout != "1\n" || out.ExitCode != 0 {
+		t.Fatalf("退出码 0 应成功: ok=%v msg=%s out=%+v", ok, msg, out)
+	}
+	body := fn.bodies[0]
+	if !reflect.DeepEqual(body["command"], []interface{}{"/bin/sh", "-c", "echo $A"}) {
+		t.Errorf("command 应为 argv 数组，实际 %v", body["command"])
+	}
+	if body["hostname"] != "c1" || body["cwd"] != "/root" || body["timeout"] != float64(5) || body["max_output"] != float64(1024) {
+		t.Errorf("请求体 = %v", body)
+	}
+	if env, _ := body["env"].(map[string]interface{}); env["A"] != "1" {
+		t.Errorf("env = %v", body["env"])
+	}
+
+	tests := []struct {
+		name          string
+		code          int
+		data          map[string]interface{}
+		wantOK        bool
+		wantMsg       string
+		wantOutput    bool
+		wantTruncated bool
+		wantTimedOut  bool
+	}{
+		{
+			name:       "非零退出码",
+			code:       200,
+			data:       map[string]interface{}{"exit_code": 2, "stderr": "not found"},
+			wantMsg:    "退出码 2",
+			wantOutput: true,
+		},
+		{
+			name:         "执行超时",
+			code:         200,
+			data:         map[string]interface{}{"exit_code": -1, "timed_out": true, "stdout": "partial"},
+			wantMsg:      "执行超时（5 秒）",
+			wantOutput:   true,
+			wantTimedOut: true,
+		},
+		{
+			name:          "节点已截断输出",
+			code:          200,
+			data:          map[string]interface{}{"exit_code": 0, "stdout": "abc", "truncated": true},
+			wantOK:        true,
+			wantMsg:       "退出码 0",
+			wantOutput:    true,
+			wantTruncated: true,
+		},
+		{
+			name:          "节点返回超过上限的输出",
+			code:          200,
+			data:          map[string]interface{}{"exit_code": 0, "stdout": strings.Repeat("中", 400), "stderr": strings.Repeat("e", 2000)},
+			wantOK:        true,
+			wantMsg:       "退出码 0",
+			wantOutput:    true,
+			wantTruncated: true,
+		},
+		{
+			name:    "节点接口失败",
+			code:    500,
+			wantMsg: "执行失败: 节点返回失败",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fn.respond(tt.code, tt.data)
+			ok, msg, out := exec(target)
+			if ok != tt.wantOK || msg != tt.wantMsg {
+				t.Fatalf("结果 ok=%v msg=%q，期望 ok=%v msg=%q", ok, msg, tt.wantOK, tt.wantMsg)
+			}
+			if (out != nil) != tt.wantOutput {
+				t.Fatalf("输出 = %+v", out)
+			}
+			if out == nil {
+				return
+			}
+			if out.Truncated != tt.wantTruncated || out.TimedOut != tt.wantTimedOut {
+				t.Errorf("truncated=%v timed_out=%v", out.Truncated, out.TimedOut)
+			}
+			if len(out.Stdout) > 1024 || len(out.Stderr) > 1024 {
+				t.Errorf("输出超过上限: stdout %d 字节，stderr %d 字节", len(out.Stdout), len(out.Stderr))
+			}
+			if !utf8.ValidString(out.Stdout) {
+				t.Error("截断后的输出应为合法 UTF-8")
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package services
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"lxdweb/config"
+	"lxdweb/models"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// CleanContainerPath 规范化容器内路径，只接受绝对路径
+func CleanContainerPath(p string) (string, error) {
+	if p == "" {
+		return "", errors.New("路径不能为空")
+	}
+	if !strings.HasPrefix(p, "/") || strings.ContainsRune(p, 0) {
+		return "", fmt.Errorf("路径无效: %s", p)
+	}
+	return path.Clean(p), nil
+}
+
+func pathUnder(p, root string) bool {
+	root = path.Clean(root)
+	return root == "/" || p == root || strings.HasPrefix(p, root+"/")
+}
+
+// CheckFilePath 校验路径是否允许访问，write 为 true 时同时检查只读路径。
+// 这里只能检查请求中的路径字符串，符号链接由节点按 fileAccessPolicy 在真实路径上再次校验
+func CheckFilePath(p string, write bool) (string, error) {
+	p, err := CleanContainerPath(p)
+	if err != nil {
+		return "", err
+	}
+	cfg := config.AppConfig.FileManager
+	for _, denied := range cfg.DeniedPaths {
+		if pathUnder(p, denied) {
+			return "", fmt.Errorf("禁止访问路径: %s", p)
+		}
+	}
+	if write {
+		if p == "/" {
+			return "", errors.New("禁止修改根目录")
+		}
+		for _, ro := range cfg.ReadOnlyPaths {
+			if pathUnder(p, ro) {
+				return "", fmt.Errorf("路径为只读: %s", p)
+			}
+		}
+	}
+	return p, nil
+}
+
+// fileAccessPolicy 返回发送给节点的访问策略，节点不跟随最后一级路径的符号链接
+func fileAccessPolicy() models.FileAccessPolicy {
+	cfg := config.AppConfig.FileManager
+	policy := models.FileAccessPolicy{
+		DeniedPaths:   make([]string, 0, len(cfg.DeniedPaths)),
+		ReadOnlyPaths: make([]string, 0, len(cfg.ReadOnlyPaths)),
+	}
+	for _, p := range cfg.DeniedPaths {
+		policy.DeniedPaths = append(policy.DeniedPaths, path.Clean(p))
+	}
+	for _, p := range cfg.ReadOnlyPaths {
+		policy.ReadOnlyPaths = append(policy.ReadOnlyPaths, path.Clean(p))
+	}
+	return policy
+}
+
+// fileQuery 构造带访问策略的节点文件接口查询参数
+func fileQuery(hostname, p string) url.Values {
+	policy, _ := json.Marshal(fileAccessPolicy())
+	return url.Values{"hostname": {hostname}, "path": {p}, "policy": {string(policy)}}
+}
+
+// nodeFileRequest 以原始请求体访问节点文件接口，用于上传和下载这类非 JSON 的传输
+func nodeFileRequest(node models.Node, method, apiPath string, query url.Values, body io.Reader, size int64) (*http.Response, error) {
+	client := &http.Client{
+		Timeout: 10 * time.Minute,
+		Transport: NodeAPITransport(node, &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}),
+	}
+	req, err := http.NewRequest(method, node.Address+apiPath+"?"+query.Encode(), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+		req.Header.Set("Content-Type", "application/octet-stream")
+	}
+	if node.APIKey != "" {
+		req.Header.Set("apikey", node.APIKey)
+	}
+	return client.Do(req)
+}
+
+// nodeFileError 解析节点返回的 JSON 错误
+func nodeFileError(resp *http.Response) error {
+	var result map[string]interface{}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&result); err != nil {
+		return fmt.Errorf("节点返回 HTTP %d", resp.StatusCode)
+	}
+	if result["code"] == float64(200) {
+		return nil
+	}
+	return fmt.Errorf("%v", result["msg"])
+}
+
+// ListContainerFiles 列出容器内目录
+func ListContainerFiles(node models.Node, hostname, dir string) ([]models.ContainerFile, error) {
+	query := fileQuery(hostname, dir)
+	result := callNodeAPI(node, "GET", "/api/files/list?"+query.Encode(), nil)
+	if result["code"] != float64(200) {
+		return nil, fmt.Errorf("%v", result["msg"])
+	}
+	data, err := json.Marshal(result["data"])
+	if err != nil {
+		return nil, err
+	}
+	var files []models.ContainerFile
+	if err := json.Unmarshal(data, &files); err != nil {
+		return nil, fmt.Errorf("文件列表格式错误: %v", err)
+	}
+	return files, nil
+}
+
+// OpenContainerFile 打开容器内文件用于读取，超过 limit 字节时返回错误；调用方负责关闭
+func OpenContainerFile(node models.Node, hostname, p string, limit int64) (io.ReadCloser, int64, error) {
+	resp, err := nodeFileRequest(node, "GET", "/api/files/download", fileQuery(hostname, p), nil, 0)
+	if err != nil {
+		return nil, 0, err
+	}
+	if resp.StatusCode != http.StatusOK || strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		defer resp.Body.Close()
+		return nil, 0, nodeFileError(resp)
+	}
+	if resp.ContentLength < 0 {
+		resp.Body.Close()
+		return nil, 0, errors.New("节点未返回文件大小")
+	}
+	if resp.ContentLength > limit {
+		resp.Body.Close()
+		return nil, 0, fmt.Errorf("文件大小 %d 字节超过限制 %d 字节", resp.ContentLength, limit)
+	}
+	return resp.Body, resp.ContentLength, nil
+}
+
+// WriteContainerFile 写入容器内文件，已存在时覆盖；mode 为空时由节点保留原权限或使用默认权限
+func WriteContainerFile(node models.Node, hostname, p string, body io.Reader, size int64, mode string) error {
+	query := fileQuery(hostname, p)
+	if mode != "" {
+		query.Set("mode", mode)
+	}
+	resp, err := nodeFileRequest(node, "POST", "/api/files/upload", query, body, size)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return nodeFileError(resp)
+}
+
+// DeleteContainerFile 删除容器内文件，目录需要 recursive
+func DeleteContainerFile(node models.Node, hostname, p string, recursive bool) error {
+	return nodeActionError(callNodeAPI(node, "POST", "/api/files/delete", map[string]interface{}{
+		"hostname":  hostname,
+		"path":      p,
+		"recursive": recursive,
+		"policy":    fileAccessPolicy(),
+	}))
+}
+
+// MakeContainerDir 在容器内创建目录（含上级目录）
+func MakeContainerDir(node models.Node, hostname, p string) error {
+	return nodeActionError(callNodeAPI(node, "POST", "/api/files/mkdir", map[string]interface{}{
+		"hostname": hostname,
+		"path":     p,
+		"policy":   fileAccessPolicy(),
+	}))
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"encoding/json"
+	"io"
+	"lxdweb/config"
+	"lxdweb/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func setupFileConfig(t *testing.T) {
+	t.Helper()
+	old := config.AppConfig
+	config.AppConfig = &config.Config{}
+	config.AppConfig.FileManager.DeniedPaths = []string{"/proc", "/sys/"}
+	config.AppConfig.FileManager.ReadOnlyPaths = []string{"/etc/shadow"}
+	t.Cleanup(func() { config.AppConfig = old })
+}
+
+func TestCheckFilePath(t *testing.T) {
+	setupFileConfig(t)
+	tests := []struct {
+		path  string
+		write bool
+		want  string
+		ok    bool
+	}{
+		{"/root/a.txt", true, "/root/a.txt", true},
+		{"/root/../etc/passwd", false, "/etc/passwd", true},
+		{"/proc/1/environ", false, "", false},
+		{"/root/../proc", false, "", false},
+		{"/sys", false, "", false},
+		{"/processes", false, "/processes", true},
+		{"/etc/shadow", false, "/etc/shadow", true},
+		{"/etc/shadow", true, "", false},
+		{"/", true, "", false},
+		{"root/a.txt", false, "", false},
+		{"", false, "", false},
+	}
+	for _, tt := range tests {
+		got, err := CheckFilePath(tt.path, tt.write)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("CheckFilePath(%q, %v) = %q, %v", tt.path, tt.write, got, err)
+		}
+	}
+}
+
+func TestFileRequestsSendPolicy(t *testing.T) {
+	setupFileConfig(t)
+	config.AppConfig.FileManager.DeniedPaths = append(config.AppConfig.FileManager.DeniedPaths, "/root/")
+
+	var policies []models.FileAccessPolicy
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw := r.URL.Query().Get("policy")
+		if raw == "" {
+			var body struct {
+				Policy json.RawMessage `json:"policy"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			raw = string(body.Policy)
+		} else {
+			io.Copy(io.Discard, r.Body)
+		}
+		var policy models.FileAccessPolicy
+		if err := json.Unmarshal([]byte(raw), &policy); err != nil {
+			t.Errorf("%s 未携带访问策略: %q", r.URL.Path, raw)
+		}
+		policies = append(policies, policy)
+		json.NewEncoder(w).Encode(map[string]interface{}{"code": 200, "msg": "success", "data": []models.ContainerFile{}})
+	}))
+	defer server.Close()
+	node := models.Node{Name: "node-1", Address: server.URL}
+
+	if _, err := ListContainerFiles(node, "c1", "/tmp"); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteContainerFile(node, "c1", "/tmp/a", strings.NewReader("x"), 1, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := DeleteContainerFile(node, "c1", "/tmp/a", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := MakeContainerDir(node, "c1", "/tmp/b"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(policies) != 4 {
+		t.Fatalf("期望 4 个请求，实际 %d", len(policies))
+	}
+	for _, policy := range policies {
+		if strings.Join(policy.DeniedPaths, ",") != "/proc,/sys,/root" ||
+			strings.Join(policy.ReadOnlyPaths, ",") != "/etc/shadow" || policy.FollowSymlinks {
+			t.Errorf("访问策略 = %+v", policy)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package services
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"lxdweb/config"
+	"lxdweb/database"
+	"lxdweb/models"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// healthCounter 记录节点连续成功/失败次数，用于抑制状态抖动
+type healthCounter struct {
+	consecutiveFail int
+	consecutiveOK   int
+}
+
+var (
+	healthMutex    sync.Mutex
+	healthCounters = make(map[uint]*healthCounter)
+)
+
+func StartHealthCheckService() {
+	interval := time.Duration(config.AppConfig.Health.Interval) * time.Second
+	log.Printf("[HEALTH] 节点健康检查服务启动，间隔 %v", interval)
+
+	go probeAllNodes()
+
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			probeAllNodes()
+		}
+	}()
+
+	cleanupTicker := time.NewTicker(1 * time.Hour)
+	go func() {
+		for range cleanupTicker.C {
+			cleanupHealthHistory()
+		}
+	}()
+}
+
+func probeAllNodes() {
+	var nodes []models.Node
+	database.DB.Find(&nodes)
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, 10)
+
+	for _, node := range nodes {
+		wg.Add(1)
+		go func(n models.Node) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			CheckNodeHealth(n, false)
+		}(node)
+	}
+
+	wg.Wait()
+}
+
+// probeNode 请求节点 /api/check 并返回耗时
+func probeNode(node models.Node) (int64, error) {
+	client := &http.Client{
+		Timeout: time.Duration(config.AppConfig.Health.Timeout) * time.Second,
+		Transport: NodeAPITransport(node, &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}),
+	}
+
+	req, err := http.NewRequest("GET", node.Address+"/api/check", nil)
+	if err != nil {
+		return 0, err
+	}
+	if node.APIKey != "" {
+		req.Header.Set("apikey", node.APIKey)
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	latency := time.Since(start).Milliseconds()
+	if err != nil {
+		return latency, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return latency, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return latency, nil
+}
+
+// nextHealthStatus 根据连续成功/失败次数计算节点新状态
+// 手动检查跳过抖动抑制，直接按本次结果判定
+func nextHealthStatus(current string, counter healthCounter, success bool, latencyMs int64, manual bool) string {
+	cfg := config.AppConfig.Health
+	slow := latencyMs > int64(cfg.DegradedLatencyMs)
+
+	if !success {
+		if manual || counter.consecutiveFail >= cfg.FailThreshold {
+			return models.NodeStatusDown
+		}
+		if current == models.NodeStatusActive {
+			return models.NodeStatusDegraded
+		}
+		return current
+	}
+
+	if current == models.NodeStatusActive || current == models.NodeStatusDegraded {
+		if slow {
+			return models.NodeStatusDegraded
+		}
+		if current == models.NodeStatusDegraded && !manual && counter.consecutiveOK < cfg.RecoverThreshold {
+			return current
+		}
+		return models.NodeStatusActive
+	}
+
+	if manual || counter.consecutiveOK >= cfg.RecoverThreshold {
+		if slow {
+			return models.NodeStatusDegraded
+		}
+		return models.NodeStatusActive
+	}
+	return current
+}
+
+// CheckNodeHealth 检查单个节点健康状态，更新节点状态并写入历史记录
+func CheckNodeHealth(node models.Node, manual bool) (models.NodeStatusHistory, error) {
+	latency, probeErr := probeNode(node)
+	success := probeErr == nil
+
+	healthMutex.Lock()
+	counter, ok := healthCounters[node.ID]
+	if !ok {
+		counter = &healthCounter{}
+		healthCounters[node.ID] = counter
+	}
+	if success {
+		counter.consecutiveOK++
+		counter.consecutiveFail = 0
+	} else {
+		counter.consecutiveFail++
+		counter.consecutiveOK = 0
+	}
+	newStatus := nextHealthStatus(node.Status, *counter, success, latency, manual)
+	healthMutex.Unlock()
+
+	now := time.Now()
+	history := models.NodeStatusHistory{
+		NodeID:     node.ID,
+		Status:     newStatus,
+		PrevStatus: node.Status,
+		Success:    success,
+		LatencyMs:  latency,
+		Manual:     manual,
+		CheckedAt:  now,
+	}
+	if probeErr != nil {
+		history.Error = probeErr.Error()
+	}
+	database.DB.Create(&history)
+
+	database.DB.Model(&models.Node{}).Where("id = ?", node.ID).Updates(map[string]interface{}{
+		"status":     newStatus,
+		"last_check": now,
+	})
+
+	recovered := !isOnlineStatus(node.Status) && isOnlineStatus(newStatus)
+	if newStatus != node.Status {
+		log.Printf("[HEALTH] 节点 %s 状态变化: %s -> %s (延迟 %dms)", node.Name, node.Status, newStatus, latency)
+	}
+	if newStatus == models.NodeStatusDown && node.Status != models.NodeStatusDown {
+		EmitWebhookEvent(models.WebhookEventNodeDown, map[string]interface{}{
+			"node_id":     node.ID,
+			"node_name":   node.Name,
+			"prev_status": node.Status,
+			"error":       history.Error,
+		})
+	} else if node.Status == models.NodeStatusDown && isOnlineStatus(newStatus) {
+		EmitWebhookEvent(models.WebhookEventNodeUp, map[string]interface{}{
+			"node_id":   node.ID,
+			"node_name": node.Name,
+			"status":    newStatus,
+		})
+	}
+	if success && (manual || recovered) {
+		go RefreshNodeCache(node.ID)
+	}
+
+	return history, probeErr
+}
+
+func isOnlineStatus(status string) bool {
+	for _, s := range models.OnlineNodeStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// NodeUptime 计算节点在指定时间段内的可用率（百分比），无数据时返回 -1
+func NodeUptime(nodeID uint, since time.Time) float64 {
+	var total, success int64
+	database.DB.Model(&models.NodeStatusHistory{}).
+		Where("node_id = ? AND checked_at >= ?", nodeID, since).
+		Count(&total)
+	if total == 0 {
+		return -1
+	}
+	database.DB.Model(&models.NodeStatusHistory{}).
+		Where("node_id = ? AND checked_at >= ? AND success = ?", nodeID, since, true).
+		Count(&success)
+	return float64(success) * 100 / float64(total)
+}
+
+func cleanupHealthHistory() {
+	cutoff := time.Now().AddDate(0, 0, -config.AppConfig.Health.RetentionDays)
+	result := database.DB.Where("checked_at < ?", cutoff).Delete(&models.NodeStatusHistory{})
+	if result.RowsAffected > 0 {
+		log.Printf("[HEALTH] 清理过期健康检查记录 %d 条", result.RowsAffected)
+	}
+}
+
+// ResetHealthCounter 清除节点的连续检查计数
+func ResetHealthCounter(nodeID uint) {
+	healthMutex.Lock()
+	delete(healthCounters, nodeID)
+	healthMutex.Unlock()
+}
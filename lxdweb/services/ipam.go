@@ -0,0 +1,691 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"lxdweb/database"
+	"lxdweb/models"
+	"math"
+	"math/big"
+	"net/netip"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNoIPPool 节点未配置对应地址族的可用地址池，调用方应退回由节点自行选址
+var ErrNoIPPool = errors.New("节点未配置该地址族的地址池")
+
+const (
+	// ipamScanLimit 单个地址池一次分配最多扫描的地址数，避免IPv6大前缀遍历过久
+	ipamScanLimit = 1 << 16
+	// ipamOrphanGrace 已分配但节点上不存在的地址，超过该时间后才在同步时回收
+	ipamOrphanGrace = 10 * time.Minute
+	// ipamExpandLimit 一次批量录入地址段时允许展开的最大地址数
+	ipamExpandLimit = 1024
+)
+
+// ipamMu 串行化地址分配，防止并发创建绑定时分到同一地址
+var ipamMu sync.Mutex
+
+// IPPoolUsage 地址池使用率
+type IPPoolUsage struct {
+	PoolID       uint    `json:"pool_id"`
+	Total        float64 `json:"total"`
+	Allocated    int64   `json:"allocated"`
+	Reserved     int64   `json:"reserved"`
+	Blacklisted  int64   `json:"blacklisted"`
+	Free         float64 `json:"free"`
+	UsagePercent float64 `json:"usage_percent"`
+}
+
+// IPConflict 地址冲突记录
+type IPConflict struct {
+	NodeID   uint   `json:"node_id"`
+	NodeName string `json:"node_name"`
+	Family   string `json:"family"`
+	Address  string `json:"address"`
+	Hostname string `json:"hostname"`
+	Type     string `json:"type"`
+	Detail   string `json:"detail"`
+}
+
+// ipPoolRange 解析后的地址池可分配范围
+type ipPoolRange struct {
+	prefix  netip.Prefix
+	first   netip.Addr
+	last    netip.Addr
+	gateway netip.Addr
+}
+
+func (r ipPoolRange) contains(addr netip.Addr) bool {
+	return r.prefix.Contains(addr) && addr.Compare(r.first) >= 0 && addr.Compare(r.last) <= 0
+}
+
+// size 可分配地址数量（不含网关）
+func (r ipPoolRange) size() float64 {
+	n := new(big.Int).Sub(addrToBig(r.last), addrToBig(r.first))
+	n.Add(n, big.NewInt(1))
+	if r.gateway.IsValid() && r.contains(r.gateway) {
+		n.Sub(n, big.NewInt(1))
+	}
+	f, _ := new(big.Float).SetInt(n).Float64()
+	return f
+}
+
+func addrToBig(addr netip.Addr) *big.Int {
+	return new(big.Int).SetBytes(addr.AsSlice())
+}
+
+// prefixLastAddr 前缀内最后一个地址
+func prefixLastAddr(p netip.Prefix) netip.Addr {
+	b := p.Masked().Addr().AsSlice()
+	for i := p.Bits(); i < len(b)*8; i++ {
+		b[i/8] |= 0x80 >> (i % 8)
+	}
+	addr, _ := netip.AddrFromSlice(b)
+	return addr
+}
+
+// ParseIPAddress 解析并规范化单个地址，允许带前缀长度（如 2001:db8::1/64）
+func ParseIPAddress(s string) (netip.Addr, error) {
+	s = strings.TrimSpace(s)
+	if i := strings.Index(s, "/"); i >= 0 {
+		s = s[:i]
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("无效的IP地址: %s", s)
+	}
+	return addr.Unmap().WithZone(""), nil
+}
+
+func addrFamily(addr netip.Addr) string {
+	if addr.Is4() {
+		return models.IPFamilyV4
+	}
+	return models.IPFamilyV6
+}
+
+func parseIPPoolRange(pool models.IPPool) (ipPoolRange, error) {
+	var r ipPoolRange
+
+	prefix, err := netip.ParsePrefix(strings.TrimSpace(pool.CIDR))
+	if err != nil {
+		return r, fmt.Errorf("无效的CIDR: %s", pool.CIDR)
+	}
+	r.prefix = prefix.Masked()
+	if addrFamily(r.prefix.Addr()) != pool.Family {
+		return r, fmt.Errorf("CIDR %s 与地址族 %s 不匹配", pool.CIDR, pool.Family)
+	}
+
+	// 默认跳过网络地址、广播地址（IPv4）和子网路由器任播地址（IPv6）
+	r.first = r.prefix.Addr()
+	r.last = prefixLastAddr(r.prefix)
+	if r.prefix.Addr().Is4() {
+		if r.prefix.Bits() <= 30 {
+			r.first = r.first.Next()
+			r.last = r.last.Prev()
+		}
+	} else if r.prefix.Bits() < 128 {
+		r.first = r.first.Next()
+	}
+
+	if pool.RangeStart != "" {
+		start, err := ParseIPAddress(pool.RangeStart)
+		if err != nil {
+			return r, err
+		}
+		if !r.prefix.Contains(start) {
+			return r, fmt.Errorf("起始地址 %s 不在 %s 内", start, r.prefix)
+		}
+		r.first = start
+	}
+	if pool.RangeEnd != "" {
+		end, err := ParseIPAddress(pool.RangeEnd)
+		if err != nil {
+			return r, err
+		}
+		if !r.prefix.Contains(end) {
+			return r, fmt.Errorf("结束地址 %s 不在 %s 内", end, r.prefix)
+		}
+		r.last = end
+	}
+	if r.first.Compare(r.last) > 0 {
+		return r, fmt.Errorf("起始地址 %s 大于结束地址 %s", r.first, r.last)
+	}
+
+	if pool.Gateway != "" {
+		gw, err := ParseIPAddress(pool.Gateway)
+		if err != nil {
+			return r, err
+		}
+		if !r.prefix.Contains(gw) {
+			return r, fmt.Errorf("网关 %s 不在 %s 内", gw, r.prefix)
+		}
+		r.gateway = gw
+	}
+
+	return r, nil
+}
+
+// NormalizeIPPool 校验地址池参数、规范化地址写法，并检查与同节点其他地址池是否重叠
+func NormalizeIPPool(pool *models.IPPool) error {
+	r, err := parseIPPoolRange(*pool)
+	if err != nil {
+		return err
+	}
+
+	pool.CIDR = r.prefix.String()
+	if pool.RangeStart != "" {
+		pool.RangeStart = r.first.String()
+	}
+	if pool.RangeEnd != "" {
+		pool.RangeEnd = r.last.String()
+	}
+	if r.gateway.IsValid() {
+		pool.Gateway = r.gateway.String()
+	}
+
+	var others []models.IPPool
+	database.DB.Where("node_id = ? AND family = ? AND id <> ?", pool.NodeID, pool.Family, pool.ID).Find(&others)
+	for _, other := range others {
+		or, err := parseIPPoolRange(other)
+		if err != nil {
+			continue
+		}
+		if r.first.Compare(or.last) <= 0 && or.first.Compare(r.last) <= 0 {
+			return fmt.Errorf("与地址池 %s (%s) 范围重叠", other.Name, other.CIDR)
+		}
+	}
+
+	return nil
+}
+
+// ReassignIPPoolAddresses 地址池范围变化后重新计算节点地址所属的地址池
+func ReassignIPPoolAddresses(nodeID uint, family string) {
+	var pools []models.IPPool
+	database.DB.Where("node_id = ? AND family = ?", nodeID, family).Find(&pools)
+
+	var rows []models.IPAddress
+	database.DB.Where("node_id = ? AND family = ?", nodeID, family).Find(&rows)
+
+	for _, row := range rows {
+		poolID := uint(0)
+		if addr, err := ParseIPAddress(row.Address); err == nil {
+			if pool := matchIPPool(pools, addr); pool != nil {
+				poolID = pool.ID
+			}
+		}
+		if poolID != row.PoolID {
+			database.DB.Model(&models.IPAddress{}).Where("id = ?", row.ID).Update("pool_id", poolID)
+		}
+	}
+}
+
+func matchIPPool(pools []models.IPPool, addr netip.Addr) *models.IPPool {
+	for i := range pools {
+		r, err := parseIPPoolRange(pools[i])
+		if err != nil {
+			continue
+		}
+		if r.contains(addr) {
+			return &pools[i]
+		}
+	}
+	return nil
+}
+
+// IPPoolUtilization 统计地址池使用率
+func IPPoolUtilization(pool models.IPPool) IPPoolUsage {
+	usage := IPPoolUsage{PoolID: pool.ID}
+
+	if r, err := parseIPPoolRange(pool); err == nil {
+		usage.Total = r.size()
+	}
+
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	database.DB.Model(&models.IPAddress{}).
+		Select("status, COUNT(*) as count").
+		Where("pool_id = ?", pool.ID).
+		Group("status").
+		Scan(&rows)
+
+	for _, row := range rows {
+		switch row.Status {
+		case models.IPStatusAllocated:
+			usage.Allocated = row.Count
+		case models.IPStatusReserved:
+			usage.Reserved = row.Count
+		case models.IPStatusBlacklisted:
+			usage.Blacklisted = row.Count
+		}
+	}
+
+	used := float64(usage.Allocated + usage.Reserved + usage.Blacklisted)
+	usage.Free = math.Max(usage.Total-used, 0)
+	if usage.Total > 0 {
+		usage.UsagePercent = math.Round(used/usage.Total*10000) / 100
+	}
+
+	return usage
+}
+
+// boundAddresses 从绑定缓存读取节点上实际存在的地址，返回 地址 -> 容器名
+func boundAddresses(nodeID uint, family string) map[string]string {
+	result := make(map[string]string)
+
+	if family == models.IPFamilyV4 {
+		var bindings []models.IPv4BindingCache
+		database.DB.Where("node_id = ?", nodeID).Find(&bindings)
+		for _, b := range bindings {
+			if addr, err := ParseIPAddress(b.IPv4Address); err == nil {
+				result[addr.String()] = b.Hostname
+			}
+		}
+	} else {
+		var bindings []models.IPv6BindingCache
+		database.DB.Where("node_id = ?", nodeID).Find(&bindings)
+		for _, b := range bindings {
+			if addr, err := ParseIPAddress(b.IPv6Address); err == nil {
+				result[addr.String()] = b.Hostname
+			}
+		}
+	}
+
+	return result
+}
+
+// AllocateIP 从节点的地址池中为容器分配一个空闲地址
+func AllocateIP(nodeID uint, family, hostname string) (models.IPAddress, error) {
+	ipamMu.Lock()
+	defer ipamMu.Unlock()
+
+	var pools []models.IPPool
+	database.DB.Where("node_id = ? AND family = ? AND enabled = ?", nodeID, family, true).Order("id").Find(&pools)
+	if len(pools) == 0 {
+		return models.IPAddress{}, ErrNoIPPool
+	}
+
+	used := boundAddresses(nodeID, family)
+	var taken []string
+	database.DB.Model(&models.IPAddress{}).Where("node_id = ? AND family = ?", nodeID, family).Pluck("address", &taken)
+	for _, addr := range taken {
+		used[addr] = ""
+	}
+	for addr, conflict := range clusterAddresses(nodeID, family) {
+		used[addr] = conflict.Owner
+	}
+
+	for _, pool := range pools {
+		r, err := parseIPPoolRange(pool)
+		if err != nil {
+			log.Printf("[IPAM] 地址池 %s 配置无效: %v", pool.Name, err)
+			continue
+		}
+
+		addr := r.first
+		for i := 0; i < ipamScanLimit && addr.IsValid() && addr.Compare(r.last) <= 0; i++ {
+			if _, ok := used[addr.String()]; !ok && addr != r.gateway {
+				now := time.Now()
+				row := models.IPAddress{
+					PoolID:      pool.ID,
+					NodeID:      nodeID,
+					Family:      family,
+					Address:     addr.String(),
+					Status:      models.IPStatusAllocated,
+					Hostname:    hostname,
+					Source:      models.IPSourceBinding,
+					AllocatedAt: &now,
+				}
+				if err := database.DB.Create(&row).Error; err != nil {
+					return models.IPAddress{}, fmt.Errorf("记录地址分配失败: %v", err)
+				}
+				log.Printf("[IPAM] 节点 %d 从地址池 %s 分配 %s 给 %s", nodeID, pool.Name, row.Address, hostname)
+				return row, nil
+			}
+			addr = addr.Next()
+		}
+	}
+
+	return models.IPAddress{}, fmt.Errorf("%s 地址池已无可用地址", family)
+}
+
+// ClaimIP 将指定地址登记为已分配给容器，地址必须位于节点地址池内且未被占用
+func ClaimIP(nodeID uint, address, hostname, source string) (models.IPAddress, error) {
+	ipamMu.Lock()
+	defer ipamMu.Unlock()
+
+	return claimIPLocked(nodeID, address, hostname, source)
+}
+
+func claimIPLocked(nodeID uint, address, hostname, source string) (models.IPAddress, error) {
+	addr, err := ParseIPAddress(address)
+	if err != nil {
+		return models.IPAddress{}, err
+	}
+	family := addrFamily(addr)
+
+	var pools []models.IPPool
+	database.DB.Where("node_id = ? AND family = ?", nodeID, family).Find(&pools)
+	pool := matchIPPool(pools, addr)
+	if pool == nil {
+		return models.IPAddress{}, fmt.Errorf("地址 %s 不在节点的任何地址池中", addr)
+	}
+
+	now := time.Now()
+	var row models.IPAddress
+	if err := database.DB.Where("node_id = ? AND address = ?", nodeID, addr.String()).First(&row).Error; err != nil {
+		row = models.IPAddress{
+			PoolID:      pool.ID,
+			NodeID:      nodeID,
+			Family:      family,
+			Address:     addr.String(),
+			Status:      models.IPStatusAllocated,
+			Hostname:    hostname,
+			Source:      source,
+			AllocatedAt: &now,
+		}
+		if err := database.DB.Create(&row).Error; err != nil {
+			return models.IPAddress{}, fmt.Errorf("记录地址分配失败: %v", err)
+		}
+		return row, nil
+	}
+
+	switch row.Status {
+	case models.IPStatusBlacklisted:
+		return row, fmt.Errorf("地址 %s 已被列入黑名单", addr)
+	case models.IPStatusAllocated:
+		if row.Hostname != "" && row.Hostname != hostname {
+			return row, fmt.Errorf("地址 %s 已分配给 %s", addr, row.Hostname)
+		}
+	case models.IPStatusReserved:
+		if row.Hostname != "" && row.Hostname != hostname {
+			return row, fmt.Errorf("地址 %s 已为 %s 预留", addr, row.Hostname)
+		}
+	}
+
+	row.PoolID = pool.ID
+	row.Status = models.IPStatusAllocated
+	row.Hostname = hostname
+	row.Source = source
+	row.AllocatedAt = &now
+	if err := database.DB.Save(&row).Error; err != nil {
+		return row, fmt.Errorf("记录地址分配失败: %v", err)
+	}
+	return row, nil
+}
+
+// ReleaseIP 释放已分配的地址，预留和黑名单记录保持不变
+func ReleaseIP(nodeID uint, address string) {
+	addr, err := ParseIPAddress(address)
+	if err != nil {
+		return
+	}
+
+	result := database.DB.Where("node_id = ? AND address = ? AND status = ?", nodeID, addr.String(), models.IPStatusAllocated).
+		Delete(&models.IPAddress{})
+	if result.RowsAffected > 0 {
+		log.Printf("[IPAM] 节点 %d 释放地址 %s", nodeID, addr)
+	}
+}
+
+// RecordBindingIP 绑定创建成功后登记节点实际使用的地址，地址不在地址池内时忽略
+func RecordBindingIP(nodeID uint, address, hostname string) {
+	addr, err := ParseIPAddress(address)
+	if err != nil {
+		return
+	}
+
+	var pools []models.IPPool
+	database.DB.Where("node_id = ? AND family = ?", nodeID, addrFamily(addr)).Find(&pools)
+	if matchIPPool(pools, addr) == nil {
+		return
+	}
+
+	if _, err := ClaimIP(nodeID, addr.String(), hostname, models.IPSourceBinding); err != nil {
+		log.Printf("[IPAM] 节点 %d 登记地址 %s 失败: %v", nodeID, addr, err)
+	}
+}
+
+// MarkIPAddress 将地址池内的地址标记为预留或黑名单
+func MarkIPAddress(pool models.IPPool, address, status, hostname, note string) (models.IPAddress, error) {
+	r, err := parseIPPoolRange(pool)
+	if err != nil {
+		return models.IPAddress{}, err
+	}
+	addr, err := ParseIPAddress(address)
+	if err != nil {
+		return models.IPAddress{}, err
+	}
+	if !r.contains(addr) {
+		return models.IPAddress{}, fmt.Errorf("地址 %s 不在地址池 %s 的可分配范围内", addr, pool.Name)
+	}
+
+	ipamMu.Lock()
+	defer ipamMu.Unlock()
+
+	var row models.IPAddress
+	if err := database.DB.Where("node_id = ? AND address = ?", pool.NodeID, addr.String()).First(&row).Error; err == nil {
+		if row.Status == models.IPStatusAllocated {
+			return row, fmt.Errorf("地址 %s 已分配给 %s，请先删除绑定", addr, row.Hostname)
+		}
+	} else {
+		row = models.IPAddress{
+			NodeID:  pool.NodeID,
+			Family:  pool.Family,
+			Address: addr.String(),
+		}
+	}
+
+	if _, ok := boundAddresses(pool.NodeID, pool.Family)[addr.String()]; ok && status == models.IPStatusBlacklisted {
+		return row, fmt.Errorf("地址 %s 正在节点上使用，无法拉黑", addr)
+	}
+
+	row.PoolID = pool.ID
+	row.Status = status
+	row.Hostname = hostname
+	row.Source = models.IPSourceManual
+	row.Note = note
+	if err := database.DB.Save(&row).Error; err != nil {
+		return row, err
+	}
+	return row, nil
+}
+
+// ExpandIPAddressInput 展开地址输入，支持单个地址和 起始-结束 形式的地址段
+func ExpandIPAddressInput(input string) ([]string, error) {
+	input = strings.TrimSpace(input)
+	parts := strings.SplitN(input, "-", 2)
+	if len(parts) == 1 {
+		addr, err := ParseIPAddress(input)
+		if err != nil {
+			return nil, err
+		}
+		return []string{addr.String()}, nil
+	}
+
+	start, err := ParseIPAddress(parts[0])
+	if err != nil {
+		return nil, err
+	}
+	end, err := ParseIPAddress(parts[1])
+	if err != nil {
+		return nil, err
+	}
+	if start.Is4() != end.Is4() || start.Compare(end) > 0 {
+		return nil, fmt.Errorf("无效的地址段: %s", input)
+	}
+
+	var result []string
+	for addr := start; addr.IsValid() && addr.Compare(end) <= 0; addr = addr.Next() {
+		if len(result) >= ipamExpandLimit {
+			return nil, fmt.Errorf("地址段 %s 超过 %d 个地址", input, ipamExpandLimit)
+		}
+		result = append(result, addr.String())
+	}
+	return result, nil
+}
+
+// ReconcileIPAM 用同步得到的绑定缓存校正地址池记录：登记节点上已使用但未记录的地址，回收节点上已不存在的分配
+func ReconcileIPAM(nodeID uint, family string) {
+	var pools []models.IPPool
+	database.DB.Where("node_id = ? AND family = ?", nodeID, family).Find(&pools)
+	if len(pools) == 0 {
+		return
+	}
+
+	bound := boundAddresses(nodeID, family)
+
+	ipamMu.Lock()
+	adopted := 0
+	for address, hostname := range bound {
+		addr, _ := ParseIPAddress(address)
+		if matchIPPool(pools, addr) == nil {
+			continue
+		}
+		var count int64
+		database.DB.Model(&models.IPAddress{}).Where("node_id = ? AND address = ?", nodeID, address).Count(&count)
+		if count > 0 {
+			continue
+		}
+		if _, err := claimIPLocked(nodeID, address, hostname, models.IPSourceSync); err == nil {
+			adopted++
+		}
+	}
+
+	released := 0
+	var allocated []models.IPAddress
+	database.DB.Where("node_id = ? AND family = ? AND status = ?", nodeID, family, models.IPStatusAllocated).Find(&allocated)
+	for _, row := range allocated {
+		if _, ok := bound[row.Address]; ok {
+			continue
+		}
+		if row.AllocatedAt != nil && time.Since(*row.AllocatedAt) < ipamOrphanGrace {
+			continue
+		}
+		database.DB.Delete(&row)
+		released++
+	}
+	ipamMu.Unlock()
+
+	conflicts := DetectIPConflicts(nodeID)
+	if adopted > 0 || released > 0 || len(conflicts) > 0 {
+		log.Printf("[IPAM] 节点 %d %s 地址校正完成: 登记 %d, 回收 %d, 冲突 %d", nodeID, family, adopted, released, len(conflicts))
+	}
+}
+
+// DetectIPConflicts 对比节点上的实际绑定与地址池记录，nodeID 为 0 时检查所有节点
+func DetectIPConflicts(nodeID uint) []IPConflict {
+	type binding struct {
+		nodeID   uint
+		nodeName string
+		family   string
+		address  string
+		hostname string
+	}
+
+	var all []binding
+	var v4 []models.IPv4BindingCache
+	database.DB.Find(&v4)
+	for _, b := range v4 {
+		if addr, err := ParseIPAddress(b.IPv4Address); err == nil {
+			all = append(all, binding{b.NodeID, b.NodeName, models.IPFamilyV4, addr.String(), b.Hostname})
+		}
+	}
+	var v6 []models.IPv6BindingCache
+	database.DB.Find(&v6)
+	for _, b := range v6 {
+		if addr, err := ParseIPAddress(b.IPv6Address); err == nil {
+			all = append(all, binding{b.NodeID, b.NodeName, models.IPFamilyV6, addr.String(), b.Hostname})
+		}
+	}
+
+	byAddress := make(map[string][]binding)
+	for _, b := range all {
+		byAddress[b.address] = append(byAddress[b.address], b)
+	}
+
+	rowQuery := database.DB.Model(&models.IPAddress{})
+	poolQuery := database.DB.Model(&models.IPPool{})
+	if nodeID > 0 {
+		rowQuery = rowQuery.Where("node_id = ?", nodeID)
+		poolQuery = poolQuery.Where("node_id = ?", nodeID)
+	}
+	var rows []models.IPAddress
+	rowQuery.Find(&rows)
+	records := make(map[string]models.IPAddress)
+	for _, row := range rows {
+		records[fmt.Sprintf("%d|%s", row.NodeID, row.Address)] = row
+	}
+	var pools []models.IPPool
+	poolQuery.Find(&pools)
+	poolsByNode := make(map[string][]models.IPPool)
+	for _, pool := range pools {
+		key := fmt.Sprintf("%d|%s", pool.NodeID, pool.Family)
+		poolsByNode[key] = append(poolsByNode[key], pool)
+	}
+
+	var conflicts []IPConflict
+	for _, b := range all {
+		if nodeID > 0 && b.nodeID != nodeID {
+			continue
+		}
+		add := func(typ, detail string) {
+			conflicts = append(conflicts, IPConflict{
+				NodeID:   b.nodeID,
+				NodeName: b.nodeName,
+				Family:   b.family,
+				Address:  b.address,
+				Hostname: b.hostname,
+				Type:     typ,
+				Detail:   detail,
+			})
+		}
+
+		var others []string
+		for _, o := range byAddress[b.address] {
+			if o.nodeID != b.nodeID || o.hostname != b.hostname {
+				others = append(others, fmt.Sprintf("%s/%s", o.nodeName, o.hostname))
+			}
+		}
+		if len(others) > 0 {
+			add("duplicate", "地址同时绑定在 "+strings.Join(others, ", "))
+		}
+
+		row, tracked := records[fmt.Sprintf("%d|%s", b.nodeID, b.address)]
+		switch {
+		case tracked && row.Status == models.IPStatusBlacklisted:
+			add("blacklisted_in_use", "黑名单地址正在使用")
+		case tracked && row.Status == models.IPStatusReserved && row.Hostname != "" && row.Hostname != b.hostname:
+			add("reserved_in_use", "地址已为 "+row.Hostname+" 预留")
+		case tracked && row.Status == models.IPStatusAllocated && row.Hostname != "" && row.Hostname != b.hostname:
+			add("hostname_mismatch", "地址池记录分配给 "+row.Hostname)
+		case !tracked:
+			nodePools := poolsByNode[fmt.Sprintf("%d|%s", b.nodeID, b.family)]
+			if len(nodePools) == 0 {
+				continue
+			}
+			addr, _ := ParseIPAddress(b.address)
+			if matchIPPool(nodePools, addr) == nil {
+				add("outside_pool", "地址不在节点的任何地址池中")
+			} else {
+				add("untracked", "地址在地址池内但未登记")
+			}
+		}
+	}
+
+	sort.Slice(conflicts, func(i, j int) bool {
+		if conflicts[i].NodeID != conflicts[j].NodeID {
+			return conflicts[i].NodeID < conflicts[j].NodeID
+		}
+		return conflicts[i].Address < conflicts[j].Address
+	})
+
+	return conflicts
+}
@@ -105,6 +105,8 @@ func RefreshNodeIPv6Bindings(nodeID uint) error {
 		}
 	}
 
+	ReconcileIPAM(node.ID, models.IPFamilyV6)
+
 	task.Status = "completed"
 	task.SuccessCount = successCount
 	task.FailedCount = failedCount
@@ -228,6 +230,8 @@ func SyncNodeIPv6Bindings(nodeID uint) error {
 		}
 	}
 
+	ReconcileIPAM(node.ID, models.IPFamilyV6)
+
 	task.Status = "completed"
 	task.TotalCount = totalBindings
 	task.SuccessCount = successCount
@@ -299,9 +303,9 @@ func updateIPv6Cache(node models.Node, data map[string]interface{}) error {
 func callNodeAPIForIPv6(node models.Node, method, path string, data interface{}) map[string]interface{} {
 	client := &http.Client{
 		Timeout: 30 * time.Second,
-		Transport: &http.Transport{
+		Transport: NodeAPITransport(node, &http.Transport{
 			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		},
+		}),
 	}
 
 	var body io.Reader
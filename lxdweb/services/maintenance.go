@@ -0,0 +1,217 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"lxdweb/database"
+	"lxdweb/models"
+	"time"
+)
+
+func StartMaintenanceService() {
+	log.Println("[MAINTENANCE] 维护窗口检查服务启动")
+
+	ticker := time.NewTicker(1 * time.Minute)
+	go func() {
+		for range ticker.C {
+			closeExpiredMaintenance()
+		}
+	}()
+}
+
+// closeExpiredMaintenance 结束已超过计划时间的维护窗口
+func closeExpiredMaintenance() {
+	var nodes []models.Node
+	database.DB.Where("maintenance = ? AND maintenance_until IS NOT NULL AND maintenance_until <= ?", true, time.Now()).Find(&nodes)
+
+	for _, node := range nodes {
+		if err := ExitMaintenance(node, 0, "system"); err != nil {
+			log.Printf("[MAINTENANCE] 节点 %s 自动结束维护失败: %v", node.Name, err)
+		} else {
+			log.Printf("[MAINTENANCE] 节点 %s 维护窗口到期，已自动结束维护", node.Name)
+		}
+	}
+}
+
+// ErrNodeInMaintenance 节点处于维护模式，不接受新容器、重装或批量操作
+var ErrNodeInMaintenance = errors.New("节点处于维护模式")
+
+// CheckNodeAvailable 节点处于维护模式时返回包装了 ErrNodeInMaintenance 的错误
+func CheckNodeAvailable(node models.Node) error {
+	if node.Maintenance {
+		return fmt.Errorf("%w: %s", ErrNodeInMaintenance, node.Name)
+	}
+	return nil
+}
+
+// IsNodeInMaintenance 判断节点是否处于维护模式
+func IsNodeInMaintenance(nodeID uint) bool {
+	var node models.Node
+	if err := database.DB.Select("maintenance").First(&node, nodeID).Error; err != nil {
+		return false
+	}
+	return node.Maintenance
+}
+
+// EnterMaintenance 节点进入维护模式，按需排空容器，返回排空任务（无排空时为 nil）
+func EnterMaintenance(node models.Node, req models.MaintenanceRequest, adminID uint, ip string) (*models.BatchTask, error) {
+	if node.Maintenance {
+		return nil, fmt.Errorf("节点 %s 已处于维护模式", node.Name)
+	}
+	if req.Until != nil && req.Until.Before(time.Now()) {
+		return nil, fmt.Errorf("维护结束时间不能早于当前时间")
+	}
+
+	var target *models.Node
+	if req.Drain == "migrate" {
+		var err error
+		target, err = resolveMigrationTarget(node, req.TargetNodeID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	if err := database.DB.Model(&node).Updates(map[string]interface{}{
+		"maintenance":        true,
+		"maintenance_reason": req.Reason,
+		"maintenance_start":  now,
+		"maintenance_until":  req.Until,
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	details := map[string]interface{}{
+		"node_name": node.Name,
+		"reason":    req.Reason,
+		"start":     now,
+		"until":     req.Until,
+		"drain":     req.Drain,
+	}
+	if target != nil {
+		details["target_node_id"] = target.ID
+		details["target_node_name"] = target.Name
+	}
+	RecordOperation(AuditEntry{
+		AdminID:       adminID,
+		OperationType: "node_maintenance_enter",
+		TargetType:    "node",
+		TargetID:      node.ID,
+		Details:       details,
+		IPAddress:     ip,
+	})
+
+	log.Printf("[MAINTENANCE] 节点 %s 进入维护模式: %s", node.Name, req.Reason)
+
+	switch req.Drain {
+	case "stop":
+		return startDrain(node, "drain_stop", func(t BatchTarget) (bool, string) {
+			result := callNodeAPI(t.Node, "GET", "/api/stop?hostname="+t.Hostname, nil)
+			msg, _ := result["msg"].(string)
+			return result["code"] == float64(200), msg
+		})
+	case "migrate":
+		return startDrain(node, "drain_migrate", migrateContainerFunc(*target))
+	}
+	return nil, nil
+}
+
+// ExitMaintenance 节点退出维护模式并记录维护窗口
+func ExitMaintenance(node models.Node, adminID uint, ip string) error {
+	if !node.Maintenance {
+		return fmt.Errorf("节点 %s 未处于维护模式", node.Name)
+	}
+
+	now := time.Now()
+	if err := database.DB.Model(&node).Updates(map[string]interface{}{
+		"maintenance":        false,
+		"maintenance_reason": "",
+		"maintenance_start":  nil,
+		"maintenance_until":  nil,
+	}).Error; err != nil {
+		return err
+	}
+
+	details := map[string]interface{}{
+		"node_name": node.Name,
+		"reason":    node.MaintenanceReason,
+		"start":     node.MaintenanceStart,
+		"end":       now,
+	}
+	if node.MaintenanceStart != nil {
+		details["duration_seconds"] = int(now.Sub(*node.MaintenanceStart).Seconds())
+	}
+	RecordOperation(AuditEntry{
+		AdminID:       adminID,
+		OperationType: "node_maintenance_exit",
+		TargetType:    "node",
+		TargetID:      node.ID,
+		Details:       details,
+		IPAddress:     ip,
+	})
+
+	log.Printf("[MAINTENANCE] 节点 %s 退出维护模式", node.Name)
+	return nil
+}
+
+func resolveMigrationTarget(node models.Node, targetNodeID uint) (*models.Node, error) {
+	if targetNodeID != 0 {
+		if targetNodeID == node.ID {
+			return nil, fmt.Errorf("迁移目标不能是当前节点")
+		}
+		var target models.Node
+		if err := database.DB.First(&target, targetNodeID).Error; err != nil {
+			return nil, fmt.Errorf("迁移目标节点不存在")
+		}
+		if target.Maintenance || !isOnlineStatus(target.Status) {
+			return nil, fmt.Errorf("迁移目标节点 %s 不可用", target.Name)
+		}
+		return &target, nil
+	}
+
+	var candidates []models.Node
+	database.DB.Where("id != ? AND status IN ? AND maintenance = ?", node.ID, models.OnlineNodeStatuses, false).Find(&candidates)
+	for _, sel := range []NodeSelector{
+		{Group: node.Group, Region: node.Region},
+		{Group: node.Group},
+		{},
+	} {
+		for _, candidate := range candidates {
+			if sel.Match(candidate) {
+				return &candidate, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("没有可用的迁移目标节点")
+}
+
+// migrateContainerFunc 通过源节点的 /api/migrate 接口将容器迁移到目标节点，
+// 接口约定见 docs/node_api.md
+func migrateContainerFunc(target models.Node) BatchFunc {
+	return func(t BatchTarget) (bool, string) {
+		result := callNodeAPITimeout(t.Node, "POST", "/api/migrate", map[string]interface{}{
+			"hostname":       t.Hostname,
+			"target_address": target.Address,
+			"target_apikey":  target.APIKey,
+		}, 30*time.Minute)
+		msg, _ := result["msg"].(string)
+		if result["code"] != float64(200) {
+			return false, msg
+		}
+		database.DB.Unscoped().Where("node_id = ? AND hostname = ?", t.Node.ID, t.Hostname).Delete(&models.ContainerCache{})
+		return true, fmt.Sprintf("已迁移到节点 %s", target.Name)
+	}
+}
+
+func startDrain(node models.Node, action string, fn BatchFunc) (*models.BatchTask, error) {
+	var containers []models.ContainerCache
+	database.DB.Where("node_id = ?", node.ID).Find(&containers)
+
+	targets := make([]BatchTarget, 0, len(containers))
+	for _, container := range containers {
+		targets = append(targets, BatchTarget{Node: node, Hostname: container.Hostname})
+	}
+
+	return StartBatchTask(action, fmt.Sprintf("node_id=%d", node.ID), targets, fn)
+}
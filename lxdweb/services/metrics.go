@@ -0,0 +1,479 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"lxdweb/database"
+	"lxdweb/models"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// nodeAPILatencyBuckets 节点API请求耗时直方图分桶（秒）
+var nodeAPILatencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// nodeAPIStat 单个节点的API调用统计
+type nodeAPIStat struct {
+	nodeName     string
+	requests     uint64
+	errors       uint64
+	latencySum   float64
+	bucketCounts []uint64
+}
+
+var (
+	nodeAPIMutex sync.Mutex
+	nodeAPIStats = make(map[uint]*nodeAPIStat)
+)
+
+// instrumentedTransport 记录节点API请求耗时和错误数
+type instrumentedTransport struct {
+	node models.Node
+	base http.RoundTripper
+}
+
+func (t *instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	failed := err != nil || resp.StatusCode >= 500
+	recordNodeAPICall(t.node, time.Since(start), failed)
+	return resp, err
+}
+
+// NodeAPITransport 包装访问节点的 Transport，用于采集 /metrics 中的节点API指标
+func NodeAPITransport(node models.Node, base http.RoundTripper) http.RoundTripper {
+	return &instrumentedTransport{node: node, base: base}
+}
+
+func recordNodeAPICall(node models.Node, duration time.Duration, failed bool) {
+	seconds := duration.Seconds()
+
+	nodeAPIMutex.Lock()
+	defer nodeAPIMutex.Unlock()
+
+	stat, ok := nodeAPIStats[node.ID]
+	if !ok {
+		stat = &nodeAPIStat{bucketCounts: make([]uint64, len(nodeAPILatencyBuckets))}
+		nodeAPIStats[node.ID] = stat
+	}
+	stat.nodeName = node.Name
+	stat.requests++
+	if failed {
+		stat.errors++
+	}
+	stat.latencySum += seconds
+	for i, bound := range nodeAPILatencyBuckets {
+		if seconds <= bound {
+			stat.bucketCounts[i]++
+		}
+	}
+}
+
+// metricFamily Prometheus 文本格式中的一个指标族
+type metricFamily struct {
+	name    string
+	typ     string
+	help    string
+	samples []string
+}
+
+func (f *metricFamily) add(labels map[string]string, value float64) {
+	f.addSuffixed("", labels, value)
+}
+
+func (f *metricFamily) addSuffixed(suffix string, labels map[string]string, value float64) {
+	f.samples = append(f.samples, f.name+suffix+formatMetricLabels(labels)+" "+strconv.FormatFloat(value, 'g', -1, 64))
+}
+
+// metricsRegistry 按注册顺序输出指标族
+type metricsRegistry struct {
+	families []*metricFamily
+}
+
+func (r *metricsRegistry) family(name, typ, help string) *metricFamily {
+	f := &metricFamily{name: name, typ: typ, help: help}
+	r.families = append(r.families, f)
+	return f
+}
+
+func (r *metricsRegistry) writeTo(w io.Writer) error {
+	for _, f := range r.families {
+		if len(f.samples) == 0 {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, f.typ); err != nil {
+			return err
+		}
+		for _, sample := range f.samples {
+			if _, err := io.WriteString(w, sample+"\n"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func formatMetricLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf(`%s="%s"`, k, replacer.Replace(labels[k])))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func boolMetric(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func nodeLabels(node models.Node) map[string]string {
+	return map[string]string{
+		"node":    node.Name,
+		"node_id": strconv.FormatUint(uint64(node.ID), 10),
+		"group":   node.Group,
+		"region":  node.Region,
+	}
+}
+
+// WriteMetrics 以 Prometheus 文本格式输出节点、容器和 lxdweb 自身的指标
+func WriteMetrics(w io.Writer) error {
+	reg := &metricsRegistry{}
+
+	var nodes []models.Node
+	database.DB.Order("id asc").Find(&nodes)
+	nodeMap := make(map[uint]models.Node, len(nodes))
+	for _, node := range nodes {
+		nodeMap[node.ID] = node
+	}
+
+	collectNodeMetrics(reg, nodes)
+	collectContainerMetrics(reg, nodeMap)
+	collectSyncMetrics(reg, nodeMap)
+	collectNodeAPIMetrics(reg)
+	collectQueueMetrics(reg)
+
+	return reg.writeTo(w)
+}
+
+func collectNodeMetrics(reg *metricsRegistry, nodes []models.Node) {
+	up := reg.family("lxdweb_node_up", "gauge", "Whether the node is online (active or degraded).")
+	status := reg.family("lxdweb_node_status", "gauge", "Current node health status, 1 for the active status label.")
+	maintenance := reg.family("lxdweb_node_maintenance", "gauge", "Whether the node is in maintenance mode.")
+	lastCheck := reg.family("lxdweb_node_last_check_timestamp_seconds", "gauge", "Unix time of the last node health check.")
+	info := reg.family("lxdweb_node_info", "gauge", "Node version information from the cached system info.")
+	system := reg.family("lxdweb_node_system_value", "gauge", "Numeric fields of the cached node system info.")
+	cacheAge := reg.family("lxdweb_node_info_cache_age_seconds", "gauge", "Seconds since the node system info cache was refreshed.")
+
+	var caches []models.NodeInfoCache
+	database.DB.Find(&caches)
+	cacheMap := make(map[uint]models.NodeInfoCache, len(caches))
+	for _, cache := range caches {
+		cacheMap[cache.NodeID] = cache
+	}
+
+	now := time.Now()
+	for _, node := range nodes {
+		labels := nodeLabels(node)
+		up.add(labels, boolMetric(isOnlineStatus(node.Status)))
+		for _, s := range []string{models.NodeStatusActive, models.NodeStatusDegraded, models.NodeStatusDown, models.NodeStatusInactive} {
+			status.add(mergeLabels(labels, "status", s), boolMetric(node.Status == s))
+		}
+		maintenance.add(labels, boolMetric(node.Maintenance))
+		if node.LastCheck != nil {
+			lastCheck.add(labels, float64(node.LastCheck.Unix()))
+		}
+
+		cache, ok := cacheMap[node.ID]
+		if !ok || cache.SystemInfo == "" {
+			continue
+		}
+		cacheAge.add(labels, now.Sub(cache.LastSync).Seconds())
+
+		var sysInfo map[string]interface{}
+		if err := json.Unmarshal([]byte(cache.SystemInfo), &sysInfo); err != nil {
+			continue
+		}
+		version, _ := sysInfo["version"].(string)
+		lxdVersion, _ := sysInfo["lxd_version"].(string)
+		arch := ""
+		if sys, ok := sysInfo["system"].(map[string]interface{}); ok {
+			arch, _ = sys["arch"].(string)
+		}
+		info.add(mergeLabels(labels, "version", version, "lxd_version", lxdVersion, "arch", arch), 1)
+
+		values := flattenNumeric("", sysInfo)
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			system.add(mergeLabels(labels, "key", key), values[key])
+		}
+	}
+}
+
+func collectContainerMetrics(reg *metricsRegistry, nodeMap map[uint]models.Node) {
+	running := reg.family("lxdweb_container_running", "gauge", "Whether the container is running.")
+	cpus := reg.family("lxdweb_container_cpus", "gauge", "Number of CPUs assigned to the container.")
+	cpuUsage := reg.family("lxdweb_container_cpu_usage_percent", "gauge", "Container CPU usage percentage.")
+	memUsage := reg.family("lxdweb_container_memory_usage_bytes", "gauge", "Container memory usage in bytes.")
+	memTotal := reg.family("lxdweb_container_memory_total_bytes", "gauge", "Container memory limit in bytes.")
+	diskUsage := reg.family("lxdweb_container_disk_usage_bytes", "gauge", "Container disk usage in bytes.")
+	diskTotal := reg.family("lxdweb_container_disk_total_bytes", "gauge", "Container disk limit in bytes.")
+	trafficIn := reg.family("lxdweb_container_traffic_in_bytes", "gauge", "Container inbound traffic in the current period.")
+	trafficOut := reg.family("lxdweb_container_traffic_out_bytes", "gauge", "Container outbound traffic in the current period.")
+	trafficTotal := reg.family("lxdweb_container_traffic_total_bytes", "gauge", "Container total traffic in the current period.")
+	trafficLimit := reg.family("lxdweb_container_traffic_limit_bytes", "gauge", "Container traffic limit, 0 means unlimited.")
+	lastSync := reg.family("lxdweb_container_last_sync_timestamp_seconds", "gauge", "Unix time the container cache was last synced.")
+	nodeContainers := reg.family("lxdweb_node_containers", "gauge", "Number of containers on the node by status.")
+
+	var containers []models.ContainerCache
+	database.DB.Order("node_id asc, hostname asc").Find(&containers)
+
+	type statusKey struct {
+		nodeID uint
+		status string
+	}
+	counts := make(map[statusKey]int)
+
+	for _, c := range containers {
+		node, ok := nodeMap[c.NodeID]
+		if !ok {
+			continue
+		}
+		counts[statusKey{c.NodeID, strings.ToLower(c.Status)}]++
+
+		labels := mergeLabels(map[string]string{
+			"node":    node.Name,
+			"node_id": strconv.FormatUint(uint64(node.ID), 10),
+		}, "hostname", c.Hostname)
+
+		running.add(labels, boolMetric(strings.EqualFold(c.Status, "running")))
+		cpus.add(labels, float64(c.CPUs))
+		cpuUsage.add(labels, c.CPUUsage)
+		memUsage.add(labels, float64(c.MemoryUsage))
+		memTotal.add(labels, float64(c.MemoryTotal))
+		diskUsage.add(labels, float64(c.DiskUsage))
+		diskTotal.add(labels, float64(c.DiskTotal))
+		trafficIn.add(labels, float64(c.TrafficIn))
+		trafficOut.add(labels, float64(c.TrafficOut))
+		trafficTotal.add(labels, float64(c.TrafficTotal))
+		trafficLimit.add(labels, float64(c.TrafficLimit)*1024*1024*1024)
+		if !c.LastSync.IsZero() {
+			lastSync.add(labels, float64(c.LastSync.Unix()))
+		}
+	}
+
+	keys := make([]statusKey, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].nodeID != keys[j].nodeID {
+			return keys[i].nodeID < keys[j].nodeID
+		}
+		return keys[i].status < keys[j].status
+	})
+	for _, k := range keys {
+		nodeContainers.add(mergeLabels(nodeLabels(nodeMap[k.nodeID]), "status", k.status), float64(counts[k]))
+	}
+}
+
+func collectSyncMetrics(reg *metricsRegistry, nodeMap map[uint]models.Node) {
+	lastDuration := reg.family("lxdweb_sync_last_duration_seconds", "gauge", "Duration of the last finished sync task per node and kind.")
+	lastSuccess := reg.family("lxdweb_sync_last_success", "gauge", "Whether the last finished sync task succeeded.")
+	lastEnd := reg.family("lxdweb_sync_last_end_timestamp_seconds", "gauge", "Unix time the last sync task finished.")
+	tasks := reg.family("lxdweb_sync_tasks_total", "counter", "Number of recorded sync tasks by status.")
+
+	type taskRow struct {
+		NodeID    uint
+		Status    string
+		StartTime *time.Time
+		EndTime   *time.Time
+	}
+	type countRow struct {
+		NodeID uint
+		Status string
+		Total  int64
+	}
+
+	kinds := []struct {
+		name  string
+		model interface{}
+	}{
+		{"container", &models.SyncTask{}},
+		{"nat", &models.NATSyncTask{}},
+		{"ipv6", &models.IPv6SyncTask{}},
+		{"ipv4", &models.IPv4SyncTask{}},
+		{"proxy", &models.ProxySyncTask{}},
+	}
+
+	for _, kind := range kinds {
+		var lastIDs []uint
+		database.DB.Model(kind.model).
+			Where("status IN ?", []string{"completed", "failed"}).
+			Group("node_id").
+			Pluck("max(id)", &lastIDs)
+
+		var rows []taskRow
+		if len(lastIDs) > 0 {
+			database.DB.Model(kind.model).Where("id IN ?", lastIDs).Scan(&rows)
+		}
+		for _, row := range rows {
+			node, ok := nodeMap[row.NodeID]
+			if !ok || row.StartTime == nil || row.EndTime == nil {
+				continue
+			}
+			labels := mergeLabels(nodeLabels(node), "kind", kind.name)
+			lastDuration.add(labels, row.EndTime.Sub(*row.StartTime).Seconds())
+			lastSuccess.add(labels, boolMetric(row.Status == "completed"))
+			lastEnd.add(labels, float64(row.EndTime.Unix()))
+		}
+
+		var counts []countRow
+		database.DB.Model(kind.model).
+			Select("node_id, status, count(*) as total").
+			Group("node_id, status").
+			Scan(&counts)
+		for _, row := range counts {
+			node, ok := nodeMap[row.NodeID]
+			if !ok {
+				continue
+			}
+			tasks.add(mergeLabels(nodeLabels(node), "kind", kind.name, "status", row.Status), float64(row.Total))
+		}
+	}
+}
+
+func collectNodeAPIMetrics(reg *metricsRegistry) {
+	requests := reg.family("lxdweb_node_api_requests_total", "counter", "Number of HTTP requests sent to node APIs.")
+	errors := reg.family("lxdweb_node_api_errors_total", "counter", "Number of node API requests that failed or returned 5xx.")
+	latency := reg.family("lxdweb_node_api_request_duration_seconds", "histogram", "Node API request latency.")
+
+	nodeAPIMutex.Lock()
+	defer nodeAPIMutex.Unlock()
+
+	ids := make([]uint, 0, len(nodeAPIStats))
+	for id := range nodeAPIStats {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	for _, id := range ids {
+		stat := nodeAPIStats[id]
+		labels := map[string]string{
+			"node":    stat.nodeName,
+			"node_id": strconv.FormatUint(uint64(id), 10),
+		}
+		requests.add(labels, float64(stat.requests))
+		errors.add(labels, float64(stat.errors))
+		for i, bound := range nodeAPILatencyBuckets {
+			latency.addSuffixed("_bucket", mergeLabels(labels, "le", strconv.FormatFloat(bound, 'g', -1, 64)), float64(stat.bucketCounts[i]))
+		}
+		latency.addSuffixed("_bucket", mergeLabels(labels, "le", "+Inf"), float64(stat.requests))
+		latency.addSuffixed("_sum", labels, stat.latencySum)
+		latency.addSuffixed("_count", labels, float64(stat.requests))
+	}
+}
+
+func collectQueueMetrics(reg *metricsRegistry) {
+	queue := reg.family("lxdweb_job_queue_depth", "gauge", "Number of pending or running background jobs.")
+
+	type countRow struct {
+		Status string
+		Total  int64
+	}
+
+	var batchCounts []countRow
+	database.DB.Model(&models.BatchTask{}).
+		Select("status, count(*) as total").
+		Where("status IN ?", []string{"pending", "running"}).
+		Group("status").
+		Scan(&batchCounts)
+	batchMap := map[string]int64{"pending": 0, "running": 0}
+	for _, row := range batchCounts {
+		batchMap[row.Status] = row.Total
+	}
+	queue.add(map[string]string{"queue": "batch", "status": "pending"}, float64(batchMap["pending"]))
+	queue.add(map[string]string{"queue": "batch", "status": "running"}, float64(batchMap["running"]))
+
+	for _, kind := range []struct {
+		name  string
+		model interface{}
+	}{
+		{"sync_container", &models.SyncTask{}},
+		{"sync_nat", &models.NATSyncTask{}},
+		{"sync_ipv6", &models.IPv6SyncTask{}},
+		{"sync_ipv4", &models.IPv4SyncTask{}},
+		{"sync_proxy", &models.ProxySyncTask{}},
+	} {
+		var running int64
+		database.DB.Model(kind.model).Where("status = ?", "running").Count(&running)
+		queue.add(map[string]string{"queue": kind.name, "status": "running"}, float64(running))
+	}
+
+	alerts := reg.family("lxdweb_alerts", "gauge", "Number of open alert events by status and severity.")
+	var alertCounts []struct {
+		Status   string
+		Severity string
+		Total    int64
+	}
+	database.DB.Model(&models.AlertEvent{}).
+		Select("status, severity, count(*) as total").
+		Where("status IN ?", []string{models.AlertStatusPending, models.AlertStatusFiring}).
+		Group("status, severity").
+		Scan(&alertCounts)
+	for _, row := range alertCounts {
+		alerts.add(map[string]string{"status": row.Status, "severity": row.Severity}, float64(row.Total))
+	}
+}
+
+// mergeLabels 复制标签并追加键值对
+func mergeLabels(base map[string]string, kv ...string) map[string]string {
+	labels := make(map[string]string, len(base)+len(kv)/2)
+	for k, v := range base {
+		labels[k] = v
+	}
+	for i := 0; i+1 < len(kv); i += 2 {
+		labels[kv[i]] = kv[i+1]
+	}
+	return labels
+}
+
+// flattenNumeric 展开 JSON 中的数值字段，键名以点号连接
+func flattenNumeric(prefix string, data map[string]interface{}) map[string]float64 {
+	result := make(map[string]float64)
+	for key, value := range data {
+		fullKey := key
+		if prefix != "" {
+			fullKey = prefix + "." + key
+		}
+		switch v := value.(type) {
+		case float64:
+			result[fullKey] = v
+		case map[string]interface{}:
+			for k, n := range flattenNumeric(fullKey, v) {
+				result[k] = n
+			}
+		}
+	}
+	return result
+}
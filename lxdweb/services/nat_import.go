@@ -0,0 +1,312 @@
+package services
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"lxdweb/database"
+	"lxdweb/models"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// NAT导入行状态
+const (
+	NATImportOK       = "ok"
+	NATImportInvalid  = "invalid"
+	NATImportConflict = "conflict"
+	NATImportCreated  = "created"
+	NATImportFailed   = "failed"
+)
+
+// NATImportMaxRows 单次导入的最大行数
+const NATImportMaxRows = 2000
+
+// natCSVColumns 导出CSV的列顺序
+var natCSVColumns = []string{
+	"container_hostname", "protocol", "external_port", "external_port_end",
+	"internal_port", "internal_port_end", "description",
+}
+
+// NATImportResult 单行导入结果，Row 从1开始计数（不含表头）
+type NATImportResult struct {
+	Row     int                 `json:"row"`
+	Rule    models.NATImportRow `json:"rule"`
+	Status  string              `json:"status"`
+	Message string              `json:"message"`
+}
+
+// NATExportRows 导出节点（可选限定容器）的NAT规则，合并规则表和同步缓存，
+// 同一端口映射的TCP和UDP两条记录合并为 tcp+udp
+func NATExportRows(nodeID uint, hostname string) []models.NATImportRow {
+	type entry struct {
+		row       models.NATImportRow
+		protocols map[string]bool
+	}
+	entries := make(map[string]*entry)
+	add := func(row models.NATImportRow, protocols []string) {
+		key := fmt.Sprintf("%s|%d|%d|%d|%d", row.ContainerHostname, row.ExternalPort, row.ExternalPortEnd, row.InternalPort, row.InternalPortEnd)
+		e, ok := entries[key]
+		if !ok {
+			e = &entry{row: row, protocols: make(map[string]bool)}
+			entries[key] = e
+		}
+		if e.row.Description == "" {
+			e.row.Description = row.Description
+		}
+		for _, proto := range protocols {
+			e.protocols[proto] = true
+		}
+	}
+
+	var rules []models.NATRule
+	query := database.DB.Where("node_id = ?", nodeID)
+	if hostname != "" {
+		query = query.Where("container_hostname = ?", hostname)
+	}
+	query.Find(&rules)
+	for _, r := range rules {
+		add(models.NATImportRow{
+			ContainerHostname: r.ContainerHostname,
+			ExternalPort:      r.ExternalPort,
+			ExternalPortEnd:   r.ExternalPortEnd,
+			InternalPort:      r.InternalPort,
+			InternalPortEnd:   r.InternalPortEnd,
+			Description:       r.Description,
+		}, NATProtocols(r.Protocol))
+	}
+
+	var cached []models.NATRuleCache
+	query = database.DB.Where("node_id = ?", nodeID)
+	if hostname != "" {
+		query = query.Where("container_hostname = ?", hostname)
+	}
+	query.Find(&cached)
+	for _, r := range cached {
+		add(models.NATImportRow{
+			ContainerHostname: r.ContainerHostname,
+			ExternalPort:      r.ExternalPort,
+			ExternalPortEnd:   r.ExternalPortEnd,
+			InternalPort:      r.InternalPort,
+			InternalPortEnd:   r.InternalPortEnd,
+			Description:       r.Description,
+		}, NATProtocols(strings.ToLower(r.Protocol)))
+	}
+
+	rows := make([]models.NATImportRow, 0, len(entries))
+	for _, e := range entries {
+		switch {
+		case e.protocols["tcp"] && e.protocols["udp"]:
+			e.row.Protocol = models.NATProtocolBoth
+		case e.protocols["udp"]:
+			e.row.Protocol = "udp"
+		default:
+			e.row.Protocol = "tcp"
+		}
+		rows = append(rows, e.row)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].ContainerHostname != rows[j].ContainerHostname {
+			return rows[i].ContainerHostname < rows[j].ContainerHostname
+		}
+		return rows[i].ExternalPort < rows[j].ExternalPort
+	})
+	return rows
+}
+
+// WriteNATRowsCSV 以表头+数据行的格式写出CSV
+func WriteNATRowsCSV(w io.Writer, rows []models.NATImportRow) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(natCSVColumns); err != nil {
+		return err
+	}
+	for _, r := range rows {
+		record := []string{
+			r.ContainerHostname, r.Protocol,
+			strconv.Itoa(r.ExternalPort), optionalPort(r.ExternalPortEnd),
+			strconv.Itoa(r.InternalPort), optionalPort(r.InternalPortEnd),
+			r.Description,
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func optionalPort(port int) string {
+	if port == 0 {
+		return ""
+	}
+	return strconv.Itoa(port)
+}
+
+// ParseNATRowsCSV 按表头列名解析CSV，列顺序不限，hostname 可作为 container_hostname 的别名
+func ParseNATRowsCSV(r io.Reader) ([]models.NATImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("读取CSV表头失败: %v", err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if name == "hostname" {
+			name = "container_hostname"
+		}
+		columns[name] = i
+	}
+	for _, required := range []string{"container_hostname", "protocol", "internal_port"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("CSV缺少列: %s", required)
+		}
+	}
+
+	var rows []models.NATImportRow
+	line := 1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 行解析失败: %v", line, err)
+		}
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		port := func(name string) (int, error) {
+			value := field(name)
+			if value == "" {
+				return 0, nil
+			}
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return 0, fmt.Errorf("第 %d 行 %s 不是有效端口: %s", line, name, value)
+			}
+			return n, nil
+		}
+
+		row := models.NATImportRow{
+			ContainerHostname: field("container_hostname"),
+			Protocol:          strings.ToLower(field("protocol")),
+			Description:       field("description"),
+		}
+		if row.ContainerHostname == "" && row.Protocol == "" {
+			continue
+		}
+		if row.ExternalPort, err = port("external_port"); err != nil {
+			return nil, err
+		}
+		if row.ExternalPortEnd, err = port("external_port_end"); err != nil {
+			return nil, err
+		}
+		if row.InternalPort, err = port("internal_port"); err != nil {
+			return nil, err
+		}
+		if row.InternalPortEnd, err = port("internal_port_end"); err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// ValidateNATImport 逐行校验导入数据：字段合法性、容器是否存在、与现有规则及同批次前序行的端口冲突、容器配额。
+// 外部端口为0的行将在导入时从端口池分配，此处只校验端口池是否存在
+func ValidateNATImport(nodeID uint, rows []models.NATImportRow) []NATImportResult {
+	results := make([]NATImportResult, 0, len(rows))
+	batch := make(map[string]int)
+
+	var hostnames []string
+	database.DB.Model(&models.ContainerCache{}).Where("node_id = ?", nodeID).Pluck("hostname", &hostnames)
+	containers := make(map[string]bool, len(hostnames))
+	for _, h := range hostnames {
+		containers[h] = true
+	}
+
+	var poolCount int64
+	database.DB.Model(&models.NATPortPool{}).Where("node_id = ? AND enabled = ?", nodeID, true).Count(&poolCount)
+
+	for i, row := range rows {
+		result := NATImportResult{Row: i + 1, Rule: row, Status: NATImportOK}
+		fail := func(status, format string, args ...interface{}) {
+			result.Status = status
+			result.Message = fmt.Sprintf(format, args...)
+		}
+
+		switch {
+		case row.ContainerHostname == "":
+			fail(NATImportInvalid, "缺少容器名称")
+		case row.Protocol != "tcp" && row.Protocol != "udp" && row.Protocol != models.NATProtocolBoth:
+			fail(NATImportInvalid, "不支持的协议: %s", row.Protocol)
+		case row.InternalPort < 1 || row.InternalPort > 65535:
+			fail(NATImportInvalid, "内部端口 %d 超出范围", row.InternalPort)
+		case row.ExternalPort < 0 || row.ExternalPort > 65535:
+			fail(NATImportInvalid, "外部端口 %d 超出范围", row.ExternalPort)
+		case !containers[row.ContainerHostname]:
+			fail(NATImportInvalid, "节点上不存在容器 %s", row.ContainerHostname)
+		}
+		if result.Status != NATImportOK {
+			results = append(results, result)
+			continue
+		}
+
+		externalEnd, _, err := NormalizeNATRange(row.ExternalPort, row.ExternalPortEnd, row.InternalPort, row.InternalPortEnd)
+		if err != nil {
+			fail(NATImportInvalid, "%v", err)
+			results = append(results, result)
+			continue
+		}
+		if row.ExternalPort > 0 {
+			result.Rule.ExternalPortEnd = externalEnd
+		}
+
+		if row.ExternalPort == 0 {
+			if poolCount == 0 {
+				fail(NATImportInvalid, "%v", ErrNoNATPortPool)
+			} else {
+				result.Message = "外部端口将从端口池自动分配"
+			}
+			results = append(results, result)
+			continue
+		}
+
+		start, end := NATPortSpan(row.ExternalPort, externalEnd)
+		if err := CheckNATPortRangeAvailable(nodeID, row.Protocol, start, end); err != nil {
+			fail(NATImportConflict, "%v", err)
+			results = append(results, result)
+			continue
+		}
+		for _, proto := range NATProtocols(row.Protocol) {
+			for port := start; port <= end && result.Status == NATImportOK; port++ {
+				if prev, ok := batch[fmt.Sprintf("%s|%d", proto, port)]; ok {
+					fail(NATImportConflict, "%s 端口 %d 与第 %d 行冲突", proto, port, prev)
+				}
+			}
+		}
+		if result.Status == NATImportOK {
+			if err := CheckNATQuota(nodeID, row.ContainerHostname, NATPortList(start, end)); err != nil {
+				fail(NATImportConflict, "%v", err)
+			}
+		}
+		if result.Status == NATImportOK {
+			for _, proto := range NATProtocols(row.Protocol) {
+				for port := start; port <= end; port++ {
+					batch[fmt.Sprintf("%s|%d", proto, port)] = result.Row
+				}
+			}
+		}
+		results = append(results, result)
+	}
+	return results
+}
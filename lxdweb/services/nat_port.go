@@ -0,0 +1,400 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"lxdweb/config"
+	"lxdweb/database"
+	"lxdweb/models"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrNoNATPortPool 节点未配置启用的端口池
+var ErrNoNATPortPool = errors.New("节点未配置可用的NAT端口池，请指定外部端口")
+
+// natReservationTTL 自动分配的端口在规则落库前的保留时间
+const natReservationTTL = 2 * time.Minute
+
+// NATMaxRangeSize 单条端口段规则最多包含的端口数
+const NATMaxRangeSize = 1000
+
+var (
+	natPortMu           sync.Mutex
+	natPortReservations = make(map[string]time.Time)
+)
+
+// NATPortPoolUsage 端口池使用率
+type NATPortPoolUsage struct {
+	PoolID       uint    `json:"pool_id"`
+	Total        int     `json:"total"`
+	Used         int     `json:"used"`
+	UsedTCP      int     `json:"used_tcp"`
+	UsedUDP      int     `json:"used_udp"`
+	Free         int     `json:"free"`
+	UsagePercent float64 `json:"usage_percent"`
+}
+
+// natReservationKey 端口保留的键；端口全局唯一时保留对所有节点生效
+func natReservationKey(nodeID uint, protocol string, port int) string {
+	if config.AppConfig.Uniqueness.PortScope == UniqueScopeGlobal {
+		nodeID = 0
+	}
+	return fmt.Sprintf("%d|%s|%d", nodeID, protocol, port)
+}
+
+// NATProtocols 展开规则协议，tcp+udp 规则同时占用两种协议的端口
+func NATProtocols(protocol string) []string {
+	if protocol == models.NATProtocolBoth {
+		return []string{"tcp", "udp"}
+	}
+	return []string{protocol}
+}
+
+// NATPortSpan 规则占用的外部端口区间，单端口规则的结束端口为0
+func NATPortSpan(start, end int) (int, int) {
+	if end < start {
+		return start, start
+	}
+	return start, end
+}
+
+// NormalizeNATRange 校验端口段参数并补全外部结束端口，返回端口数量。
+// 内部结束端口为0表示单端口规则；外部起始端口为0时由端口池分配，此时不计算外部结束端口
+func NormalizeNATRange(externalStart, externalEnd, internalStart, internalEnd int) (int, int, error) {
+	if internalEnd == 0 {
+		if externalEnd != 0 && externalEnd != externalStart {
+			return 0, 0, fmt.Errorf("端口段规则需要同时指定内部结束端口")
+		}
+		return 0, 1, nil
+	}
+
+	if internalStart > internalEnd {
+		return 0, 0, fmt.Errorf("内部起始端口 %d 大于结束端口 %d", internalStart, internalEnd)
+	}
+	count := internalEnd - internalStart + 1
+	if count > NATMaxRangeSize {
+		return 0, 0, fmt.Errorf("端口段最多包含 %d 个端口", NATMaxRangeSize)
+	}
+	if externalStart == 0 {
+		return 0, count, nil
+	}
+
+	if externalEnd == 0 {
+		externalEnd = externalStart + count - 1
+	}
+	if externalStart > externalEnd {
+		return 0, 0, fmt.Errorf("外部起始端口 %d 大于结束端口 %d", externalStart, externalEnd)
+	}
+	if externalEnd-externalStart+1 != count {
+		return 0, 0, fmt.Errorf("外部和内部端口数量必须一致")
+	}
+	if externalEnd > 65535 {
+		return 0, 0, fmt.Errorf("外部结束端口 %d 超出范围", externalEnd)
+	}
+	return externalEnd, count, nil
+}
+
+// natUsedPorts 节点上指定协议已占用的外部端口，返回 端口 -> 容器名，同时检查规则表和同步缓存。
+// 端口段规则按区间展开，tcp+udp 规则对两种协议都计为占用
+func natUsedPorts(nodeID uint, protocol string) map[int]string {
+	used := make(map[int]string)
+	protocols := append(NATProtocols(protocol), models.NATProtocolBoth)
+
+	var rules []models.NATRule
+	database.DB.Where("node_id = ? AND protocol IN ?", nodeID, protocols).Find(&rules)
+	for _, r := range rules {
+		start, end := NATPortSpan(r.ExternalPort, r.ExternalPortEnd)
+		for port := start; port <= end; port++ {
+			used[port] = r.ContainerHostname
+		}
+	}
+
+	var cached []models.NATRuleCache
+	database.DB.Where("node_id = ? AND LOWER(protocol) IN ?", nodeID, protocols).Find(&cached)
+	for _, r := range cached {
+		start, end := NATPortSpan(r.ExternalPort, r.ExternalPortEnd)
+		for port := start; port <= end; port++ {
+			used[port] = r.ContainerHostname
+		}
+	}
+
+	return used
+}
+
+// containerNATPorts 容器在节点上已使用的外部端口，同一端口的不同协议只计一次
+func containerNATPorts(nodeID uint, hostname string) map[int]bool {
+	ports := make(map[int]bool)
+
+	var rules []models.NATRule
+	database.DB.Where("node_id = ? AND container_hostname = ?", nodeID, hostname).Find(&rules)
+	for _, r := range rules {
+		start, end := NATPortSpan(r.ExternalPort, r.ExternalPortEnd)
+		for port := start; port <= end; port++ {
+			ports[port] = true
+		}
+	}
+
+	var cached []models.NATRuleCache
+	database.DB.Where("node_id = ? AND container_hostname = ?", nodeID, hostname).Find(&cached)
+	for _, r := range cached {
+		start, end := NATPortSpan(r.ExternalPort, r.ExternalPortEnd)
+		for port := start; port <= end; port++ {
+			ports[port] = true
+		}
+	}
+
+	return ports
+}
+
+// ContainerNATPortCount 返回容器在节点上已占用的外部端口数，与 CheckNATQuota 的计数方式一致
+func ContainerNATPortCount(nodeID uint, hostname string) int {
+	return len(containerNATPorts(nodeID, hostname))
+}
+
+// CheckNATPortAvailable 检查外部端口是否已被规则、同步缓存或未完成的自动分配占用
+func CheckNATPortAvailable(nodeID uint, protocol string, port int) error {
+	return CheckNATPortRangeAvailable(nodeID, protocol, port, port)
+}
+
+// CheckNATPortRangeAvailable 检查外部端口段内每个端口是否可用
+func CheckNATPortRangeAvailable(nodeID uint, protocol string, start, end int) error {
+	if start < 1 || end > 65535 || start > end {
+		return fmt.Errorf("端口 %d-%d 超出范围", start, end)
+	}
+	used := natUsedPorts(nodeID, protocol)
+	cluster := clusterNATPorts(nodeID, protocol)
+	for port := start; port <= end; port++ {
+		if owner, ok := used[port]; ok {
+			return &ResourceConflict{
+				Type:     ConflictTypePort,
+				Value:    strconv.Itoa(port),
+				Protocol: protocol,
+				NodeID:   nodeID,
+				Owner:    owner,
+				Scope:    config.AppConfig.Uniqueness.PortScope,
+			}
+		}
+		if conflict, ok := cluster[port]; ok {
+			return conflict
+		}
+	}
+
+	natPortMu.Lock()
+	defer natPortMu.Unlock()
+	now := time.Now()
+	for _, proto := range NATProtocols(protocol) {
+		for port := start; port <= end; port++ {
+			if expire, ok := natPortReservations[natReservationKey(nodeID, proto, port)]; ok && now.Before(expire) {
+				return fmt.Errorf("%s 端口 %d 正在分配中", proto, port)
+			}
+		}
+	}
+	return nil
+}
+
+// CheckNATQuota 检查容器再使用 ports 中的外部端口后是否超出容器配额和端口池配额
+func CheckNATQuota(nodeID uint, hostname string, ports []int) error {
+	current := containerNATPorts(nodeID, hostname)
+	added := make([]int, 0, len(ports))
+	for _, port := range ports {
+		if !current[port] {
+			added = append(added, port)
+		}
+	}
+
+	var quota models.NATPortQuota
+	if err := database.DB.Where("node_id = ? AND hostname = ?", nodeID, hostname).First(&quota).Error; err == nil && quota.MaxPorts > 0 {
+		if len(current)+len(added) > quota.MaxPorts {
+			return fmt.Errorf("容器 %s 的NAT端口配额为 %d，已使用 %d", hostname, quota.MaxPorts, len(current))
+		}
+	}
+
+	var pools []models.NATPortPool
+	database.DB.Where("node_id = ? AND container_quota > 0", nodeID).Find(&pools)
+	for _, pool := range pools {
+		inPool := 0
+		for port := range current {
+			if port >= pool.PortStart && port <= pool.PortEnd {
+				inPool++
+			}
+		}
+		for _, port := range added {
+			if port >= pool.PortStart && port <= pool.PortEnd {
+				inPool++
+			}
+		}
+		if inPool > pool.ContainerQuota {
+			return fmt.Errorf("容器 %s 在端口池 %s 中最多使用 %d 个端口", hostname, pool.Name, pool.ContainerQuota)
+		}
+	}
+
+	return nil
+}
+
+// NATPortList 将端口区间展开为端口列表
+func NATPortList(start, end int) []int {
+	start, end = NATPortSpan(start, end)
+	ports := make([]int, 0, end-start+1)
+	for port := start; port <= end; port++ {
+		ports = append(ports, port)
+	}
+	return ports
+}
+
+// AllocateNATPort 从节点端口池中为容器分配 count 个连续的空闲外部端口并返回起始端口，
+// 分配结果在 natReservationTTL 内保留，端口段不会跨越端口池
+func AllocateNATPort(nodeID uint, hostname, protocol string, count int) (int, error) {
+	if count < 1 {
+		count = 1
+	}
+
+	var pools []models.NATPortPool
+	database.DB.Where("node_id = ? AND enabled = ?", nodeID, true).Order("id").Find(&pools)
+	if len(pools) == 0 {
+		return 0, ErrNoNATPortPool
+	}
+
+	used := natUsedPorts(nodeID, protocol)
+	for port, conflict := range clusterNATPorts(nodeID, protocol) {
+		used[port] = conflict.Owner
+	}
+	protocols := NATProtocols(protocol)
+
+	natPortMu.Lock()
+	defer natPortMu.Unlock()
+
+	now := time.Now()
+	for key, expire := range natPortReservations {
+		if now.After(expire) {
+			delete(natPortReservations, key)
+		}
+	}
+
+	free := func(port int) bool {
+		if _, ok := used[port]; ok {
+			return false
+		}
+		for _, proto := range protocols {
+			if _, ok := natPortReservations[natReservationKey(nodeID, proto, port)]; ok {
+				return false
+			}
+		}
+		return true
+	}
+
+	var quotaErr error
+	for _, pool := range pools {
+		run := 0
+		for port := pool.PortStart; port <= pool.PortEnd; port++ {
+			if !free(port) {
+				run = 0
+				continue
+			}
+			run++
+			if run < count {
+				continue
+			}
+
+			start := port - count + 1
+			if err := CheckNATQuota(nodeID, hostname, NATPortList(start, port)); err != nil {
+				quotaErr = err
+				break
+			}
+			for p := start; p <= port; p++ {
+				for _, proto := range protocols {
+					natPortReservations[natReservationKey(nodeID, proto, p)] = now.Add(natReservationTTL)
+				}
+			}
+			return start, nil
+		}
+	}
+
+	if quotaErr != nil {
+		return 0, quotaErr
+	}
+	if count > 1 {
+		return 0, fmt.Errorf("端口池中没有 %d 个连续可用的 %s 端口", count, protocol)
+	}
+	return 0, fmt.Errorf("端口池已无可用的 %s 端口", protocol)
+}
+
+// ReleaseNATPortReservation 规则创建完成（成功或失败）后释放自动分配的保留
+func ReleaseNATPortReservation(nodeID uint, protocol string, start, end int) {
+	start, end = NATPortSpan(start, end)
+
+	natPortMu.Lock()
+	defer natPortMu.Unlock()
+	for _, proto := range NATProtocols(protocol) {
+		for port := start; port <= end; port++ {
+			delete(natPortReservations, natReservationKey(nodeID, proto, port))
+		}
+	}
+}
+
+// FindFreeNATPorts 列出端口池中最多 count 个空闲外部端口
+func FindFreeNATPorts(nodeID uint, protocol string, count int) []int {
+	var pools []models.NATPortPool
+	database.DB.Where("node_id = ? AND enabled = ?", nodeID, true).Order("id").Find(&pools)
+
+	used := natUsedPorts(nodeID, protocol)
+	for port, conflict := range clusterNATPorts(nodeID, protocol) {
+		used[port] = conflict.Owner
+	}
+	ports := make([]int, 0, count)
+	for _, pool := range pools {
+		for port := pool.PortStart; port <= pool.PortEnd && len(ports) < count; port++ {
+			if _, ok := used[port]; !ok {
+				ports = append(ports, port)
+			}
+		}
+	}
+	return ports
+}
+
+// ValidateNATPortPool 校验端口池范围，同节点端口池不能重叠
+func ValidateNATPortPool(pool models.NATPortPool) error {
+	if pool.PortStart > pool.PortEnd {
+		return fmt.Errorf("起始端口 %d 大于结束端口 %d", pool.PortStart, pool.PortEnd)
+	}
+
+	var others []models.NATPortPool
+	database.DB.Where("node_id = ? AND id <> ?", pool.NodeID, pool.ID).Find(&others)
+	for _, other := range others {
+		if pool.PortStart <= other.PortEnd && other.PortStart <= pool.PortEnd {
+			return fmt.Errorf("与端口池 %s (%d-%d) 范围重叠", other.Name, other.PortStart, other.PortEnd)
+		}
+	}
+	return nil
+}
+
+// NATPortPoolUtilization 统计端口池使用率，TCP和UDP任一协议占用即计为已使用
+func NATPortPoolUtilization(pool models.NATPortPool) NATPortPoolUsage {
+	usage := NATPortPoolUsage{
+		PoolID: pool.ID,
+		Total:  pool.PortEnd - pool.PortStart + 1,
+	}
+
+	used := make(map[int]bool)
+	for port := range natUsedPorts(pool.NodeID, "tcp") {
+		if port >= pool.PortStart && port <= pool.PortEnd {
+			usage.UsedTCP++
+			used[port] = true
+		}
+	}
+	for port := range natUsedPorts(pool.NodeID, "udp") {
+		if port >= pool.PortStart && port <= pool.PortEnd {
+			usage.UsedUDP++
+			used[port] = true
+		}
+	}
+
+	usage.Used = len(used)
+	usage.Free = usage.Total - usage.Used
+	if usage.Total > 0 {
+		usage.UsagePercent = math.Round(float64(usage.Used)/float64(usage.Total)*10000) / 100
+	}
+	return usage
+}
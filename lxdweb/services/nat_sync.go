@@ -8,6 +8,7 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
@@ -28,7 +29,7 @@ func StartNATSyncService() {
 // SyncAllNodesNATAsync 同步所有活动节点的NAT规则
 func SyncAllNodesNATAsync() {
 	var nodes []models.Node
-	database.DB.Where("status = ?", "active").Find(&nodes)
+	database.DB.Where("status IN ? AND maintenance = ?", models.OnlineNodeStatuses, false).Find(&nodes)
 	
 	log.Printf("[NAT-SYNC] 开始实时同步 %d 个活动节点的NAT规则", len(nodes))
 	
@@ -125,9 +126,10 @@ func RefreshNodeNATRules(nodeID uint, manual bool) error {
 			failedCount++
 		} else {
 			successCount++
-			hostname, _ := ruleData["hostname"].(string)
-			external, _ := ruleData["external"].(float64)
+			hostname, _ := ruleData["container_name"].(string)
+			external, _ := ruleData["external_port"].(float64)
 			protocol, _ := ruleData["protocol"].(string)
+			protocol = strings.ToLower(protocol)
 			key := fmt.Sprintf("%d-%s-%d-%s", node.ID, hostname, int(external), protocol)
 			existingRules[key] = true
 		}
@@ -250,6 +252,7 @@ func SyncNodeNATRules(nodeID uint, manual bool) error {
 						successCount++
 						external, _ := ruleData["external_port"].(float64)
 						protocol, _ := ruleData["protocol"].(string)
+						protocol = strings.ToLower(protocol)
 						key := fmt.Sprintf("%d-%s-%d-%s", node.ID, hostname, int(external), protocol)
 						existingRules[key] = true
 					}
@@ -290,6 +293,15 @@ func updateNATCache(node models.Node, data map[string]interface{}) error {
 	external, _ := data["external_port"].(float64)
 	internal, _ := data["internal_port"].(float64)
 	protocol, _ := data["protocol"].(string)
+	protocol = strings.ToLower(protocol)
+	externalEnd, ok := data["external_port_end"].(float64)
+	if !ok {
+		externalEnd, _ = data["dport_end"].(float64)
+	}
+	internalEnd, ok := data["internal_port_end"].(float64)
+	if !ok {
+		internalEnd, _ = data["sport_end"].(float64)
+	}
 	
 	if hostname == "" || protocol == "" {
 		return fmt.Errorf("缺少必要字段")
@@ -318,6 +330,8 @@ func updateNATCache(node models.Node, data map[string]interface{}) error {
 		ExternalPort:      int(external),
 		Protocol:          protocol,
 		InternalPort:      int(internal),
+		ExternalPortEnd:   int(externalEnd),
+		InternalPortEnd:   int(internalEnd),
 		LastSync:          time.Now(),
 		SyncError:         "",
 	}
@@ -337,8 +351,8 @@ func updateNATCache(node models.Node, data map[string]interface{}) error {
 			{Name: "protocol"},
 		},
 		DoUpdates: clause.AssignmentColumns([]string{
-			"node_name", "internal_port", "description", "status",
-			"last_sync", "sync_error",
+			"node_name", "internal_port", "external_port_end", "internal_port_end",
+			"description", "status", "last_sync", "sync_error",
 		}),
 	}).Create(&cache)
 	
@@ -348,9 +362,9 @@ func updateNATCache(node models.Node, data map[string]interface{}) error {
 func callNodeAPIForNAT(node models.Node, method, path string, data interface{}) map[string]interface{} {
 	client := &http.Client{
 		Timeout: 30 * time.Second,
-		Transport: &http.Transport{
+		Transport: NodeAPITransport(node, &http.Transport{
 			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		},
+		}),
 	}
 	
 	var body io.Reader
@@ -3,6 +3,7 @@ package services
 import (
 	"crypto/tls"
 	"encoding/json"
+	"fmt"
 	"log"
 	"lxdweb/database"
 	"lxdweb/models"
@@ -30,7 +31,7 @@ func StartNodeCacheService() {
 
 func refreshAllNodeCache() {
 	var nodes []models.Node
-	database.DB.Find(&nodes)
+	database.DB.Where("maintenance = ?", false).Find(&nodes)
 	
 	if len(nodes) == 0 {
 		return
@@ -59,15 +60,15 @@ func refreshAllNodeCache() {
 func cacheNodeInfo(node models.Node) {
 	client := &http.Client{
 		Timeout: 8 * time.Second,
-		Transport: &http.Transport{
+		Transport: NodeAPITransport(node, &http.Transport{
 			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		},
+		}),
 	}
 	
 	req, err := http.NewRequest("GET", node.Address+"/", nil)
 	if err != nil {
 		log.Printf("[NODE-CACHE] 节点 %s 创建请求失败: %v", node.Name, err)
-		clearNodeCache(node.ID)
+		markNodeCacheError(node.ID, "创建请求失败: "+err.Error())
 		return
 	}
 	
@@ -78,21 +79,21 @@ func cacheNodeInfo(node models.Node) {
 	resp, err := client.Do(req)
 	if err != nil {
 		log.Printf("[NODE-CACHE] 节点 %s 连接失败: %v", node.Name, err)
-		clearNodeCache(node.ID)
+		markNodeCacheError(node.ID, "连接失败: "+err.Error())
 		return
 	}
 	defer resp.Body.Close()
 	
 	if resp.StatusCode != 200 {
 		log.Printf("[NODE-CACHE] 节点 %s 返回状态码: %d", node.Name, resp.StatusCode)
-		clearNodeCache(node.ID)
+		markNodeCacheError(node.ID, fmt.Sprintf("HTTP %d", resp.StatusCode))
 		return
 	}
 	
 	var sysInfo map[string]interface{}
 	if err := json.NewDecoder(resp.Body).Decode(&sysInfo); err != nil {
 		log.Printf("[NODE-CACHE] 节点 %s 解析响应失败: %v", node.Name, err)
-		clearNodeCache(node.ID)
+		markNodeCacheError(node.ID, "解析响应失败: "+err.Error())
 		return
 	}
 
@@ -110,7 +111,7 @@ func cacheNodeInfo(node models.Node) {
 	
 	result := database.DB.Clauses(clause.OnConflict{
 		Columns:   []clause.Column{{Name: "node_id"}},
-		DoUpdates: clause.AssignmentColumns([]string{"system_info", "last_sync"}),
+		DoUpdates: clause.AssignmentColumns([]string{"system_info", "last_sync", "last_error", "last_error_at"}),
 	}).Create(&cache)
 	
 	if result.Error != nil {
@@ -120,8 +121,18 @@ func cacheNodeInfo(node models.Node) {
 	}
 }
 
-func clearNodeCache(nodeID uint) {
-	database.DB.Unscoped().Where("node_id = ?", nodeID).Delete(&models.NodeInfoCache{})
+// markNodeCacheError 记录缓存刷新失败原因，保留上一次成功获取的系统信息
+func markNodeCacheError(nodeID uint, msg string) {
+	now := time.Now()
+	cache := models.NodeInfoCache{
+		NodeID:      nodeID,
+		LastError:   msg,
+		LastErrorAt: &now,
+	}
+	database.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "node_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"last_error", "last_error_at", "updated_at"}),
+	}).Create(&cache)
 }
 
 func GetNodeCache(nodeID uint) (map[string]interface{}, error) {
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"fmt"
+	"lxdweb/database"
+	"lxdweb/models"
+)
+
+// NodeSelector 按分组、地域、标签筛选节点
+type NodeSelector struct {
+	Group  string   `json:"group"`
+	Region string   `json:"region"`
+	Tags   []string `json:"tags"`
+}
+
+func (s NodeSelector) IsEmpty() bool {
+	return s.Group == "" && s.Region == "" && len(models.NormalizeTags(s.Tags)) == 0
+}
+
+func (s NodeSelector) Match(node models.Node) bool {
+	if s.Group != "" && node.Group != s.Group {
+		return false
+	}
+	if s.Region != "" && node.Region != s.Region {
+		return false
+	}
+	return node.HasTags(s.Tags)
+}
+
+func (s NodeSelector) String() string {
+	return fmt.Sprintf("group=%s region=%s tags=%s", s.Group, s.Region, models.JoinTags(s.Tags))
+}
+
+// FindNodes 查询符合条件的节点，activeOnly 为 true 时只返回在线且未处于维护模式的节点
+func FindNodes(sel NodeSelector, activeOnly bool) ([]models.Node, error) {
+	var nodes []models.Node
+	query := database.DB.Order("id asc")
+	if sel.Group != "" {
+		query = query.Where("node_group = ?", sel.Group)
+	}
+	if sel.Region != "" {
+		query = query.Where("region = ?", sel.Region)
+	}
+	if activeOnly {
+		query = query.Where("status IN ? AND maintenance = ?", models.OnlineNodeStatuses, false)
+	}
+	if err := query.Find(&nodes).Error; err != nil {
+		return nil, err
+	}
+
+	result := make([]models.Node, 0, len(nodes))
+	for _, node := range nodes {
+		if sel.Match(node) {
+			result = append(result, node)
+		}
+	}
+	return result, nil
+}
+
+// SelectPlacementNode 在符合条件的在线节点中选择容器数量最少的节点
+func SelectPlacementNode(sel NodeSelector) (*models.Node, error) {
+	nodes, err := FindNodes(sel, true)
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("没有符合条件的可用节点 (%s)", sel.String())
+	}
+
+	type countRow struct {
+		NodeID uint
+		Total  int
+	}
+	var rows []countRow
+	database.DB.Model(&models.ContainerCache{}).
+		Select("node_id, count(*) as total").
+		Group("node_id").
+		Scan(&rows)
+	counts := make(map[uint]int)
+	for _, row := range rows {
+		counts[row.NodeID] = row.Total
+	}
+
+	best := nodes[0]
+	for _, node := range nodes[1:] {
+		if counts[node.ID] < counts[best.ID] {
+			best = node
+		}
+	}
+	return &best, nil
+}
@@ -0,0 +1,344 @@
+package services
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"lxdweb/config"
+	"lxdweb/database"
+	"lxdweb/models"
+	"math"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ParseCertificatePEM 解析PEM证书链中的第一张（叶子）证书
+func ParseCertificatePEM(certPEM string) (*x509.Certificate, error) {
+	rest := []byte(certPEM)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return nil, errors.New("未找到有效的PEM证书")
+		}
+		if block.Type == "CERTIFICATE" {
+			return x509.ParseCertificate(block.Bytes)
+		}
+	}
+}
+
+// ValidateCertificatePair 校验证书与私钥是否匹配，返回叶子证书
+func ValidateCertificatePair(certPEM, keyPEM string) (*x509.Certificate, error) {
+	pair, err := tls.X509KeyPair([]byte(certPEM), []byte(keyPEM))
+	if err != nil {
+		return nil, fmt.Errorf("证书与私钥不匹配或格式错误: %v", err)
+	}
+	return x509.ParseCertificate(pair.Certificate[0])
+}
+
+// CertificateCoversDomain 检查证书是否覆盖指定域名（含通配符）
+func CertificateCoversDomain(leaf *x509.Certificate, domain string) bool {
+	return leaf.VerifyHostname(domain) == nil
+}
+
+// certificateStatus 按到期时间计算证书状态
+func certificateStatus(notAfter *time.Time, now time.Time) string {
+	if notAfter == nil {
+		return models.CertStatusUnknown
+	}
+	if now.After(*notAfter) {
+		return models.CertStatusExpired
+	}
+	renewBefore := time.Duration(config.AppConfig.ACME.RenewBeforeDays) * 24 * time.Hour
+	if notAfter.Sub(now) < renewBefore {
+		return models.CertStatusExpiring
+	}
+	return models.CertStatusValid
+}
+
+// applyCertificateInfo 将叶子证书的签发者、有效期等信息写入记录
+func applyCertificateInfo(cert *models.ProxyCertificate, leaf *x509.Certificate) {
+	notBefore := leaf.NotBefore
+	notAfter := leaf.NotAfter
+	sum := sha256.Sum256(leaf.Raw)
+
+	cert.Issuer = leaf.Issuer.String()
+	cert.Subject = leaf.Subject.String()
+	cert.SerialNumber = leaf.SerialNumber.Text(16)
+	cert.DNSNames = strings.Join(leaf.DNSNames, ",")
+	cert.Fingerprint = hex.EncodeToString(sum[:])
+	cert.NotBefore = &notBefore
+	cert.NotAfter = &notAfter
+	cert.DomainMismatch = !CertificateCoversDomain(leaf, cert.Domain)
+	cert.Status = certificateStatus(cert.NotAfter, time.Now())
+}
+
+// findProxyCertificate 按节点和域名查找证书记录，不存在时返回带基本字段的新记录
+func findProxyCertificate(nodeID uint, domain string) models.ProxyCertificate {
+	var cert models.ProxyCertificate
+	if err := database.DB.Where("node_id = ? AND domain = ?", nodeID, domain).First(&cert).Error; err != nil {
+		cert = models.ProxyCertificate{NodeID: nodeID, Domain: domain, AutoRenew: true}
+	}
+	return cert
+}
+
+// RecordCustomCertificate 保存手动上传的证书并解析有效期，证书与私钥不匹配时返回错误
+func RecordCustomCertificate(nodeID uint, hostname, domain, certPEM, keyPEM string) (*models.ProxyCertificate, error) {
+	leaf, err := ValidateCertificatePair(certPEM, keyPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	cert := findProxyCertificate(nodeID, domain)
+	if hostname != "" {
+		cert.Hostname = hostname
+	}
+	cert.Source = models.CertSourceCustom
+	cert.CertPEM = certPEM
+	cert.KeyPEM = keyPEM
+	cert.LastError = ""
+	applyCertificateInfo(&cert, leaf)
+
+	if err := database.DB.Save(&cert).Error; err != nil {
+		return nil, err
+	}
+	return &cert, nil
+}
+
+// RequestACMECertificate 为域名创建或重置ACME证书记录，签发由 IssueProxyCertificate 完成
+func RequestACMECertificate(nodeID uint, hostname, domain string) (*models.ProxyCertificate, error) {
+	cert := findProxyCertificate(nodeID, domain)
+	if hostname != "" {
+		cert.Hostname = hostname
+	}
+	cert.Source = models.CertSourceACME
+	if cert.Status != models.CertStatusIssuing {
+		cert.Status = models.CertStatusPending
+	}
+	if cert.ID == 0 {
+		cert.AutoRenew = true
+	}
+	if err := database.DB.Save(&cert).Error; err != nil {
+		return nil, err
+	}
+	return &cert, nil
+}
+
+// SyncProxyCertificates 根据代理配置缓存维护证书记录：为启用SSL的代理补建记录并关联配置，
+// 清理已关联但代理已被删除的记录
+func SyncProxyCertificates(nodeID uint) {
+	var configs []models.ProxyConfigCache
+	database.DB.Where("node_id = ?", nodeID).Find(&configs)
+
+	domains := make(map[string]models.ProxyConfigCache, len(configs))
+	for _, cfg := range configs {
+		domains[cfg.Domain] = cfg
+	}
+
+	var certs []models.ProxyCertificate
+	database.DB.Where("node_id = ?", nodeID).Find(&certs)
+	tracked := make(map[string]bool, len(certs))
+	for _, cert := range certs {
+		cfg, ok := domains[cert.Domain]
+		if !ok {
+			if cert.ProxyConfigID != 0 {
+				database.DB.Delete(&cert)
+				log.Printf("[CERT] 代理 %s 已不存在，删除证书记录", cert.Domain)
+			}
+			continue
+		}
+		tracked[cert.Domain] = true
+		if cert.ProxyConfigID != cfg.ID || cert.Hostname != cfg.Hostname {
+			database.DB.Model(&cert).Updates(map[string]interface{}{
+				"proxy_config_id": cfg.ID,
+				"hostname":        cfg.Hostname,
+			})
+		}
+	}
+
+	for _, cfg := range configs {
+		if !cfg.SSLEnabled || tracked[cfg.Domain] {
+			continue
+		}
+		source := models.CertSourceSelfSigned
+		if cfg.SSLType == models.CertSourceCustom {
+			source = models.CertSourceCustom
+		}
+		database.DB.Create(&models.ProxyCertificate{
+			NodeID:        nodeID,
+			ProxyConfigID: cfg.ID,
+			Hostname:      cfg.Hostname,
+			Domain:        cfg.Domain,
+			Source:        source,
+			Status:        models.CertStatusUnknown,
+			AutoRenew:     true,
+		})
+	}
+}
+
+// RefreshCertificateStatuses 按当前时间重新计算已知有效期证书的状态，签发中和签发失败的记录不变
+func RefreshCertificateStatuses() {
+	var certs []models.ProxyCertificate
+	database.DB.Where("not_after IS NOT NULL AND status NOT IN ?",
+		[]string{models.CertStatusIssuing, models.CertStatusFailed}).Find(&certs)
+
+	now := time.Now()
+	for _, cert := range certs {
+		if status := certificateStatus(cert.NotAfter, now); status != cert.Status {
+			database.DB.Model(&cert).Update("status", status)
+		}
+	}
+}
+
+// DeleteProxyCertificate 删除域名的证书记录
+func DeleteProxyCertificate(nodeID uint, domain string) {
+	err := database.DB.Where("node_id = ? AND domain = ?", nodeID, domain).Delete(&models.ProxyCertificate{}).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Printf("[CERT] 删除证书记录失败 %s: %v", domain, err)
+	}
+}
+
+// certProbeTimeout 连接节点获取证书的超时时间
+const certProbeTimeout = 10 * time.Second
+
+// CheckProxyCertificates 检查所有在线节点上启用SSL的反向代理实际提供的证书，
+// 记录有效期、签发者和SAN，无法连接时退回到已保存的证书
+func CheckProxyCertificates() {
+	var nodes []models.Node
+	database.DB.Where("status IN ?", models.OnlineNodeStatuses).Find(&nodes)
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, 10)
+
+	for _, node := range nodes {
+		var certs []models.ProxyCertificate
+		database.DB.Joins("JOIN proxy_config_caches ON proxy_config_caches.node_id = proxy_certificates.node_id AND proxy_config_caches.domain = proxy_certificates.domain").
+			Where("proxy_certificates.node_id = ? AND proxy_config_caches.ssl_enabled = ? AND proxy_config_caches.deleted_at IS NULL", node.ID, true).
+			Find(&certs)
+
+		for _, cert := range certs {
+			wg.Add(1)
+			go func(n models.Node, c models.ProxyCertificate) {
+				defer wg.Done()
+				sem <- struct{}{}
+				defer func() { <-sem }()
+				checkProxyCertificate(n, c)
+			}(node, cert)
+		}
+	}
+
+	wg.Wait()
+}
+
+func checkProxyCertificate(node models.Node, cert models.ProxyCertificate) {
+	now := time.Now()
+	cert.CheckedAt = &now
+	cert.CheckError = ""
+
+	leaf, err := probeServedCertificate(node, cert.Domain)
+	if err != nil {
+		cert.CheckError = err.Error()
+		if cert.CertPEM != "" {
+			leaf, _ = ParseCertificatePEM(cert.CertPEM)
+		}
+	}
+
+	columns := []string{"checked_at", "check_error"}
+	if leaf != nil {
+		status := cert.Status
+		applyCertificateInfo(&cert, leaf)
+		if status == models.CertStatusIssuing {
+			cert.Status = status
+		}
+		columns = append(columns, "issuer", "subject", "serial_number", "dns_names", "fingerprint",
+			"not_before", "not_after", "domain_mismatch", "status")
+	}
+
+	if err := database.DB.Model(&cert).Select(columns).Updates(&cert).Error; err != nil {
+		log.Printf("[CERT] 保存证书检查结果失败 %s: %v", cert.Domain, err)
+		return
+	}
+	if cert.DomainMismatch || cert.Status == models.CertStatusExpired {
+		log.Printf("[CERT] 节点 %s 域名 %s 证书异常: 状态 %s, 域名不匹配 %v", node.Name, cert.Domain, cert.Status, cert.DomainMismatch)
+	}
+}
+
+// probeServedCertificate 以代理域名作为SNI连接节点443端口，返回节点实际提供的叶子证书
+func probeServedCertificate(node models.Node, domain string) (*x509.Certificate, error) {
+	host := node.Address
+	if u, err := url.Parse(node.Address); err == nil && u.Hostname() != "" {
+		host = u.Hostname()
+	}
+
+	dialer := &net.Dialer{Timeout: certProbeTimeout}
+	conn, err := tls.DialWithDialer(dialer, "tcp", net.JoinHostPort(host, "443"), &tls.Config{
+		ServerName:         domain,
+		InsecureSkipVerify: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("连接节点443端口失败: %v", err)
+	}
+	defer conn.Close()
+
+	peers := conn.ConnectionState().PeerCertificates
+	if len(peers) == 0 {
+		return nil, errors.New("节点未返回证书")
+	}
+	return peers[0], nil
+}
+
+// CertificateDaysLeft 返回证书剩余有效天数（向下取整，已过期为负数）
+func CertificateDaysLeft(notAfter time.Time, now time.Time) int {
+	return int(math.Floor(notAfter.Sub(now).Hours() / 24))
+}
+
+// ProxyConfigCacheViews 为反向代理缓存附加证书检查结果
+func ProxyConfigCacheViews(configs []models.ProxyConfigCache) []models.ProxyConfigCacheView {
+	views := make([]models.ProxyConfigCacheView, 0, len(configs))
+	if len(configs) == 0 {
+		return views
+	}
+
+	nodeIDs := make([]uint, 0, len(configs))
+	for _, cfg := range configs {
+		nodeIDs = append(nodeIDs, cfg.NodeID)
+	}
+	var certs []models.ProxyCertificate
+	database.DB.Where("node_id IN ?", nodeIDs).Find(&certs)
+	certMap := make(map[string]models.ProxyCertificate, len(certs))
+	for _, cert := range certs {
+		certMap[fmt.Sprintf("%d:%s", cert.NodeID, cert.Domain)] = cert
+	}
+
+	now := time.Now()
+	for _, cfg := range configs {
+		view := models.ProxyConfigCacheView{ProxyConfigCache: cfg}
+		if cert, ok := certMap[fmt.Sprintf("%d:%s", cfg.NodeID, cfg.Domain)]; ok && cfg.SSLEnabled {
+			view.CertStatus = cert.Status
+			view.CertIssuer = cert.Issuer
+			view.CertDNSNames = cert.DNSNames
+			view.CertNotAfter = cert.NotAfter
+			view.CertDomainMismatch = cert.DomainMismatch
+			view.CertCheckedAt = cert.CheckedAt
+			view.CertCheckError = cert.CheckError
+			if cert.NotAfter != nil {
+				daysLeft := CertificateDaysLeft(*cert.NotAfter, now)
+				view.CertDaysLeft = &daysLeft
+				view.CertExpired = now.After(*cert.NotAfter)
+				view.CertExpiring = !view.CertExpired && certificateStatus(cert.NotAfter, now) == models.CertStatusExpiring
+			}
+		}
+		views = append(views, view)
+	}
+	return views
+}
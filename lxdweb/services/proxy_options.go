@@ -0,0 +1,250 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"lxdweb/database"
+	"lxdweb/models"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm/clause"
+)
+
+const (
+	ProxyMaxHeaders   = 20
+	ProxyMaxLocations = 20
+	ProxyMaxRedirects = 20
+
+	proxyDefaultBodySize = "100M"
+)
+
+var (
+	proxyHeaderNamePattern = regexp.MustCompile(`^[A-Za-z0-9-]{1,64}$`)
+	proxyPathPattern       = regexp.MustCompile(`^/[A-Za-z0-9._~/-]*$`)
+	proxyBodySizePattern   = regexp.MustCompile(`^[0-9]{1,6}[kKmMgG]?$`)
+	proxyUsernamePattern   = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+	proxyHostnamePattern   = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9-]{0,62}$`)
+)
+
+// proxyDirectiveUnsafe 检查值中是否含有会破坏 nginx 指令的字符
+func proxyDirectiveUnsafe(value string) bool {
+	return strings.ContainsAny(value, "\"\\\r\n;{}") || strings.TrimSpace(value) != value
+}
+
+// NormalizeProxyOptions 校验反向代理高级选项并补全默认值
+func NormalizeProxyOptions(opts *models.ProxyOptions, sslEnabled bool) error {
+	forceHTTPS := sslEnabled
+	if opts.ForceHTTPS != nil {
+		forceHTTPS = *opts.ForceHTTPS && sslEnabled
+	}
+	opts.ForceHTTPS = &forceHTTPS
+
+	if opts.ClientMaxBodySize == "" {
+		opts.ClientMaxBodySize = proxyDefaultBodySize
+	}
+	if !proxyBodySizePattern.MatchString(opts.ClientMaxBodySize) {
+		return fmt.Errorf("请求体大小格式错误: %s，应为数字加可选单位 k/m/g", opts.ClientMaxBodySize)
+	}
+
+	if len(opts.Headers) > ProxyMaxHeaders {
+		return fmt.Errorf("自定义请求头最多 %d 个", ProxyMaxHeaders)
+	}
+	seenHeaders := make(map[string]bool, len(opts.Headers))
+	for _, h := range opts.Headers {
+		if !proxyHeaderNamePattern.MatchString(h.Name) {
+			return fmt.Errorf("请求头名称无效: %s", h.Name)
+		}
+		if proxyDirectiveUnsafe(h.Value) {
+			return fmt.Errorf("请求头 %s 的值包含非法字符", h.Name)
+		}
+		key := strings.ToLower(h.Name)
+		if seenHeaders[key] {
+			return fmt.Errorf("请求头重复: %s", h.Name)
+		}
+		seenHeaders[key] = true
+	}
+
+	if opts.BasicAuth != nil {
+		if !proxyUsernamePattern.MatchString(opts.BasicAuth.Username) {
+			return fmt.Errorf("Basic Auth 用户名只能包含字母、数字、点、下划线和短横线")
+		}
+		if len(opts.BasicAuth.Password) < 6 || len(opts.BasicAuth.Password) > 128 {
+			return fmt.Errorf("Basic Auth 密码长度应为 6-128 位")
+		}
+	}
+
+	if len(opts.Locations) > ProxyMaxLocations {
+		return fmt.Errorf("路径后端最多 %d 个", ProxyMaxLocations)
+	}
+	paths := make(map[string]bool, len(opts.Locations)+len(opts.Redirects))
+	for i := range opts.Locations {
+		loc := &opts.Locations[i]
+		if !proxyPathPattern.MatchString(loc.Path) {
+			return fmt.Errorf("路径格式错误: %s", loc.Path)
+		}
+		if loc.Port < 1 || loc.Port > 65535 {
+			return fmt.Errorf("路径 %s 的后端端口无效: %d", loc.Path, loc.Port)
+		}
+		if loc.Hostname != "" && !proxyHostnamePattern.MatchString(loc.Hostname) {
+			return fmt.Errorf("路径 %s 的后端容器名称无效: %s", loc.Path, loc.Hostname)
+		}
+		if loc.StripPrefix && !strings.HasSuffix(loc.Path, "/") {
+			// 去除前缀时 location 与 proxy_pass 都需以 / 结尾，否则后端收到的路径会出现双斜杠
+			loc.Path += "/"
+		}
+		if paths[loc.Path] {
+			return fmt.Errorf("路径重复: %s", loc.Path)
+		}
+		paths[loc.Path] = true
+	}
+
+	if len(opts.Redirects) > ProxyMaxRedirects {
+		return fmt.Errorf("跳转规则最多 %d 个", ProxyMaxRedirects)
+	}
+	for i := range opts.Redirects {
+		r := &opts.Redirects[i]
+		if !proxyPathPattern.MatchString(r.Path) {
+			return fmt.Errorf("跳转路径格式错误: %s", r.Path)
+		}
+		if r.Path == "/" {
+			return fmt.Errorf("不能对根路径设置跳转")
+		}
+		if paths[r.Path] {
+			return fmt.Errorf("路径重复: %s", r.Path)
+		}
+		paths[r.Path] = true
+
+		if r.Code == 0 {
+			r.Code = 301
+		}
+		switch r.Code {
+		case 301, 302, 307, 308:
+		default:
+			return fmt.Errorf("跳转状态码只能是 301/302/307/308")
+		}
+		if !strings.HasPrefix(r.Target, "/") && !strings.HasPrefix(r.Target, "http://") && !strings.HasPrefix(r.Target, "https://") {
+			return fmt.Errorf("跳转目标必须是以 / 开头的路径或 http(s) 地址: %s", r.Target)
+		}
+		if strings.ContainsAny(r.Target, " \t") || proxyDirectiveUnsafe(r.Target) {
+			return fmt.Errorf("跳转目标包含非法字符: %s", r.Target)
+		}
+	}
+
+	return nil
+}
+
+// ProxyHTPasswdHash 生成 nginx auth_basic_user_file 支持的 {SSHA} 密码哈希
+func ProxyHTPasswdHash(password string) (string, error) {
+	salt := make([]byte, 8)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	sum := sha1.Sum(append([]byte(password), salt...))
+	return "{SSHA}" + base64.StdEncoding.EncodeToString(append(sum[:], salt...)), nil
+}
+
+// ProxyNodePayload 组装下发到节点 /api/proxy/add 的参数
+func ProxyNodePayload(hostname, domain string, port int, description string, opts models.ProxyOptions, authHash string) map[string]interface{} {
+	forceHTTPS := opts.ForceHTTPS != nil && *opts.ForceHTTPS
+	data := map[string]interface{}{
+		"hostname":             hostname,
+		"domain":               domain,
+		"container_port":       port,
+		"description":          description,
+		"force_https":          forceHTTPS,
+		"websocket":            opts.WebSocket,
+		"client_max_body_size": opts.ClientMaxBodySize,
+	}
+
+	headers := make([]map[string]interface{}, 0, len(opts.Headers))
+	for _, h := range opts.Headers {
+		headers = append(headers, map[string]interface{}{"name": h.Name, "value": h.Value})
+	}
+	data["headers"] = headers
+
+	locations := make([]map[string]interface{}, 0, len(opts.Locations))
+	for _, loc := range opts.Locations {
+		backend := loc.Hostname
+		if backend == "" {
+			backend = hostname
+		}
+		locations = append(locations, map[string]interface{}{
+			"path":         loc.Path,
+			"hostname":     backend,
+			"port":         loc.Port,
+			"strip_prefix": loc.StripPrefix,
+		})
+	}
+	data["locations"] = locations
+
+	redirects := make([]map[string]interface{}, 0, len(opts.Redirects))
+	for _, r := range opts.Redirects {
+		redirects = append(redirects, map[string]interface{}{"path": r.Path, "target": r.Target, "code": r.Code})
+	}
+	data["redirects"] = redirects
+
+	if opts.BasicAuth != nil && authHash != "" {
+		data["basic_auth_user"] = opts.BasicAuth.Username
+		data["basic_auth_htpasswd"] = opts.BasicAuth.Username + ":" + authHash
+	}
+
+	return data
+}
+
+// ProxyCacheOptions 解析缓存中保存的高级选项，旧数据没有选项时返回默认值
+func ProxyCacheOptions(cache models.ProxyConfigCache) models.ProxyOptions {
+	var opts models.ProxyOptions
+	if cache.Options != "" {
+		json.Unmarshal([]byte(cache.Options), &opts)
+	}
+	if opts.ForceHTTPS == nil {
+		forceHTTPS := cache.SSLEnabled
+		opts.ForceHTTPS = &forceHTTPS
+	}
+	if opts.ClientMaxBodySize == "" {
+		opts.ClientMaxBodySize = proxyDefaultBodySize
+	}
+	return opts
+}
+
+// ProxyCachePayload 按缓存的配置重新组装节点参数，用于重新部署证书等场景
+func ProxyCachePayload(cache models.ProxyConfigCache) map[string]interface{} {
+	return ProxyNodePayload(cache.Hostname, cache.Domain, cache.BackendPort, "", ProxyCacheOptions(cache), cache.AuthHash)
+}
+
+// SaveProxyOptions 保存创建代理时提交的高级选项（不含密码明文），
+// 选项由 lxdweb 维护，节点同步不会覆盖
+func SaveProxyOptions(node models.Node, hostname, domain string, port int, sslEnabled bool, sslType string, opts models.ProxyOptions, authHash string) error {
+	if opts.BasicAuth != nil {
+		opts.BasicAuth = &models.ProxyBasicAuth{Username: opts.BasicAuth.Username}
+	}
+	raw, err := json.Marshal(opts)
+	if err != nil {
+		return err
+	}
+
+	cache := models.ProxyConfigCache{
+		NodeID:      node.ID,
+		NodeName:    node.Name,
+		Hostname:    hostname,
+		Domain:      domain,
+		BackendPort: port,
+		SSLEnabled:  sslEnabled,
+		SSLType:     sslType,
+		Options:     string(raw),
+		AuthHash:    authHash,
+		Status:      "active",
+	}
+	return database.DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{
+			{Name: "node_id"},
+			{Name: "hostname"},
+			{Name: "domain"},
+		},
+		DoUpdates: clause.AssignmentColumns([]string{"options", "auth_hash"}),
+	}).Create(&cache).Error
+}
@@ -115,6 +115,8 @@ func RefreshNodeProxyConfigs(nodeID uint) error {
 	log.Printf("[PROXY-REFRESH] 节点 %s Proxy配置刷新完成: 成功 %d, 失败 %d, 总计 %d",
 		node.Name, successCount, failedCount, task.TotalCount)
 
+	SyncProxyCertificates(node.ID)
+
 	return nil
 }
 
@@ -236,6 +238,8 @@ func SyncNodeProxyConfigs(nodeID uint) error {
 	log.Printf("[PROXY-SYNC] 节点 %s Proxy配置实时同步完成: 成功 %d, 失败 %d, 总计 %d",
 		node.Name, successCount, failedCount, task.TotalCount)
 
+	SyncProxyCertificates(node.ID)
+
 	return nil
 }
 
@@ -304,7 +308,7 @@ func updateProxyCache(node models.Node, data map[string]interface{}) error {
 			{Name: "domain"},
 		},
 		DoUpdates: clause.AssignmentColumns([]string{
-			"node_name", "backend_port", "ssl_enabled", "status",
+			"node_name", "backend_port", "ssl_enabled", "ssl_type", "status",
 			"last_sync", "sync_error",
 		}),
 	}).Create(&cache)
@@ -315,9 +319,9 @@ func updateProxyCache(node models.Node, data map[string]interface{}) error {
 func callNodeAPIForProxy(node models.Node, method, path string, data interface{}) map[string]interface{} {
 	client := &http.Client{
 		Timeout: 30 * time.Second,
-		Transport: &http.Transport{
+		Transport: NodeAPITransport(node, &http.Transport{
 			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		},
+		}),
 	}
 
 	var body io.Reader
@@ -0,0 +1,262 @@
+package services
+
+import (
+	"encoding/json"
+	"log"
+	"lxdweb/config"
+	"lxdweb/database"
+	"lxdweb/models"
+	"strings"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// metricRollups 逐级汇总规则：source 精度的数据汇总到 target 精度
+var metricRollups = []struct {
+	source string
+	target string
+	step   time.Duration
+}{
+	{models.MetricResolutionRaw, models.MetricResolution5Min, 5 * time.Minute},
+	{models.MetricResolution5Min, models.MetricResolutionHourly, time.Hour},
+	{models.MetricResolutionHourly, models.MetricResolutionDaily, 24 * time.Hour},
+}
+
+// StartResourceMetricService 启动资源历史汇总服务，每5分钟汇总一次并清理过期数据
+func StartResourceMetricService() {
+	log.Println("[METRIC] 资源历史汇总服务启动")
+
+	ticker := time.NewTicker(5 * time.Minute)
+	go func() {
+		for range ticker.C {
+			for _, r := range metricRollups {
+				rollupResourceMetrics(r.source, r.target, r.step)
+			}
+			cleanupResourceMetrics()
+		}
+	}()
+}
+
+// metricBucket 返回时间 t 在指定精度下所属区间的起始时间
+func metricBucket(t time.Time, resolution string) time.Time {
+	switch resolution {
+	case models.MetricResolution5Min:
+		return t.Truncate(5 * time.Minute)
+	case models.MetricResolutionHourly:
+		return t.Truncate(time.Hour)
+	case models.MetricResolutionDaily:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	}
+	return t
+}
+
+// RecordContainerMetric 记录容器的一次原始资源采样
+func RecordContainerMetric(cache models.ContainerCache) {
+	point := models.ResourceMetric{
+		Scope:        models.MetricScopeContainer,
+		NodeID:       cache.NodeID,
+		Hostname:     cache.Hostname,
+		Resolution:   models.MetricResolutionRaw,
+		Timestamp:    time.Now(),
+		CPUUsage:     cache.CPUUsage,
+		CPUMax:       cache.CPUUsage,
+		MemoryUsage:  cache.MemoryUsage,
+		MemoryTotal:  cache.MemoryTotal,
+		DiskUsage:    cache.DiskUsage,
+		DiskTotal:    cache.DiskTotal,
+		TrafficTotal: cache.TrafficTotal,
+		TrafficLimit: cache.TrafficLimit,
+		Samples:      1,
+	}
+	if err := database.DB.Create(&point).Error; err != nil {
+		log.Printf("[METRIC] 记录容器 %s 资源采样失败: %v", cache.Hostname, err)
+	}
+}
+
+// nodeResources 节点信息中 resources 字段上报的宿主机资源用量，字段约定见 docs/node_api.md
+type nodeResources struct {
+	CPUUsage    float64 `json:"cpu_usage"`
+	MemoryUsage uint64  `json:"memory_usage_raw"`
+	MemoryTotal uint64  `json:"memory_total_raw"`
+	DiskUsage   uint64  `json:"disk_usage_raw"`
+	DiskTotal   uint64  `json:"disk_total_raw"`
+}
+
+// nodeHostUsage 从节点信息缓存读取宿主机资源用量，节点未上报时返回 false
+func nodeHostUsage(systemInfo string) (nodeResources, bool) {
+	var info struct {
+		Resources *nodeResources `json:"resources"`
+	}
+	if systemInfo == "" || json.Unmarshal([]byte(systemInfo), &info) != nil || info.Resources == nil {
+		return nodeResources{}, false
+	}
+	return *info.Resources, true
+}
+
+// RecordNodeMetric 记录节点的一次原始资源采样。CPU、内存和磁盘取自节点信息缓存中的宿主机用量，
+// 随节点信息缓存刷新而更新；容器数、运行数和流量为节点上所有容器缓存的汇总
+func RecordNodeMetric(nodeID uint) {
+	var caches []models.ContainerCache
+	database.DB.Where("node_id = ?", nodeID).Find(&caches)
+
+	point := models.ResourceMetric{
+		Scope:      models.MetricScopeNode,
+		NodeID:     nodeID,
+		Resolution: models.MetricResolutionRaw,
+		Timestamp:  time.Now(),
+		Containers: len(caches),
+		Samples:    1,
+	}
+	for _, c := range caches {
+		point.TrafficTotal += c.TrafficTotal
+		if strings.EqualFold(c.Status, "running") {
+			point.Running++
+		}
+	}
+
+	var info models.NodeInfoCache
+	if err := database.DB.Where("node_id = ?", nodeID).First(&info).Error; err == nil {
+		if r, ok := nodeHostUsage(info.SystemInfo); ok {
+			point.CPUUsage = r.CPUUsage
+			point.MemoryUsage = r.MemoryUsage
+			point.MemoryTotal = r.MemoryTotal
+			point.DiskUsage = r.DiskUsage
+			point.DiskTotal = r.DiskTotal
+		}
+	}
+	point.CPUMax = point.CPUUsage
+
+	if err := database.DB.Create(&point).Error; err != nil {
+		log.Printf("[METRIC] 记录节点 %d 资源采样失败: %v", nodeID, err)
+	}
+}
+
+// rollupResourceMetrics 将最近两个区间内的 source 数据按 target 精度重新汇总，
+// 当前未结束的区间同样汇总，重复执行结果一致
+func rollupResourceMetrics(source, target string, step time.Duration) {
+	now := time.Now()
+	from := metricBucket(now.Add(-2*step), target)
+
+	var points []models.ResourceMetric
+	database.DB.Where("resolution = ? AND timestamp >= ?", source, from).Order("timestamp").Find(&points)
+	if len(points) == 0 {
+		return
+	}
+
+	type bucketKey struct {
+		scope    string
+		nodeID   uint
+		hostname string
+		ts       int64
+	}
+	buckets := make(map[bucketKey]*models.ResourceMetric)
+	order := make([]bucketKey, 0)
+	for _, p := range points {
+		ts := metricBucket(p.Timestamp, target)
+		key := bucketKey{p.Scope, p.NodeID, p.Hostname, ts.Unix()}
+		b, ok := buckets[key]
+		if !ok {
+			b = &models.ResourceMetric{
+				Scope:      p.Scope,
+				NodeID:     p.NodeID,
+				Hostname:   p.Hostname,
+				Resolution: target,
+				Timestamp:  ts,
+			}
+			buckets[key] = b
+			order = append(order, key)
+		}
+
+		// 使用率按采样数加权累加，最后统一求平均；容量和流量取区间内最新值
+		weight := p.Samples
+		if weight <= 0 {
+			weight = 1
+		}
+		b.CPUUsage += p.CPUUsage * float64(weight)
+		b.MemoryUsage += p.MemoryUsage * uint64(weight)
+		b.DiskUsage += p.DiskUsage * uint64(weight)
+		b.Containers += p.Containers * weight
+		b.Running += p.Running * weight
+		b.Samples += weight
+		if p.CPUMax > b.CPUMax {
+			b.CPUMax = p.CPUMax
+		}
+		b.MemoryTotal = p.MemoryTotal
+		b.DiskTotal = p.DiskTotal
+		b.TrafficTotal = p.TrafficTotal
+		b.TrafficLimit = p.TrafficLimit
+	}
+
+	rows := make([]models.ResourceMetric, 0, len(order))
+	for _, key := range order {
+		b := buckets[key]
+		n := b.Samples
+		b.CPUUsage /= float64(n)
+		b.MemoryUsage /= uint64(n)
+		b.DiskUsage /= uint64(n)
+		b.Containers /= n
+		b.Running /= n
+		rows = append(rows, *b)
+	}
+
+	err := database.DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{
+			{Name: "scope"},
+			{Name: "node_id"},
+			{Name: "hostname"},
+			{Name: "resolution"},
+			{Name: "timestamp"},
+		},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"cpu_usage", "cpu_max", "memory_usage", "memory_total",
+			"disk_usage", "disk_total", "traffic_total", "traffic_limit",
+			"containers", "running", "samples",
+		}),
+	}).CreateInBatches(rows, 100).Error
+	if err != nil {
+		log.Printf("[METRIC] 汇总 %s -> %s 失败: %v", source, target, err)
+	}
+}
+
+// cleanupResourceMetrics 按各精度的保留时间删除过期数据
+func cleanupResourceMetrics() {
+	cfg := config.AppConfig.ResourceHistory
+	now := time.Now()
+	retention := map[string]time.Time{
+		models.MetricResolutionRaw:    now.Add(-time.Duration(cfg.RawHours) * time.Hour),
+		models.MetricResolution5Min:   now.AddDate(0, 0, -cfg.FiveMinDays),
+		models.MetricResolutionHourly: now.AddDate(0, 0, -cfg.HourlyDays),
+		models.MetricResolutionDaily:  now.AddDate(0, 0, -cfg.DailyDays),
+	}
+	for resolution, cutoff := range retention {
+		result := database.DB.Where("resolution = ? AND timestamp < ?", resolution, cutoff).Delete(&models.ResourceMetric{})
+		if result.Error != nil {
+			log.Printf("[METRIC] 清理 %s 精度历史数据失败: %v", resolution, result.Error)
+		}
+	}
+}
+
+// MetricResolutionFor 按查询时间跨度选择精度，跨度越大精度越粗，且不超过该精度的保留时间
+func MetricResolutionFor(span time.Duration) string {
+	cfg := config.AppConfig.ResourceHistory
+	day := 24 * time.Hour
+	switch {
+	case span <= 6*time.Hour && span <= time.Duration(cfg.RawHours)*time.Hour:
+		return models.MetricResolutionRaw
+	case span <= 3*day && span <= time.Duration(cfg.FiveMinDays)*day:
+		return models.MetricResolution5Min
+	case span <= time.Duration(cfg.HourlyDays)*day:
+		return models.MetricResolutionHourly
+	}
+	return models.MetricResolutionDaily
+}
+
+// QueryResourceMetrics 查询容器或节点在 [from, to] 内指定精度的资源历史
+func QueryResourceMetrics(scope string, nodeID uint, hostname string, resolution string, from, to time.Time) []models.ResourceMetric {
+	var points []models.ResourceMetric
+	database.DB.Where("scope = ? AND node_id = ? AND hostname = ? AND resolution = ? AND timestamp >= ? AND timestamp <= ?",
+		scope, nodeID, hostname, resolution, from, to).
+		Order("timestamp").Find(&points)
+	return points
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"lxdweb/database"
+	"lxdweb/models"
+	"testing"
+)
+
+func TestRecordNodeMetric(t *testing.T) {
+	setupTestDB(t, &models.ResourceMetric{}, &models.ContainerCache{}, &models.NodeInfoCache{})
+
+	for _, c := range []models.ContainerCache{
+		{NodeID: 1, Hostname: "a", Status: "Running", CPUUsage: 80, MemoryUsage: 1 << 30, MemoryTotal: 2 << 30, DiskTotal: 10 << 30, TrafficTotal: 100},
+		{NodeID: 1, Hostname: "b", Status: "Running", CPUUsage: 90, MemoryUsage: 1 << 30, MemoryTotal: 2 << 30, DiskTotal: 10 << 30, TrafficTotal: 200},
+		{NodeID: 1, Hostname: "c", Status: "Stopped"},
+	} {
+		if err := database.DB.Create(&c).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	latest := func() models.ResourceMetric {
+		t.Helper()
+		var m models.ResourceMetric
+		if err := database.DB.Where("scope = ? AND node_id = ?", models.MetricScopeNode, 1).
+			Order("id DESC").First(&m).Error; err != nil {
+			t.Fatalf("未记录节点采样: %v", err)
+		}
+		return m
+	}
+
+	// 节点未上报宿主机用量时不以容器用量之和代替
+	RecordNodeMetric(1)
+	m := latest()
+	if m.CPUUsage != 0 || m.MemoryUsage != 0 || m.MemoryTotal != 0 || m.DiskTotal != 0 {
+		t.Fatalf("没有宿主机用量时 CPU/内存/磁盘应为0，实际 %+v", m)
+	}
+	if m.Containers != 3 || m.Running != 2 || m.TrafficTotal != 300 {
+		t.Fatalf("容器汇总 containers=%d running=%d traffic=%d", m.Containers, m.Running, m.TrafficTotal)
+	}
+
+	info := models.NodeInfoCache{
+		NodeID:     1,
+		SystemInfo: `{"version":"1.0","resources":{"cpu_usage":37.5,"memory_usage_raw":3000,"memory_total_raw":8000,"disk_usage_raw":50000,"disk_total_raw":100000}}`,
+	}
+	if err := database.DB.Create(&info).Error; err != nil {
+		t.Fatal(err)
+	}
+	RecordNodeMetric(1)
+	m = latest()
+	if m.CPUUsage != 37.5 || m.CPUMax != 37.5 {
+		t.Errorf("CPU 应取宿主机用量 37.5，实际 %v/%v", m.CPUUsage, m.CPUMax)
+	}
+	if m.MemoryUsage != 3000 || m.MemoryTotal != 8000 || m.DiskUsage != 50000 || m.DiskTotal != 100000 {
+		t.Errorf("内存/磁盘应取宿主机用量，实际 %+v", m)
+	}
+	if m.Containers != 3 || m.Running != 2 || m.TrafficTotal != 300 {
+		t.Errorf("容器汇总 containers=%d running=%d traffic=%d", m.Containers, m.Running, m.TrafficTotal)
+	}
+}
@@ -0,0 +1,319 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"lxdweb/config"
+	"lxdweb/database"
+	"lxdweb/models"
+	"lxdweb/pkg/cronexpr"
+	"sync"
+	"time"
+)
+
+// scheduleLateTolerance 计划时间之后多久内执行仍视为准时，超过则按错过处理
+const scheduleLateTolerance = 2 * time.Minute
+
+var (
+	scheduleMu      sync.Mutex
+	scheduleRunning = make(map[uint]bool)
+)
+
+// StartSchedulerService 启动计划任务调度服务，每30秒检查一次到期任务
+func StartSchedulerService() {
+	log.Println("[SCHEDULE] 计划任务调度服务启动")
+
+	// 服务重启前未结束的执行已中断
+	database.DB.Model(&models.ScheduledTaskRun{}).Where("status = ?", "running").
+		Updates(map[string]interface{}{"status": "failed", "error_message": "服务重启，执行中断"})
+
+	ticker := time.NewTicker(30 * time.Second)
+	go func() {
+		dispatchDueSchedules()
+		for range ticker.C {
+			dispatchDueSchedules()
+		}
+	}()
+
+	cleanupTicker := time.NewTicker(1 * time.Hour)
+	go func() {
+		for range cleanupTicker.C {
+			cleanupScheduleRuns()
+		}
+	}()
+}
+
+// ScheduleLocation 解析任务时区，未设置时使用配置的默认时区
+func ScheduleLocation(tz string) (*time.Location, error) {
+	if tz == "" {
+		tz = config.AppConfig.Scheduler.Timezone
+	}
+	if tz == "" || tz == "Local" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("无效的时区: %s", tz)
+	}
+	return loc, nil
+}
+
+// NextScheduleRuns 按任务时区计算 after 之后的 n 次执行时间
+func NextScheduleRuns(expr, tz string, after time.Time, n int) ([]time.Time, error) {
+	schedule, err := cronexpr.Parse(expr)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := ScheduleLocation(tz)
+	if err != nil {
+		return nil, err
+	}
+
+	runs := make([]time.Time, 0, n)
+	t := after.In(loc)
+	for len(runs) < n {
+		t = schedule.Next(t)
+		if t.IsZero() {
+			break
+		}
+		runs = append(runs, t)
+	}
+	if len(runs) == 0 {
+		return nil, errors.New("cron 表达式没有可执行的时间")
+	}
+	return runs, nil
+}
+
+// PrepareScheduledTask 校验任务配置并计算下次执行时间
+func PrepareScheduledTask(task *models.ScheduledTask) error {
+	switch task.TargetType {
+	case models.ScheduleTargetContainer:
+		if task.NodeID == 0 || task.Hostname == "" {
+			return errors.New("容器任务需要指定节点和容器名")
+		}
+	case models.ScheduleTargetGroup:
+		if task.NodeGroup == "" {
+			return errors.New("分组任务需要指定节点分组")
+		}
+	}
+	if task.MissedPolicy == "" {
+		task.MissedPolicy = models.ScheduleMissedSkip
+	}
+
+	runs, err := NextScheduleRuns(task.CronExpr, task.Timezone, time.Now(), 1)
+	if err != nil {
+		return err
+	}
+	// 统一按服务器时区存储，保证数据库中的时间比较一致
+	next := runs[0].In(time.Local)
+	task.NextRunAt = &next
+	return nil
+}
+
+// dispatchDueSchedules 执行所有到期的计划任务，错过执行时间的按任务策略跳过或补执行一次
+func dispatchDueSchedules() {
+	now := time.Now()
+	var tasks []models.ScheduledTask
+	database.DB.Where("enabled = ? AND next_run_at IS NOT NULL AND next_run_at <= ?", true, now).Find(&tasks)
+
+	for _, task := range tasks {
+		scheduledAt := *task.NextRunAt
+
+		// 先推进下次执行时间，避免执行耗时较长时被重复调度
+		var next *time.Time
+		if runs, err := NextScheduleRuns(task.CronExpr, task.Timezone, now, 1); err == nil {
+			t := runs[0].In(time.Local)
+			next = &t
+		} else {
+			log.Printf("[SCHEDULE] 任务 %s 计算下次执行时间失败: %v", task.Name, err)
+		}
+		database.DB.Model(&task).Update("next_run_at", next)
+
+		late := now.Sub(scheduledAt)
+		if late <= scheduleLateTolerance {
+			RunScheduledTask(task, models.ScheduleTriggerSchedule, scheduledAt)
+			continue
+		}
+
+		window := time.Duration(config.AppConfig.Scheduler.CatchupWindow) * time.Minute
+		if task.MissedPolicy == models.ScheduleMissedRunOnce && late <= window {
+			log.Printf("[SCHEDULE] 任务 %s 错过执行时间 %s，补执行一次", task.Name, scheduledAt.Format("2006-01-02 15:04"))
+			RunScheduledTask(task, models.ScheduleTriggerCatchup, scheduledAt)
+			continue
+		}
+
+		log.Printf("[SCHEDULE] 任务 %s 错过执行时间 %s，已跳过", task.Name, scheduledAt.Format("2006-01-02 15:04"))
+		recordSkippedRun(task, models.ScheduleTriggerSchedule, scheduledAt,
+			fmt.Sprintf("错过执行时间（延迟 %s），按策略 %s 跳过", late.Truncate(time.Second), task.MissedPolicy))
+	}
+}
+
+func recordSkippedRun(task models.ScheduledTask, trigger string, scheduledAt time.Time, reason string) *models.ScheduledTaskRun {
+	run := models.ScheduledTaskRun{
+		TaskID:       task.ID,
+		Trigger:      trigger,
+		Status:       "skipped",
+		ScheduledAt:  scheduledAt,
+		ErrorMessage: reason,
+	}
+	database.DB.Create(&run)
+	database.DB.Model(&task).Updates(map[string]interface{}{"last_status": run.Status})
+	return &run
+}
+
+// RunScheduledTask 创建执行记录并在后台执行任务，同一任务上次执行未结束时跳过
+func RunScheduledTask(task models.ScheduledTask, trigger string, scheduledAt time.Time) *models.ScheduledTaskRun {
+	scheduleMu.Lock()
+	if scheduleRunning[task.ID] {
+		scheduleMu.Unlock()
+		return recordSkippedRun(task, trigger, scheduledAt, "上次执行尚未完成")
+	}
+	scheduleRunning[task.ID] = true
+	scheduleMu.Unlock()
+
+	now := time.Now()
+	run := models.ScheduledTaskRun{
+		TaskID:      task.ID,
+		Trigger:     trigger,
+		Status:      "running",
+		ScheduledAt: scheduledAt,
+		StartTime:   &now,
+	}
+	database.DB.Create(&run)
+
+	go func() {
+		defer func() {
+			scheduleMu.Lock()
+			delete(scheduleRunning, task.ID)
+			scheduleMu.Unlock()
+		}()
+		executeScheduledRun(task, run)
+	}()
+	return &run
+}
+
+func executeScheduledRun(task models.ScheduledTask, run models.ScheduledTaskRun) {
+	log.Printf("[SCHEDULE] 任务 %s 开始执行: %s (%s)", task.Name, task.Action, run.Trigger)
+
+	targets, err := scheduleTargets(task)
+	var fn BatchFunc
+	if err == nil {
+		fn, err = scheduleActionFunc(task.Action)
+	}
+
+	if err != nil {
+		run.Status = "failed"
+		run.ErrorMessage = err.Error()
+	} else {
+		results := executeBatch(targets, fn.withOutput())
+		if task.Action == models.ScheduleActionTrafficReset {
+			RestoreTrafficEnforcements()
+		}
+
+		run.TotalCount = len(results)
+		for _, r := range results {
+			if r.Success {
+				run.SuccessCount++
+			} else {
+				run.FailedCount++
+			}
+		}
+		resultsJSON, _ := json.Marshal(results)
+		run.Results = string(resultsJSON)
+
+		switch {
+		case run.TotalCount == 0:
+			run.Status = "failed"
+			run.ErrorMessage = "没有匹配的容器"
+		case run.FailedCount == 0:
+			run.Status = "success"
+		case run.SuccessCount == 0:
+			run.Status = "failed"
+		default:
+			run.Status = "partial"
+		}
+	}
+
+	endTime := time.Now()
+	run.EndTime = &endTime
+	database.DB.Save(&run)
+	database.DB.Model(&task).Updates(map[string]interface{}{
+		"last_run_at": run.StartTime,
+		"last_status": run.Status,
+	})
+
+	var runErr error
+	if run.Status == "failed" {
+		msg := run.ErrorMessage
+		if msg == "" {
+			msg = "全部容器执行失败"
+		}
+		runErr = errors.New(msg)
+	}
+	RecordOperation(AuditEntry{
+		OperationType: "schedule_run",
+		TargetType:    "scheduled_task",
+		TargetID:      task.ID,
+		Details: map[string]interface{}{
+			"name":          task.Name,
+			"action":        task.Action,
+			"trigger":       run.Trigger,
+			"run_id":        run.ID,
+			"total_count":   run.TotalCount,
+			"success_count": run.SuccessCount,
+			"failed_count":  run.FailedCount,
+		},
+		Err: runErr,
+	})
+
+	log.Printf("[SCHEDULE] 任务 %s 执行完成: %s, 成功 %d, 失败 %d", task.Name, run.Status, run.SuccessCount, run.FailedCount)
+}
+
+// scheduleTargets 解析任务目标容器，只包含在线且未维护的节点上的容器
+func scheduleTargets(task models.ScheduledTask) ([]BatchTarget, error) {
+	if task.TargetType == models.ScheduleTargetGroup {
+		return ResolveBatchTargets(NodeSelector{Group: task.NodeGroup}, nil, nil)
+	}
+	return ResolveBatchTargets(NodeSelector{}, []uint{task.NodeID}, []string{task.Hostname})
+}
+
+// scheduleActionFunc 返回计划任务动作对应的批量函数
+func scheduleActionFunc(action string) (BatchFunc, error) {
+	switch action {
+	case models.ScheduleActionTrafficReset:
+		return func(t BatchTarget) (bool, string) {
+			result := callNodeAPI(t.Node, "POST", "/api/traffic/reset?hostname="+t.Hostname, nil)
+			msg, _ := result["msg"].(string)
+			if result["code"] != float64(200) {
+				return false, msg
+			}
+			if err := CloseTrafficPeriod(t.Node.ID, t.Hostname, models.TrafficCloseReset); err != nil {
+				log.Printf("[SCHEDULE] 容器 %s 关闭流量账期失败: %v", t.Hostname, err)
+			}
+			return true, msg
+		}, nil
+	case models.ScheduleActionSnapshot:
+		return func(t BatchTarget) (bool, string) {
+			result := callNodeAPI(t.Node, "POST", "/api/snapshot", map[string]interface{}{
+				"hostname": t.Hostname,
+				"name":     "auto-" + time.Now().Format("20060102-1504"),
+			})
+			msg, _ := result["msg"].(string)
+			return result["code"] == float64(200), msg
+		}, nil
+	}
+	return ContainerActionFunc(action)
+}
+
+// cleanupScheduleRuns 删除超过保留天数的执行记录
+func cleanupScheduleRuns() {
+	cutoff := time.Now().AddDate(0, 0, -config.AppConfig.Scheduler.RunRetention)
+	result := database.DB.Where("created_at < ?", cutoff).Delete(&models.ScheduledTaskRun{})
+	if result.Error != nil {
+		log.Printf("[SCHEDULE] 清理执行记录失败: %v", result.Error)
+	} else if result.RowsAffected > 0 {
+		log.Printf("[SCHEDULE] 已清理 %d 条过期执行记录", result.RowsAffected)
+	}
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"fmt"
+	"lxdweb/database"
+	"lxdweb/models"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// ParseSSHPublicKey 解析 authorized_keys 格式的公钥，返回规范化后的公钥、类型、指纹和注释
+func ParseSSHPublicKey(key string) (models.SSHKey, error) {
+	key = strings.TrimSpace(key)
+	if strings.ContainsAny(key, "\r\n") {
+		return models.SSHKey{}, fmt.Errorf("只能填写一个公钥")
+	}
+	pub, comment, options, _, err := ssh.ParseAuthorizedKey([]byte(key))
+	if err != nil {
+		return models.SSHKey{}, fmt.Errorf("公钥格式错误: %v", err)
+	}
+	if len(options) > 0 {
+		return models.SSHKey{}, fmt.Errorf("公钥库不支持带选项的公钥")
+	}
+
+	normalized := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(pub)))
+	if comment != "" {
+		normalized += " " + comment
+	}
+	return models.SSHKey{
+		PublicKey:   normalized,
+		KeyType:     pub.Type(),
+		Fingerprint: ssh.FingerprintSHA256(pub),
+		Comment:     comment,
+	}, nil
+}
+
+// ResolveSSHKeys 合并公钥库中的公钥和直接提交的公钥，校验格式并去重
+func ResolveSSHKeys(keyIDs []uint, keys []string) ([]string, error) {
+	all := make([]string, 0, len(keyIDs)+len(keys))
+	if len(keyIDs) > 0 {
+		var stored []models.SSHKey
+		if err := database.DB.Where("id IN ?", keyIDs).Find(&stored).Error; err != nil {
+			return nil, err
+		}
+		found := make(map[uint]bool, len(stored))
+		for _, k := range stored {
+			found[k.ID] = true
+			all = append(all, k.PublicKey)
+		}
+		for _, id := range keyIDs {
+			if !found[id] {
+				return nil, fmt.Errorf("公钥 %d 不存在", id)
+			}
+		}
+	}
+	return ValidateSSHKeys(append(all, keys...))
+}
+
+// SetContainerSSHKeys 通过节点写入容器 root 用户的 authorized_keys
+func SetContainerSSHKeys(node models.Node, hostname string, keys []string, mode string) error {
+	return nodeActionError(callNodeAPI(node, "POST", "/api/ssh-keys", map[string]interface{}{
+		"hostname": hostname,
+		"keys":     keys,
+		"mode":     mode,
+	}))
+}
+
+// SetContainerPasswordAuth 通过节点启用或禁用容器 SSH 密码登录
+func SetContainerPasswordAuth(node models.Node, hostname string, enabled bool) error {
+	return nodeActionError(callNodeAPI(node, "POST", "/api/ssh-password-auth", map[string]interface{}{
+		"hostname": hostname,
+		"enabled":  enabled,
+	}))
+}
@@ -0,0 +1,302 @@
+package services
+
+import (
+	"log"
+	"lxdweb/config"
+	"lxdweb/database"
+	"lxdweb/models"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TrafficCounter 节点上报的容器流量计数器（字节），Split 表示节点是否区分了入站和出站
+type TrafficCounter struct {
+	Total uint64
+	In    uint64
+	Out   uint64
+	Split bool
+}
+
+// TrafficBucket 流量历史的一个统计区间
+type TrafficBucket struct {
+	Time  time.Time `json:"time"`
+	Total uint64    `json:"total"`
+	In    uint64    `json:"in"`
+	Out   uint64    `json:"out"`
+}
+
+// trafficMu 串行化采样与账期结转，避免并发同步时重复创建账期
+var trafficMu sync.Mutex
+
+// StartTrafficService 启动流量账期服务，定期结转到期账期、恢复上一账期的超额处理并清理过期采样
+func StartTrafficService() {
+	log.Println("[TRAFFIC] 流量账期服务启动")
+
+	ticker := time.NewTicker(1 * time.Hour)
+	go func() {
+		for range ticker.C {
+			closeExpiredTrafficPeriods()
+			RestoreTrafficEnforcements()
+			cleanupTrafficSamples()
+		}
+	}()
+}
+
+// ParseTrafficCounter 从节点返回的容器信息中读取流量计数器，
+// 节点未上报入站/出站明细时 In/Out 为0，不再按总量拆分估算
+func ParseTrafficCounter(data map[string]interface{}) (TrafficCounter, bool) {
+	var counter TrafficCounter
+	in, hasIn := data["traffic_in_raw"].(float64)
+	out, hasOut := data["traffic_out_raw"].(float64)
+	if hasIn && hasOut {
+		counter.In = uint64(in)
+		counter.Out = uint64(out)
+		counter.Total = counter.In + counter.Out
+		counter.Split = true
+	}
+	if total, ok := data["traffic_usage_raw"].(float64); ok {
+		counter.Total = uint64(total)
+	} else if !counter.Split {
+		return counter, false
+	}
+	return counter, true
+}
+
+// billingAnchorDate 返回指定月份的账期起始时间，起始日超过当月天数时取当月最后一天
+func billingAnchorDate(year int, month time.Month, day int, loc *time.Location) time.Time {
+	if last := time.Date(year, month+1, 0, 0, 0, 0, 0, loc).Day(); day > last {
+		day = last
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, loc)
+}
+
+// BillingPeriodBounds 计算时间 t 所在账期的起止时间
+func BillingPeriodBounds(anchorDay int, t time.Time) (time.Time, time.Time) {
+	start := billingAnchorDate(t.Year(), t.Month(), anchorDay, t.Location())
+	if t.Before(start) {
+		start = billingAnchorDate(t.Year(), t.Month()-1, anchorDay, t.Location())
+	}
+	end := billingAnchorDate(start.Year(), start.Month()+1, anchorDay, t.Location())
+	return start, end
+}
+
+// BillingAnchorDay 返回容器的账期起始日，未单独设置时使用默认值
+func BillingAnchorDay(nodeID uint, hostname string) int {
+	return billingAnchorDay(database.DB, nodeID, hostname)
+}
+
+// billingAnchorDay 在指定连接上读取账期起始日，事务内需传入 tx，避免另占连接
+func billingAnchorDay(db *gorm.DB, nodeID uint, hostname string) int {
+	var cycle models.TrafficBillingCycle
+	if err := db.Where("node_id = ? AND hostname = ?", nodeID, hostname).First(&cycle).Error; err == nil {
+		return cycle.AnchorDay
+	}
+	return config.AppConfig.Traffic.DefaultAnchorDay
+}
+
+// counterDelta 计算计数器增量，计数器变小说明节点侧已重置，此时当前值即为增量
+func counterDelta(current, previous uint64) uint64 {
+	if current >= previous {
+		return current - previous
+	}
+	return current
+}
+
+// RecordTrafficSample 记录一次流量采样，并将与上次采样的差值计入当前账期
+func RecordTrafficSample(nodeID uint, hostname string, counter TrafficCounter, trafficLimit int) error {
+	trafficMu.Lock()
+	defer trafficMu.Unlock()
+
+	now := time.Now()
+	sample := models.TrafficSample{
+		NodeID:       nodeID,
+		Hostname:     hostname,
+		SampledAt:    now,
+		CounterTotal: counter.Total,
+		CounterIn:    counter.In,
+		CounterOut:   counter.Out,
+	}
+
+	// 首次采样时节点计数器即为本账期已用流量
+	var last models.TrafficSample
+	if err := database.DB.Where("node_id = ? AND hostname = ?", nodeID, hostname).
+		Order("sampled_at DESC").First(&last).Error; err == nil {
+		sample.DeltaTotal = counterDelta(counter.Total, last.CounterTotal)
+		sample.DeltaIn = counterDelta(counter.In, last.CounterIn)
+		sample.DeltaOut = counterDelta(counter.Out, last.CounterOut)
+	} else {
+		sample.DeltaTotal = counter.Total
+		sample.DeltaIn = counter.In
+		sample.DeltaOut = counter.Out
+	}
+
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&sample).Error; err != nil {
+			return err
+		}
+
+		period, err := openTrafficPeriod(tx, nodeID, hostname, now)
+		if err != nil {
+			return err
+		}
+		return tx.Model(&period).Updates(map[string]interface{}{
+			"traffic_total": gorm.Expr("traffic_total + ?", sample.DeltaTotal),
+			"traffic_in":    gorm.Expr("traffic_in + ?", sample.DeltaIn),
+			"traffic_out":   gorm.Expr("traffic_out + ?", sample.DeltaOut),
+			"traffic_limit": trafficLimit,
+		}).Error
+	})
+}
+
+// openTrafficPeriod 返回容器当前的 open 账期，已到期的账期先结转，没有时按账期起始日新建
+func openTrafficPeriod(tx *gorm.DB, nodeID uint, hostname string, now time.Time) (models.TrafficPeriod, error) {
+	var period models.TrafficPeriod
+	err := tx.Where("node_id = ? AND hostname = ? AND status = ?", nodeID, hostname, models.TrafficPeriodOpen).
+		Order("period_start DESC").First(&period).Error
+	if err == nil && now.Before(period.PeriodEnd) {
+		return period, nil
+	}
+	if err == nil {
+		if err := closeTrafficPeriod(tx, &period, period.PeriodEnd, models.TrafficCloseRollover); err != nil {
+			return period, err
+		}
+	}
+
+	start, end := BillingPeriodBounds(billingAnchorDay(tx, nodeID, hostname), now)
+	period = models.TrafficPeriod{
+		NodeID:      nodeID,
+		Hostname:    hostname,
+		PeriodStart: start,
+		PeriodEnd:   end,
+		Status:      models.TrafficPeriodOpen,
+	}
+	return period, tx.Create(&period).Error
+}
+
+func closeTrafficPeriod(tx *gorm.DB, period *models.TrafficPeriod, at time.Time, reason string) error {
+	now := time.Now()
+	period.Status = models.TrafficPeriodClosed
+	period.CloseReason = reason
+	period.PeriodEnd = at
+	period.ClosedAt = &now
+	return tx.Model(period).Updates(map[string]interface{}{
+		"status":       period.Status,
+		"close_reason": reason,
+		"period_end":   at,
+		"closed_at":    now,
+	}).Error
+}
+
+// CloseTrafficPeriod 提前关闭容器当前账期并从现在开始新账期，用于重置流量和修改账期起始日。
+// 重置流量时同时写入零值采样作为新的计数基准
+func CloseTrafficPeriod(nodeID uint, hostname, reason string) error {
+	trafficMu.Lock()
+	defer trafficMu.Unlock()
+
+	now := time.Now()
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		var period models.TrafficPeriod
+		err := tx.Where("node_id = ? AND hostname = ? AND status = ?", nodeID, hostname, models.TrafficPeriodOpen).
+			Order("period_start DESC").First(&period).Error
+		limit := 0
+		if err == nil {
+			limit = period.TrafficLimit
+			if err := closeTrafficPeriod(tx, &period, now, reason); err != nil {
+				return err
+			}
+		}
+
+		if reason == models.TrafficCloseReset {
+			baseline := models.TrafficSample{NodeID: nodeID, Hostname: hostname, SampledAt: now}
+			if err := tx.Create(&baseline).Error; err != nil {
+				return err
+			}
+		}
+
+		_, end := BillingPeriodBounds(billingAnchorDay(tx, nodeID, hostname), now)
+		next := models.TrafficPeriod{
+			NodeID:       nodeID,
+			Hostname:     hostname,
+			PeriodStart:  now,
+			PeriodEnd:    end,
+			TrafficLimit: limit,
+			Status:       models.TrafficPeriodOpen,
+		}
+		if err := tx.Create(&next).Error; err != nil {
+			return err
+		}
+		log.Printf("[TRAFFIC] 容器 %s (节点 %d) 账期已关闭(%s)，新账期至 %s", hostname, nodeID, reason, end.Format("2006-01-02"))
+		return nil
+	})
+}
+
+// SetBillingAnchorDay 设置容器账期起始日，当前账期在修改时关闭
+func SetBillingAnchorDay(nodeID uint, hostname string, day int) error {
+	cycle := models.TrafficBillingCycle{NodeID: nodeID, Hostname: hostname}
+	database.DB.Where("node_id = ? AND hostname = ?", nodeID, hostname).First(&cycle)
+	if cycle.ID > 0 && cycle.AnchorDay == day {
+		return nil
+	}
+	cycle.AnchorDay = day
+	if err := database.DB.Save(&cycle).Error; err != nil {
+		return err
+	}
+	return CloseTrafficPeriod(nodeID, hostname, models.TrafficCloseAnchor)
+}
+
+// TrafficHistory 按小时或天汇总容器在 [from, to) 内的流量增量
+func TrafficHistory(nodeID uint, hostname string, from, to time.Time, daily bool) []TrafficBucket {
+	var samples []models.TrafficSample
+	database.DB.Where("node_id = ? AND hostname = ? AND sampled_at >= ? AND sampled_at < ?", nodeID, hostname, from, to).
+		Order("sampled_at").Find(&samples)
+
+	buckets := make([]TrafficBucket, 0)
+	index := make(map[time.Time]int)
+	for _, s := range samples {
+		t := s.SampledAt.Truncate(time.Hour)
+		if daily {
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+		}
+		i, ok := index[t]
+		if !ok {
+			i = len(buckets)
+			index[t] = i
+			buckets = append(buckets, TrafficBucket{Time: t})
+		}
+		buckets[i].Total += s.DeltaTotal
+		buckets[i].In += s.DeltaIn
+		buckets[i].Out += s.DeltaOut
+	}
+	return buckets
+}
+
+// closeExpiredTrafficPeriods 结转已到期但期间没有新采样的账期，新账期在下次采样时创建
+func closeExpiredTrafficPeriods() {
+	trafficMu.Lock()
+	defer trafficMu.Unlock()
+
+	var periods []models.TrafficPeriod
+	database.DB.Where("status = ? AND period_end <= ?", models.TrafficPeriodOpen, time.Now()).Find(&periods)
+	for i := range periods {
+		if err := closeTrafficPeriod(database.DB, &periods[i], periods[i].PeriodEnd, models.TrafficCloseRollover); err != nil {
+			log.Printf("[TRAFFIC] 结转账期 %d 失败: %v", periods[i].ID, err)
+		}
+	}
+	if len(periods) > 0 {
+		log.Printf("[TRAFFIC] 已结转 %d 个到期账期", len(periods))
+	}
+}
+
+// cleanupTrafficSamples 删除超过保留天数的采样明细，每个容器保留最后一条作为计数基准
+func cleanupTrafficSamples() {
+	cutoff := time.Now().AddDate(0, 0, -config.AppConfig.Traffic.SampleRetention)
+	latest := database.DB.Model(&models.TrafficSample{}).Select("MAX(id)").Group("node_id, hostname")
+	result := database.DB.Where("sampled_at < ? AND id NOT IN (?)", cutoff, latest).Delete(&models.TrafficSample{})
+	if result.Error != nil {
+		log.Printf("[TRAFFIC] 清理流量采样失败: %v", result.Error)
+	} else if result.RowsAffected > 0 {
+		log.Printf("[TRAFFIC] 已清理 %d 条过期流量采样", result.RowsAffected)
+	}
+}
@@ -0,0 +1,379 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"lxdweb/database"
+	"lxdweb/models"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 超额处理恢复原因
+const (
+	TrafficRestorePeriod = "period" // 新账期开始
+	TrafficRestoreManual = "manual" // 管理员手动解除
+)
+
+var trafficActionNames = map[string]string{
+	models.TrafficActionNotify:   "仅通知",
+	models.TrafficActionThrottle: "限速",
+	models.TrafficActionSuspend:  "暂停容器",
+}
+
+// 超额动作或恢复失败后的重试间隔，从 trafficRetryBase 开始按失败次数翻倍
+const (
+	trafficRetryBase = 5 * time.Minute
+	trafficRetryMax  = 6 * time.Hour
+)
+
+// errTrafficNoBandwidth 缺少原始带宽时限速后无法恢复，重试也不会成功
+var errTrafficNoBandwidth = errors.New("未获取到容器原始带宽，无法限速后恢复")
+
+// trafficPolicyMu 串行化超额判断与处理，避免并发同步时对同一容器重复执行动作
+var trafficPolicyMu sync.Mutex
+
+// TrafficPolicyFor 返回容器生效的流量策略：优先使用容器绑定的策略，否则使用默认策略，
+// 策略被停用时视为没有策略
+func TrafficPolicyFor(nodeID uint, hostname string) *models.TrafficPolicy {
+	var policy models.TrafficPolicy
+	var binding models.TrafficPolicyBinding
+	if err := database.DB.Where("node_id = ? AND hostname = ?", nodeID, hostname).First(&binding).Error; err == nil {
+		if err := database.DB.First(&policy, binding.PolicyID).Error; err != nil || !policy.Enabled {
+			return nil
+		}
+		return &policy
+	}
+	if err := database.DB.Where("is_default = ? AND enabled = ?", true, true).First(&policy).Error; err != nil {
+		return nil
+	}
+	return &policy
+}
+
+// EvaluateTrafficQuota 在容器同步后检查当前账期流量，超过策略阈值时执行超额动作；
+// 上一账期遗留的处理记录先恢复
+func EvaluateTrafficQuota(node models.Node, cache models.ContainerCache) {
+	trafficPolicyMu.Lock()
+	defer trafficPolicyMu.Unlock()
+
+	var period models.TrafficPeriod
+	if err := database.DB.Where("node_id = ? AND hostname = ? AND status = ?", node.ID, cache.Hostname, models.TrafficPeriodOpen).
+		Order("period_start DESC").First(&period).Error; err != nil {
+		return
+	}
+
+	var stale []models.TrafficEnforcement
+	database.DB.Where("node_id = ? AND hostname = ? AND status = ? AND period_id <> ?",
+		node.ID, cache.Hostname, models.TrafficEnforcementActive, period.ID).Find(&stale)
+	for i := range stale {
+		if trafficRetryDue(stale[i]) {
+			restoreTrafficEnforcement(&stale[i], TrafficRestorePeriod, 0, "")
+		}
+	}
+
+	if period.TrafficLimit <= 0 {
+		return
+	}
+	policy := TrafficPolicyFor(node.ID, cache.Hostname)
+	if policy == nil {
+		return
+	}
+	usage := float64(period.TrafficTotal) / (float64(period.TrafficLimit) * 1024 * 1024 * 1024) * 100
+	threshold := policy.ThresholdPercent
+	if threshold <= 0 {
+		threshold = 100
+	}
+	if usage < threshold {
+		return
+	}
+
+	// 本账期已处理或已手动解除的不再重复执行，失败的记录到达重试时间后再执行
+	var enforcement models.TrafficEnforcement
+	if err := database.DB.Where("node_id = ? AND hostname = ? AND period_id = ?", node.ID, cache.Hostname, period.ID).
+		Order("id DESC").First(&enforcement).Error; err == nil {
+		if enforcement.Status != models.TrafficEnforcementFailed || !trafficRetryDue(enforcement) {
+			return
+		}
+	}
+	prevStatus := enforcement.Status
+
+	enforcement.NodeID = node.ID
+	enforcement.Hostname = cache.Hostname
+	enforcement.PeriodID = period.ID
+	enforcement.PolicyID = policy.ID
+	enforcement.PolicyName = policy.Name
+	enforcement.Action = policy.Action
+	enforcement.UsagePercent = usage
+	enforcement.OriginalIngress = cache.Ingress
+	enforcement.OriginalEgress = cache.Egress
+	enforcement.AppliedAt = time.Now()
+
+	err := applyTrafficAction(node, *policy, cache)
+	enforcement.Status = models.TrafficEnforcementActive
+	if err != nil {
+		enforcement.Status = models.TrafficEnforcementFailed
+		markTrafficRetry(&enforcement, err)
+		log.Printf("[TRAFFIC] 容器 %s (节点 %s) 第 %d 次执行超额动作 %s 失败，将于 %s 重试: %v",
+			cache.Hostname, node.Name, enforcement.Attempts, policy.Action, enforcement.NextRetryAt.Format("15:04:05"), err)
+	} else {
+		clearTrafficRetry(&enforcement)
+		log.Printf("[TRAFFIC] 容器 %s (节点 %s) 流量已用 %.2f%%，执行超额动作 %s", cache.Hostname, node.Name, usage, policy.Action)
+	}
+	if saveErr := database.DB.Save(&enforcement).Error; saveErr != nil {
+		log.Printf("[TRAFFIC] 保存容器 %s 超额处理记录失败: %v", cache.Hostname, saveErr)
+	}
+
+	// 重试仍然失败时状态没有变化，不重复写审计日志和发送事件
+	if enforcement.Status == prevStatus {
+		return
+	}
+
+	RecordOperation(AuditEntry{
+		OperationType: "traffic_enforce",
+		TargetType:    "container",
+		TargetID:      node.ID,
+		Details: map[string]interface{}{
+			"hostname":       cache.Hostname,
+			"policy":         policy.Name,
+			"action":         policy.Action,
+			"usage_percent":  usage,
+			"period_id":      period.ID,
+			"enforcement_id": enforcement.ID,
+		},
+		Err: err,
+	})
+
+	EmitContainerEvent(models.WebhookEventTrafficExceeded, node, cache.Hostname, map[string]interface{}{
+		"policy":         policy.Name,
+		"action":         policy.Action,
+		"usage_percent":  usage,
+		"threshold":      threshold,
+		"traffic_total":  period.TrafficTotal,
+		"traffic_limit":  period.TrafficLimit,
+		"period_id":      period.ID,
+		"enforcement_id": enforcement.ID,
+		"status":         enforcement.Status,
+		"error":          enforcement.Error,
+	})
+	if err == nil {
+		if policy.Action == models.TrafficActionSuspend {
+			EmitContainerEvent(models.WebhookEventContainerSuspended, node, cache.Hostname, map[string]interface{}{"source": "traffic_policy"})
+		}
+		notifyTrafficEnforcement(*policy, node, enforcement, models.AlertStatusFiring, threshold)
+	}
+}
+
+// applyTrafficAction 在节点上执行超额动作
+func applyTrafficAction(node models.Node, policy models.TrafficPolicy, cache models.ContainerCache) error {
+	switch policy.Action {
+	case models.TrafficActionThrottle:
+		if cache.Ingress == "" && cache.Egress == "" {
+			return errTrafficNoBandwidth
+		}
+		return setContainerBandwidth(node, cache.Hostname, policy.ThrottleIngress, policy.ThrottleEgress)
+	case models.TrafficActionSuspend:
+		return nodeActionError(callNodeAPI(node, "GET", "/api/suspend?hostname="+cache.Hostname, nil))
+	}
+	return nil
+}
+
+// setContainerBandwidth 通过节点的 /api/bandwidth 接口调整容器入站/出站带宽限制，
+// 接口约定见 docs/node_api.md
+func setContainerBandwidth(node models.Node, hostname, ingress, egress string) error {
+	return nodeActionError(callNodeAPI(node, "POST", "/api/bandwidth", map[string]interface{}{
+		"hostname": hostname,
+		"ingress":  ingress,
+		"egress":   egress,
+	}))
+}
+
+// trafficRetryDue 判断失败的执行或恢复是否已到重试时间
+func trafficRetryDue(e models.TrafficEnforcement) bool {
+	return e.NextRetryAt == nil || !time.Now().Before(*e.NextRetryAt)
+}
+
+// markTrafficRetry 记录一次失败并计算下次重试时间，重试也无法成功的错误直接使用最大间隔
+func markTrafficRetry(e *models.TrafficEnforcement, err error) {
+	e.Attempts++
+	e.Error = err.Error()
+
+	delay := trafficRetryMax
+	if !errors.Is(err, errTrafficNoBandwidth) {
+		delay = trafficRetryBase
+		for i := 1; i < e.Attempts && delay < trafficRetryMax; i++ {
+			delay *= 2
+		}
+		if delay > trafficRetryMax {
+			delay = trafficRetryMax
+		}
+	}
+	next := time.Now().Add(delay)
+	e.NextRetryAt = &next
+}
+
+func clearTrafficRetry(e *models.TrafficEnforcement) {
+	e.Attempts = 0
+	e.NextRetryAt = nil
+	e.Error = ""
+}
+
+func nodeActionError(result map[string]interface{}) error {
+	if result["code"] == float64(200) {
+		return nil
+	}
+	return fmt.Errorf("%v", result["msg"])
+}
+
+// restoreTrafficEnforcement 恢复限速或暂停前的状态，失败时保持 active 并按退避间隔重试，
+// 自动恢复只在第一次失败时写审计日志
+func restoreTrafficEnforcement(e *models.TrafficEnforcement, reason string, adminID uint, ip string) error {
+	var node models.Node
+	err := database.DB.First(&node, e.NodeID).Error
+	if err == nil {
+		switch e.Action {
+		case models.TrafficActionThrottle:
+			err = setContainerBandwidth(node, e.Hostname, e.OriginalIngress, e.OriginalEgress)
+		case models.TrafficActionSuspend:
+			err = nodeActionError(callNodeAPI(node, "GET", "/api/unsuspend?hostname="+e.Hostname, nil))
+		}
+	}
+
+	if err != nil {
+		markTrafficRetry(e, err)
+		log.Printf("[TRAFFIC] 容器 %s (节点 %d) 第 %d 次恢复超额动作 %s 失败: %v", e.Hostname, e.NodeID, e.Attempts, e.Action, err)
+	} else {
+		now := time.Now()
+		e.Status = models.TrafficEnforcementRestored
+		e.RestoredAt = &now
+		e.RestoreReason = reason
+		clearTrafficRetry(e)
+		log.Printf("[TRAFFIC] 容器 %s (节点 %d) 已恢复超额动作 %s (%s)", e.Hostname, e.NodeID, e.Action, reason)
+	}
+	if saveErr := database.DB.Save(e).Error; saveErr != nil {
+		log.Printf("[TRAFFIC] 保存容器 %s 超额处理记录失败: %v", e.Hostname, saveErr)
+	}
+	if err != nil && adminID == 0 && e.Attempts > 1 {
+		return err
+	}
+
+	RecordOperation(AuditEntry{
+		AdminID:       adminID,
+		OperationType: "traffic_restore",
+		TargetType:    "container",
+		TargetID:      e.NodeID,
+		Details: map[string]interface{}{
+			"hostname":       e.Hostname,
+			"policy":         e.PolicyName,
+			"action":         e.Action,
+			"reason":         reason,
+			"period_id":      e.PeriodID,
+			"enforcement_id": e.ID,
+		},
+		IPAddress: ip,
+		Err:       err,
+	})
+
+	if err == nil {
+		var policy models.TrafficPolicy
+		if database.DB.First(&policy, e.PolicyID).Error == nil {
+			notifyTrafficEnforcement(policy, node, *e, models.AlertStatusResolved, policy.ThresholdPercent)
+		}
+	}
+	return err
+}
+
+// RestoreTrafficEnforcements 恢复账期已结束的超额处理，由流量账期服务定期调用
+func RestoreTrafficEnforcements() {
+	trafficPolicyMu.Lock()
+	defer trafficPolicyMu.Unlock()
+
+	closed := database.DB.Model(&models.TrafficPeriod{}).Select("id").Where("status = ?", models.TrafficPeriodClosed)
+	var list []models.TrafficEnforcement
+	database.DB.Where("status = ? AND period_id IN (?)", models.TrafficEnforcementActive, closed).Find(&list)
+	for i := range list {
+		if trafficRetryDue(list[i]) {
+			restoreTrafficEnforcement(&list[i], TrafficRestorePeriod, 0, "")
+		}
+	}
+}
+
+// ReleaseTrafficEnforcement 手动解除超额处理，本账期内不再自动执行
+func ReleaseTrafficEnforcement(id uint, adminID uint, ip string) (*models.TrafficEnforcement, error) {
+	trafficPolicyMu.Lock()
+	defer trafficPolicyMu.Unlock()
+
+	var e models.TrafficEnforcement
+	if err := database.DB.First(&e, id).Error; err != nil {
+		return nil, err
+	}
+	if e.Status == models.TrafficEnforcementRestored {
+		return &e, nil
+	}
+	if e.Status == models.TrafficEnforcementFailed {
+		// 执行失败的记录没有改动容器状态，直接标记为已解除
+		now := time.Now()
+		e.Status = models.TrafficEnforcementRestored
+		e.RestoredAt = &now
+		e.RestoreReason = TrafficRestoreManual
+		err := database.DB.Save(&e).Error
+		RecordOperation(AuditEntry{
+			AdminID:       adminID,
+			OperationType: "traffic_restore",
+			TargetType:    "container",
+			TargetID:      e.NodeID,
+			Details: map[string]interface{}{
+				"hostname":       e.Hostname,
+				"policy":         e.PolicyName,
+				"action":         e.Action,
+				"reason":         TrafficRestoreManual,
+				"period_id":      e.PeriodID,
+				"enforcement_id": e.ID,
+			},
+			IPAddress: ip,
+			Err:       err,
+		})
+		return &e, err
+	}
+	return &e, restoreTrafficEnforcement(&e, TrafficRestoreManual, adminID, ip)
+}
+
+// notifyTrafficEnforcement 通过策略关联的告警渠道发送超额或恢复通知
+func notifyTrafficEnforcement(policy models.TrafficPolicy, node models.Node, e models.TrafficEnforcement, status string, threshold float64) {
+	channelIDs := policy.ChannelIDList()
+	if len(channelIDs) == 0 {
+		return
+	}
+
+	now := time.Now()
+	target := alertTargetLabel(node.Name, e.Hostname)
+	lines := []string{
+		"策略: " + policy.Name,
+		"动作: " + trafficActionNames[e.Action],
+		"对象: " + target,
+		fmt.Sprintf("流量使用率: %.2f%% (阈值 %.2f%%)", e.UsagePercent, threshold),
+		"执行时间: " + e.AppliedAt.Format("2006-01-02 15:04:05"),
+	}
+	title := fmt.Sprintf("[TRAFFIC] %s 流量超额，已执行%s", target, trafficActionNames[e.Action])
+	if status == models.AlertStatusResolved {
+		title = fmt.Sprintf("[TRAFFIC] %s 超额处理已解除", target)
+		lines = append(lines, "恢复时间: "+now.Format("2006-01-02 15:04:05"))
+	}
+
+	dispatchAlert(channelIDs, AlertMessage{
+		Title:     title,
+		Text:      strings.Join(lines, "\n"),
+		Status:    status,
+		Severity:  "warning",
+		RuleName:  policy.Name,
+		Metric:    models.AlertMetricTrafficUsage,
+		NodeID:    node.ID,
+		NodeName:  node.Name,
+		Hostname:  e.Hostname,
+		Value:     e.UsagePercent,
+		Threshold: threshold,
+		StartedAt: e.AppliedAt,
+		Time:      now,
+	})
+}
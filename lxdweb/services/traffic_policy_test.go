@@ -0,0 +1,130 @@
+package services
+
+import (
+	"encoding/json"
+	"lxdweb/database"
+	"lxdweb/models"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeNode 模拟节点接口，code 为节点返回的业务状态码
+type fakeNode struct {
+	calls  atomic.Int32
+	code   atomic.Int32
+	server *httptest.Server
+}
+
+func newFakeNode(t *testing.T) *fakeNode {
+	t.Helper()
+	n := &fakeNode{}
+	n.code.Store(500)
+	n.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n.calls.Add(1)
+		json.NewEncoder(w).Encode(map[string]interface{}{"code": n.code.Load(), "msg": "节点返回失败"})
+	}))
+	t.Cleanup(n.server.Close)
+	return n
+}
+
+func setupTrafficQuota(t *testing.T, action string, fn *fakeNode) (models.Node, models.ContainerCache) {
+	t.Helper()
+	setupAlertConfig(t)
+	setupTestDB(t, &models.Node{}, &models.TrafficPeriod{}, &models.TrafficPolicy{},
+		&models.TrafficPolicyBinding{}, &models.TrafficEnforcement{}, &models.OperationLog{},
+		&models.Webhook{}, &models.WebhookDelivery{}, &models.AlertChannel{})
+
+	node := models.Node{Name: "node-1", Address: fn.server.URL, Status: models.NodeStatusActive}
+	database.DB.Create(&node)
+	database.DB.Create(&models.TrafficPolicy{Name: "默认", Action: action, ThresholdPercent: 100,
+		ThrottleIngress: "1Mbit", ThrottleEgress: "1Mbit", IsDefault: true, Enabled: true})
+	database.DB.Create(&models.TrafficPeriod{NodeID: node.ID, Hostname: "c1", PeriodStart: time.Now().AddDate(0, 0, -1),
+		TrafficTotal: 2 << 30, TrafficLimit: 1, Status: models.TrafficPeriodOpen})
+	database.DB.Create(&models.Webhook{Name: "billing", URL: "http://127.0.0.1:1/hook",
+		Events: models.WebhookEventTrafficExceeded, Enabled: true})
+
+	return node, models.ContainerCache{NodeID: node.ID, Hostname: "c1", Ingress: "100Mbit", Egress: "100Mbit"}
+}
+
+func countRows(t *testing.T, model interface{}) int64 {
+	t.Helper()
+	var n int64
+	database.DB.Model(model).Count(&n)
+	return n
+}
+
+func loadEnforcement(t *testing.T) models.TrafficEnforcement {
+	t.Helper()
+	var e models.TrafficEnforcement
+	if err := database.DB.First(&e).Error; err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestEvaluateTrafficQuotaRetryBackoff(t *testing.T) {
+	fn := newFakeNode(t)
+	node, cache := setupTrafficQuota(t, models.TrafficActionSuspend, fn)
+
+	EvaluateTrafficQuota(node, cache)
+	e := loadEnforcement(t)
+	if e.Status != models.TrafficEnforcementFailed || e.Attempts != 1 || e.NextRetryAt == nil {
+		t.Fatalf("第一次失败后记录 = %+v", e)
+	}
+	if countRows(t, &models.OperationLog{}) != 1 || countRows(t, &models.WebhookDelivery{}) != 1 {
+		t.Fatal("第一次失败应写入一条审计日志并发送一次事件")
+	}
+
+	// 未到重试时间不再调用节点
+	EvaluateTrafficQuota(node, cache)
+	if fn.calls.Load() != 1 {
+		t.Fatalf("退避期内不应重试，节点调用 %d 次", fn.calls.Load())
+	}
+
+	database.DB.Model(&e).Update("next_retry_at", time.Now().Add(-time.Second))
+	EvaluateTrafficQuota(node, cache)
+	e = loadEnforcement(t)
+	if fn.calls.Load() != 2 || e.Attempts != 2 {
+		t.Fatalf("到达重试时间应重试，节点调用 %d 次，记录 %+v", fn.calls.Load(), e)
+	}
+	if delay := time.Until(*e.NextRetryAt); delay < 9*time.Minute || delay > trafficRetryBase*2 {
+		t.Errorf("第二次失败后的重试间隔 = %v，期望约 %v", delay, trafficRetryBase*2)
+	}
+	if countRows(t, &models.OperationLog{}) != 1 || countRows(t, &models.WebhookDelivery{}) != 1 {
+		t.Fatal("重试仍失败时状态未变化，不应重复写审计日志或发送事件")
+	}
+
+	fn.code.Store(200)
+	database.DB.Model(&e).Update("next_retry_at", time.Now().Add(-time.Second))
+	EvaluateTrafficQuota(node, cache)
+	e = loadEnforcement(t)
+	if e.Status != models.TrafficEnforcementActive || e.Attempts != 0 || e.NextRetryAt != nil || e.Error != "" {
+		t.Fatalf("重试成功后记录 = %+v", e)
+	}
+	if countRows(t, &models.OperationLog{}) != 2 || countRows(t, &models.WebhookDelivery{}) != 2 {
+		t.Fatal("状态变为生效时应写入审计日志并发送事件")
+	}
+
+	EvaluateTrafficQuota(node, cache)
+	if fn.calls.Load() != 3 {
+		t.Fatalf("已生效的处理不应重复执行，节点调用 %d 次", fn.calls.Load())
+	}
+}
+
+func TestEvaluateTrafficQuotaThrottleWithoutBandwidth(t *testing.T) {
+	fn := newFakeNode(t)
+	node, cache := setupTrafficQuota(t, models.TrafficActionThrottle, fn)
+	cache.Ingress, cache.Egress = "", ""
+
+	EvaluateTrafficQuota(node, cache)
+	e := loadEnforcement(t)
+	if e.Status != models.TrafficEnforcementFailed || fn.calls.Load() != 0 {
+		t.Fatalf("缺少原始带宽时不应调用节点，记录 %+v", e)
+	}
+	if delay := time.Until(*e.NextRetryAt); delay < trafficRetryMax-time.Minute {
+		t.Errorf("无法恢复的错误应使用最大重试间隔，实际 %v", delay)
+	}
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"lxdweb/config"
+	"lxdweb/database"
+	"lxdweb/models"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("加载时区 %s 失败: %v", name, err)
+	}
+	return loc
+}
+
+func TestBillingPeriodBounds(t *testing.T) {
+	shanghai := mustLoadLocation(t, "Asia/Shanghai")
+	utc := time.UTC
+
+	tests := []struct {
+		name      string
+		anchor    int
+		at        time.Time
+		wantStart time.Time
+		wantEnd   time.Time
+	}{
+		{
+			name:      "月中采样",
+			anchor:    1,
+			at:        time.Date(2026, 3, 15, 12, 0, 0, 0, utc),
+			wantStart: time.Date(2026, 3, 1, 0, 0, 0, 0, utc),
+			wantEnd:   time.Date(2026, 4, 1, 0, 0, 0, 0, utc),
+		},
+		{
+			name:      "恰好在起始时刻属于新账期",
+			anchor:    15,
+			at:        time.Date(2026, 3, 15, 0, 0, 0, 0, utc),
+			wantStart: time.Date(2026, 3, 15, 0, 0, 0, 0, utc),
+			wantEnd:   time.Date(2026, 4, 15, 0, 0, 0, 0, utc),
+		},
+		{
+			name:      "起始日之前属于上一账期",
+			anchor:    15,
+			at:        time.Date(2026, 3, 14, 23, 59, 59, 0, utc),
+			wantStart: time.Date(2026, 2, 15, 0, 0, 0, 0, utc),
+			wantEnd:   time.Date(2026, 3, 15, 0, 0, 0, 0, utc),
+		},
+		{
+			name:      "31日起始在2月取月末",
+			anchor:    31,
+			at:        time.Date(2026, 2, 28, 8, 0, 0, 0, utc),
+			wantStart: time.Date(2026, 2, 28, 0, 0, 0, 0, utc),
+			wantEnd:   time.Date(2026, 3, 31, 0, 0, 0, 0, utc),
+		},
+		{
+			name:      "31日起始在2月末之前属于1月账期",
+			anchor:    31,
+			at:        time.Date(2026, 2, 27, 8, 0, 0, 0, utc),
+			wantStart: time.Date(2026, 1, 31, 0, 0, 0, 0, utc),
+			wantEnd:   time.Date(2026, 2, 28, 0, 0, 0, 0, utc),
+		},
+		{
+			name:      "31日起始在闰年2月取29日",
+			anchor:    31,
+			at:        time.Date(2028, 3, 10, 0, 0, 0, 0, utc),
+			wantStart: time.Date(2028, 2, 29, 0, 0, 0, 0, utc),
+			wantEnd:   time.Date(2028, 3, 31, 0, 0, 0, 0, utc),
+		},
+		{
+			name:      "30日起始在小月之后恢复30日",
+			anchor:    30,
+			at:        time.Date(2026, 3, 1, 0, 0, 0, 0, utc),
+			wantStart: time.Date(2026, 2, 28, 0, 0, 0, 0, utc),
+			wantEnd:   time.Date(2026, 3, 30, 0, 0, 0, 0, utc),
+		},
+		{
+			name:      "1月初跨年回到上一年12月",
+			anchor:    5,
+			at:        time.Date(2027, 1, 3, 0, 0, 0, 0, utc),
+			wantStart: time.Date(2026, 12, 5, 0, 0, 0, 0, utc),
+			wantEnd:   time.Date(2027, 1, 5, 0, 0, 0, 0, utc),
+		},
+		{
+			name:      "12月账期结束于次年1月",
+			anchor:    31,
+			at:        time.Date(2026, 12, 31, 1, 0, 0, 0, utc),
+			wantStart: time.Date(2026, 12, 31, 0, 0, 0, 0, utc),
+			wantEnd:   time.Date(2027, 1, 31, 0, 0, 0, 0, utc),
+		},
+		{
+			name:      "按采样时间所在时区计算",
+			anchor:    1,
+			at:        time.Date(2026, 3, 31, 20, 0, 0, 0, utc).In(shanghai),
+			wantStart: time.Date(2026, 4, 1, 0, 0, 0, 0, shanghai),
+			wantEnd:   time.Date(2026, 5, 1, 0, 0, 0, 0, shanghai),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := BillingPeriodBounds(tt.anchor, tt.at)
+			if !start.Equal(tt.wantStart) || !end.Equal(tt.wantEnd) {
+				t.Fatalf("BillingPeriodBounds(%d, %s) = [%s, %s)，期望 [%s, %s)",
+					tt.anchor, tt.at, start, end, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestBillingAnchorDate(t *testing.T) {
+	tests := []struct {
+		year  int
+		month time.Month
+		day   int
+		want  int
+	}{
+		{2026, time.January, 31, 31},
+		{2026, time.February, 31, 28},
+		{2028, time.February, 30, 29},
+		{2026, time.April, 31, 30},
+		{2026, time.April, 15, 15},
+	}
+	for _, tt := range tests {
+		got := billingAnchorDate(tt.year, tt.month, tt.day, time.UTC)
+		if got.Month() != tt.month || got.Day() != tt.want {
+			t.Errorf("billingAnchorDate(%d, %s, %d) = %s，期望 %s %d 日", tt.year, tt.month, tt.day, got, tt.month, tt.want)
+		}
+	}
+}
+
+func TestCounterDelta(t *testing.T) {
+	tests := []struct {
+		name     string
+		current  uint64
+		previous uint64
+		want     uint64
+	}{
+		{"正常增长", 1500, 1000, 500},
+		{"没有变化", 1000, 1000, 0},
+		{"节点计数器重置", 200, 1000, 200},
+		{"重置后归零", 0, 1000, 0},
+		{"首次从零开始", 300, 0, 300},
+	}
+	for _, tt := range tests {
+		if got := counterDelta(tt.current, tt.previous); got != tt.want {
+			t.Errorf("%s: counterDelta(%d, %d) = %d，期望 %d", tt.name, tt.current, tt.previous, got, tt.want)
+		}
+	}
+}
+
+func TestRecordTrafficSampleCounterReset(t *testing.T) {
+	setupTestDB(t, &models.TrafficSample{}, &models.TrafficPeriod{}, &models.TrafficBillingCycle{})
+	old := config.AppConfig
+	config.AppConfig = &config.Config{}
+	config.AppConfig.Traffic.DefaultAnchorDay = 1
+	t.Cleanup(func() { config.AppConfig = old })
+
+	for _, c := range []TrafficCounter{
+		{Total: 1000, In: 400, Out: 600, Split: true},
+		{Total: 1500, In: 600, Out: 900, Split: true},
+		{Total: 200, In: 50, Out: 150, Split: true},
+		{Total: 300, In: 100, Out: 200, Split: true},
+	} {
+		if err := RecordTrafficSample(1, "web", c, 0); err != nil {
+			t.Fatalf("记录采样失败: %v", err)
+		}
+	}
+
+	var periods []models.TrafficPeriod
+	database.DB.Where("node_id = ? AND hostname = ?", 1, "web").Find(&periods)
+	if len(periods) != 1 {
+		t.Fatalf("期望 1 个账期，实际 %d", len(periods))
+	}
+	p := periods[0]
+	// 1000 + 500 + 200（重置后当前值即增量）+ 100
+	if p.TrafficTotal != 1800 || p.TrafficIn != 700 || p.TrafficOut != 1100 {
+		t.Fatalf("账期流量 total=%d in=%d out=%d，期望 1800/700/1100", p.TrafficTotal, p.TrafficIn, p.TrafficOut)
+	}
+}
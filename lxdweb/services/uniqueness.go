@@ -0,0 +1,190 @@
+package services
+
+import (
+	"fmt"
+	"lxdweb/config"
+	"lxdweb/database"
+	"lxdweb/models"
+	"strings"
+)
+
+// 唯一性检查范围
+const (
+	UniqueScopeGlobal = "global"
+	UniqueScopeNode   = "node"
+)
+
+// 冲突资源类型
+const (
+	ConflictTypeDomain = "domain"
+	ConflictTypeIP     = "ip"
+	ConflictTypePort   = "port"
+)
+
+// ResourceConflict 资源唯一性冲突，记录占用该资源的节点和容器
+type ResourceConflict struct {
+	Type     string `json:"type"`
+	Value    string `json:"value"`
+	Protocol string `json:"protocol,omitempty"`
+	NodeID   uint   `json:"node_id"`
+	NodeName string `json:"node_name"`
+	Owner    string `json:"owner"`
+	Scope    string `json:"scope"`
+}
+
+func (c *ResourceConflict) Error() string {
+	var target string
+	switch c.Type {
+	case ConflictTypeDomain:
+		target = "域名 " + c.Value
+	case ConflictTypeIP:
+		target = "地址 " + c.Value
+	default:
+		target = c.Protocol + " 端口 " + c.Value
+	}
+	if c.NodeName != "" {
+		return fmt.Sprintf("%s 已被节点 %s 上的 %s 占用", target, c.NodeName, c.Owner)
+	}
+	return fmt.Sprintf("%s 已被 %s 占用", target, c.Owner)
+}
+
+// nodeNames 返回 节点ID -> 节点名称，用于补全不带节点名的规则表
+func nodeNames() map[uint]string {
+	var nodes []models.Node
+	database.DB.Select("id", "name").Find(&nodes)
+	names := make(map[uint]string, len(nodes))
+	for _, n := range nodes {
+		names[n.ID] = n.Name
+	}
+	return names
+}
+
+// CheckDomainUnique 检查域名是否已被反向代理使用，global 范围检查所有节点的缓存
+func CheckDomainUnique(nodeID uint, domain string) *ResourceConflict {
+	domain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+	scope := config.AppConfig.Uniqueness.DomainScope
+
+	query := database.DB.Where("LOWER(domain) = ?", domain)
+	if scope == UniqueScopeNode {
+		query = query.Where("node_id = ?", nodeID)
+	}
+	var cache models.ProxyConfigCache
+	if err := query.First(&cache).Error; err != nil {
+		return nil
+	}
+	return &ResourceConflict{
+		Type:     ConflictTypeDomain,
+		Value:    domain,
+		NodeID:   cache.NodeID,
+		NodeName: cache.NodeName,
+		Owner:    cache.Hostname,
+		Scope:    scope,
+	}
+}
+
+// CheckIPUnique 检查公网地址是否已绑定到本节点的其他容器，
+// global 范围下同时检查其他节点的绑定和地址池分配记录
+func CheckIPUnique(nodeID uint, address, hostname string) *ResourceConflict {
+	addr, err := ParseIPAddress(address)
+	if err != nil {
+		return nil
+	}
+	family := addrFamily(addr)
+
+	if owner, ok := boundAddresses(nodeID, family)[addr.String()]; ok && owner != hostname {
+		return &ResourceConflict{
+			Type:     ConflictTypeIP,
+			Value:    addr.String(),
+			NodeID:   nodeID,
+			NodeName: nodeNames()[nodeID],
+			Owner:    owner,
+			Scope:    config.AppConfig.Uniqueness.IPScope,
+		}
+	}
+	return clusterAddresses(nodeID, family)[addr.String()]
+}
+
+// clusterAddresses 在 global 范围下返回其他节点已绑定或已分配的地址，node 范围下返回空
+func clusterAddresses(nodeID uint, family string) map[string]*ResourceConflict {
+	used := make(map[string]*ResourceConflict)
+	if config.AppConfig.Uniqueness.IPScope != UniqueScopeGlobal {
+		return used
+	}
+	names := nodeNames()
+
+	add := func(id uint, address, owner string) {
+		addr, err := ParseIPAddress(address)
+		if err != nil {
+			return
+		}
+		used[addr.String()] = &ResourceConflict{
+			Type:     ConflictTypeIP,
+			Value:    addr.String(),
+			NodeID:   id,
+			NodeName: names[id],
+			Owner:    owner,
+			Scope:    UniqueScopeGlobal,
+		}
+	}
+
+	var rows []models.IPAddress
+	database.DB.Where("node_id <> ? AND family = ? AND status = ?", nodeID, family, models.IPStatusAllocated).Find(&rows)
+	for _, row := range rows {
+		add(row.NodeID, row.Address, row.Hostname)
+	}
+
+	if family == models.IPFamilyV4 {
+		var bindings []models.IPv4BindingCache
+		database.DB.Where("node_id <> ?", nodeID).Find(&bindings)
+		for _, b := range bindings {
+			add(b.NodeID, b.IPv4Address, b.Hostname)
+		}
+	} else {
+		var bindings []models.IPv6BindingCache
+		database.DB.Where("node_id <> ?", nodeID).Find(&bindings)
+		for _, b := range bindings {
+			add(b.NodeID, b.IPv6Address, b.Hostname)
+		}
+	}
+
+	return used
+}
+
+// clusterNATPorts 在 global 范围下返回其他节点已占用的外部端口，node 范围下返回空
+func clusterNATPorts(nodeID uint, protocol string) map[int]*ResourceConflict {
+	used := make(map[int]*ResourceConflict)
+	if config.AppConfig.Uniqueness.PortScope != UniqueScopeGlobal {
+		return used
+	}
+	protocols := append(NATProtocols(protocol), models.NATProtocolBoth)
+	names := nodeNames()
+
+	add := func(id uint, hostname, proto string, start, end int) {
+		start, end = NATPortSpan(start, end)
+		for port := start; port <= end; port++ {
+			used[port] = &ResourceConflict{
+				Type:     ConflictTypePort,
+				Value:    fmt.Sprintf("%d", port),
+				Protocol: proto,
+				NodeID:   id,
+				NodeName: names[id],
+				Owner:    hostname,
+				Scope:    UniqueScopeGlobal,
+			}
+		}
+	}
+
+	var rules []models.NATRule
+	database.DB.Where("node_id <> ? AND protocol IN ?", nodeID, protocols).Find(&rules)
+	for _, r := range rules {
+		add(r.NodeID, r.ContainerHostname, r.Protocol, r.ExternalPort, r.ExternalPortEnd)
+	}
+
+	var cached []models.NATRuleCache
+	database.DB.Where("node_id <> ? AND LOWER(protocol) IN ?", nodeID, protocols).Find(&cached)
+	for _, r := range cached {
+		add(r.NodeID, r.ContainerHostname, strings.ToLower(r.Protocol), r.ExternalPort, r.ExternalPortEnd)
+	}
+
+	return used
+}
@@ -0,0 +1,301 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"lxdweb/config"
+	"lxdweb/database"
+	"lxdweb/models"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// webhookResponseLimit 投递记录中保存的响应体最大长度
+const webhookResponseLimit = 2048
+
+// webhookKick 有新投递时唤醒投递循环，缓冲为1即可合并多次唤醒
+var webhookKick = make(chan struct{}, 1)
+
+// WebhookPayload 投递给订阅方的事件内容
+type WebhookPayload struct {
+	ID        string                 `json:"id"`
+	Event     string                 `json:"event"`
+	CreatedAt time.Time              `json:"created_at"`
+	Data      map[string]interface{} `json:"data"`
+}
+
+// StartWebhookService 启动 webhook 投递服务，处理待投递和到期重试的记录
+func StartWebhookService() {
+	log.Println("[WEBHOOK] webhook 投递服务启动")
+
+	go func() {
+		ticker := time.NewTicker(10 * time.Second)
+		processWebhookQueue()
+		for {
+			select {
+			case <-ticker.C:
+			case <-webhookKick:
+			}
+			processWebhookQueue()
+		}
+	}()
+
+	cleanupTicker := time.NewTicker(1 * time.Hour)
+	go func() {
+		for range cleanupTicker.C {
+			cleanupWebhookDeliveries()
+		}
+	}()
+}
+
+func kickWebhookQueue() {
+	select {
+	case webhookKick <- struct{}{}:
+	default:
+	}
+}
+
+// GenerateWebhookSecret 生成签名密钥
+func GenerateWebhookSecret() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(buf), nil
+}
+
+func newWebhookEventID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return "evt_" + hex.EncodeToString(buf)
+}
+
+// EmitWebhookEvent 为订阅了该事件的 webhook 创建投递记录，由投递服务异步发送
+func EmitWebhookEvent(event string, data map[string]interface{}) {
+	var hooks []models.Webhook
+	database.DB.Where("enabled = ?", true).Find(&hooks)
+
+	var targets []models.Webhook
+	for _, h := range hooks {
+		if h.Subscribes(event) {
+			targets = append(targets, h)
+		}
+	}
+	if len(targets) == 0 {
+		return
+	}
+
+	if err := enqueueWebhookEvent(event, data, targets); err != nil {
+		log.Printf("[WEBHOOK] 事件 %s 入队失败: %v", event, err)
+	}
+}
+
+// SendWebhookPing 向指定 webhook 投递一条测试事件
+func SendWebhookPing(hook models.Webhook) error {
+	return enqueueWebhookEvent(models.WebhookEventPing, map[string]interface{}{
+		"webhook_id": hook.ID,
+		"name":       hook.Name,
+	}, []models.Webhook{hook})
+}
+
+func enqueueWebhookEvent(event string, data map[string]interface{}, hooks []models.Webhook) error {
+	payload := WebhookPayload{
+		ID:        newWebhookEventID(),
+		Event:     event,
+		CreatedAt: time.Now(),
+		Data:      data,
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, h := range hooks {
+		delivery := models.WebhookDelivery{
+			WebhookID:     h.ID,
+			EventID:       payload.ID,
+			Event:         event,
+			Payload:       string(body),
+			Status:        models.WebhookDeliveryPending,
+			NextAttemptAt: &now,
+		}
+		if err := database.DB.Create(&delivery).Error; err != nil {
+			return err
+		}
+	}
+	kickWebhookQueue()
+	return nil
+}
+
+// RedeliverWebhook 以原事件内容创建一条新的投递记录
+func RedeliverWebhook(id uint) (*models.WebhookDelivery, error) {
+	var orig models.WebhookDelivery
+	if err := database.DB.First(&orig, id).Error; err != nil {
+		return nil, err
+	}
+	var hook models.Webhook
+	if err := database.DB.First(&hook, orig.WebhookID).Error; err != nil {
+		return nil, errors.New("webhook 不存在")
+	}
+
+	now := time.Now()
+	delivery := models.WebhookDelivery{
+		WebhookID:     orig.WebhookID,
+		EventID:       orig.EventID,
+		Event:         orig.Event,
+		Payload:       orig.Payload,
+		Status:        models.WebhookDeliveryPending,
+		NextAttemptAt: &now,
+		RedeliveryOf:  orig.ID,
+	}
+	if err := database.DB.Create(&delivery).Error; err != nil {
+		return nil, err
+	}
+	kickWebhookQueue()
+	return &delivery, nil
+}
+
+// processWebhookQueue 投递所有到期的记录
+func processWebhookQueue() {
+	var deliveries []models.WebhookDelivery
+	database.DB.Where("status = ? AND next_attempt_at <= ?", models.WebhookDeliveryPending, time.Now()).
+		Order("id").Limit(100).Find(&deliveries)
+
+	for i := range deliveries {
+		deliverWebhook(&deliveries[i])
+	}
+}
+
+// deliverWebhook 投递一次，失败时按指数退避安排重试，超过最大次数后标记为失败
+func deliverWebhook(d *models.WebhookDelivery) {
+	cfg := config.AppConfig.Webhook
+	now := time.Now()
+	d.Attempts++
+	d.LastAttemptAt = &now
+
+	var hook models.Webhook
+	err := database.DB.First(&hook, d.WebhookID).Error
+	if err != nil {
+		err = errors.New("webhook 已删除")
+		d.Attempts = cfg.MaxAttempts
+	} else if !hook.Enabled {
+		err = errors.New("webhook 已停用")
+		d.Attempts = cfg.MaxAttempts
+	} else {
+		d.ResponseCode, d.ResponseBody, err = postWebhook(hook, *d)
+	}
+
+	if err == nil {
+		d.Status = models.WebhookDeliverySuccess
+		d.Error = ""
+		d.DeliveredAt = &now
+		d.NextAttemptAt = nil
+	} else {
+		d.Error = err.Error()
+		if d.Attempts >= cfg.MaxAttempts {
+			d.Status = models.WebhookDeliveryFailed
+			d.NextAttemptAt = nil
+			log.Printf("[WEBHOOK] 投递 %d (%s -> %s) 失败，已达最大次数: %v", d.ID, d.Event, hook.Name, err)
+		} else {
+			next := now.Add(webhookBackoff(d.Attempts))
+			d.NextAttemptAt = &next
+			log.Printf("[WEBHOOK] 投递 %d (%s -> %s) 失败，%s 后重试: %v", d.ID, d.Event, hook.Name, next.Sub(now).Truncate(time.Second), err)
+		}
+	}
+
+	if saveErr := database.DB.Save(d).Error; saveErr != nil {
+		log.Printf("[WEBHOOK] 保存投递记录 %d 失败: %v", d.ID, saveErr)
+	}
+}
+
+// webhookBackoff 第 n 次失败后的重试间隔
+func webhookBackoff(attempts int) time.Duration {
+	cfg := config.AppConfig.Webhook
+	maxDelay := time.Duration(cfg.RetryMaxSeconds) * time.Second
+	delay := time.Duration(cfg.RetryBaseSeconds) * time.Second
+	for i := 1; i < attempts && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	return delay
+}
+
+// SignWebhookPayload 计算签名：HMAC-SHA256(secret, "<timestamp>.<body>") 的十六进制
+func SignWebhookPayload(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func postWebhook(hook models.Webhook, d models.WebhookDelivery) (int, string, error) {
+	body := []byte(d.Payload)
+	req, err := http.NewRequest("POST", hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", err
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "lxdweb-webhook")
+	req.Header.Set("X-LXDWeb-Event", d.Event)
+	req.Header.Set("X-LXDWeb-Event-ID", d.EventID)
+	req.Header.Set("X-LXDWeb-Delivery", strconv.FormatUint(uint64(d.ID), 10))
+	req.Header.Set("X-LXDWeb-Timestamp", strconv.FormatInt(timestamp, 10))
+	if hook.Secret != "" {
+		req.Header.Set("X-LXDWeb-Signature", "sha256="+SignWebhookPayload(hook.Secret, timestamp, body))
+	}
+
+	client := &http.Client{Timeout: time.Duration(config.AppConfig.Webhook.Timeout) * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, webhookResponseLimit))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, string(respBody), fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return resp.StatusCode, string(respBody), nil
+}
+
+// cleanupWebhookDeliveries 删除超过保留天数的投递记录
+func cleanupWebhookDeliveries() {
+	cutoff := time.Now().AddDate(0, 0, -config.AppConfig.Webhook.DeliveryRetention)
+	result := database.DB.Where("created_at < ? AND status <> ?", cutoff, models.WebhookDeliveryPending).
+		Delete(&models.WebhookDelivery{})
+	if result.Error != nil {
+		log.Printf("[WEBHOOK] 清理投递记录失败: %v", result.Error)
+	} else if result.RowsAffected > 0 {
+		log.Printf("[WEBHOOK] 已清理 %d 条过期投递记录", result.RowsAffected)
+	}
+}
+
+// EmitContainerEvent 发送容器事件，extra 中的字段合并到事件数据
+func EmitContainerEvent(event string, node models.Node, hostname string, extra map[string]interface{}) {
+	data := map[string]interface{}{
+		"node_id":   node.ID,
+		"node_name": node.Name,
+		"hostname":  hostname,
+	}
+	for k, v := range extra {
+		data[k] = v
+	}
+	EmitWebhookEvent(event, data)
+}